@@ -0,0 +1,151 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"github.com/refundable-tgm/huginn/db"
+	"log"
+	"strings"
+	"time"
+)
+
+// APIKeyPrefix starts every API key, so keys can be told apart from access tokens
+const APIKeyPrefix = "huginn_"
+
+// MaxAPIKeyLifetime is the longest time an API key can be valid for
+const MaxAPIKeyLifetime = 2 * 365 * 24 * time.Hour
+
+// lengths of the random parts of an API key in bytes
+const (
+	// apiKeyIDLength is the length of the id of a key
+	apiKeyIDLength = 8
+	// apiKeySecretLength is the length of the secret of a key
+	apiKeySecretLength = 32
+)
+
+// ErrInvalidAPIKey is returned if an API key is unknown, revoked or expired
+var ErrInvalidAPIKey = errors.New("invalid API key")
+
+// ErrInvalidExpiry is returned if an API key should expire in the past or later than MaxAPIKeyLifetime
+var ErrInvalidExpiry = errors.New("API keys have to expire within two years")
+
+// ErrNoServicePrincipal is returned if an API key should be bound to a teacher who isn't a service principal
+var ErrNoServicePrincipal = errors.New("API keys can only be bound to service principals")
+
+// ErrNoEndpoints is returned if an API key should be created without listing the endpoints it may call
+var ErrNoEndpoints = errors.New("API keys have to list the endpoints they may call")
+
+// ErrPrivilegedPrincipal is returned if an API key should be bound to a service principal with super user rights
+// API keys skip the second factor these rights require, so they are never granted to service principals
+var ErrPrivilegedPrincipal = errors.New("service principals can't be super users")
+
+// IsAPIKey checks whether the token presented by a client is an API key and not an access token
+func IsAPIKey(token string) bool {
+	return strings.HasPrefix(token, APIKeyPrefix)
+}
+
+// NewAPIKey creates an API key for the service principal, which has to exist already and mustn't be a super user
+// the key has to list the endpoints it may call
+// returns the stored key and the key itself, which can't be recovered later
+func NewAPIKey(ctx context.Context, key db.APIKey) (db.APIKey, string, error) {
+	if !key.ExpiresAt.After(time.Now()) || time.Until(key.ExpiresAt) > MaxAPIKeyLifetime {
+		return db.APIKey{}, "", ErrInvalidExpiry
+	}
+	if len(key.Endpoints) == 0 {
+		return db.APIKey{}, "", ErrNoEndpoints
+	}
+	mongo := db.MongoDatabaseConnector{}
+	if err := mongo.Connect(ctx); err != nil {
+		return db.APIKey{}, "", err
+	}
+	defer mongo.Close()
+	principal, err := mongo.GetTeacherByShort(key.Principal)
+	if err != nil {
+		return db.APIKey{}, "", err
+	}
+	if principal.Provider != db.ServiceProvider {
+		return db.APIKey{}, "", ErrNoServicePrincipal
+	}
+	if principal.SuperUser {
+		return db.APIKey{}, "", ErrPrivilegedPrincipal
+	}
+	id, err := randomHex(apiKeyIDLength)
+	if err != nil {
+		return db.APIKey{}, "", err
+	}
+	secret, err := randomHex(apiKeySecretLength)
+	if err != nil {
+		return db.APIKey{}, "", err
+	}
+	plain := APIKeyPrefix + id + "_" + secret
+	key.ID = id
+	key.Hash = hashAPIKey(plain)
+	key.CreatedAt = time.Now()
+	key.RevokedAt = time.Time{}
+	key.LastUsed = time.Time{}
+	key.LastUsedIP = ""
+	key.Uses = 0
+	stored, err := mongo.CreateAPIKey(key)
+	if err != nil {
+		return db.APIKey{}, "", err
+	}
+	return stored, plain, nil
+}
+
+// VerifyAPIKey checks the API key presented from the address ip and records its use
+// returns the stored key, or ErrInvalidAPIKey if the key is unknown, revoked or expired or its service principal is deactivated or a super user
+func VerifyAPIKey(ctx context.Context, plain, ip string) (db.APIKey, error) {
+	parts := strings.Split(strings.TrimPrefix(plain, APIKeyPrefix), "_")
+	if !IsAPIKey(plain) || len(parts) != 2 {
+		return db.APIKey{}, ErrInvalidAPIKey
+	}
+	mongo := db.MongoDatabaseConnector{}
+	if err := mongo.Connect(ctx); err != nil {
+		return db.APIKey{}, err
+	}
+	defer mongo.Close()
+	key, err := mongo.GetAPIKey(parts[0])
+	if errors.Is(err, db.ErrNotFound) {
+		return db.APIKey{}, ErrInvalidAPIKey
+	}
+	if err != nil {
+		return db.APIKey{}, err
+	}
+	if subtle.ConstantTimeCompare([]byte(key.Hash), []byte(hashAPIKey(plain))) != 1 ||
+		!key.RevokedAt.IsZero() || !time.Now().Before(key.ExpiresAt) {
+		return db.APIKey{}, ErrInvalidAPIKey
+	}
+	principal, err := mongo.GetTeacherByShort(key.Principal)
+	if errors.Is(err, db.ErrNotFound) || err == nil && (!principal.DeactivatedAt.IsZero() || principal.SuperUser) {
+		return db.APIKey{}, ErrInvalidAPIKey
+	}
+	if err != nil {
+		return db.APIKey{}, err
+	}
+	if err := mongo.RecordAPIKeyUse(key.ID, ip); err != nil {
+		// the usage is only statistics, so a failure doesn't reject the request
+		log.Println("Couldn't record the use of API key ", key.ID, ": ", err)
+	}
+	return key, nil
+}
+
+// PermitsEndpoint checks whether the API key may call the endpoint with the given path (e.g. /getAllApplications)
+// keys listing no endpoints may call none
+func PermitsEndpoint(key db.APIKey, path string) bool {
+	for _, endpoint := range key.Endpoints {
+		if strings.EqualFold(strings.TrimSpace(endpoint), path) {
+			return true
+		}
+	}
+	return false
+}
+
+// hashAPIKey returns the hash an API key is stored as
+// keys are long random strings, so a fast hash is sufficient unlike for passwords
+func hashAPIKey(plain string) string {
+	sum := sha256.Sum256([]byte(plain))
+	return hex.EncodeToString(sum[:])
+}
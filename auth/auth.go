@@ -0,0 +1,161 @@
+// Package auth authenticates users through the configured providers (ldap, OpenID Connect and local accounts)
+// and maps them onto the teachers stored in the database.
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/refundable-tgm/huginn/db"
+	"os"
+	"strings"
+)
+
+// ProvidersEnv is the environment variable listing the enabled providers (comma separated, e.g. ldap,local,oidc)
+// password logins try the enabled providers in the listed order
+const ProvidersEnv = "AUTH_PROVIDERS"
+
+// DefaultProviders are the providers enabled if AUTH_PROVIDERS isn't set
+const DefaultProviders = db.LDAPProvider
+
+// ErrInvalidCredentials is returned if no enabled provider accepts the given credentials
+var ErrInvalidCredentials = errors.New("invalid credentials")
+
+// ErrUnavailable is returned if a provider needed for the login can't be reached
+var ErrUnavailable = errors.New("authentication provider unavailable")
+
+// ErrUnknownProvider is returned if a provider isn't known or isn't enabled
+var ErrUnknownProvider = errors.New("unknown authentication provider")
+
+// ErrInvalidConfig is returned if the configuration of the providers is invalid
+var ErrInvalidConfig = errors.New("invalid authentication configuration")
+
+// Provider authenticates users and maps them onto teachers
+type Provider interface {
+	// Name identifies the provider in AUTH_PROVIDERS and in the provider of teachers
+	Name() string
+}
+
+// PasswordProvider is a provider verifying a username and a password
+type PasswordProvider interface {
+	Provider
+	// Authenticate verifies the credentials and returns the teacher they belong to, the teacher is created on its first login
+	// returns ErrInvalidCredentials if the provider doesn't accept the credentials
+	Authenticate(ctx context.Context, username, password string) (db.Teacher, error)
+}
+
+// RedirectProvider is a provider authenticating users on an external login page the users are redirected to
+type RedirectProvider interface {
+	Provider
+	// AuthURL returns the url of the external login page, state and nonce have to be returned to the callback
+	AuthURL(state, nonce string) (string, error)
+	// Callback exchanges the code returned by the login page and returns the teacher it belongs to, the teacher is created on its first login
+	// returns ErrInvalidCredentials if the code is invalid or doesn't belong to the nonce
+	Callback(ctx context.Context, code, nonce string) (db.Teacher, error)
+}
+
+// Providers returns the providers enabled by AUTH_PROVIDERS in the configured order
+func Providers() ([]Provider, error) {
+	providers := make([]Provider, 0)
+	for _, name := range strings.Split(envOr(ProvidersEnv, DefaultProviders), ",") {
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "":
+			continue
+		case db.LDAPProvider:
+			providers = append(providers, ldapProvider{})
+		case db.LocalProvider:
+			providers = append(providers, localProvider{})
+		case db.OIDCProvider:
+			provider, err := newOIDCProvider()
+			if err != nil {
+				return nil, err
+			}
+			providers = append(providers, provider)
+		default:
+			return nil, fmt.Errorf("%w: %v contains the unknown provider %v", ErrInvalidConfig, ProvidersEnv, name)
+		}
+	}
+	if len(providers) == 0 {
+		return nil, fmt.Errorf("%w: %v doesn't enable any provider", ErrInvalidConfig, ProvidersEnv)
+	}
+	return providers, nil
+}
+
+// Names returns the names of the enabled providers
+func Names() ([]string, error) {
+	providers, err := Providers()
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(providers))
+	for _, provider := range providers {
+		names = append(names, provider.Name())
+	}
+	return names, nil
+}
+
+// Authenticate verifies username and password sent from the address ip with every enabled password provider until one accepts them
+// logins of throttled usernames or addresses are rejected with a *ThrottledError before any provider is asked
+// returns ErrInvalidCredentials if none accepts them, or ErrUnavailable if a provider which couldn't be reached might have accepted them
+func Authenticate(ctx context.Context, username, password, ip string) (db.Teacher, error) {
+	providers, err := Providers()
+	if err != nil {
+		return db.Teacher{}, err
+	}
+	if err := Allow(username, ip); err != nil {
+		return db.Teacher{}, err
+	}
+	teacher, err := authenticate(ctx, providers, username, password)
+	if errors.Is(err, ErrInvalidCredentials) {
+		Fail(ctx, username, ip, "invalid credentials")
+	} else {
+		Release(username, ip)
+	}
+	return teacher, err
+}
+
+// authenticate verifies username and password with every password provider until one accepts them
+func authenticate(ctx context.Context, providers []Provider, username, password string) (db.Teacher, error) {
+	var unavailable error
+	for _, provider := range providers {
+		p, ok := provider.(PasswordProvider)
+		if !ok {
+			continue
+		}
+		teacher, err := p.Authenticate(ctx, username, password)
+		if errors.Is(err, ErrInvalidCredentials) {
+			continue
+		}
+		if errors.Is(err, ErrUnavailable) {
+			unavailable = err
+			continue
+		}
+		return teacher, err
+	}
+	if unavailable != nil {
+		return db.Teacher{}, unavailable
+	}
+	return db.Teacher{}, ErrInvalidCredentials
+}
+
+// redirectProvider returns the enabled redirect provider with the given name
+func redirectProvider(name string) (RedirectProvider, error) {
+	providers, err := Providers()
+	if err != nil {
+		return nil, err
+	}
+	for _, provider := range providers {
+		if p, ok := provider.(RedirectProvider); ok && p.Name() == name {
+			return p, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %v", ErrUnknownProvider, name)
+}
+
+// envOr returns the value of the environment variable, or fallback if it isn't set
+func envOr(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
@@ -0,0 +1,37 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/refundable-tgm/huginn/db"
+	"github.com/refundable-tgm/huginn/ldap"
+)
+
+// ldapProvider authenticates teachers with their directory account
+type ldapProvider struct{}
+
+// Name returns db.LDAPProvider
+func (ldapProvider) Name() string {
+	return db.LDAPProvider
+}
+
+// Authenticate binds to the ldap server with the credentials and returns the teacher synchronized with the directory
+func (ldapProvider) Authenticate(ctx context.Context, username, password string) (db.Teacher, error) {
+	err := ldap.AuthenticateUserCredentials(ctx, username, password)
+	if errors.Is(err, ldap.ErrInvalidCredentials) {
+		return db.Teacher{}, ErrInvalidCredentials
+	}
+	if errors.Is(err, ldap.ErrUnavailable) {
+		return db.Teacher{}, fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+	if err != nil {
+		return db.Teacher{}, err
+	}
+	mongo := db.MongoDatabaseConnector{}
+	if err := mongo.Connect(ctx); err != nil {
+		return db.Teacher{}, err
+	}
+	defer mongo.Close()
+	return mongo.GetTeacherByShort(username)
+}
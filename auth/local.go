@@ -0,0 +1,124 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"github.com/refundable-tgm/huginn/db"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+	"strings"
+)
+
+// PasswordHashEnv is the environment variable selecting the algorithm new passwords are hashed with (bcrypt or argon2id)
+// passwords hashed with either algorithm can always be verified
+const PasswordHashEnv = "AUTH_PASSWORD_HASH"
+
+// MinPasswordLength is the minimal length of the passwords of local accounts
+const MinPasswordLength = 10
+
+// Algorithms passwords can be hashed with
+const (
+	// Bcrypt hashes passwords with bcrypt, it is used if AUTH_PASSWORD_HASH isn't set
+	Bcrypt = "bcrypt"
+	// Argon2id hashes passwords with argon2id
+	Argon2id = "argon2id"
+)
+
+// parameters of argon2id as recommended by OWASP
+const (
+	argon2Time    = 2
+	argon2Memory  = 19 * 1024
+	argon2Threads = 1
+	argon2KeyLen  = 32
+	argon2SaltLen = 16
+)
+
+// ErrWeakPassword is returned if a password is shorter than MinPasswordLength
+var ErrWeakPassword = fmt.Errorf("passwords have to be at least %v characters long", MinPasswordLength)
+
+// dummyHash is compared against if a local account doesn't exist, so unknown users take as long as wrong passwords
+var dummyHash, _ = bcrypt.GenerateFromPassword([]byte("huginn"), bcrypt.DefaultCost)
+
+// localProvider authenticates teachers with a password stored in huginn
+type localProvider struct{}
+
+// Name returns db.LocalProvider
+func (localProvider) Name() string {
+	return db.LocalProvider
+}
+
+// Authenticate compares the password with the hash stored for the local account with the given username
+func (localProvider) Authenticate(ctx context.Context, username, password string) (db.Teacher, error) {
+	mongo := db.MongoDatabaseConnector{}
+	if err := mongo.Connect(ctx); err != nil {
+		return db.Teacher{}, err
+	}
+	defer mongo.Close()
+	teacher, err := mongo.GetTeacherByShort(strings.ToLower(strings.TrimSpace(username)))
+	if err != nil && !errors.Is(err, db.ErrNotFound) {
+		return db.Teacher{}, err
+	}
+	if err != nil || teacher.Provider != db.LocalProvider || teacher.PasswordHash == "" {
+		_ = bcrypt.CompareHashAndPassword(dummyHash, []byte(password))
+		return db.Teacher{}, ErrInvalidCredentials
+	}
+	if !VerifyPassword(teacher.PasswordHash, password) {
+		return db.Teacher{}, ErrInvalidCredentials
+	}
+	return teacher, nil
+}
+
+// HashPassword hashes the password with the algorithm configured by AUTH_PASSWORD_HASH
+// returns ErrWeakPassword if the password is too short
+func HashPassword(password string) (string, error) {
+	if len([]rune(password)) < MinPasswordLength {
+		return "", ErrWeakPassword
+	}
+	switch strings.ToLower(envOr(PasswordHashEnv, Bcrypt)) {
+	case Bcrypt:
+		hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+		return string(hash), err
+	case Argon2id:
+		salt := make([]byte, argon2SaltLen)
+		if _, err := rand.Read(salt); err != nil {
+			return "", err
+		}
+		key := argon2.IDKey([]byte(password), salt, argon2Time, argon2Memory, argon2Threads, argon2KeyLen)
+		return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2.Version, argon2Memory, argon2Time, argon2Threads,
+			base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+	}
+	return "", fmt.Errorf("%w: %v has to be %v or %v", ErrInvalidConfig, PasswordHashEnv, Bcrypt, Argon2id)
+}
+
+// VerifyPassword checks whether the password matches the hash, which was created with bcrypt or argon2id
+func VerifyPassword(hash, password string) bool {
+	if !strings.HasPrefix(hash, "$argon2id$") {
+		return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+	}
+	// $argon2id$v=19$m=19456,t=2,p=1$<salt>$<key>
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 {
+		return false
+	}
+	var version, memory, time, threads int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return false
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &memory, &time, &threads); err != nil || memory <= 0 || time <= 0 || threads <= 0 || threads > 255 {
+		return false
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return false
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return false
+	}
+	computed := argon2.IDKey([]byte(password), salt, uint32(time), uint32(memory), uint8(threads), uint32(len(key)))
+	return subtle.ConstantTimeCompare(key, computed) == 1
+}
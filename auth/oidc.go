@@ -0,0 +1,427 @@
+package auth
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/dgrijalva/jwt-go"
+	"github.com/google/uuid"
+	"github.com/refundable-tgm/huginn/db"
+	"github.com/refundable-tgm/huginn/untis"
+	"io/ioutil"
+	"log"
+	"math/big"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Environment variables configuring the OpenID Connect provider
+const (
+	// OIDCIssuerEnv contains the issuer url of the identity provider, e.g. https://login.microsoftonline.com/<tenant>/v2.0
+	OIDCIssuerEnv = "OIDC_ISSUER"
+	// OIDCClientIDEnv contains the client id huginn is registered with at the identity provider
+	OIDCClientIDEnv = "OIDC_CLIENT_ID"
+	// OIDCClientSecretFileEnv contains the path to the file holding the client secret
+	OIDCClientSecretFileEnv = "OIDC_CLIENT_SECRET_FILE"
+	// OIDCRedirectURLEnv contains the url the identity provider redirects to after the login, it has to pass code and state to the callback endpoint
+	OIDCRedirectURLEnv = "OIDC_REDIRECT_URL"
+	// OIDCScopesEnv contains the requested scopes (space separated)
+	OIDCScopesEnv = "OIDC_SCOPES"
+	// OIDCUsernameClaimEnv contains the claim of the id token holding the short name of the teacher followed by @ and its domain
+	OIDCUsernameClaimEnv = "OIDC_USERNAME_CLAIM"
+	// OIDCDomainsEnv contains the domains (comma separated) usernames of the identity provider have to belong to, e.g. tgm.ac.at
+	// accounts of other domains, e.g. guests of the tenant, are rejected
+	OIDCDomainsEnv = "OIDC_DOMAINS"
+)
+
+// RequestTimeout is the maximum time a request to the identity provider may take
+const RequestTimeout = 15 * time.Second
+
+// discoveryTTL is the time the configuration and keys of an identity provider are cached
+const discoveryTTL = time.Hour
+
+// HTTPClient is used for all requests to the identity provider
+var HTTPClient = &http.Client{Timeout: RequestTimeout}
+
+// discovery is the part of the configuration of an identity provider huginn needs
+type discovery struct {
+	// Issuer has to match the configured issuer
+	Issuer string `json:"issuer"`
+	// AuthorizationEndpoint is the login page users are redirected to
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	// TokenEndpoint exchanges codes for tokens
+	TokenEndpoint string `json:"token_endpoint"`
+	// JWKSURI lists the keys id tokens are signed with
+	JWKSURI string `json:"jwks_uri"`
+}
+
+// issuerCache holds the configuration and the keys of identity providers by their issuer
+var issuerCache = struct {
+	sync.Mutex
+	entries map[string]issuerEntry
+}{entries: make(map[string]issuerEntry)}
+
+// issuerEntry is the cached configuration and keys of an identity provider
+type issuerEntry struct {
+	// discovery is the configuration of the identity provider
+	discovery discovery
+	// keys are the public keys by their key id
+	keys map[string]*rsa.PublicKey
+	// fetched is the time the entry was loaded at
+	fetched time.Time
+}
+
+// oidcProvider authenticates teachers using the authorization code flow of OpenID Connect
+type oidcProvider struct {
+	// issuer url of the identity provider
+	issuer string
+	// clientID huginn is registered with
+	clientID string
+	// clientSecret belonging to the client id
+	clientSecret string
+	// redirectURL the identity provider redirects to
+	redirectURL string
+	// scopes requested
+	scopes string
+	// usernameClaim holds the short name of the teacher
+	usernameClaim string
+	// domains are the domains usernames have to belong to
+	domains map[string]bool
+}
+
+// newOIDCProvider reads the configuration of the OpenID Connect provider from the environment
+func newOIDCProvider() (oidcProvider, error) {
+	p := oidcProvider{
+		issuer:        strings.TrimSuffix(os.Getenv(OIDCIssuerEnv), "/"),
+		clientID:      os.Getenv(OIDCClientIDEnv),
+		redirectURL:   os.Getenv(OIDCRedirectURLEnv),
+		scopes:        envOr(OIDCScopesEnv, "openid profile email"),
+		usernameClaim: envOr(OIDCUsernameClaimEnv, "preferred_username"),
+		domains:       make(map[string]bool),
+	}
+	for _, domain := range strings.Split(os.Getenv(OIDCDomainsEnv), ",") {
+		if domain = strings.ToLower(strings.TrimSpace(domain)); domain != "" {
+			p.domains[domain] = true
+		}
+	}
+	if p.issuer == "" || p.clientID == "" || p.redirectURL == "" || len(p.domains) == 0 {
+		return p, fmt.Errorf("%w: %v, %v, %v and %v are required for oidc", ErrInvalidConfig, OIDCIssuerEnv, OIDCClientIDEnv, OIDCRedirectURLEnv, OIDCDomainsEnv)
+	}
+	if file := os.Getenv(OIDCClientSecretFileEnv); file != "" {
+		secret, err := ioutil.ReadFile(file)
+		if err != nil {
+			return p, err
+		}
+		p.clientSecret = strings.TrimSpace(string(secret))
+	}
+	return p, nil
+}
+
+// Name returns db.OIDCProvider
+func (oidcProvider) Name() string {
+	return db.OIDCProvider
+}
+
+// AuthURL returns the url of the login page of the identity provider
+func (p oidcProvider) AuthURL(state, nonce string) (string, error) {
+	entry, err := p.load(false)
+	if err != nil {
+		return "", err
+	}
+	u, err := url.Parse(entry.discovery.AuthorizationEndpoint)
+	if err != nil {
+		return "", fmt.Errorf("%w: invalid authorization endpoint: %v", ErrUnavailable, err)
+	}
+	query := u.Query()
+	query.Set("response_type", "code")
+	query.Set("client_id", p.clientID)
+	query.Set("redirect_uri", p.redirectURL)
+	query.Set("scope", p.scopes)
+	query.Set("state", state)
+	query.Set("nonce", nonce)
+	u.RawQuery = query.Encode()
+	return u.String(), nil
+}
+
+// Callback exchanges the code for an id token, verifies it and returns the teacher it belongs to
+func (p oidcProvider) Callback(ctx context.Context, code, nonce string) (db.Teacher, error) {
+	raw, err := p.exchange(code)
+	if err != nil {
+		return db.Teacher{}, err
+	}
+	claims, err := p.verify(raw, nonce)
+	if err != nil {
+		return db.Teacher{}, err
+	}
+	username, err := p.claimedUsername(claims)
+	if err != nil {
+		return db.Teacher{}, err
+	}
+	subject, _ := claims["sub"].(string)
+	if subject == "" {
+		return db.Teacher{}, fmt.Errorf("%w: the id token doesn't contain the claim sub", ErrInvalidCredentials)
+	}
+	mongo := db.MongoDatabaseConnector{}
+	if err := mongo.Connect(ctx); err != nil {
+		return db.Teacher{}, err
+	}
+	defer mongo.Close()
+	longname := claimedName(claims)
+	mail, _ := claims["email"].(string)
+	teacher, err := mongo.GetTeacherByShort(username)
+	if err == nil {
+		if teacher.Provider == db.LocalProvider || teacher.Provider == db.ServiceProvider {
+			// local accounts and service principals can't be taken over by an account of the identity provider with the same name
+			return db.Teacher{}, ErrInvalidCredentials
+		}
+		if teacher.OIDCSubject != "" && teacher.OIDCSubject != subject {
+			// the teacher is bound to another account of the identity provider, e.g. the short name was given to someone else
+			return db.Teacher{}, ErrInvalidCredentials
+		}
+		update := teacher.OIDCSubject == ""
+		teacher.OIDCSubject = subject
+		if teacher.Provider == db.OIDCProvider && (longname != "" && longname != teacher.Longname || mail != "" && mail != teacher.Mail) {
+			// teachers of other providers are synchronized by them, so only the ones created here are updated
+			if longname != "" {
+				teacher.Longname = longname
+			}
+			if mail != "" {
+				teacher.Mail = mail
+			}
+			update = true
+		}
+		if update {
+			if err := mongo.UpdateTeacher(teacher.UUID, teacher); err != nil {
+				log.Println("Couldn't update ", username, " from the id token: ", err)
+			}
+		}
+		return teacher, nil
+	}
+	if !errors.Is(err, db.ErrNotFound) {
+		return db.Teacher{}, err
+	}
+	teacher = db.Teacher{
+		UUID:        uuid.NewString(),
+		Short:       username,
+		Longname:    longname,
+		Mail:        mail,
+		Provider:    db.OIDCProvider,
+		Untis:       untisName(username, longname),
+		OIDCSubject: subject,
+	}
+	return mongo.CreateTeacher(teacher)
+}
+
+// exchange redeems the code at the token endpoint and returns the raw id token
+func (p oidcProvider) exchange(code string) (string, error) {
+	entry, err := p.load(false)
+	if err != nil {
+		return "", err
+	}
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", p.redirectURL)
+	form.Set("client_id", p.clientID)
+	if p.clientSecret != "" {
+		form.Set("client_secret", p.clientSecret)
+	}
+	res, err := HTTPClient.PostForm(entry.discovery.TokenEndpoint, form)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+	defer res.Body.Close()
+	body := struct {
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}{}
+	if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("%w: invalid token response: %v", ErrUnavailable, err)
+	}
+	if body.Error == "invalid_grant" {
+		return "", fmt.Errorf("%w: %v", ErrInvalidCredentials, body.ErrorDescription)
+	}
+	if body.Error != "" || res.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("token endpoint returned %v: %v %v", res.StatusCode, body.Error, body.ErrorDescription)
+	}
+	if body.IDToken == "" {
+		return "", fmt.Errorf("token endpoint didn't return an id token")
+	}
+	return body.IDToken, nil
+}
+
+// verify checks the signature, issuer, audience, expiry and nonce of the id token and returns its claims
+func (p oidcProvider) verify(raw, nonce string) (jwt.MapClaims, error) {
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(raw, claims, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodRSA); !ok {
+			return nil, fmt.Errorf("unexpected signing method %v", token.Header["alg"])
+		}
+		kid, _ := token.Header["kid"].(string)
+		entry, err := p.load(false)
+		if err != nil {
+			return nil, err
+		}
+		if key, ok := entry.keys[kid]; ok {
+			return key, nil
+		}
+		// the identity provider might have rotated its keys
+		if entry, err = p.load(true); err != nil {
+			return nil, err
+		}
+		if key, ok := entry.keys[kid]; ok {
+			return key, nil
+		}
+		return nil, fmt.Errorf("unknown key %v", kid)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid id token: %v", ErrInvalidCredentials, err)
+	}
+	if !claims.VerifyExpiresAt(time.Now().Unix(), true) {
+		return nil, fmt.Errorf("%w: the id token has no expiry", ErrInvalidCredentials)
+	}
+	if iss, _ := claims["iss"].(string); strings.TrimSuffix(iss, "/") != p.issuer {
+		return nil, fmt.Errorf("%w: the id token was issued by %v", ErrInvalidCredentials, iss)
+	}
+	if !audienceContains(claims["aud"], p.clientID) {
+		return nil, fmt.Errorf("%w: the id token wasn't issued for %v", ErrInvalidCredentials, p.clientID)
+	}
+	if n, _ := claims["nonce"].(string); n != nonce {
+		return nil, fmt.Errorf("%w: the nonce of the id token doesn't match", ErrInvalidCredentials)
+	}
+	return claims, nil
+}
+
+// load returns the cached configuration and keys of the identity provider, they are fetched if they expired or refresh is set
+func (p oidcProvider) load(refresh bool) (issuerEntry, error) {
+	issuerCache.Lock()
+	defer issuerCache.Unlock()
+	entry, ok := issuerCache.entries[p.issuer]
+	if ok && !refresh && time.Since(entry.fetched) < discoveryTTL {
+		return entry, nil
+	}
+	entry = issuerEntry{fetched: time.Now()}
+	if err := getJSON(p.issuer+"/.well-known/openid-configuration", &entry.discovery); err != nil {
+		return entry, err
+	}
+	if strings.TrimSuffix(entry.discovery.Issuer, "/") != p.issuer {
+		return entry, fmt.Errorf("%w: the identity provider identifies as %v", ErrInvalidConfig, entry.discovery.Issuer)
+	}
+	keys := struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+		} `json:"keys"`
+	}{}
+	if err := getJSON(entry.discovery.JWKSURI, &keys); err != nil {
+		return entry, err
+	}
+	entry.keys = make(map[string]*rsa.PublicKey)
+	for _, key := range keys.Keys {
+		if key.Kty != "RSA" {
+			continue
+		}
+		n, err := base64.RawURLEncoding.DecodeString(key.N)
+		if err != nil {
+			continue
+		}
+		e, err := base64.RawURLEncoding.DecodeString(key.E)
+		if err != nil {
+			continue
+		}
+		entry.keys[key.Kid] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+	}
+	issuerCache.entries[p.issuer] = entry
+	return entry, nil
+}
+
+// getJSON fetches the json document at the url into v
+func getJSON(u string, v interface{}) error {
+	res, err := HTTPClient.Get(u)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("%w: %v returned %v", ErrUnavailable, u, res.Status)
+	}
+	if err := json.NewDecoder(res.Body).Decode(v); err != nil {
+		return fmt.Errorf("%w: invalid response of %v: %v", ErrUnavailable, u, err)
+	}
+	return nil
+}
+
+// audienceContains checks whether the aud claim, a string or a list of strings, contains the client id
+func audienceContains(aud interface{}, clientID string) bool {
+	switch a := aud.(type) {
+	case string:
+		return a == clientID
+	case []interface{}:
+		for _, value := range a {
+			if value == clientID {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// claimedUsername returns the short name of the teacher contained in the username claim, which has to end with @ and one of the configured domains
+// the domain is dropped from the short name
+func (p oidcProvider) claimedUsername(claims jwt.MapClaims) (string, error) {
+	claim, _ := claims[p.usernameClaim].(string)
+	claim = strings.ToLower(strings.TrimSpace(claim))
+	i := strings.LastIndex(claim, "@")
+	if i <= 0 {
+		return "", fmt.Errorf("%w: the id token doesn't contain the claim %v with a domain", ErrInvalidCredentials, p.usernameClaim)
+	}
+	if !p.domains[claim[i+1:]] {
+		return "", fmt.Errorf("%w: the domain %v isn't allowed by %v", ErrInvalidCredentials, claim[i+1:], OIDCDomainsEnv)
+	}
+	return claim[:i], nil
+}
+
+// claimedName returns the full name (first name + last name) contained in the claims
+func claimedName(claims jwt.MapClaims) string {
+	given, _ := claims["given_name"].(string)
+	family, _ := claims["family_name"].(string)
+	if given != "" && family != "" {
+		return given + " " + family
+	}
+	name, _ := claims["name"].(string)
+	return name
+}
+
+// untisName looks up the untis abbreviation of the teacher with the given full name
+// it is only possible with the untis service account, otherwise the teacher has to set it afterwards
+func untisName(username, longname string) string {
+	if longname == "" || !untis.HasServiceAccount() {
+		return ""
+	}
+	client, err := untis.ClientFor(username)
+	if err != nil {
+		log.Println("Couldn't look up the untis name of ", username, ": ", err)
+		return ""
+	}
+	id, err := client.ResolveTeacherID(longname)
+	if err != nil {
+		log.Println("Couldn't look up the untis name of ", username, ": ", err)
+		return ""
+	}
+	names, err := client.ResolveTeachers([]int{id})
+	if err != nil || len(names) == 0 {
+		log.Println("Couldn't look up the untis name of ", username, ": ", err)
+		return ""
+	}
+	return names[0]
+}
@@ -0,0 +1,331 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"github.com/dgrijalva/jwt-go"
+	"github.com/refundable-tgm/huginn/auth/oidctest"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"testing"
+	"time"
+)
+
+// redirectURL is the callback url the tests register at the mock issuer
+const redirectURL = "https://refundable.tgm.ac.at/api/finishLogin/oidc"
+
+// setenv sets the environment variable for the duration of the test
+func setenv(t *testing.T, key, value string) {
+	previous, ok := os.LookupEnv(key)
+	if err := os.Setenv(key, value); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if ok {
+			_ = os.Setenv(key, previous)
+		} else {
+			_ = os.Unsetenv(key)
+		}
+	})
+}
+
+// startIssuer starts a mock issuer with the fixtures of oidctest and configures the oidc provider to use it
+func startIssuer(t *testing.T) (oidcProvider, oidctest.Fixtures) {
+	fixtures, err := oidctest.LoadFixtures("oidctest/testdata/fixtures.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server, err := oidctest.NewServer(fixtures)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(server.Close)
+	secret, err := ioutil.TempFile(t.TempDir(), "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := secret.WriteString(fixtures.ClientSecret + "\n"); err != nil {
+		t.Fatal(err)
+	}
+	_ = secret.Close()
+	setenv(t, ProvidersEnv, "oidc")
+	setenv(t, OIDCIssuerEnv, server.URL+"/")
+	setenv(t, OIDCClientIDEnv, fixtures.ClientID)
+	setenv(t, OIDCClientSecretFileEnv, secret.Name())
+	setenv(t, OIDCRedirectURLEnv, redirectURL)
+	setenv(t, OIDCDomainsEnv, "tgm.ac.at, TGM.at")
+	p, err := newOIDCProvider()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p, fixtures
+}
+
+// logIn opens the login page as the user with the given login hint and returns the parameters of the redirect back to huginn
+func logIn(t *testing.T, authURL, hint string) url.Values {
+	u, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	query := u.Query()
+	query.Set("login_hint", hint)
+	u.RawQuery = query.Encode()
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	res, err := client.Get(u.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusFound {
+		t.Fatalf("login page returned %v, want a redirect", res.Status)
+	}
+	location, err := url.Parse(res.Header.Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := location.Scheme + "://" + location.Host + location.Path; got != redirectURL {
+		t.Fatalf("redirected to %v, want %v", got, redirectURL)
+	}
+	return location.Query()
+}
+
+func TestRedirectFlow(t *testing.T) {
+	p, fixtures := startIssuer(t)
+	authURL, err := BeginLogin("oidc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	query := u.Query()
+	for key, want := range map[string]string{
+		"response_type": "code",
+		"client_id":     fixtures.ClientID,
+		"redirect_uri":  redirectURL,
+		"scope":         "openid profile email",
+	} {
+		if got := query.Get(key); got != want {
+			t.Errorf("%v = %q, want %q", key, got, want)
+		}
+	}
+	state, nonce := query.Get("state"), query.Get("nonce")
+	if state == "" || nonce == "" || state == nonce {
+		t.Fatalf("state %q and nonce %q have to be distinct random values", state, nonce)
+	}
+	pending.Lock()
+	login, ok := pending.logins[state]
+	pending.Unlock()
+	if !ok || login.nonce != nonce || login.provider != "oidc" {
+		t.Fatalf("login wasn't recorded for the state: %+v", login)
+	}
+
+	callback := logIn(t, authURL, "szakall@tgm.ac.at")
+	if got := callback.Get("state"); got != state {
+		t.Fatalf("state = %q, want %q", got, state)
+	}
+	raw, err := p.exchange(callback.Get("code"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims, err := p.verify(raw, nonce)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if username, err := p.claimedUsername(claims); err != nil || username != "szakall" {
+		t.Errorf("claimedUsername() = %q, %v, want szakall", username, err)
+	}
+	if name := claimedName(claims); name != "Stefan Zakall" {
+		t.Errorf("claimedName() = %q, want Stefan Zakall", name)
+	}
+	if _, err := p.exchange(callback.Get("code")); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("redeeming a code twice returned %v, want ErrInvalidCredentials", err)
+	}
+}
+
+func TestFinishLoginState(t *testing.T) {
+	startIssuer(t)
+	tests := []struct {
+		name  string
+		state func(t *testing.T) string
+	}{
+		{"unknown state", func(t *testing.T) string {
+			return "0123456789abcdef0123456789abcdef"
+		}},
+		{"expired state", func(t *testing.T) string {
+			authURL, err := BeginLogin("oidc")
+			if err != nil {
+				t.Fatal(err)
+			}
+			u, _ := url.Parse(authURL)
+			state := u.Query().Get("state")
+			pending.Lock()
+			login := pending.logins[state]
+			login.expires = time.Now().Add(-time.Second)
+			pending.logins[state] = login
+			pending.Unlock()
+			return state
+		}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			state := test.state(t)
+			if _, err := FinishLogin(context.Background(), state, "code"); !errors.Is(err, ErrInvalidCredentials) {
+				t.Errorf("FinishLogin() returned %v, want ErrInvalidCredentials", err)
+			}
+			pending.Lock()
+			_, ok := pending.logins[state]
+			pending.Unlock()
+			if ok {
+				t.Error("the state wasn't dropped")
+			}
+		})
+	}
+}
+
+func TestVerify(t *testing.T) {
+	p, _ := startIssuer(t)
+	other, _ := startIssuer(t)
+	tests := []struct {
+		name   string
+		issuer oidcProvider
+		nonce  string
+		verify func(p oidcProvider) oidcProvider
+		valid  bool
+	}{
+		{name: "valid", issuer: p, nonce: "nonce", valid: true},
+		{name: "other nonce", issuer: p, nonce: "other", valid: false},
+		{name: "missing nonce", issuer: p, nonce: "", valid: false},
+		{name: "other audience", issuer: p, nonce: "nonce", valid: false, verify: func(p oidcProvider) oidcProvider {
+			p.clientID = "other"
+			return p
+		}},
+		{name: "other issuer", issuer: other, nonce: "nonce", valid: false, verify: func(oidcProvider) oidcProvider {
+			return p
+		}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			authURL, err := test.issuer.AuthURL("state", "nonce")
+			if err != nil {
+				t.Fatal(err)
+			}
+			raw, err := test.issuer.exchange(logIn(t, authURL, "mborko@tgm.ac.at").Get("code"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			verifier := test.issuer
+			if test.verify != nil {
+				verifier = test.verify(test.issuer)
+			}
+			_, err = verifier.verify(raw, test.nonce)
+			if test.valid && err != nil {
+				t.Errorf("verify() returned %v, want the token to be valid", err)
+			}
+			if !test.valid && !errors.Is(err, ErrInvalidCredentials) {
+				t.Errorf("verify() returned %v, want ErrInvalidCredentials", err)
+			}
+		})
+	}
+}
+
+func TestClaimMapping(t *testing.T) {
+	domains := map[string]bool{"tgm.ac.at": true}
+	tests := []struct {
+		name     string
+		claim    string
+		claims   jwt.MapClaims
+		username string
+		longname string
+	}{
+		{
+			name:     "preferred username with domain",
+			claim:    "preferred_username",
+			claims:   jwt.MapClaims{"preferred_username": " SZakall@TGM.ac.at ", "given_name": "Stefan", "family_name": "Zakall", "name": "Zakall Stefan"},
+			username: "szakall",
+			longname: "Stefan Zakall",
+		},
+		{
+			name:     "other claim",
+			claim:    "email",
+			claims:   jwt.MapClaims{"email": "ehuber@tgm.ac.at", "name": "Eva Huber"},
+			username: "ehuber",
+			longname: "Eva Huber",
+		},
+		{
+			name:     "only the family name",
+			claim:    "preferred_username",
+			claims:   jwt.MapClaims{"preferred_username": "mborko@tgm.ac.at", "family_name": "Borko", "name": "Michael Borko"},
+			username: "mborko",
+			longname: "Michael Borko",
+		},
+		{
+			name:   "foreign domain",
+			claim:  "preferred_username",
+			claims: jwt.MapClaims{"preferred_username": "szakall@evil.example"},
+		},
+		{
+			name:   "subdomain of an allowed domain",
+			claim:  "preferred_username",
+			claims: jwt.MapClaims{"preferred_username": "szakall@evil.tgm.ac.at"},
+		},
+		{
+			name:   "allowed domain inside a foreign one",
+			claim:  "preferred_username",
+			claims: jwt.MapClaims{"preferred_username": "szakall@tgm.ac.at@evil.example"},
+		},
+		{
+			name:   "without domain",
+			claim:  "preferred_username",
+			claims: jwt.MapClaims{"preferred_username": "szakall"},
+		},
+		{
+			name:   "only the domain",
+			claim:  "preferred_username",
+			claims: jwt.MapClaims{"preferred_username": "@tgm.ac.at"},
+		},
+		{
+			name:   "missing claim",
+			claim:  "preferred_username",
+			claims: jwt.MapClaims{"email": "mborko@tgm.ac.at"},
+		},
+		{
+			name:   "claim of another type",
+			claim:  "preferred_username",
+			claims: jwt.MapClaims{"preferred_username": 42},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			p := oidcProvider{usernameClaim: test.claim, domains: domains}
+			username, err := p.claimedUsername(test.claims)
+			if test.username == "" {
+				if !errors.Is(err, ErrInvalidCredentials) {
+					t.Errorf("claimedUsername() = %q, %v, want ErrInvalidCredentials", username, err)
+				}
+				return
+			}
+			if err != nil || username != test.username {
+				t.Errorf("claimedUsername() = %q, %v, want %q", username, err, test.username)
+			}
+			if longname := claimedName(test.claims); longname != test.longname {
+				t.Errorf("claimedName() = %q, want %q", longname, test.longname)
+			}
+		})
+	}
+}
+
+func TestDomainsRequired(t *testing.T) {
+	startIssuer(t)
+	for _, domains := range []string{"", " , "} {
+		setenv(t, OIDCDomainsEnv, domains)
+		if _, err := newOIDCProvider(); !errors.Is(err, ErrInvalidConfig) {
+			t.Errorf("newOIDCProvider() with %v=%q returned %v, want ErrInvalidConfig", OIDCDomainsEnv, domains, err)
+		}
+	}
+}
@@ -0,0 +1,274 @@
+// Package oidctest provides a mock OpenID Connect issuer supporting the authorization code flow,
+// so logins through OpenID Connect can be tried without a real identity provider.
+package oidctest
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/dgrijalva/jwt-go"
+	"html/template"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// KeyID is the key id of the key the mock signs id tokens with
+const KeyID = "oidctest"
+
+// TokenLifetime is the time id tokens issued by the mock are valid
+const TokenLifetime = time.Hour
+
+// Fixtures is the data served by the mock
+type Fixtures struct {
+	// ClientID the client has to authenticate with
+	ClientID string `json:"clientId"`
+	// ClientSecret the client has to authenticate with, it isn't checked if empty
+	ClientSecret string `json:"clientSecret"`
+	// Users which can log in
+	Users []User `json:"users"`
+}
+
+// User is an account of the mock
+type User struct {
+	// Subject identifies the user at the issuer
+	Subject string `json:"sub"`
+	// PreferredUsername of the user, e.g. szakall@tgm.ac.at
+	PreferredUsername string `json:"preferred_username"`
+	// GivenName of the user
+	GivenName string `json:"given_name"`
+	// FamilyName of the user
+	FamilyName string `json:"family_name"`
+	// Email address of the user
+	Email string `json:"email"`
+}
+
+// LoadFixtures reads fixtures from the json file at path
+func LoadFixtures(path string) (Fixtures, error) {
+	fixtures := Fixtures{}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return fixtures, err
+	}
+	if err := json.Unmarshal(data, &fixtures); err != nil {
+		return fixtures, fmt.Errorf("invalid fixtures %v: %v", path, err)
+	}
+	return fixtures, nil
+}
+
+// grant is a code issued by the authorization endpoint which wasn't redeemed yet
+type grant struct {
+	// user who logged in
+	user User
+	// nonce passed to the authorization endpoint
+	nonce string
+	// redirectURI passed to the authorization endpoint
+	redirectURI string
+}
+
+// Issuer is a http handler implementing the discovery, authorization, token and key endpoints of an OpenID Connect issuer
+// the authorization endpoint logs in the user given by login_hint immediately, without it a page listing all users is shown
+type Issuer struct {
+	// url the issuer is reachable at
+	url string
+	// fixtures served by the mock
+	fixtures Fixtures
+	// key the id tokens are signed with
+	key *rsa.PrivateKey
+	// grants are the issued codes
+	grants map[string]grant
+	// mu guards grants
+	mu sync.Mutex
+}
+
+// NewHandler returns a mock issuer reachable at issuerURL serving the given fixtures
+func NewHandler(issuerURL string, fixtures Fixtures) (*Issuer, error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, err
+	}
+	return &Issuer{
+		url:      strings.TrimSuffix(issuerURL, "/"),
+		fixtures: fixtures,
+		key:      key,
+		grants:   make(map[string]grant),
+	}, nil
+}
+
+// NewServer starts a local http server with a mock issuer serving the given fixtures, it has to be closed by the caller
+// the backend can be pointed at it by setting OIDC_ISSUER to the URL of the server
+func NewServer(fixtures Fixtures) (*httptest.Server, error) {
+	server := httptest.NewServer(nil)
+	issuer, err := NewHandler(server.URL, fixtures)
+	if err != nil {
+		server.Close()
+		return nil, err
+	}
+	server.Config.Handler = issuer
+	return server, nil
+}
+
+// ServeHTTP answers requests to the endpoints of the issuer
+func (i *Issuer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/.well-known/openid-configuration":
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"issuer":                                i.url,
+			"authorization_endpoint":                i.url + "/authorize",
+			"token_endpoint":                        i.url + "/token",
+			"jwks_uri":                              i.url + "/keys",
+			"response_types_supported":              []string{"code"},
+			"subject_types_supported":               []string{"public"},
+			"id_token_signing_alg_values_supported": []string{"RS256"},
+		})
+	case "/authorize":
+		i.authorize(w, r)
+	case "/token":
+		i.token(w, r)
+	case "/keys":
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"keys": []map[string]string{{
+				"kty": "RSA",
+				"use": "sig",
+				"alg": "RS256",
+				"kid": KeyID,
+				"n":   base64.RawURLEncoding.EncodeToString(i.key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(i.key.E)).Bytes()),
+			}},
+		})
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// chooser is the page listing the users if no login_hint is given
+var chooser = template.Must(template.New("chooser").Parse(`<!DOCTYPE html>
+<html><head><title>oidctest</title></head><body><h1>Log in as</h1><ul>
+{{range .}}<li><a href="{{.URL}}">{{.Name}}</a></li>{{end}}
+</ul></body></html>`))
+
+// authorize issues a code for the user given by login_hint and redirects back to the client
+func (i *Issuer) authorize(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	redirectURI := query.Get("redirect_uri")
+	if query.Get("client_id") != i.fixtures.ClientID || redirectURI == "" {
+		http.Error(w, "unknown client or missing redirect_uri", http.StatusBadRequest)
+		return
+	}
+	if query.Get("response_type") != "code" {
+		http.Error(w, "only the authorization code flow is supported", http.StatusBadRequest)
+		return
+	}
+	hint := query.Get("login_hint")
+	if hint == "" {
+		links := make([]struct{ URL, Name string }, 0, len(i.fixtures.Users))
+		for _, user := range i.fixtures.Users {
+			query.Set("login_hint", user.PreferredUsername)
+			links = append(links, struct{ URL, Name string }{"?" + query.Encode(), user.PreferredUsername})
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_ = chooser.Execute(w, links)
+		return
+	}
+	for _, user := range i.fixtures.Users {
+		if user.PreferredUsername != hint && user.Subject != hint {
+			continue
+		}
+		code := randomString()
+		i.mu.Lock()
+		i.grants[code] = grant{user: user, nonce: query.Get("nonce"), redirectURI: redirectURI}
+		i.mu.Unlock()
+		target, err := url.Parse(redirectURI)
+		if err != nil {
+			http.Error(w, "invalid redirect_uri", http.StatusBadRequest)
+			return
+		}
+		params := target.Query()
+		params.Set("code", code)
+		params.Set("state", query.Get("state"))
+		target.RawQuery = params.Encode()
+		http.Redirect(w, r, target.String(), http.StatusFound)
+		return
+	}
+	http.Error(w, "unknown user "+hint, http.StatusNotFound)
+}
+
+// token redeems a code and returns an id token signed by the issuer
+func (i *Issuer) token(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost || r.ParseForm() != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_request"})
+		return
+	}
+	clientID, clientSecret, ok := r.BasicAuth()
+	if !ok {
+		clientID, clientSecret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+	}
+	if clientID != i.fixtures.ClientID || (i.fixtures.ClientSecret != "" && clientSecret != i.fixtures.ClientSecret) {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+		return
+	}
+	if r.PostForm.Get("grant_type") != "authorization_code" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "unsupported_grant_type"})
+		return
+	}
+	code := r.PostForm.Get("code")
+	i.mu.Lock()
+	g, ok := i.grants[code]
+	delete(i.grants, code)
+	i.mu.Unlock()
+	if !ok || g.redirectURI != r.PostForm.Get("redirect_uri") {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant", "error_description": "unknown code or redirect_uri"})
+		return
+	}
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"iss":                i.url,
+		"sub":                g.user.Subject,
+		"aud":                clientID,
+		"iat":                now.Unix(),
+		"exp":                now.Add(TokenLifetime).Unix(),
+		"preferred_username": g.user.PreferredUsername,
+		"given_name":         g.user.GivenName,
+		"family_name":        g.user.FamilyName,
+		"name":               strings.TrimSpace(g.user.GivenName + " " + g.user.FamilyName),
+		"email":              g.user.Email,
+	}
+	if g.nonce != "" {
+		claims["nonce"] = g.nonce
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = KeyID
+	signed, err := token.SignedString(i.key)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "server_error"})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token": randomString(),
+		"token_type":   "Bearer",
+		"expires_in":   int(TokenLifetime.Seconds()),
+		"id_token":     signed,
+	})
+}
+
+// writeJSON encodes v as the json body of the response
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// randomString returns 32 random hex characters
+func randomString() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
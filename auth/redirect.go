@@ -0,0 +1,92 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"github.com/refundable-tgm/huginn/db"
+	"sync"
+	"time"
+)
+
+// LoginTimeout is the time a user has to finish a login on the page of a redirect provider
+const LoginTimeout = 10 * time.Minute
+
+// pendingLogin is a login started by BeginLogin which wasn't finished yet
+type pendingLogin struct {
+	// provider is the name of the redirect provider
+	provider string
+	// nonce the id token has to contain
+	nonce string
+	// expires is the time the login has to be finished by
+	expires time.Time
+}
+
+// pending holds the started logins by their state
+var pending = struct {
+	sync.Mutex
+	logins map[string]pendingLogin
+}{logins: make(map[string]pendingLogin)}
+
+// BeginLogin starts a login with the redirect provider with the given name
+// returns the url of the login page the user has to be redirected to
+func BeginLogin(provider string) (string, error) {
+	p, err := redirectProvider(provider)
+	if err != nil {
+		return "", err
+	}
+	state, err := randomString()
+	if err != nil {
+		return "", err
+	}
+	nonce, err := randomString()
+	if err != nil {
+		return "", err
+	}
+	u, err := p.AuthURL(state, nonce)
+	if err != nil {
+		return "", err
+	}
+	pending.Lock()
+	defer pending.Unlock()
+	now := time.Now()
+	for s, login := range pending.logins {
+		if now.After(login.expires) {
+			delete(pending.logins, s)
+		}
+	}
+	pending.logins[state] = pendingLogin{provider: provider, nonce: nonce, expires: now.Add(LoginTimeout)}
+	return u, nil
+}
+
+// FinishLogin finishes the login started with the given state using the code returned by the login page
+// returns ErrInvalidCredentials if the state is unknown or expired, every state can only be used once
+func FinishLogin(ctx context.Context, state, code string) (db.Teacher, error) {
+	pending.Lock()
+	login, ok := pending.logins[state]
+	delete(pending.logins, state)
+	pending.Unlock()
+	if !ok || time.Now().After(login.expires) {
+		return db.Teacher{}, fmt.Errorf("%w: unknown or expired login", ErrInvalidCredentials)
+	}
+	p, err := redirectProvider(login.provider)
+	if err != nil {
+		return db.Teacher{}, err
+	}
+	return p.Callback(ctx, code, login.nonce)
+}
+
+// randomString returns 32 random hex characters
+func randomString() (string, error) {
+	return randomHex(16)
+}
+
+// randomHex returns n random bytes encoded as hex
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,338 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"github.com/refundable-tgm/huginn/db"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Environment variables configuring the throttling of failed logins
+const (
+	// UserLockoutAttemptsEnv contains the amount of failed logins after which a username is locked out
+	UserLockoutAttemptsEnv = "LOGIN_USER_LOCKOUT_ATTEMPTS"
+	// IPLockoutAttemptsEnv contains the amount of failed logins after which an address is locked out
+	IPLockoutAttemptsEnv = "LOGIN_IP_LOCKOUT_ATTEMPTS"
+	// LockoutDurationEnv contains the time a lockout lasts, failures older than it are forgotten
+	LockoutDurationEnv = "LOGIN_LOCKOUT_DURATION"
+	// MaxBackoffEnv contains the maximum time a login has to wait after a failure before the lockout
+	MaxBackoffEnv = "LOGIN_MAX_BACKOFF"
+)
+
+// defaults of the throttling
+const (
+	// DefaultUserLockoutAttempts is used if LOGIN_USER_LOCKOUT_ATTEMPTS isn't set
+	DefaultUserLockoutAttempts = 10
+	// DefaultIPLockoutAttempts is used if LOGIN_IP_LOCKOUT_ATTEMPTS isn't set, it is higher as many users share the address of the school
+	DefaultIPLockoutAttempts = 50
+	// DefaultLockoutDuration is used if LOGIN_LOCKOUT_DURATION isn't set
+	DefaultLockoutDuration = 30 * time.Minute
+	// DefaultMaxBackoff is used if LOGIN_MAX_BACKOFF isn't set
+	DefaultMaxBackoff = 5 * time.Minute
+)
+
+// Kinds of throttled keys
+const (
+	// UserThrottle throttles a username
+	UserThrottle = "user"
+	// IPThrottle throttles an address
+	IPThrottle = "ip"
+)
+
+// freeAttempts is the share of the lockout attempts which can fail without any backoff
+const freeAttempts = 3
+
+// baseBackoff is the backoff after the first failure exceeding the free attempts, it doubles with every further failure
+const baseBackoff = time.Second
+
+// ThrottledError is returned if a login is rejected because its username or address failed too often
+type ThrottledError struct {
+	// Kind of the throttled key
+	Kind string
+	// Until is the time logins are accepted again
+	Until time.Time
+	// Locked is true if the key is locked out and not only backing off
+	Locked bool
+}
+
+// Error describes the throttling
+func (e *ThrottledError) Error() string {
+	if e.Locked {
+		return fmt.Sprintf("too many failed logins, the %v is locked until %v", e.Kind, e.Until.Format("15:04:05"))
+	}
+	return fmt.Sprintf("too many failed logins, try again in %v", time.Until(e.Until).Round(time.Second))
+}
+
+// Lockout is the state of a throttled username or address
+type Lockout struct {
+	// Kind of the key, user or ip
+	Kind string `json:"kind" example:"user"`
+	// Key is the username or address
+	Key string `json:"key" example:"szakall"`
+	// Failures is the amount of failed logins since the last success
+	Failures int `json:"failures" example:"10"`
+	// LastFailure is the time of the last failed login
+	LastFailure time.Time `json:"last_failure"`
+	// Until is the time logins are accepted again
+	Until time.Time `json:"until"`
+	// Locked is true if the key is locked out and not only backing off
+	Locked bool `json:"locked" example:"true"`
+	// pending is the amount of attempts allowed but neither failed nor released yet
+	pending int
+}
+
+// throttles holds the state of every username and address with failed logins by kind and key
+var throttles = struct {
+	sync.Mutex
+	entries map[string]*Lockout
+}{entries: make(map[string]*Lockout)}
+
+// throttleLimits are the configured limits of the throttling
+type throttleLimits struct {
+	// lockout maps the kinds of keys onto the amount of failures after which they are locked out
+	lockout map[string]int
+	// duration of a lockout
+	duration time.Duration
+	// maxBackoff is the maximum backoff before a lockout
+	maxBackoff time.Duration
+}
+
+// loadThrottleLimits reads the limits of the throttling from the environment, invalid values are logged and replaced by the defaults
+func loadThrottleLimits() throttleLimits {
+	return throttleLimits{
+		lockout: map[string]int{
+			UserThrottle: intEnv(UserLockoutAttemptsEnv, DefaultUserLockoutAttempts),
+			IPThrottle:   intEnv(IPLockoutAttemptsEnv, DefaultIPLockoutAttempts),
+		},
+		duration:   durationEnv(LockoutDurationEnv, DefaultLockoutDuration),
+		maxBackoff: durationEnv(MaxBackoffEnv, DefaultMaxBackoff),
+	}
+}
+
+// throttleKeys returns the keys of the username and address of a login
+func throttleKeys(username, ip string) map[string]string {
+	keys := make(map[string]string, 2)
+	if username = strings.ToLower(strings.TrimSpace(username)); username != "" {
+		keys[UserThrottle] = username
+	}
+	if ip != "" {
+		keys[IPThrottle] = ip
+	}
+	return keys
+}
+
+// Allow checks whether a login of the username from the address may be tried now and reserves the attempt
+// every allowed attempt has to be finished with Fail or Release, attempts in progress count as failures while checking further ones
+// so once the free attempts are used up only one attempt at a time is allowed and concurrent logins can't skip the backoff
+// returns a *ThrottledError if one of them is backing off or locked out
+// rejected logins aren't audited, so hammering a locked account doesn't flood the audit entries
+func Allow(username, ip string) error {
+	limits := loadThrottleLimits()
+	now := time.Now()
+	keys := throttleKeys(username, ip)
+	throttles.Lock()
+	var throttled *ThrottledError
+	for kind, key := range keys {
+		entry, ok := throttles.entries[kind+":"+key]
+		if !ok {
+			continue
+		}
+		until := entry.Until
+		if entry.pending > 0 && entry.Failures+entry.pending >= limits.lockout[kind]/freeAttempts && !now.Before(until) {
+			until = now.Add(baseBackoff)
+		}
+		if !now.Before(until) {
+			continue
+		}
+		if throttled == nil || until.After(throttled.Until) {
+			throttled = &ThrottledError{Kind: kind, Until: until, Locked: entry.Locked}
+		}
+	}
+	if throttled == nil {
+		for kind, key := range keys {
+			entry, ok := throttles.entries[kind+":"+key]
+			if !ok {
+				entry = &Lockout{Kind: kind, Key: key}
+				throttles.entries[kind+":"+key] = entry
+			}
+			entry.pending++
+		}
+	}
+	throttles.Unlock()
+	if throttled == nil {
+		forget(limits, now)
+		return nil
+	}
+	return throttled
+}
+
+// Release finishes an attempt reserved with Allow which didn't fail
+func Release(username, ip string) {
+	throttles.Lock()
+	defer throttles.Unlock()
+	for kind, key := range throttleKeys(username, ip) {
+		if entry, ok := throttles.entries[kind+":"+key]; ok && entry.pending > 0 {
+			entry.pending--
+		}
+	}
+}
+
+// Fail finishes an attempt reserved with Allow as failed login of the username from the address
+// both back off exponentially and are locked out after too many failures
+func Fail(ctx context.Context, username, ip, reason string) {
+	limits := loadThrottleLimits()
+	now := time.Now()
+	locked := make([]Lockout, 0)
+	throttles.Lock()
+	for kind, key := range throttleKeys(username, ip) {
+		entry, ok := throttles.entries[kind+":"+key]
+		if !ok {
+			entry = &Lockout{Kind: kind, Key: key}
+			throttles.entries[kind+":"+key] = entry
+		}
+		if entry.pending > 0 {
+			entry.pending--
+		}
+		if now.Sub(entry.LastFailure) > limits.duration {
+			*entry = Lockout{Kind: kind, Key: key, pending: entry.pending}
+		}
+		entry.Failures++
+		entry.LastFailure = now
+		lockout := limits.lockout[kind]
+		switch {
+		case entry.Failures >= lockout:
+			if !entry.Locked {
+				locked = append(locked, *entry)
+			}
+			entry.Locked = true
+			entry.Until = now.Add(limits.duration)
+		case entry.Failures > lockout/freeAttempts:
+			backoff := limits.maxBackoff
+			if shift := entry.Failures - lockout/freeAttempts - 1; shift < 30 && baseBackoff<<uint(shift) < backoff {
+				backoff = baseBackoff << uint(shift)
+			}
+			entry.Until = now.Add(backoff)
+		}
+	}
+	throttles.Unlock()
+	audit(ctx, db.AuditEntry{Kind: db.AuditLoginFailed, Username: username, IP: ip, Message: reason})
+	for _, lockout := range locked {
+		log.Println("Locked out the ", lockout.Kind, " ", lockout.Key, " after ", lockout.Failures, " failed logins")
+		audit(ctx, db.AuditEntry{Kind: db.AuditLockout, Username: username, IP: ip,
+			Message: fmt.Sprintf("%v %v locked out after %v failed logins", lockout.Kind, lockout.Key, lockout.Failures)})
+	}
+}
+
+// Succeed forgets the failed logins of the username after it logged in completely, its attempts in progress stay reserved
+func Succeed(username string) {
+	throttles.Lock()
+	defer throttles.Unlock()
+	id := UserThrottle + ":" + strings.ToLower(strings.TrimSpace(username))
+	entry, ok := throttles.entries[id]
+	if !ok {
+		return
+	}
+	if entry.pending == 0 {
+		delete(throttles.entries, id)
+		return
+	}
+	*entry = Lockout{Kind: entry.Kind, Key: entry.Key, pending: entry.pending}
+}
+
+// Lockouts returns the usernames and addresses which are currently backing off or locked out, the latest first
+func Lockouts() []Lockout {
+	now := time.Now()
+	throttles.Lock()
+	lockouts := make([]Lockout, 0)
+	for _, entry := range throttles.entries {
+		if now.Before(entry.Until) {
+			lockouts = append(lockouts, *entry)
+		}
+	}
+	throttles.Unlock()
+	sort.Slice(lockouts, func(i, j int) bool {
+		return lockouts[i].LastFailure.After(lockouts[j].LastFailure)
+	})
+	return lockouts
+}
+
+// ClearLockout forgets the failed logins of the username or address, the clearing is audited with actor as the administrator
+// returns false if the key has no failed logins
+func ClearLockout(ctx context.Context, kind, key, actor string) bool {
+	if kind == UserThrottle {
+		key = strings.ToLower(strings.TrimSpace(key))
+	}
+	throttles.Lock()
+	_, ok := throttles.entries[kind+":"+key]
+	delete(throttles.entries, kind+":"+key)
+	throttles.Unlock()
+	if ok {
+		entry := db.AuditEntry{Kind: db.AuditLockoutCleared, Actor: actor, Message: kind + " " + key + " cleared"}
+		if kind == UserThrottle {
+			entry.Username = key
+		} else {
+			entry.IP = key
+		}
+		audit(ctx, entry)
+	}
+	return ok
+}
+
+// forget removes the entries whose last failure is older than the lockout duration, which aren't throttled anymore and have no attempts in progress
+func forget(limits throttleLimits, now time.Time) {
+	throttles.Lock()
+	defer throttles.Unlock()
+	for id, entry := range throttles.entries {
+		if now.Sub(entry.LastFailure) > limits.duration && !now.Before(entry.Until) && entry.pending == 0 {
+			delete(throttles.entries, id)
+		}
+	}
+}
+
+// audit stores the entry, failures are only logged so they don't prevent logins
+func audit(ctx context.Context, entry db.AuditEntry) {
+	entry.Time = time.Now()
+	mongo := db.MongoDatabaseConnector{}
+	if err := mongo.Connect(ctx); err != nil {
+		log.Println("Couldn't store audit entry: ", err)
+		return
+	}
+	defer mongo.Close()
+	if err := mongo.CreateAuditEntry(entry); err != nil {
+		log.Println("Couldn't store audit entry: ", err)
+	}
+}
+
+// intEnv returns the positive number in the environment variable, or fallback if it isn't set or invalid
+func intEnv(key string, fallback int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n <= 0 {
+		log.Println(key, " has to be a positive number, using ", fallback)
+		return fallback
+	}
+	return n
+}
+
+// durationEnv returns the positive duration in the environment variable, or fallback if it isn't set or invalid
+func durationEnv(key string, fallback time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		log.Println(key, " has to be a positive duration like 30m, using ", fallback)
+		return fallback
+	}
+	return d
+}
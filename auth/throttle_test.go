@@ -0,0 +1,120 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+)
+
+// resetThrottles forgets all failed logins before and after the test
+func resetThrottles(t *testing.T) {
+	clear := func() {
+		throttles.Lock()
+		throttles.entries = make(map[string]*Lockout)
+		throttles.Unlock()
+	}
+	clear()
+	t.Cleanup(clear)
+}
+
+// allowConcurrently tries n logins of the username at once, each from its own address, and returns the addresses allowed to try
+func allowConcurrently(username string, n int) []string {
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	allowed := make([]string, 0)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(ip string) {
+			defer wg.Done()
+			if Allow(username, ip) == nil {
+				mu.Lock()
+				allowed = append(allowed, ip)
+				mu.Unlock()
+			}
+		}(fmt.Sprintf("10.0.0.%v", i))
+	}
+	wg.Wait()
+	return allowed
+}
+
+func TestConcurrentAttempts(t *testing.T) {
+	setenv(t, UserLockoutAttemptsEnv, "9")
+	free := 9 / freeAttempts
+	tests := []struct {
+		name     string
+		failures int
+		allowed  int
+	}{
+		{name: "free attempts", failures: 0, allowed: free},
+		{name: "some free attempts used", failures: free - 1, allowed: 1},
+		{name: "free attempts used up", failures: free, allowed: 1},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			resetThrottles(t)
+			for i := 0; i < test.failures; i++ {
+				if err := Allow("szakall", "10.1.0.1"); err != nil {
+					t.Fatal(err)
+				}
+				Fail(context.Background(), "szakall", "10.1.0.1", "invalid credentials")
+			}
+			throttles.Lock()
+			if entry, ok := throttles.entries[UserThrottle+":szakall"]; ok {
+				entry.Until = entry.LastFailure
+			}
+			throttles.Unlock()
+			if allowed := allowConcurrently("szakall", 50); len(allowed) != test.allowed {
+				t.Errorf("%v of 50 concurrent logins were allowed, want %v", len(allowed), test.allowed)
+			}
+		})
+	}
+}
+
+func TestFinishAttempts(t *testing.T) {
+	setenv(t, UserLockoutAttemptsEnv, "2")
+	tests := []struct {
+		name    string
+		finish  func(ip string)
+		allowed bool
+	}{
+		{name: "released", finish: func(ip string) { Release("szakall", ip) }, allowed: true},
+		{name: "succeeded", finish: func(ip string) { Release("szakall", ip); Succeed("szakall") }, allowed: true},
+		{name: "failed", finish: func(ip string) { Fail(context.Background(), "szakall", ip, "invalid credentials") }, allowed: false},
+		{name: "in progress", finish: func(string) {}, allowed: false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			resetThrottles(t)
+			if err := Allow("szakall", "10.0.0.1"); err != nil {
+				t.Fatal(err)
+			}
+			test.finish("10.0.0.1")
+			err := Allow("szakall", "10.0.0.2")
+			var throttled *ThrottledError
+			if test.allowed && err != nil {
+				t.Errorf("Allow() returned %v, want the login to be allowed", err)
+			}
+			if !test.allowed && !errors.As(err, &throttled) {
+				t.Errorf("Allow() returned %v, want a ThrottledError", err)
+			}
+		})
+	}
+}
+
+func TestSucceedKeepsAttemptsInProgress(t *testing.T) {
+	setenv(t, UserLockoutAttemptsEnv, "2")
+	resetThrottles(t)
+	if err := Allow("szakall", "10.0.0.1"); err != nil {
+		t.Fatal(err)
+	}
+	Succeed("szakall")
+	if err := Allow("szakall", "10.0.0.2"); err == nil {
+		t.Error("a second login was allowed while the first one is still in progress")
+	}
+	Release("szakall", "10.0.0.1")
+	if err := Allow("szakall", "10.0.0.2"); err != nil {
+		t.Errorf("Allow() returned %v after the first login finished", err)
+	}
+}
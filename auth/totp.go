@@ -0,0 +1,156 @@
+package auth
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"github.com/boombuler/barcode"
+	"github.com/boombuler/barcode/qr"
+	"image/png"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// parameters of the one-time passwords, they are the defaults of RFC 6238 supported by every authenticator app
+const (
+	// totpPeriod is the time a one-time password is valid
+	totpPeriod = 30 * time.Second
+	// totpDigits is the length of a one-time password
+	totpDigits = 6
+	// totpSkew is the amount of periods before and after the current one whose passwords are accepted too
+	totpSkew = 1
+	// secretLength is the length of the secrets in bytes
+	secretLength = 20
+	// qrSize is the width and height of the QR codes in pixels
+	qrSize = 256
+)
+
+// RecoveryCodeCount is the amount of recovery codes generated for a teacher
+const RecoveryCodeCount = 10
+
+// secretEncoding encodes secrets like authenticator apps expect them
+var secretEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// Provisioning is the data needed to add a secret to an authenticator app
+type Provisioning struct {
+	// Secret encoded in base32 to enter it manually
+	Secret string `json:"secret" example:"JBSWY3DPEHPK3PXPJBSWY3DPEHPK3PXP"`
+	// URL is the otpauth:// url of the secret
+	URL string `json:"url" example:"otpauth://totp/Refundable:szakall?secret=JBSWY3DPEHPK3PXPJBSWY3DPEHPK3PXP&issuer=Refundable"`
+	// QRCode is the url encoded as QR code in a data url of a PNG image
+	QRCode string `json:"qr_code" example:"data:image/png;base64,iVBORw0KGgo..."`
+}
+
+// generateSecret returns a new random secret encoded in base32
+func generateSecret() (string, error) {
+	b := make([]byte, secretLength)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return secretEncoding.EncodeToString(b), nil
+}
+
+// provision returns the data needed to add the secret of the account to an authenticator app
+func provision(secret, account string) (Provisioning, error) {
+	issuer := envOr(TwoFactorIssuerEnv, DefaultTwoFactorIssuer)
+	query := url.Values{}
+	query.Set("secret", secret)
+	query.Set("issuer", issuer)
+	query.Set("algorithm", "SHA1")
+	query.Set("digits", fmt.Sprint(totpDigits))
+	query.Set("period", fmt.Sprint(int(totpPeriod.Seconds())))
+	u := url.URL{Scheme: "otpauth", Host: "totp", Path: "/" + issuer + ":" + account, RawQuery: query.Encode()}
+	code, err := qr.Encode(u.String(), qr.M, qr.Auto)
+	if err != nil {
+		return Provisioning{}, err
+	}
+	if code, err = barcode.Scale(code, qrSize, qrSize); err != nil {
+		return Provisioning{}, err
+	}
+	image := bytes.Buffer{}
+	if err := png.Encode(&image, code); err != nil {
+		return Provisioning{}, err
+	}
+	return Provisioning{
+		Secret: secret,
+		URL:    u.String(),
+		QRCode: "data:image/png;base64," + base64.StdEncoding.EncodeToString(image.Bytes()),
+	}, nil
+}
+
+// totpCode returns the one-time password of the secret for the given time step
+func totpCode(secret []byte, step int64) string {
+	counter := make([]byte, 8)
+	binary.BigEndian.PutUint64(counter, uint64(step))
+	mac := hmac.New(sha1.New, secret)
+	mac.Write(counter)
+	sum := mac.Sum(nil)
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	modulo := uint32(1)
+	for i := 0; i < totpDigits; i++ {
+		modulo *= 10
+	}
+	return fmt.Sprintf("%0*d", totpDigits, value%modulo)
+}
+
+// verifyTOTP checks the one-time password against the secret at the given time
+// passwords of time steps up to lastStep are rejected, so every password can only be used once
+// returns the time step the password belongs to
+func verifyTOTP(secret, code string, lastStep int64, now time.Time) (int64, bool) {
+	key, err := secretEncoding.DecodeString(strings.ToUpper(secret))
+	code = strings.Join(strings.Fields(code), "")
+	if err != nil || len(code) != totpDigits {
+		return 0, false
+	}
+	current := now.Unix() / int64(totpPeriod.Seconds())
+	for step := current - totpSkew; step <= current+totpSkew; step++ {
+		if step <= lastStep {
+			continue
+		}
+		if subtle.ConstantTimeCompare([]byte(totpCode(key, step)), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+// newRecoveryCodes generates RecoveryCodeCount recovery codes like 7kq2-m9xd-p4tz
+// returns the codes shown to the teacher and their hashes, which are stored
+func newRecoveryCodes() ([]string, []string, error) {
+	const alphabet = "abcdefghjkmnpqrstuvwxyz23456789"
+	codes := make([]string, 0, RecoveryCodeCount)
+	hashes := make([]string, 0, RecoveryCodeCount)
+	for i := 0; i < RecoveryCodeCount; i++ {
+		b := make([]byte, 12)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, err
+		}
+		code := make([]byte, 0, 14)
+		for j, c := range b {
+			if j > 0 && j%4 == 0 {
+				code = append(code, '-')
+			}
+			code = append(code, alphabet[int(c)%len(alphabet)])
+		}
+		codes = append(codes, string(code))
+		hashes = append(hashes, hashRecoveryCode(string(code)))
+	}
+	return codes, hashes, nil
+}
+
+// hashRecoveryCode returns the hash a recovery code is stored as, case, dashes and spaces are ignored
+func hashRecoveryCode(code string) string {
+	normalized := strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
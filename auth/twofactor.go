@@ -0,0 +1,340 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/refundable-tgm/huginn/db"
+	"github.com/refundable-tgm/huginn/ldap"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TwoFactorRolesEnv is the environment variable listing the roles (comma separated) which have to use a second factor
+// the role names are the ones of LDAP_GROUP_ROLES, department_head covers the heads of all departments, none disables the requirement
+const TwoFactorRolesEnv = "TWO_FACTOR_REQUIRED_ROLES"
+
+// DefaultTwoFactorRoles are the roles which have to use a second factor if TWO_FACTOR_REQUIRED_ROLES isn't set
+const DefaultTwoFactorRoles = "superuser,pek,av"
+
+// TwoFactorIssuerEnv is the environment variable containing the name authenticator apps show for the one-time passwords
+const TwoFactorIssuerEnv = "TWO_FACTOR_ISSUER"
+
+// DefaultTwoFactorIssuer is the name authenticator apps show if TWO_FACTOR_ISSUER isn't set
+const DefaultTwoFactorIssuer = "Refundable"
+
+// ChallengeTimeout is the time a user has to answer the second step of a login
+const ChallengeTimeout = 5 * time.Minute
+
+// MaxChallengeAttempts is the amount of wrong codes after which a challenge is dropped and the login has to be started again
+const MaxChallengeAttempts = 5
+
+// ErrTwoFactorEnabled is returned if a teacher who already uses a second factor starts an enrolment
+var ErrTwoFactorEnabled = errors.New("two-factor authentication is already enabled")
+
+// ErrTwoFactorDisabled is returned if an operation needs a second factor the teacher doesn't use
+var ErrTwoFactorDisabled = errors.New("two-factor authentication isn't enabled")
+
+// ErrTwoFactorRequired is returned if a teacher whose roles require a second factor tries to disable it
+var ErrTwoFactorRequired = errors.New("two-factor authentication is required for the roles of this teacher")
+
+// ErrNoEnrolment is returned if an enrolment is confirmed which wasn't started
+var ErrNoEnrolment = errors.New("no two-factor enrolment was started")
+
+// ErrInvalidCode is returned if a one-time password or recovery code is wrong
+var ErrInvalidCode = errors.New("invalid one-time password or recovery code")
+
+// ErrDeactivated is returned if a deactivated teacher tries to log in
+var ErrDeactivated = errors.New("this teacher is deactivated")
+
+// Challenge is the second step of a login, it is answered with a one-time password or a recovery code
+type Challenge struct {
+	// ID identifies the challenge
+	ID string
+	// Username is the short name of the teacher logging in
+	Username string
+	// Enrolled is false if the teacher has to enrol a second factor before answering the challenge
+	Enrolled bool
+	// Expires is the time the challenge has to be answered by
+	Expires time.Time
+	// attempts is the amount of wrong answers
+	attempts int
+}
+
+// challenges holds the open challenges by their id
+var challenges = struct {
+	sync.Mutex
+	open map[string]*Challenge
+}{open: make(map[string]*Challenge)}
+
+// RequiresTwoFactor checks whether one of the roles of the teacher is listed in TWO_FACTOR_REQUIRED_ROLES
+func RequiresTwoFactor(teacher db.Teacher) (bool, error) {
+	for _, role := range strings.Split(envOr(TwoFactorRolesEnv, DefaultTwoFactorRoles), ",") {
+		switch strings.ToLower(strings.TrimSpace(role)) {
+		case "", "none":
+		case ldap.SuperUserRole:
+			if teacher.SuperUser {
+				return true, nil
+			}
+		case ldap.AVRole:
+			if teacher.AV {
+				return true, nil
+			}
+		case ldap.AdministrationRole:
+			if teacher.Administration {
+				return true, nil
+			}
+		case ldap.PEKRole:
+			if teacher.PEK {
+				return true, nil
+			}
+		case ldap.DepartmentHeadRole:
+			if len(teacher.DepartmentHead) > 0 {
+				return true, nil
+			}
+		default:
+			return false, fmt.Errorf("%w: %v contains the unknown role %v", ErrInvalidConfig, TwoFactorRolesEnv, role)
+		}
+	}
+	return false, nil
+}
+
+// NewChallenge returns the second step of the login of the teacher, or nil if the teacher doesn't need one
+// the teacher needs one if it enabled a second factor or one of its roles requires it
+// without a second step the login is complete and the failed logins of the teacher are forgotten
+// returns ErrDeactivated if the teacher is deactivated
+func NewChallenge(teacher db.Teacher) (*Challenge, error) {
+	if !teacher.DeactivatedAt.IsZero() {
+		return nil, ErrDeactivated
+	}
+	required, err := RequiresTwoFactor(teacher)
+	if err != nil {
+		return nil, err
+	}
+	if !required && !teacher.TwoFactor.Enabled {
+		Succeed(teacher.Short)
+		return nil, nil
+	}
+	id, err := randomString()
+	if err != nil {
+		return nil, err
+	}
+	challenge := &Challenge{
+		ID:       id,
+		Username: teacher.Short,
+		Enrolled: teacher.TwoFactor.Enabled,
+		Expires:  time.Now().Add(ChallengeTimeout),
+	}
+	challenges.Lock()
+	defer challenges.Unlock()
+	for id, c := range challenges.open {
+		if time.Now().After(c.Expires) {
+			delete(challenges.open, id)
+		}
+	}
+	challenges.open[challenge.ID] = challenge
+	return challenge, nil
+}
+
+// openChallenge returns the challenge with the given id if it didn't expire
+func openChallenge(id string) (Challenge, error) {
+	challenges.Lock()
+	defer challenges.Unlock()
+	challenge, ok := challenges.open[id]
+	if !ok || time.Now().After(challenge.Expires) {
+		delete(challenges.open, id)
+		return Challenge{}, fmt.Errorf("%w: unknown or expired challenge", ErrInvalidCredentials)
+	}
+	return *challenge, nil
+}
+
+// failChallenge counts a wrong answer, the challenge is dropped after MaxChallengeAttempts
+func failChallenge(id string) {
+	challenges.Lock()
+	defer challenges.Unlock()
+	if challenge, ok := challenges.open[id]; ok {
+		challenge.attempts++
+		if challenge.attempts >= MaxChallengeAttempts {
+			delete(challenges.open, id)
+		}
+	}
+}
+
+// EnrollChallenge starts the enrolment of a second factor for a teacher who has to enrol before answering the challenge
+func EnrollChallenge(ctx context.Context, id string) (Provisioning, error) {
+	challenge, err := openChallenge(id)
+	if err != nil {
+		return Provisioning{}, err
+	}
+	return BeginEnrolment(ctx, challenge.Username)
+}
+
+// AnswerChallenge checks the one-time password or recovery code answering the challenge sent from the address ip
+// wrong answers count as failed logins of the teacher, answers of throttled teachers or addresses are rejected with a *ThrottledError
+// if the teacher enrolled during the login, the enrolment is confirmed and the new recovery codes are returned
+// returns the teacher who logged in, or ErrInvalidCredentials if the answer is wrong
+func AnswerChallenge(ctx context.Context, id, code, ip string) (db.Teacher, []string, error) {
+	challenge, err := openChallenge(id)
+	if err != nil {
+		return db.Teacher{}, nil, err
+	}
+	if err := Allow(challenge.Username, ip); err != nil {
+		return db.Teacher{}, nil, err
+	}
+	var codes []string
+	teacher, err := updateTeacher(ctx, challenge.Username, func(teacher *db.Teacher) error {
+		if teacher.TwoFactor.Enabled {
+			return verifySecondFactor(teacher, code)
+		}
+		var confirmErr error
+		codes, confirmErr = confirm(teacher, code)
+		return confirmErr
+	})
+	if errors.Is(err, ErrInvalidCode) || errors.Is(err, ErrNoEnrolment) {
+		failChallenge(id)
+		Fail(ctx, challenge.Username, ip, err.Error())
+		return db.Teacher{}, nil, fmt.Errorf("%w: %v", ErrInvalidCredentials, err)
+	}
+	Release(challenge.Username, ip)
+	if err != nil {
+		return db.Teacher{}, nil, err
+	}
+	challenges.Lock()
+	delete(challenges.open, id)
+	challenges.Unlock()
+	Succeed(challenge.Username)
+	return teacher, codes, nil
+}
+
+// BeginEnrolment creates a new secret for the teacher, it has to be confirmed with a one-time password before it is used
+func BeginEnrolment(ctx context.Context, username string) (Provisioning, error) {
+	var provisioning Provisioning
+	_, err := updateTeacher(ctx, username, func(teacher *db.Teacher) error {
+		if teacher.TwoFactor.Enabled {
+			return ErrTwoFactorEnabled
+		}
+		secret, err := generateSecret()
+		if err != nil {
+			return err
+		}
+		if provisioning, err = provision(secret, teacher.Short); err != nil {
+			return err
+		}
+		teacher.TwoFactor.PendingSecret = secret
+		return nil
+	})
+	return provisioning, err
+}
+
+// ConfirmEnrolment enables the second factor of the teacher if the one-time password matches the secret of the enrolment
+// returns the recovery codes of the teacher
+func ConfirmEnrolment(ctx context.Context, username, code string) ([]string, error) {
+	var codes []string
+	_, err := updateTeacher(ctx, username, func(teacher *db.Teacher) error {
+		var err error
+		codes, err = confirm(teacher, code)
+		return err
+	})
+	return codes, err
+}
+
+// DisableTwoFactor removes the second factor of the teacher after checking a one-time password or recovery code
+// returns ErrTwoFactorRequired if one of the roles of the teacher requires a second factor
+func DisableTwoFactor(ctx context.Context, username, code string) error {
+	_, err := updateTeacher(ctx, username, func(teacher *db.Teacher) error {
+		required, err := RequiresTwoFactor(*teacher)
+		if err != nil {
+			return err
+		}
+		if required {
+			return ErrTwoFactorRequired
+		}
+		if err := verifySecondFactor(teacher, code); err != nil {
+			return err
+		}
+		teacher.TwoFactor = db.TwoFactor{}
+		return nil
+	})
+	return err
+}
+
+// RegenerateRecoveryCodes replaces the recovery codes of the teacher after checking a one-time password or recovery code
+func RegenerateRecoveryCodes(ctx context.Context, username, code string) ([]string, error) {
+	var codes []string
+	_, err := updateTeacher(ctx, username, func(teacher *db.Teacher) error {
+		if err := verifySecondFactor(teacher, code); err != nil {
+			return err
+		}
+		var hashes []string
+		var err error
+		if codes, hashes, err = newRecoveryCodes(); err != nil {
+			return err
+		}
+		teacher.TwoFactor.RecoveryCodes = hashes
+		return nil
+	})
+	return codes, err
+}
+
+// confirm enables the pending secret of the teacher if the one-time password matches it and returns new recovery codes
+func confirm(teacher *db.Teacher, code string) ([]string, error) {
+	if teacher.TwoFactor.Enabled {
+		return nil, ErrTwoFactorEnabled
+	}
+	if teacher.TwoFactor.PendingSecret == "" {
+		return nil, ErrNoEnrolment
+	}
+	step, ok := verifyTOTP(teacher.TwoFactor.PendingSecret, code, 0, time.Now())
+	if !ok {
+		return nil, ErrInvalidCode
+	}
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	teacher.TwoFactor = db.TwoFactor{
+		Enabled:       true,
+		EnabledAt:     time.Now(),
+		Secret:        teacher.TwoFactor.PendingSecret,
+		RecoveryCodes: hashes,
+		LastStep:      step,
+	}
+	return codes, nil
+}
+
+// verifySecondFactor checks a one-time password or a recovery code of the teacher, a used recovery code is removed
+func verifySecondFactor(teacher *db.Teacher, code string) error {
+	if !teacher.TwoFactor.Enabled {
+		return ErrTwoFactorDisabled
+	}
+	if step, ok := verifyTOTP(teacher.TwoFactor.Secret, code, teacher.TwoFactor.LastStep, time.Now()); ok {
+		teacher.TwoFactor.LastStep = step
+		return nil
+	}
+	hash := hashRecoveryCode(code)
+	for i, stored := range teacher.TwoFactor.RecoveryCodes {
+		if stored == hash {
+			teacher.TwoFactor.RecoveryCodes = append(teacher.TwoFactor.RecoveryCodes[:i:i], teacher.TwoFactor.RecoveryCodes[i+1:]...)
+			return nil
+		}
+	}
+	return ErrInvalidCode
+}
+
+// updateTeacher applies change to the stored teacher with the given short name and saves it if change succeeds
+func updateTeacher(ctx context.Context, username string, change func(teacher *db.Teacher) error) (db.Teacher, error) {
+	mongo := db.MongoDatabaseConnector{}
+	if err := mongo.Connect(ctx); err != nil {
+		return db.Teacher{}, err
+	}
+	defer mongo.Close()
+	teacher, err := mongo.GetTeacherByShort(username)
+	if err != nil {
+		return db.Teacher{}, err
+	}
+	if err := change(&teacher); err != nil {
+		return db.Teacher{}, err
+	}
+	return teacher, mongo.UpdateTeacher(teacher.UUID, teacher)
+}
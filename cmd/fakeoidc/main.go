@@ -0,0 +1,27 @@
+// Command fakeoidc serves a mock OpenID Connect issuer with fixture users for offline development.
+// Point the backend at it by setting OIDC_ISSUER to http://<addr>, OIDC_DOMAINS to tgm.ac.at and enabling oidc in AUTH_PROVIDERS
+package main
+
+import (
+	"flag"
+	"github.com/refundable-tgm/huginn/auth/oidctest"
+	"log"
+	"net/http"
+)
+
+// main function starting the mock issuer
+func main() {
+	addr := flag.String("addr", "localhost:8082", "address the mock issuer listens on")
+	fixtures := flag.String("fixtures", "auth/oidctest/testdata/fixtures.json", "path to the json file containing the fixtures")
+	flag.Parse()
+	data, err := oidctest.LoadFixtures(*fixtures)
+	if err != nil {
+		log.Fatal(err)
+	}
+	issuer, err := oidctest.NewHandler("http://"+*addr, data)
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Println("Serving mock issuer at http://" + *addr)
+	log.Fatal(http.ListenAndServe(*addr, issuer))
+}
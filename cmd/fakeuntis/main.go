@@ -0,0 +1,24 @@
+// Command fakeuntis serves a fake of the json rpc api of WebUntis with fixture data for offline development.
+// Point the backend at it by setting UNTIS_URL to http://<addr>/WebUntis/jsonrpc.do
+package main
+
+import (
+	"flag"
+	"github.com/refundable-tgm/huginn/untis/untistest"
+	"log"
+	"net/http"
+)
+
+// main function starting the fake untis server
+func main() {
+	addr := flag.String("addr", "localhost:8081", "address the fake untis server listens on")
+	fixtures := flag.String("fixtures", "untis/untistest/testdata/fixtures.json", "path to the json file containing the fixtures")
+	flag.Parse()
+	data, err := untistest.LoadFixtures(*fixtures)
+	if err != nil {
+		log.Fatal(err)
+	}
+	http.Handle("/WebUntis/jsonrpc.do", untistest.NewHandler(data))
+	log.Println("Serving fake untis at http://" + *addr + "/WebUntis/jsonrpc.do")
+	log.Fatal(http.ListenAndServe(*addr, nil))
+}
@@ -0,0 +1,103 @@
+package db
+
+import (
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"log"
+	"time"
+)
+
+// APIKey lets a machine client (e.g. an export script) call the api as a service principal without logging in
+type APIKey struct {
+	// ID identifies the key, it is part of the key itself
+	ID string `json:"id" example:"3f9c2a7e41b8d605"`
+	// Name describes what the key is used for
+	Name string `json:"name" example:"PEK monthly export"`
+	// Principal is the short name of the service principal the key acts as
+	Principal string `json:"principal" example:"svc-pek-export"`
+	// Endpoints lists the endpoints the key may call (e.g. /getAllApplications), the key may call no endpoint if it is empty
+	Endpoints []string `json:"endpoints" example:"/getAllApplications,/getTravelInvoiceExcel"`
+	// Hash is the sha256 hash of the key, the key itself is only shown when it is created
+	Hash string `json:"-"`
+	// CreatedAt is the time the key was created at
+	CreatedAt time.Time `json:"created_at"`
+	// CreatedBy is the short name of the administrator who created the key
+	CreatedBy string `json:"created_by" example:"mborko"`
+	// ExpiresAt is the time the key isn't accepted anymore
+	ExpiresAt time.Time `json:"expires_at"`
+	// RevokedAt is the time the key was revoked at, it is zero while the key isn't revoked
+	RevokedAt time.Time `json:"revoked_at"`
+	// LastUsed is the time the key was last accepted at
+	LastUsed time.Time `json:"last_used"`
+	// LastUsedIP is the address the key was last used from
+	LastUsedIP string `json:"last_used_ip" example:"10.2.24.12"`
+	// Uses is the amount of requests the key was accepted for
+	Uses int64 `json:"uses" example:"42"`
+}
+
+// CreateAPIKey stores a new API key
+// returns ErrConflict if a key with the same id exists
+func (m MongoDatabaseConnector) CreateAPIKey(key APIKey) (APIKey, error) {
+	collection := m.client.Database(m.database).Collection(APIKeyCollection)
+	if _, err := collection.InsertOne(m.context, key); err != nil {
+		log.Println(err)
+		return APIKey{}, wrapError(err)
+	}
+	return key, nil
+}
+
+// GetAPIKey returns the API key with the given id
+// returns ErrNotFound if no key has this id
+func (m MongoDatabaseConnector) GetAPIKey(id string) (APIKey, error) {
+	key := APIKey{}
+	collection := m.client.Database(m.database).Collection(APIKeyCollection)
+	if err := collection.FindOne(m.context, bson.M{"id": id}).Decode(&key); err != nil {
+		return APIKey{}, wrapError(err)
+	}
+	return key, nil
+}
+
+// GetAPIKeys returns all API keys, restricted to the given service principal if it isn't empty, the newest first
+func (m MongoDatabaseConnector) GetAPIKeys(principal string) ([]APIKey, error) {
+	filter := bson.M{}
+	if principal != "" {
+		filter["principal"] = principal
+	}
+	collection := m.client.Database(m.database).Collection(APIKeyCollection)
+	cursor, err := collection.Find(m.context, filter, options.Find().SetSort(bson.D{{Key: "createdat", Value: -1}}))
+	if err != nil {
+		return nil, wrapError(err)
+	}
+	keys := make([]APIKey, 0)
+	if err = cursor.All(m.context, &keys); err != nil {
+		return nil, wrapError(err)
+	}
+	return keys, nil
+}
+
+// RevokeAPIKey marks the API key with the given id as revoked, the key is kept so its usage can still be looked up
+// returns ErrNotFound if no key with this id which isn't revoked yet exists
+func (m MongoDatabaseConnector) RevokeAPIKey(id string) error {
+	collection := m.client.Database(m.database).Collection(APIKeyCollection)
+	result, err := collection.UpdateOne(m.context,
+		bson.M{"id": id, "revokedat": time.Time{}},
+		bson.M{"$set": bson.M{"revokedat": time.Now()}})
+	if err != nil {
+		log.Println(err)
+		return wrapError(err)
+	}
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// RecordAPIKeyUse counts a request the API key with the given id was accepted for
+func (m MongoDatabaseConnector) RecordAPIKeyUse(id, ip string) error {
+	collection := m.client.Database(m.database).Collection(APIKeyCollection)
+	_, err := collection.UpdateOne(m.context, bson.M{"id": id}, bson.M{
+		"$set": bson.M{"lastused": time.Now(), "lastusedip": ip},
+		"$inc": bson.M{"uses": 1},
+	})
+	return wrapError(err)
+}
@@ -0,0 +1,87 @@
+package db
+
+import (
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"log"
+	"time"
+)
+
+// delegations and deputy assignments both pass rights of one teacher to another for a limited time
+// they are stored in their own collections with the same layout: uuid, from, till, createdat, createdby and revokedat
+// the helpers below implement their storage once, the collection and the names of the two teacher fields are passed in
+
+// insertAssignment stores a new assignment in the given collection
+func (m MongoDatabaseConnector) insertAssignment(collection string, assignment interface{}) error {
+	if _, err := m.client.Database(m.database).Collection(collection).InsertOne(m.context, assignment); err != nil {
+		log.Println(err)
+		return wrapError(err)
+	}
+	return nil
+}
+
+// findAssignment decodes the assignment with the given uuid of the given collection into result
+// returns ErrNotFound if no assignment has this uuid
+func (m MongoDatabaseConnector) findAssignment(collection, uuid string, result interface{}) error {
+	if err := m.client.Database(m.database).Collection(collection).FindOne(m.context, bson.M{"uuid": uuid}).Decode(result); err != nil {
+		return wrapError(err)
+	}
+	return nil
+}
+
+// findAssignments decodes all assignments of the given collection matching the filter into results, the newest first
+// results has to point to a slice
+func (m MongoDatabaseConnector) findAssignments(collection string, filter interface{}, results interface{}) error {
+	cursor, err := m.client.Database(m.database).Collection(collection).Find(m.context, filter,
+		options.Find().SetSort(bson.D{{Key: "createdat", Value: -1}}))
+	if err != nil {
+		return wrapError(err)
+	}
+	if err = cursor.All(m.context, results); err != nil {
+		return wrapError(err)
+	}
+	return nil
+}
+
+// involvedIn returns a filter matching the assignments whose granting or receiving field contains the short name
+func involvedIn(granting, receiving, short string) bson.M {
+	return bson.M{"$or": bson.A{bson.M{granting: short}, bson.M{receiving: short}}}
+}
+
+// activeFor returns a filter matching the assignments whose receiving field contains the short name, which apply at the given time
+func activeFor(receiving, short string, at time.Time) bson.M {
+	return bson.M{
+		receiving:   short,
+		"revokedat": time.Time{},
+		"from":      bson.M{"$lte": at},
+		"till":      bson.M{"$gt": at},
+	}
+}
+
+// revokeAssignment marks the assignment with the given uuid of the given collection as revoked, it is kept so it can still be looked up
+// returns ErrNotFound if no assignment with this uuid which isn't revoked yet exists
+func (m MongoDatabaseConnector) revokeAssignment(collection, uuid string) error {
+	result, err := m.client.Database(m.database).Collection(collection).UpdateOne(m.context,
+		bson.M{"uuid": uuid, "revokedat": time.Time{}},
+		bson.M{"$set": bson.M{"revokedat": time.Now()}})
+	if err != nil {
+		log.Println(err)
+		return wrapError(err)
+	}
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// renameAssignments replaces the short name old by replacement in the granting, receiving and createdby fields of all assignments of the given collection
+func (m MongoDatabaseConnector) renameAssignments(collection, granting, receiving, old, replacement string) error {
+	c := m.client.Database(m.database).Collection(collection)
+	for _, field := range []string{granting, receiving, "createdby"} {
+		if _, err := c.UpdateMany(m.context, bson.M{field: old}, bson.M{"$set": bson.M{field: replacement}}); err != nil {
+			log.Println(err)
+			return wrapError(err)
+		}
+	}
+	return nil
+}
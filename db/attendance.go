@@ -0,0 +1,90 @@
+package db
+
+import (
+	"sort"
+	"time"
+)
+
+// Attendance is an interval a teacher attends a SchoolEvent in
+type Attendance struct {
+	// the start of the interval
+	From time.Time `json:"from"`
+	// the end of the interval
+	Till time.Time `json:"till"`
+}
+
+// Overlaps checks whether the attendance overlaps the other one
+func (a Attendance) Overlaps(other Attendance) bool {
+	return a.From.Before(other.Till) && other.From.Before(a.Till)
+}
+
+// AttendanceIntervals returns the intervals the teacher attends the school event of the application in
+// these are the Attendances, or AttendanceFrom till AttendanceTill if none are given, or the whole application if neither is set
+// the intervals are limited to the time of the application, sorted and overlapping intervals are merged
+func (t SchoolEventTeacherDetails) AttendanceIntervals(application Application) []Attendance {
+	intervals := t.Attendances
+	if len(intervals) == 0 && !t.AttendanceFrom.IsZero() && !t.AttendanceTill.IsZero() {
+		intervals = []Attendance{{t.AttendanceFrom, t.AttendanceTill}}
+	}
+	if len(intervals) == 0 {
+		return []Attendance{{application.StartTime, application.EndTime}}
+	}
+	return normalizeAttendances(intervals, application.StartTime, application.EndTime)
+}
+
+// AttendanceOf returns the intervals the teacher with the given short name is away because of the application
+// these are the attendance intervals for teachers of a school event and the whole application for everyone else
+func (application Application) AttendanceOf(short string) []Attendance {
+	if application.Kind == SchoolEvent {
+		for _, teacher := range application.SchoolEventDetails.Teachers {
+			if teacher.Shortname == short {
+				return teacher.AttendanceIntervals(application)
+			}
+		}
+	}
+	return []Attendance{{application.StartTime, application.EndTime}}
+}
+
+// AttendancesOverlap checks whether any interval of a overlaps any interval of b
+func AttendancesOverlap(a, b []Attendance) bool {
+	for _, x := range a {
+		for _, y := range b {
+			if x.Overlaps(y) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// normalizeAttendances limits the intervals to start and end (if they are set), drops empty intervals,
+// sorts the remaining ones and merges overlapping or adjacent intervals
+func normalizeAttendances(intervals []Attendance, start, end time.Time) []Attendance {
+	limited := make([]Attendance, 0, len(intervals))
+	for _, interval := range intervals {
+		if !start.IsZero() && interval.From.Before(start) {
+			interval.From = start
+		}
+		if !end.IsZero() && interval.Till.After(end) {
+			interval.Till = end
+		}
+		if interval.Till.After(interval.From) {
+			limited = append(limited, interval)
+		}
+	}
+	sort.Slice(limited, func(i, j int) bool {
+		return limited[i].From.Before(limited[j].From)
+	})
+	merged := make([]Attendance, 0, len(limited))
+	for _, interval := range limited {
+		last := len(merged) - 1
+		if last >= 0 && !interval.From.After(merged[last].Till) {
+			if interval.Till.After(merged[last].Till) {
+				merged[last].Till = interval.Till
+			}
+			continue
+		}
+		merged = append(merged, interval)
+	}
+	return merged
+}
@@ -0,0 +1,81 @@
+package db
+
+import (
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"log"
+	"time"
+)
+
+// Kinds of audit entries
+const (
+	// AuditLoginFailed a login was rejected because of wrong credentials or a wrong second factor
+	AuditLoginFailed = "login_failed"
+	// AuditLockout a username or address was locked out after too many failed logins
+	AuditLockout = "lockout"
+	// AuditLockoutCleared an administrator cleared a lockout
+	AuditLockoutCleared = "lockout_cleared"
+	// AuditDelegationCreated a teacher let another one act on their behalf
+	AuditDelegationCreated = "delegation_created"
+	// AuditDelegationRevoked a delegation was revoked before it ended
+	AuditDelegationRevoked = "delegation_revoked"
+	// AuditOnBehalf a delegate acted on behalf of the delegator, the delegate is the actor
+	AuditOnBehalf = "on_behalf"
+	// AuditDeputyCreated an approver named a deputy
+	AuditDeputyCreated = "deputy_created"
+	// AuditDeputyRevoked a deputy assignment was revoked before it ended
+	AuditDeputyRevoked = "deputy_revoked"
+	// AuditAsDeputy a deputy acted on an application in place of the approver, the deputy is the actor
+	AuditAsDeputy = "as_deputy"
+	// AuditTwoFactorReset an administrator removed the second factor of a teacher
+	AuditTwoFactorReset = "two_factor_reset"
+	// AuditLocalAccountSet an administrator created a local account or set its password
+	AuditLocalAccountSet = "local_account_set"
+	// AuditPermissionsSet an administrator replaced the manually granted roles of a teacher
+	AuditPermissionsSet = "permissions_set"
+)
+
+// AuditEntry records a security relevant event
+type AuditEntry struct {
+	// Time the event happened at
+	Time time.Time `json:"time"`
+	// Kind of the event
+	Kind string `json:"kind" example:"login_failed"`
+	// Username the event concerns
+	Username string `json:"username" example:"szakall"`
+	// IP address the event originated from
+	IP string `json:"ip" example:"10.2.24.12"`
+	// Actor is the short name of the teacher who caused the event, if it isn't the user itself (e.g. an administrator or a delegate)
+	Actor string `json:"actor" example:"mborko"`
+	// Message describes the event
+	Message string `json:"message" example:"invalid credentials"`
+}
+
+// CreateAuditEntry stores an audit entry
+func (m MongoDatabaseConnector) CreateAuditEntry(entry AuditEntry) error {
+	collection := m.client.Database(m.database).Collection(AuditCollection)
+	if _, err := collection.InsertOne(m.context, entry); err != nil {
+		log.Println(err)
+		return wrapError(err)
+	}
+	return nil
+}
+
+// GetAuditEntries returns the newest audit entries, restricted to the given username if it isn't empty
+// at most limit entries are returned
+func (m MongoDatabaseConnector) GetAuditEntries(username string, limit int64) ([]AuditEntry, error) {
+	filter := bson.M{}
+	if username != "" {
+		filter["username"] = username
+	}
+	collection := m.client.Database(m.database).Collection(AuditCollection)
+	cursor, err := collection.Find(m.context, filter, options.Find().SetSort(bson.D{{Key: "time", Value: -1}}).SetLimit(limit))
+	if err != nil {
+		return nil, wrapError(err)
+	}
+	entries := make([]AuditEntry, 0)
+	if err = cursor.All(m.context, &entries); err != nil {
+		return nil, wrapError(err)
+	}
+	return entries, nil
+}
@@ -35,6 +35,18 @@ const (
 	Companion
 )
 
+// Authentication providers a teacher can log in with
+const (
+	// LDAPProvider teachers log in with their directory account, teachers without provider belong to it too
+	LDAPProvider = "ldap"
+	// OIDCProvider teachers log in through an OpenID Connect identity provider
+	OIDCProvider = "oidc"
+	// LocalProvider teachers log in with a password stored in huginn
+	LocalProvider = "local"
+	// ServiceProvider teachers are service principals of API keys, they can't log in
+	ServiceProvider = "service"
+)
+
 // Enum for different modes of travel
 const (
 	OfficialBusinessCardClass2 = iota
@@ -101,6 +113,8 @@ type Application struct {
 	DestinationAddress string `json:"destination_address" example:"Karl Hönck Heim, Kärnten"`
 	// The timestamp this application was changed last
 	LastChanged time.Time `json:"last_changed"`
+	// The timestamp this application entered InProcess, it is set by the server and zero in every other progress state
+	InProcessSince time.Time `json:"in_process_since"`
 	// Further Details if this is of the kind SchoolEvent, if not this will be empty
 	SchoolEventDetails SchoolEventDetails `json:"school_event_details"`
 	// Further Details if this is of the kind Training, if not this will be empty
@@ -133,10 +147,12 @@ type SchoolEventTeacherDetails struct {
 	Name string `json:"name" example:"Stefan Zakall"`
 	// The short name (abbrevation) of a teacher
 	Shortname string `json:"shortname" example:"szakall"`
-	// The teacher will be attending the SchoolEvent from
+	// The teacher will be attending the SchoolEvent from (only used if Attendances is empty)
 	AttendanceFrom time.Time `json:"attendance_from"`
-	// The teacher will be attend the SchoolEvent till
+	// The teacher will be attend the SchoolEvent till (only used if Attendances is empty)
 	AttendanceTill time.Time `json:"attendance_till"`
+	// The intervals the teacher attends the SchoolEvent in, if the teacher doesn't attend the whole event at once
+	Attendances []Attendance `json:"attendances"`
 	// The group number
 	Group int `json:"group" example:"1"`
 	// Where the teacher starts their travel from
@@ -349,7 +365,9 @@ type Row struct {
 	Sum float32 `json:"sum" example:"4.32"`
 }
 
-// Teacher includes further information of a teacher (which isnt saved in the LDAP-instance)
+// Teacher includes further information of a teacher
+// the name, title, departments, staff number, mail address and groups are synchronized from the LDAP-instance if it provides them
+// its permissions are the union of the roles granted manually and the roles derived from its directory groups
 type Teacher struct {
 	// the uuid of this Teacher
 	UUID string `json:"uuid" example:"3fcf7f67-e0ed-4339-99b4-a6765aaa3dc4"`
@@ -365,6 +383,12 @@ type Teacher struct {
 	Administration bool `json:"administration" example:"true"`
 	// whether this Teacher as pek rights
 	PEK bool `json:"pek" example:"true"`
+	// The Departments this Teacher is head of
+	DepartmentHead []string `json:"department_head" example:"HIT"`
+	// The roles granted manually (e.g. through the set teacher permissions endpoint)
+	GrantedRoles Roles `json:"granted_roles"`
+	// The roles derived from the directory groups of the Teacher, they are replaced on every synchronization
+	DirectoryRoles Roles `json:"directory_roles"`
 	// Degree of the Teacher
 	Degree string `json:"degree" example:"DI"`
 	// Title of the Teacher
@@ -381,4 +405,39 @@ type Teacher struct {
 	Departments []string `json:"departments" example:"HIT,HBG"`
 	// The Untis abbrevation of the teacher
 	Untis string `json:"untis" example:"ZAKS"`
+	// The mail address of the teacher (synchronized from the directory)
+	Mail string `json:"mail" example:"szakall@tgm.ac.at"`
+	// The distinguished names of the directory groups the teacher is a member of (synchronized from the directory)
+	Groups []string `json:"groups" example:"CN=Lehrer,OU=Groups,DC=tgm,DC=ac,DC=at"`
+	// The time the teacher was last synchronized with the directory
+	LastSynced time.Time `json:"last_synced"`
+	// The time the teacher was first found missing in the directory, it is zero while the teacher exists there
+	MissingSince time.Time `json:"missing_since"`
+	// The time the teacher was deactivated at (e.g. after leaving the school), it is zero while the teacher is active
+	// deactivated teachers can't log in and aren't listed unless requested
+	DeactivatedAt time.Time `json:"deactivated_at"`
+	// The authentication provider the teacher logs in with (ldap if empty)
+	Provider string `json:"provider" example:"ldap"`
+	// The hashed password of teachers logging in with the local provider
+	PasswordHash string `json:"-"`
+	// The subject (sub claim) of the account at the OpenID Connect identity provider, the teacher is bound to it on the first login there
+	OIDCSubject string `json:"-"`
+	// The second factor (TOTP) of the teacher
+	TwoFactor TwoFactor `json:"two_factor"`
+}
+
+// TwoFactor is the configuration of the time-based one-time passwords (TOTP) a teacher logs in with in addition to the password
+type TwoFactor struct {
+	// whether the teacher has to enter a one-time password at login
+	Enabled bool `json:"enabled" example:"true"`
+	// the time the second factor was enabled at
+	EnabledAt time.Time `json:"enabled_at"`
+	// the base32 encoded secret the one-time passwords are derived from
+	Secret string `json:"-"`
+	// the secret of an enrolment which wasn't confirmed yet
+	PendingSecret string `json:"-"`
+	// the sha256 hashes of the unused recovery codes
+	RecoveryCodes []string `json:"-"`
+	// the last time step a one-time password was accepted for, so every password can only be used once
+	LastStep int64 `json:"-"`
 }
@@ -0,0 +1,71 @@
+package db
+
+import "time"
+
+// Delegation lets the delegate create and edit the applications and invoices of the delegator for a limited time
+type Delegation struct {
+	// UUID identifies the delegation
+	UUID string `json:"uuid" example:"0b5a3c56-4e0d-4d6c-9a57-3f1b9b1f2c11"`
+	// Delegator is the short name of the teacher whose applications may be edited
+	Delegator string `json:"delegator" example:"szakall"`
+	// Delegate is the short name of the teacher acting on behalf of the delegator
+	Delegate string `json:"delegate" example:"ehuber"`
+	// From is the time the delegation starts
+	From time.Time `json:"from"`
+	// Till is the time the delegation ends
+	Till time.Time `json:"till"`
+	// CreatedAt is the time the delegation was created at
+	CreatedAt time.Time `json:"created_at"`
+	// CreatedBy is the short name of the teacher who created the delegation
+	CreatedBy string `json:"created_by" example:"szakall"`
+	// RevokedAt is the time the delegation was revoked at, it is zero while the delegation isn't revoked
+	RevokedAt time.Time `json:"revoked_at"`
+}
+
+// CreateDelegation stores a new delegation
+func (m MongoDatabaseConnector) CreateDelegation(delegation Delegation) (Delegation, error) {
+	if err := m.insertAssignment(DelegationCollection, delegation); err != nil {
+		return Delegation{}, err
+	}
+	return delegation, nil
+}
+
+// GetDelegation returns the delegation with the given uuid
+// returns ErrNotFound if no delegation has this uuid
+func (m MongoDatabaseConnector) GetDelegation(uuid string) (Delegation, error) {
+	delegation := Delegation{}
+	if err := m.findAssignment(DelegationCollection, uuid, &delegation); err != nil {
+		return Delegation{}, err
+	}
+	return delegation, nil
+}
+
+// GetDelegations returns the delegations the teacher with the given short name granted or received, the newest first
+func (m MongoDatabaseConnector) GetDelegations(short string) ([]Delegation, error) {
+	return m.findDelegations(involvedIn("delegator", "delegate", short))
+}
+
+// GetActiveDelegations returns the delegations the teacher with the given short name received which apply at the given time
+func (m MongoDatabaseConnector) GetActiveDelegations(delegate string, at time.Time) ([]Delegation, error) {
+	return m.findDelegations(activeFor("delegate", delegate, at))
+}
+
+// RevokeDelegation marks the delegation with the given uuid as revoked, it is kept so it can still be looked up
+// returns ErrNotFound if no delegation with this uuid which isn't revoked yet exists
+func (m MongoDatabaseConnector) RevokeDelegation(uuid string) error {
+	return m.revokeAssignment(DelegationCollection, uuid)
+}
+
+// renameDelegations replaces the short name old in all delegations by replacement
+func (m MongoDatabaseConnector) renameDelegations(old, replacement string) error {
+	return m.renameAssignments(DelegationCollection, "delegator", "delegate", old, replacement)
+}
+
+// findDelegations returns all delegations matching the given filter, the newest first
+func (m MongoDatabaseConnector) findDelegations(filter interface{}) ([]Delegation, error) {
+	delegations := make([]Delegation, 0)
+	if err := m.findAssignments(DelegationCollection, filter, &delegations); err != nil {
+		return nil, err
+	}
+	return delegations, nil
+}
@@ -0,0 +1,71 @@
+package db
+
+import "time"
+
+// DeputyAssignment lets the deputy approve applications in place of the approver for a limited time, e.g. while the approver is on a school trip
+type DeputyAssignment struct {
+	// UUID identifies the assignment
+	UUID string `json:"uuid" example:"5d0c7f4e-2b1a-4c8e-9f3d-6a7b8c9d0e1f"`
+	// Approver is the short name of the absent approver
+	Approver string `json:"approver" example:"mborko"`
+	// Deputy is the short name of the teacher approving in place of the approver
+	Deputy string `json:"deputy" example:"szakall"`
+	// From is the time the assignment starts
+	From time.Time `json:"from"`
+	// Till is the time the assignment ends
+	Till time.Time `json:"till"`
+	// CreatedAt is the time the assignment was created at
+	CreatedAt time.Time `json:"created_at"`
+	// CreatedBy is the short name of the teacher who created the assignment
+	CreatedBy string `json:"created_by" example:"mborko"`
+	// RevokedAt is the time the assignment was revoked at, it is zero while the assignment isn't revoked
+	RevokedAt time.Time `json:"revoked_at"`
+}
+
+// CreateDeputyAssignment stores a new deputy assignment
+func (m MongoDatabaseConnector) CreateDeputyAssignment(assignment DeputyAssignment) (DeputyAssignment, error) {
+	if err := m.insertAssignment(DeputyCollection, assignment); err != nil {
+		return DeputyAssignment{}, err
+	}
+	return assignment, nil
+}
+
+// GetDeputyAssignment returns the deputy assignment with the given uuid
+// returns ErrNotFound if no assignment has this uuid
+func (m MongoDatabaseConnector) GetDeputyAssignment(uuid string) (DeputyAssignment, error) {
+	assignment := DeputyAssignment{}
+	if err := m.findAssignment(DeputyCollection, uuid, &assignment); err != nil {
+		return DeputyAssignment{}, err
+	}
+	return assignment, nil
+}
+
+// GetDeputyAssignments returns the deputy assignments the teacher with the given short name made or received, the newest first
+func (m MongoDatabaseConnector) GetDeputyAssignments(short string) ([]DeputyAssignment, error) {
+	return m.findDeputyAssignments(involvedIn("approver", "deputy", short))
+}
+
+// GetActiveDeputyAssignments returns the deputy assignments the teacher with the given short name received which apply at the given time
+func (m MongoDatabaseConnector) GetActiveDeputyAssignments(deputy string, at time.Time) ([]DeputyAssignment, error) {
+	return m.findDeputyAssignments(activeFor("deputy", deputy, at))
+}
+
+// RevokeDeputyAssignment marks the deputy assignment with the given uuid as revoked, it is kept so it can still be looked up
+// returns ErrNotFound if no assignment with this uuid which isn't revoked yet exists
+func (m MongoDatabaseConnector) RevokeDeputyAssignment(uuid string) error {
+	return m.revokeAssignment(DeputyCollection, uuid)
+}
+
+// renameDeputyAssignments replaces the short name old in all deputy assignments by replacement
+func (m MongoDatabaseConnector) renameDeputyAssignments(old, replacement string) error {
+	return m.renameAssignments(DeputyCollection, "approver", "deputy", old, replacement)
+}
+
+// findDeputyAssignments returns all deputy assignments matching the given filter, the newest first
+func (m MongoDatabaseConnector) findDeputyAssignments(filter interface{}) ([]DeputyAssignment, error) {
+	assignments := make([]DeputyAssignment, 0)
+	if err := m.findAssignments(DeputyCollection, filter, &assignments); err != nil {
+		return nil, err
+	}
+	return assignments, nil
+}
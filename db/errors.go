@@ -0,0 +1,51 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// duplicateKeyCode is the error code the mongo db server responds with if a unique index is violated
+const duplicateKeyCode = 11000
+
+// ErrNotFound is returned if the requested document doesn't exist in the database
+var ErrNotFound = errors.New("document not found")
+
+// ErrConflict is returned if a document can't be written because it collides with an existing one
+var ErrConflict = errors.New("document conflicts with an existing one")
+
+// ErrUnavailable is returned if the database couldn't be reached or failed to process the operation
+var ErrUnavailable = errors.New("database unavailable")
+
+// wrapError converts an error returned by the mongo driver into one of the sentinel errors of this package
+// the original error message is kept, so it can still be logged
+func wrapError(err error) error {
+	if err == nil {
+		return nil
+	}
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return ErrNotFound
+	}
+	if isDuplicateKeyError(err) {
+		return fmt.Errorf("%w: %v", ErrConflict, err)
+	}
+	return fmt.Errorf("%w: %v", ErrUnavailable, err)
+}
+
+// isDuplicateKeyError checks whether an error returned by the mongo driver was caused by a violated unique index
+func isDuplicateKeyError(err error) bool {
+	var writeException mongo.WriteException
+	if errors.As(err, &writeException) {
+		for _, we := range writeException.WriteErrors {
+			if we.Code == duplicateKeyCode {
+				return true
+			}
+		}
+	}
+	var commandError mongo.CommandError
+	if errors.As(err, &commandError) {
+		return commandError.Code == duplicateKeyCode
+	}
+	return false
+}
@@ -0,0 +1,103 @@
+package db
+
+import (
+	"go.mongodb.org/mongo-driver/bson"
+	"time"
+)
+
+// ActiveProgress lists all progress states an application is considered active in
+var ActiveProgress = []int{
+	Rejected,
+	InSubmission,
+	InProcess,
+	Confirmed,
+	Running,
+	CostsPending,
+	CostsInProcess,
+}
+
+// ApplicationFilter describes which applications should be returned when searching the Application collection
+// Fields left at their zero value don't restrict the result
+type ApplicationFilter struct {
+	// Participant restricts the result to applications this teacher takes part in
+	// (listed as teacher of a school event or filer of any other application)
+	Participant *Teacher
+	// AnyParticipant restricts the result to applications at least one of these teachers takes part in
+	AnyParticipant []Teacher
+	// Department restricts the result to applications a teacher of this department takes part in
+	Department string
+	// Kinds restricts the result to applications of one of these kinds
+	Kinds []int
+	// Progress restricts the result to applications in one of these progress states
+	Progress []int
+	// Classes restricts the result to school events at least one of these classes participates in
+	Classes []string
+	// From restricts the result to applications ending after this point in time
+	From time.Time
+	// Till restricts the result to applications starting before this point in time
+	Till time.Time
+	// EscalatedBefore restricts applications in InProcess to the ones which entered this state before this point in time
+	// applications in other progress states aren't affected
+	EscalatedBefore time.Time
+}
+
+// query converts the filter into a mongo filter document
+// the teachers of the Department have to be resolved beforehand and are passed as departmentTeachers
+func (f ApplicationFilter) query(departmentTeachers []Teacher) bson.M {
+	conditions := bson.A{}
+	if f.Participant != nil {
+		conditions = append(conditions, participantQuery(*f.Participant))
+	}
+	if len(f.AnyParticipant) > 0 {
+		anyOf := bson.A{}
+		for _, teacher := range f.AnyParticipant {
+			anyOf = append(anyOf, participantQuery(teacher))
+		}
+		conditions = append(conditions, bson.M{"$or": anyOf})
+	}
+	if f.Department != "" {
+		anyOf := bson.A{}
+		for _, teacher := range departmentTeachers {
+			anyOf = append(anyOf, participantQuery(teacher))
+		}
+		if len(anyOf) == 0 {
+			// no teacher belongs to this department, so no application may match
+			anyOf = append(anyOf, bson.M{"uuid": bson.M{"$in": bson.A{}}})
+		}
+		conditions = append(conditions, bson.M{"$or": anyOf})
+	}
+	if len(f.Kinds) > 0 {
+		conditions = append(conditions, bson.M{"kind": bson.M{"$in": f.Kinds}})
+	}
+	if len(f.Progress) > 0 {
+		conditions = append(conditions, bson.M{"progress": bson.M{"$in": f.Progress}})
+	}
+	if len(f.Classes) > 0 {
+		conditions = append(conditions, bson.M{"schooleventdetails.classes": bson.M{"$in": f.Classes}})
+	}
+	if !f.From.IsZero() {
+		conditions = append(conditions, bson.M{"endtime": bson.M{"$gte": f.From}})
+	}
+	if !f.Till.IsZero() {
+		conditions = append(conditions, bson.M{"starttime": bson.M{"$lte": f.Till}})
+	}
+	if !f.EscalatedBefore.IsZero() {
+		conditions = append(conditions, bson.M{"$or": bson.A{
+			bson.M{"progress": bson.M{"$ne": InProcess}},
+			bson.M{"inprocesssince": bson.M{"$lte": f.EscalatedBefore}},
+		}})
+	}
+	if len(conditions) == 0 {
+		return bson.M{}
+	}
+	return bson.M{"$and": conditions}
+}
+
+// participantQuery returns a mongo filter document matching every application the given teacher takes part in
+func participantQuery(teacher Teacher) bson.M {
+	return bson.M{"$or": bson.A{
+		bson.M{"kind": SchoolEvent, "schooleventdetails.teachers.shortname": teacher.Short},
+		bson.M{"kind": Training, "trainingdetails.filer": teacher.Longname},
+		bson.M{"kind": OtherReason, "otherreasondetails.filer": teacher.Longname},
+	}}
+}
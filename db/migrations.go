@@ -0,0 +1,284 @@
+package db
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"log"
+	"sort"
+	"time"
+)
+
+// Migration is a versioned change of the data or the schema stored in the database
+type Migration struct {
+	// Version identifies the migration, migrations are applied in ascending order of their versions
+	Version int
+	// Description explains what the migration changes
+	Description string
+	// Up applies the migration to the given database
+	Up func(ctx context.Context, database *mongo.Database) error
+}
+
+// AppliedMigration is the record stored in the MigrationCollection for every migration that was applied
+type AppliedMigration struct {
+	// Version of the applied migration
+	Version int `json:"version"`
+	// Description of the applied migration
+	Description string `json:"description"`
+	// AppliedAt is the time the migration was applied at
+	AppliedAt time.Time `json:"applied_at"`
+}
+
+// collectionIndexes are the indexes of one collection
+type collectionIndexes struct {
+	// the name of the collection
+	collection string
+	// the indexes the collection should have
+	models []mongo.IndexModel
+}
+
+// indexes lists the indexes every collection should have, they are created by EnsureIndexes
+var indexes = []collectionIndexes{
+	{ApplicationCollection, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "uuid", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "progress", Value: 1}, {Key: "lastchanged", Value: -1}}},
+		{Keys: bson.D{{Key: "progress", Value: 1}, {Key: "inprocesssince", Value: 1}}},
+		{Keys: bson.D{{Key: "starttime", Value: 1}, {Key: "endtime", Value: 1}}},
+		{Keys: bson.D{{Key: "schooleventdetails.teachers.shortname", Value: 1}}},
+		{Keys: bson.D{{Key: "trainingdetails.filer", Value: 1}}},
+		{Keys: bson.D{{Key: "otherreasondetails.filer", Value: 1}}},
+		{Keys: bson.D{{Key: "schooleventdetails.classes", Value: 1}}},
+		{Keys: bson.D{{Key: "kind", Value: 1}}},
+		{Keys: bson.D{
+			{Key: "name", Value: "text"},
+			{Key: "notes", Value: "text"},
+			{Key: "destinationaddress", Value: "text"},
+			{Key: "trainingdetails.organizer", Value: "text"},
+			{Key: "schooleventdetails.classes", Value: "text"},
+			{Key: "schooleventdetails.teachers.name", Value: "text"},
+			{Key: "trainingdetails.filer", Value: "text"},
+			{Key: "otherreasondetails.filer", Value: "text"},
+		}, Options: options.Index().
+			SetName(ApplicationTextIndex).
+			SetDefaultLanguage(SearchLanguage).
+			SetWeights(bson.D{{Key: "name", Value: 10}, {Key: "destinationaddress", Value: 5}, {Key: "trainingdetails.organizer", Value: 5}})},
+	}},
+	{TeacherCollection, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "uuid", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "short", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "untis", Value: 1}}},
+		{Keys: bson.D{{Key: "longname", Value: 1}}},
+		{Keys: bson.D{{Key: "departments", Value: 1}}},
+	}},
+	{AuditCollection, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "time", Value: -1}}},
+		{Keys: bson.D{{Key: "username", Value: 1}, {Key: "time", Value: -1}}},
+	}},
+	{APIKeyCollection, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "id", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "principal", Value: 1}}},
+	}},
+	{DelegationCollection, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "uuid", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "delegate", Value: 1}, {Key: "till", Value: 1}}},
+		{Keys: bson.D{{Key: "delegator", Value: 1}}},
+	}},
+	{DeputyCollection, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "uuid", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "deputy", Value: 1}, {Key: "till", Value: 1}}},
+		{Keys: bson.D{{Key: "approver", Value: 1}}},
+	}},
+	{MigrationCollection, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "version", Value: 1}}, Options: options.Index().SetUnique(true)},
+	}},
+}
+
+// migrations lists all migrations known to this version of the software
+// new migrations have to be appended with a higher version, applied migrations must never be changed
+var migrations = []Migration{
+	{
+		Version:     1,
+		Description: "move teachers sharing a short name with an older teacher to TeacherDuplicate, so short names can be indexed uniquely",
+		Up:          removeDuplicateTeachers,
+	},
+	{
+		Version:     2,
+		Description: "keep the permissions set before directory groups were mapped onto roles as manually granted roles",
+		Up:          grantExistingPermissions,
+	},
+	{
+		Version:     3,
+		Description: "assume applications in process entered this state when they were changed last, so they can be escalated",
+		Up:          stampInProcessSince,
+	},
+}
+
+// PrepareDatabase applies all pending migrations and creates all indexes afterwards
+// it has to be called after InitPool and before the database is used
+func PrepareDatabase(ctx context.Context) error {
+	m := MongoDatabaseConnector{}
+	if err := m.Connect(ctx); err != nil {
+		return err
+	}
+	defer m.Close()
+	applied, err := m.Migrate()
+	if err != nil {
+		return err
+	}
+	for _, migration := range applied {
+		log.Println("Applied migration ", migration.Version, ": ", migration.Description)
+	}
+	return m.EnsureIndexes()
+}
+
+// Migrate applies every migration that isn't recorded in the MigrationCollection yet in the order of their versions
+// returns the migrations applied during this call
+func (m MongoDatabaseConnector) Migrate() ([]AppliedMigration, error) {
+	database := m.client.Database(m.database)
+	collection := database.Collection(MigrationCollection)
+	cursor, err := collection.Find(m.context, bson.M{})
+	if err != nil {
+		return nil, wrapError(err)
+	}
+	records := make([]AppliedMigration, 0)
+	if err = cursor.All(m.context, &records); err != nil {
+		return nil, wrapError(err)
+	}
+	done := make(map[int]bool)
+	for _, record := range records {
+		done[record.Version] = true
+	}
+	pending := make([]Migration, 0)
+	for _, migration := range migrations {
+		if !done[migration.Version] {
+			pending = append(pending, migration)
+		}
+	}
+	sort.Slice(pending, func(i, j int) bool {
+		return pending[i].Version < pending[j].Version
+	})
+	applied := make([]AppliedMigration, 0)
+	for _, migration := range pending {
+		if err := migration.Up(m.context, database); err != nil {
+			return applied, wrapError(err)
+		}
+		record := AppliedMigration{
+			Version:     migration.Version,
+			Description: migration.Description,
+			AppliedAt:   time.Now(),
+		}
+		if _, err := collection.InsertOne(m.context, record); err != nil {
+			return applied, wrapError(err)
+		}
+		applied = append(applied, record)
+	}
+	return applied, nil
+}
+
+// EnsureIndexes creates all indexes listed in indexes, existing indexes are left untouched
+func (m MongoDatabaseConnector) EnsureIndexes() error {
+	for _, index := range indexes {
+		collection := m.client.Database(m.database).Collection(index.collection)
+		if _, err := collection.Indexes().CreateMany(m.context, index.models); err != nil {
+			return wrapError(err)
+		}
+	}
+	return nil
+}
+
+// removeDuplicateTeachers deletes all teachers which share their short name with an older teacher
+// the deleted teachers are copied to TeacherDuplicateCollection first, so their data can still be restored or merged by hand
+func removeDuplicateTeachers(ctx context.Context, database *mongo.Database) error {
+	collection := database.Collection(TeacherCollection)
+	pipeline := mongo.Pipeline{
+		{{Key: "$sort", Value: bson.D{{Key: "_id", Value: 1}}}},
+		{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: "$short"},
+			{Key: "ids", Value: bson.D{{Key: "$push", Value: "$_id"}}},
+		}}},
+		{{Key: "$match", Value: bson.D{{Key: "ids.1", Value: bson.D{{Key: "$exists", Value: true}}}}}},
+	}
+	cursor, err := collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return err
+	}
+	duplicates := make([]struct {
+		Short string               `bson:"_id"`
+		IDs   []primitive.ObjectID `bson:"ids"`
+	}, 0)
+	if err = cursor.All(ctx, &duplicates); err != nil {
+		return err
+	}
+	backup := database.Collection(TeacherDuplicateCollection)
+	for _, duplicate := range duplicates {
+		removed := bson.M{"_id": bson.M{"$in": duplicate.IDs[1:]}}
+		cursor, err := collection.Find(ctx, removed)
+		if err != nil {
+			return err
+		}
+		documents := make([]bson.M, 0)
+		if err = cursor.All(ctx, &documents); err != nil {
+			return err
+		}
+		for _, document := range documents {
+			// upserting keeps the backup intact if the migration is repeated after it failed halfway
+			if _, err := backup.ReplaceOne(ctx, bson.M{"_id": document["_id"]}, document, options.Replace().SetUpsert(true)); err != nil {
+				return err
+			}
+		}
+		result, err := collection.DeleteMany(ctx, removed)
+		if err != nil {
+			return err
+		}
+		log.Println("Moved ", result.DeletedCount, " duplicates of the teacher ", duplicate.Short, " to ", TeacherDuplicateCollection, ": ", duplicate.IDs[1:])
+	}
+	return nil
+}
+
+// grantExistingPermissions copies the permissions of every teacher into its granted roles,
+// so they aren't lost once the permissions are derived from the granted and directory roles
+func grantExistingPermissions(ctx context.Context, database *mongo.Database) error {
+	collection := database.Collection(TeacherCollection)
+	cursor, err := collection.Find(ctx, bson.M{"grantedroles": bson.M{"$exists": false}})
+	if err != nil {
+		return err
+	}
+	teachers := make([]Teacher, 0)
+	if err = cursor.All(ctx, &teachers); err != nil {
+		return err
+	}
+	for _, teacher := range teachers {
+		granted := Roles{
+			SuperUser:      teacher.SuperUser,
+			AV:             teacher.AV,
+			Administration: teacher.Administration,
+			PEK:            teacher.PEK,
+			DepartmentHead: make([]string, 0),
+		}
+		if _, err := collection.UpdateOne(ctx, bson.M{"uuid": teacher.UUID}, bson.M{"$set": bson.M{"grantedroles": granted}}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// stampInProcessSince sets the time applications in process entered this state to the time they were changed last
+func stampInProcessSince(ctx context.Context, database *mongo.Database) error {
+	collection := database.Collection(ApplicationCollection)
+	cursor, err := collection.Find(ctx, bson.M{"progress": InProcess, "inprocesssince": bson.M{"$exists": false}})
+	if err != nil {
+		return err
+	}
+	applications := make([]Application, 0)
+	if err = cursor.All(ctx, &applications); err != nil {
+		return err
+	}
+	for _, application := range applications {
+		if _, err := collection.UpdateOne(ctx, bson.M{"uuid": application.UUID}, bson.M{"$set": bson.M{"inprocesssince": application.LastChanged}}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
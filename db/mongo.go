@@ -2,6 +2,7 @@ package db
 
 import (
 	"context"
+	"fmt"
 	"github.com/google/uuid"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
@@ -19,9 +20,39 @@ const TeacherCollection = "Teacher"
 // ApplicationCollection is the name of the collection in which the Application data is stored in
 const ApplicationCollection = "Application"
 
+// MigrationCollection is the name of the collection in which the applied migrations are recorded
+const MigrationCollection = "Migration"
+
+// AuditCollection is the name of the collection in which the audit entries are stored in
+const AuditCollection = "Audit"
+
+// APIKeyCollection is the name of the collection in which the API keys are stored in
+const APIKeyCollection = "APIKey"
+
+// DelegationCollection is the name of the collection in which the delegations are stored in
+const DelegationCollection = "Delegation"
+
+// DeputyCollection is the name of the collection in which the deputy assignments of approvers are stored in
+const DeputyCollection = "Deputy"
+
+// TeacherDuplicateCollection is the name of the collection in which the duplicate teachers removed by the first migration are kept in
+const TeacherDuplicateCollection = "TeacherDuplicate"
+
 // SuperUserPath is the path to a file containing the name of the first Teacher to become a super user
 const SuperUserPath = "/vol/files/.superuser"
 
+// RequestTimeout is the maximum time a MongoDatabaseConnector is allowed to use the shared pool for
+const RequestTimeout = 30 * time.Second
+
+// MaxPoolSize is the maximum amount of connections the shared pool keeps open to the MongoDB server
+const MaxPoolSize = 100
+
+// pool is the long-lived client (and therefore connection pool) shared by every MongoDatabaseConnector
+var pool *mongo.Client
+
+// poolDatabase is the name of the database in the mongo db server the pool operates on
+var poolDatabase string
+
 // The MongoDatabaseConnector saves data used for the mongo db connection
 type MongoDatabaseConnector struct {
 	// the name of the database in the mongo db server
@@ -34,255 +65,306 @@ type MongoDatabaseConnector struct {
 	closer context.CancelFunc
 }
 
-// Connect the MongoDatabaseConnector with the given MongoDB server
-// returns whether this operation was successful
-func (m *MongoDatabaseConnector) Connect() bool {
+// InitPool creates the shared client and its connection pool to the MongoDB server
+// it has to be called once before any MongoDatabaseConnector is connected
+func InitPool() error {
 	uri, db, ok := resolveURI()
-	if ok {
-		client, err := mongo.NewClient(options.Client().ApplyURI(uri))
-		if err != nil {
-			log.Println(err)
-			return false
-		}
-		ctx, cf := context.WithTimeout(context.Background(), 10*time.Minute)
-		err = client.Connect(ctx)
-		if err != nil {
-			log.Println(err)
-			cf()
-			return false
-		}
-		m.client = client
-		m.database = db
-		m.context = ctx
-		m.closer = cf
-		return true
+	if !ok {
+		return fmt.Errorf("couldn't resolve the mongo db uri")
 	}
-	return false
+	client, err := mongo.NewClient(options.Client().ApplyURI(uri).SetMaxPoolSize(MaxPoolSize))
+	if err != nil {
+		return err
+	}
+	ctx, cf := context.WithTimeout(context.Background(), RequestTimeout)
+	defer cf()
+	err = client.Connect(ctx)
+	if err != nil {
+		return err
+	}
+	pool = client
+	poolDatabase = db
+	return nil
 }
 
-// Close the Connection to the MongoDB
-// returns whether this operation was successful
-func (m MongoDatabaseConnector) Close() (ok bool) {
-	err := m.client.Disconnect(m.context)
-	m.closer()
-	if err != nil {
-		log.Println(err)
-		return false
+// ClosePool disconnects the shared client and closes all connections of its pool
+func ClosePool(ctx context.Context) error {
+	if pool == nil {
+		return nil
+	}
+	err := pool.Disconnect(ctx)
+	pool = nil
+	return err
+}
+
+// Connect the MongoDatabaseConnector with the shared pool
+// every operation of this MongoDatabaseConnector is bound to the given context and limited by RequestTimeout
+// returns ErrUnavailable if the pool isn't initialized
+func (m *MongoDatabaseConnector) Connect(ctx context.Context) error {
+	if pool == nil {
+		return fmt.Errorf("%w: pool isn't initialized", ErrUnavailable)
 	}
-	return true
+	m.client = pool
+	m.database = poolDatabase
+	m.context, m.closer = context.WithTimeout(ctx, RequestTimeout)
+	return nil
+}
+
+// Close releases the context of this MongoDatabaseConnector, the connections stay in the shared pool
+func (m MongoDatabaseConnector) Close() error {
+	m.closer()
+	return nil
 }
 
 // CreateApplication creates a new application in the collection in the database
-func (m MongoDatabaseConnector) CreateApplication(application Application) bool {
+// returns the created application including its generated uuid
+func (m MongoDatabaseConnector) CreateApplication(application Application) (Application, error) {
 	application.UUID = uuid.New().String()
 	collection := m.client.Database(m.database).Collection(ApplicationCollection)
 	insert, err := collection.InsertOne(m.context, application)
 	if err != nil {
 		log.Println(err)
-		return false
+		return Application{}, wrapError(err)
 	}
 	log.Println("Inserted a new application with the UUID: ", application.UUID,
 		"; the Title: ", application.Name, "; under the ID: ", insert.InsertedID)
-	return true
+	return application, nil
 }
 
 // GetApplication returns a specific application described and identified by its uuid
-func (m MongoDatabaseConnector) GetApplication(uuid string) (application Application) {
+// returns ErrNotFound if no application has this uuid
+func (m MongoDatabaseConnector) GetApplication(uuid string) (Application, error) {
+	application := Application{}
 	collection := m.client.Database(m.database).Collection(ApplicationCollection)
 	if err := collection.FindOne(m.context, bson.M{"uuid": uuid}).Decode(&application); err != nil {
-		log.Println(err)
-		return
+		return Application{}, wrapError(err)
 	}
-	return application
+	return application, nil
 }
 
 // GetAllApplications analyzes all applications contained in the collection and returns them as an array
-func (m MongoDatabaseConnector) GetAllApplications() (applications []Application) {
-	collection := m.client.Database(m.database).Collection(ApplicationCollection)
-	cursor, err := collection.Find(m.context, bson.M{})
-	if err != nil {
-		log.Println(err)
-		return
-	}
-	if err = cursor.All(m.context, &applications); err != nil {
-		log.Println(err)
-		return
-	}
-	return
+func (m MongoDatabaseConnector) GetAllApplications() ([]Application, error) {
+	return m.findApplications(bson.M{})
 }
 
 // GetActiveApplications returns all currently active applications stored in the database
-func (m MongoDatabaseConnector) GetActiveApplications() (applications []Application) {
-	filter := bson.M{
-		"progress": bson.M{
-			"$in": []int{
-				Rejected,
-				InSubmission,
-				InProcess,
-				Confirmed,
-				Running,
-				CostsPending,
-				CostsInProcess,
-			},
-		},
-	}
-	collection := m.client.Database(m.database).Collection(ApplicationCollection)
-	cursor, err := collection.Find(m.context, filter)
+func (m MongoDatabaseConnector) GetActiveApplications() ([]Application, error) {
+	return m.FindApplications(ApplicationFilter{Progress: ActiveProgress})
+}
+
+// FindApplications returns all applications matching the given filter
+// the filter is evaluated by the database, so only matching applications are loaded
+func (m MongoDatabaseConnector) FindApplications(filter ApplicationFilter) ([]Application, error) {
+	query, err := m.applicationQuery(filter)
 	if err != nil {
-		log.Println(err)
-		return
+		return nil, err
 	}
-	err = cursor.All(m.context, &applications)
+	return m.findApplications(query)
+}
+
+// GetLatestApplications returns at most limit applications matching the given filter, the last changed one first
+func (m MongoDatabaseConnector) GetLatestApplications(filter ApplicationFilter, limit int64) ([]Application, error) {
+	query, err := m.applicationQuery(filter)
 	if err != nil {
-		log.Println(err)
-		return
+		return nil, err
 	}
-	return applications
+	opts := options.Find().SetSort(bson.D{{Key: "lastchanged", Value: -1}}).SetLimit(limit)
+	return m.findApplications(query, opts)
 }
 
 // UpdateApplication updates an application with the matching uuid and updates it with the data in the update struct
-// returns true whether one Application was modified, false if an error occurred or no Application was modified
-func (m MongoDatabaseConnector) UpdateApplication(uuid string, update Application) bool {
+// returns ErrNotFound if no application has this uuid
+func (m MongoDatabaseConnector) UpdateApplication(uuid string, update Application) error {
 	update.UUID = uuid
 	collection := m.client.Database(m.database).Collection(ApplicationCollection)
 	result, err := collection.ReplaceOne(m.context, bson.M{"uuid": uuid}, update)
 	if err != nil {
 		log.Println(err)
-		return false
+		return wrapError(err)
 	}
-	return result.ModifiedCount == 1
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
 }
 
 // DeleteApplication deletes an application described by the given uuid
-// returns true if a document was deleted, false if not or if an error occurred
-func (m MongoDatabaseConnector) DeleteApplication(uuid string) bool {
+// returns ErrNotFound if no application has this uuid
+func (m MongoDatabaseConnector) DeleteApplication(uuid string) error {
 	collection := m.client.Database(m.database).Collection(ApplicationCollection)
 	result, err := collection.DeleteOne(m.context, bson.M{"uuid": uuid})
 	if err != nil {
 		log.Println(err)
-		return false
+		return wrapError(err)
+	}
+	if result.DeletedCount == 0 {
+		return ErrNotFound
 	}
-	return result.DeletedCount == 1
+	return nil
 }
 
 // DoesApplicationExist searches the database for a Application identified by a given UUID
 // and checks whether an Application can be found whilst performing this search.
-// It will return true if the Application was found, false if an error occurred or none was found.
-func (m MongoDatabaseConnector) DoesApplicationExist(uuid string) bool {
-	application := Application{}
-	collection := m.client.Database(m.database).Collection(ApplicationCollection)
-	if err := collection.FindOne(m.context, bson.M{"uuid": uuid}).Decode(&application); err != nil {
-		return false
-	}
-	return true
+// It will return true if the Application was found, false if none was found and an error if the search failed.
+func (m MongoDatabaseConnector) DoesApplicationExist(uuid string) (bool, error) {
+	return m.exists(ApplicationCollection, bson.M{"uuid": uuid})
 }
 
-// CreateTeacher creates a new application in the system
-// it will return true if this operation was successful and false if not
-func (m MongoDatabaseConnector) CreateTeacher(teacher Teacher) bool {
+// CreateTeacher creates a new teacher in the system
+// returns the created teacher or ErrConflict if a teacher with the same short name already exists
+func (m MongoDatabaseConnector) CreateTeacher(teacher Teacher) (Teacher, error) {
+	exists, err := m.DoesTeacherExistByShort(teacher.Short)
+	if err != nil {
+		return Teacher{}, err
+	}
+	if exists {
+		return Teacher{}, fmt.Errorf("%w: teacher %v already exists", ErrConflict, teacher.Short)
+	}
 	collection := m.client.Database(m.database).Collection(TeacherCollection)
 	if teacher.Short == getInitUserName() {
-		teacher.SuperUser = true
+		teacher.GrantedRoles.SuperUser = true
 	}
+	teacher.UpdatePermissions()
 	insert, err := collection.InsertOne(m.context, teacher)
 	if err != nil {
 		log.Println(err)
-		return false
+		return Teacher{}, wrapError(err)
 	}
 	log.Println("Inserted a new teacher with the UUID: ", teacher.UUID,
 		"; the shortname: ", teacher.Short, "; under the ID: ", insert.InsertedID)
-	return true
+	return teacher, nil
 }
 
 // GetTeacherByShort returns a teacher identified by a given short name
-func (m MongoDatabaseConnector) GetTeacherByShort(short string) (teacher Teacher) {
-	collection := m.client.Database(m.database).Collection(TeacherCollection)
-	if err := collection.FindOne(m.context, bson.M{"short": short}).Decode(&teacher); err != nil {
-		log.Println(err)
-		return
-	}
-	return teacher
+// returns ErrNotFound if no teacher has this short name
+func (m MongoDatabaseConnector) GetTeacherByShort(short string) (Teacher, error) {
+	return m.findTeacher(bson.M{"short": short})
+}
+
+// GetTeacherByLongname returns a teacher identified by a given long name
+// returns ErrNotFound if no teacher has this long name
+func (m MongoDatabaseConnector) GetTeacherByLongname(longname string) (Teacher, error) {
+	return m.findTeacher(bson.M{"longname": longname})
+}
+
+// GetAllTeachers returns all stored teachers
+func (m MongoDatabaseConnector) GetAllTeachers() ([]Teacher, error) {
+	return m.findTeachers(bson.M{})
 }
 
 // DoesTeacherExistByShort searches the database for a Teacher identified by a shortname
 // and checks whether a teacher can be found whilst performing this search.
-// It will return true if the teacher was found, false if an error occurred or none was found.
-func (m MongoDatabaseConnector) DoesTeacherExistByShort(short string) bool {
-	teacher := Teacher{}
-	collection := m.client.Database(m.database).Collection(TeacherCollection)
-	if err := collection.FindOne(m.context, bson.M{"short": short}).Decode(&teacher); err != nil {
-		return false
-	}
-	return true
+// It will return true if the teacher was found, false if none was found and an error if the search failed.
+func (m MongoDatabaseConnector) DoesTeacherExistByShort(short string) (bool, error) {
+	return m.exists(TeacherCollection, bson.M{"short": short})
 }
 
 // GetTeacherByUUID returns a teacher identified by a given UUID
-func (m MongoDatabaseConnector) GetTeacherByUUID(uuid string) (teacher Teacher) {
-	collection := m.client.Database(m.database).Collection(TeacherCollection)
-	if err := collection.FindOne(m.context, bson.M{"uuid": uuid}).Decode(&teacher); err != nil {
-		log.Println(err)
-		return
-	}
-	return teacher
+// returns ErrNotFound if no teacher has this uuid
+func (m MongoDatabaseConnector) GetTeacherByUUID(uuid string) (Teacher, error) {
+	return m.findTeacher(bson.M{"uuid": uuid})
 }
 
 // DoesTeacherExistByUUID searches the database for a Teacher identified by a uuid
 // and checks whether a teacher can be found whilst performing this search.
-// It will return true if the teacher was found, false if an error occurred or none was found.
-func (m MongoDatabaseConnector) DoesTeacherExistByUUID(uuid string) bool {
-	teacher := Teacher{}
-	collection := m.client.Database(m.database).Collection(TeacherCollection)
-	if err := collection.FindOne(m.context, bson.M{"uuid": uuid}).Decode(&teacher); err != nil {
-		return false
-	}
-	return true
+// It will return true if the teacher was found, false if none was found and an error if the search failed.
+func (m MongoDatabaseConnector) DoesTeacherExistByUUID(uuid string) (bool, error) {
+	return m.exists(TeacherCollection, bson.M{"uuid": uuid})
 }
 
 // DoesTeacherExistByUntis searches the database for a Teacher identified by an untis abbrevation
 // and checks whether a teacher can be found whilst performing this search.
-// It will return true if the teacher was found, false if an error occurred or none was found.
-func (m MongoDatabaseConnector) DoesTeacherExistByUntis(untis string) bool {
-	teacher := Teacher{}
-	collection := m.client.Database(m.database).Collection(TeacherCollection)
-	if err := collection.FindOne(m.context, bson.M{"untis": untis}).Decode(&teacher); err != nil {
-		return false
-	}
-	return true
+// It will return true if the teacher was found, false if none was found and an error if the search failed.
+func (m MongoDatabaseConnector) DoesTeacherExistByUntis(untis string) (bool, error) {
+	return m.exists(TeacherCollection, bson.M{"untis": untis})
 }
 
 // GetTeacherByUntis returns a teacher identified by a given untis abbrevation
-func (m MongoDatabaseConnector) GetTeacherByUntis(untis string) (teacher Teacher) {
-	collection := m.client.Database(m.database).Collection(TeacherCollection)
-	if err := collection.FindOne(m.context, bson.M{"untis": untis}).Decode(&teacher); err != nil {
-		log.Println(err)
-		return
-	}
-	return teacher
+// returns ErrNotFound if no teacher has this untis abbrevation
+func (m MongoDatabaseConnector) GetTeacherByUntis(untis string) (Teacher, error) {
+	return m.findTeacher(bson.M{"untis": untis})
 }
 
 // UpdateTeacher updates a teacher with the matching uuid and updates it with the data in the update struct
-// returns true whether one Teacher was modified, false if an error occurred or no Teacher was modified
-func (m MongoDatabaseConnector) UpdateTeacher(uuid string, update Teacher) bool {
+// returns ErrNotFound if no teacher has this uuid
+func (m MongoDatabaseConnector) UpdateTeacher(uuid string, update Teacher) error {
 	update.UUID = uuid
 	collection := m.client.Database(m.database).Collection(TeacherCollection)
 	result, err := collection.ReplaceOne(m.context, bson.M{"uuid": uuid}, update)
 	if err != nil {
 		log.Println(err)
-		return false
+		return wrapError(err)
 	}
-	return result.ModifiedCount == 1
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
 }
 
-// DeleteTeacher deletes one teacher described by a given short name
-// returns true if a document was deleted, false if none or an error occurred
-func (m MongoDatabaseConnector) DeleteTeacher(uuid string) (ok bool) {
+// DeleteTeacher deletes one teacher described by a given uuid
+// returns ErrNotFound if no teacher has this uuid
+func (m MongoDatabaseConnector) DeleteTeacher(uuid string) error {
 	collection := m.client.Database(m.database).Collection(TeacherCollection)
 	result, err := collection.DeleteOne(m.context, bson.M{"uuid": uuid})
 	if err != nil {
 		log.Println(err)
-		return false
+		return wrapError(err)
+	}
+	if result.DeletedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// findApplications returns all applications matching the given mongo filter document
+func (m MongoDatabaseConnector) findApplications(filter interface{}, opts ...*options.FindOptions) ([]Application, error) {
+	applications := make([]Application, 0)
+	collection := m.client.Database(m.database).Collection(ApplicationCollection)
+	cursor, err := collection.Find(m.context, filter, opts...)
+	if err != nil {
+		log.Println(err)
+		return nil, wrapError(err)
+	}
+	if err = cursor.All(m.context, &applications); err != nil {
+		log.Println(err)
+		return nil, wrapError(err)
+	}
+	return applications, nil
+}
+
+// findTeacher returns the first teacher matching the given filter
+func (m MongoDatabaseConnector) findTeacher(filter interface{}) (Teacher, error) {
+	teacher := Teacher{}
+	collection := m.client.Database(m.database).Collection(TeacherCollection)
+	if err := collection.FindOne(m.context, filter).Decode(&teacher); err != nil {
+		return Teacher{}, wrapError(err)
+	}
+	return teacher, nil
+}
+
+// findTeachers returns all teachers matching the given filter
+func (m MongoDatabaseConnector) findTeachers(filter interface{}) ([]Teacher, error) {
+	teachers := make([]Teacher, 0)
+	collection := m.client.Database(m.database).Collection(TeacherCollection)
+	cursor, err := collection.Find(m.context, filter)
+	if err != nil {
+		return nil, wrapError(err)
+	}
+	if err = cursor.All(m.context, &teachers); err != nil {
+		return nil, wrapError(err)
+	}
+	return teachers, nil
+}
+
+// exists checks whether at least one document in the given collection matches the filter
+func (m MongoDatabaseConnector) exists(collectionName string, filter interface{}) (bool, error) {
+	collection := m.client.Database(m.database).Collection(collectionName)
+	count, err := collection.CountDocuments(m.context, filter, options.Count().SetLimit(1))
+	if err != nil {
+		log.Println(err)
+		return false, wrapError(err)
 	}
-	return result.DeletedCount == 1
+	return count > 0, nil
 }
 
 // Constructs the URI out of the given information of the docker secrets
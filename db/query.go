@@ -0,0 +1,124 @@
+package db
+
+import (
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"reflect"
+	"strings"
+)
+
+// Fields an ApplicationQuery can be sorted by
+const (
+	SortByStartTime   = "start_time"
+	SortByLastChanged = "last_changed"
+	SortByName        = "name"
+)
+
+// sortFields maps the sort fields of an ApplicationQuery to the fields stored in the database
+var sortFields = map[string]string{
+	SortByStartTime:   "starttime",
+	SortByLastChanged: "lastchanged",
+	SortByName:        "name",
+}
+
+// ApplicationQuery combines an ApplicationFilter with ordering, paging and a projection of the result
+type ApplicationQuery struct {
+	// Filter restricts which applications are returned
+	Filter ApplicationFilter
+	// SortBy is the field the applications are sorted by (SortByStartTime, SortByLastChanged or SortByName)
+	SortBy string
+	// Descending reverses the order of the sort
+	Descending bool
+	// Skip is the amount of matching applications to skip
+	Skip int64
+	// Limit is the maximum amount of applications returned, no limit is applied if it is 0
+	Limit int64
+	// Fields are the json names of the top level fields to load, all fields are loaded if it is empty
+	Fields []string
+}
+
+// QueryApplications returns one page of the applications matching the query
+// it also returns the total amount of applications matching the filter of the query regardless of the paging
+func (m MongoDatabaseConnector) QueryApplications(q ApplicationQuery) ([]Application, int64, error) {
+	filter, err := m.applicationQuery(q.Filter)
+	if err != nil {
+		return nil, 0, err
+	}
+	opts := options.Find().SetSkip(q.Skip)
+	if q.Limit > 0 {
+		opts.SetLimit(q.Limit)
+	}
+	if q.SortBy != "" {
+		field, ok := sortFields[q.SortBy]
+		if !ok {
+			return nil, 0, fmt.Errorf("unknown sort field: %v", q.SortBy)
+		}
+		order := 1
+		if q.Descending {
+			order = -1
+		}
+		opts.SetSort(bson.D{{Key: field, Value: order}, {Key: "uuid", Value: 1}})
+	}
+	if len(q.Fields) > 0 {
+		projection, err := applicationProjection(q.Fields)
+		if err != nil {
+			return nil, 0, err
+		}
+		opts.SetProjection(projection)
+	}
+	collection := m.client.Database(m.database).Collection(ApplicationCollection)
+	total, err := collection.CountDocuments(m.context, filter)
+	if err != nil {
+		return nil, 0, wrapError(err)
+	}
+	applications, err := m.findApplications(filter, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+	return applications, total, nil
+}
+
+// GetTeachersOfDepartment returns all teachers belonging to the given department
+func (m MongoDatabaseConnector) GetTeachersOfDepartment(department string) ([]Teacher, error) {
+	return m.findTeachers(bson.M{"departments": department})
+}
+
+// applicationQuery resolves everything the filter depends on and converts it into a mongo filter document
+func (m MongoDatabaseConnector) applicationQuery(filter ApplicationFilter) (bson.M, error) {
+	var departmentTeachers []Teacher
+	if filter.Department != "" {
+		var err error
+		departmentTeachers, err = m.GetTeachersOfDepartment(filter.Department)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return filter.query(departmentTeachers), nil
+}
+
+// applicationProjection converts json field names of an Application into a mongo projection document
+func applicationProjection(fields []string) (bson.M, error) {
+	names := ApplicationFieldNames()
+	projection := bson.M{"uuid": 1}
+	for _, field := range fields {
+		name, ok := names[field]
+		if !ok {
+			return nil, fmt.Errorf("unknown field: %v", field)
+		}
+		projection[name] = 1
+	}
+	return projection, nil
+}
+
+// ApplicationFieldNames maps the json names of all top level fields of an Application to their names in the database
+func ApplicationFieldNames() map[string]string {
+	names := make(map[string]string)
+	t := reflect.TypeOf(Application{})
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		json := strings.Split(field.Tag.Get("json"), ",")[0]
+		names[json] = strings.ToLower(field.Name)
+	}
+	return names
+}
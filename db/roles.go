@@ -0,0 +1,75 @@
+package db
+
+import "sort"
+
+// Roles are permissions of a teacher coming from one source
+// the permissions a teacher actually has are the union of the roles granted manually and the ones derived from the directory
+type Roles struct {
+	// SuperUser (total admin) of this software
+	SuperUser bool `json:"super_user" example:"false"`
+	// AV rights
+	AV bool `json:"av" example:"false"`
+	// Administration rights
+	Administration bool `json:"administration" example:"false"`
+	// PEK rights
+	PEK bool `json:"pek" example:"true"`
+	// DepartmentHead lists the departments headed by the teacher
+	DepartmentHead []string `json:"department_head" example:"HIT"`
+}
+
+// Merge returns the union of both roles
+func (r Roles) Merge(other Roles) Roles {
+	return Roles{
+		SuperUser:      r.SuperUser || other.SuperUser,
+		AV:             r.AV || other.AV,
+		Administration: r.Administration || other.Administration,
+		PEK:            r.PEK || other.PEK,
+		DepartmentHead: unite(r.DepartmentHead, other.DepartmentHead),
+	}
+}
+
+// Covers checks whether r contains every role of other, including the headship of each of its departments
+func (r Roles) Covers(other Roles) bool {
+	if other.SuperUser && !r.SuperUser || other.AV && !r.AV || other.Administration && !r.Administration || other.PEK && !r.PEK {
+		return false
+	}
+	return len(unite(r.DepartmentHead, other.DepartmentHead)) == len(unite(r.DepartmentHead, nil))
+}
+
+// Permissions returns the roles the teacher actually has
+func (t Teacher) Permissions() Roles {
+	return Roles{
+		SuperUser:      t.SuperUser,
+		AV:             t.AV,
+		Administration: t.Administration,
+		PEK:            t.PEK,
+		DepartmentHead: t.DepartmentHead,
+	}
+}
+
+// UpdatePermissions sets the permissions of the teacher to the union of GrantedRoles and DirectoryRoles
+// it has to be called after changing one of them
+func (t *Teacher) UpdatePermissions() {
+	roles := t.GrantedRoles.Merge(t.DirectoryRoles)
+	t.SuperUser = roles.SuperUser
+	t.AV = roles.AV
+	t.Administration = roles.Administration
+	t.PEK = roles.PEK
+	t.DepartmentHead = roles.DepartmentHead
+}
+
+// unite returns the sorted values contained in at least one of the lists without duplicates
+func unite(a, b []string) []string {
+	contained := make(map[string]bool, len(a)+len(b))
+	united := make([]string, 0, len(a)+len(b))
+	for _, list := range [][]string{a, b} {
+		for _, value := range list {
+			if !contained[value] {
+				contained[value] = true
+				united = append(united, value)
+			}
+		}
+	}
+	sort.Strings(united)
+	return united
+}
@@ -0,0 +1,50 @@
+package db
+
+import (
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ApplicationTextIndex is the name of the text index used to search applications
+const ApplicationTextIndex = "application_text"
+
+// SearchLanguage is the language the text index stems and filters stop words with
+const SearchLanguage = "german"
+
+// SearchResult is an application matching a full text search
+type SearchResult struct {
+	// Application is the matching application
+	Application Application `bson:",inline"`
+	// Score is the relevance of the application to the search, higher scores are more relevant
+	Score float64 `bson:"score"`
+}
+
+// SearchApplications searches the name, notes, destination, organizer, classes and teachers of all applications
+// matching the filter for the given text and returns at most limit results ordered by their relevance
+func (m MongoDatabaseConnector) SearchApplications(text string, filter ApplicationFilter, limit int64) ([]SearchResult, error) {
+	query, err := m.applicationQuery(filter)
+	if err != nil {
+		return nil, err
+	}
+	search := bson.M{"$text": bson.M{"$search": text, "$language": SearchLanguage}}
+	if len(query) > 0 {
+		search = bson.M{"$and": bson.A{search, query}}
+	}
+	score := bson.M{"$meta": "textScore"}
+	opts := options.Find().
+		SetProjection(bson.M{"score": score}).
+		SetSort(bson.D{{Key: "score", Value: score}, {Key: "lastchanged", Value: -1}})
+	if limit > 0 {
+		opts.SetLimit(limit)
+	}
+	results := make([]SearchResult, 0)
+	collection := m.client.Database(m.database).Collection(ApplicationCollection)
+	cursor, err := collection.Find(m.context, search, opts)
+	if err != nil {
+		return nil, wrapError(err)
+	}
+	if err = cursor.All(m.context, &results); err != nil {
+		return nil, wrapError(err)
+	}
+	return results, nil
+}
@@ -0,0 +1,161 @@
+package db
+
+import (
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// TeacherQuery describes which teachers are listed and which page of them is returned
+type TeacherQuery struct {
+	// Search restricts the result to teachers whose short name, name, untis abbrevation or mail address contains every word of it
+	Search string
+	// Department restricts the result to teachers of this department
+	Department string
+	// Provider restricts the result to teachers of this provider, service principals are only listed if it is ServiceProvider
+	Provider string
+	// IncludeDeactivated lists deactivated teachers too
+	IncludeDeactivated bool
+	// Skip is the amount of matching teachers to skip
+	Skip int64
+	// Limit is the maximum amount of teachers returned, no limit is applied if it is 0
+	Limit int64
+}
+
+// QueryTeachers returns one page of the teachers matching the query ordered by their name
+// it also returns the total amount of matching teachers regardless of the paging
+func (m MongoDatabaseConnector) QueryTeachers(q TeacherQuery) ([]Teacher, int64, error) {
+	conditions := bson.A{}
+	for _, word := range strings.Fields(q.Search) {
+		pattern := bson.M{"$regex": regexp.QuoteMeta(word), "$options": "i"}
+		conditions = append(conditions, bson.M{"$or": bson.A{
+			bson.M{"short": pattern},
+			bson.M{"longname": pattern},
+			bson.M{"untis": pattern},
+			bson.M{"mail": pattern},
+		}})
+	}
+	if q.Department != "" {
+		conditions = append(conditions, bson.M{"departments": q.Department})
+	}
+	switch q.Provider {
+	case "":
+		conditions = append(conditions, bson.M{"provider": bson.M{"$ne": ServiceProvider}})
+	case LDAPProvider:
+		// teachers stored before providers were introduced belong to ldap, they have no provider field at all, nil matches them
+		conditions = append(conditions, bson.M{"provider": bson.M{"$in": bson.A{LDAPProvider, "", nil}}})
+	default:
+		conditions = append(conditions, bson.M{"provider": q.Provider})
+	}
+	if !q.IncludeDeactivated {
+		conditions = append(conditions, bson.M{"deactivatedat": bson.M{"$in": bson.A{time.Time{}, nil}}})
+	}
+	filter := bson.M{"$and": conditions}
+	opts := options.Find().
+		SetSort(bson.D{{Key: "longname", Value: 1}, {Key: "short", Value: 1}}).
+		SetSkip(q.Skip)
+	if q.Limit > 0 {
+		opts.SetLimit(q.Limit)
+	}
+	collection := m.client.Database(m.database).Collection(TeacherCollection)
+	total, err := collection.CountDocuments(m.context, filter)
+	if err != nil {
+		return nil, 0, wrapError(err)
+	}
+	teachers := make([]Teacher, 0)
+	cursor, err := collection.Find(m.context, filter, opts)
+	if err != nil {
+		return nil, 0, wrapError(err)
+	}
+	if err = cursor.All(m.context, &teachers); err != nil {
+		return nil, 0, wrapError(err)
+	}
+	return teachers, total, nil
+}
+
+// ReplaceParticipant rewrites every application the teacher old takes part in, so the teacher replacement takes part instead
+// a school event already listing replacement keeps its entry of replacement and drops the one of old
+// the business trip applications and travel invoices are filed forms and stay unchanged
+// returns the amount of rewritten applications
+func (m MongoDatabaseConnector) ReplaceParticipant(old, replacement Teacher) (int, error) {
+	applications, err := m.findApplications(participantQuery(old))
+	if err != nil {
+		return 0, err
+	}
+	for _, application := range applications {
+		teachers := make([]SchoolEventTeacherDetails, 0, len(application.SchoolEventDetails.Teachers))
+		listed := false
+		for _, teacher := range application.SchoolEventDetails.Teachers {
+			listed = listed || teacher.Shortname == replacement.Short && old.Short != replacement.Short
+		}
+		for _, teacher := range application.SchoolEventDetails.Teachers {
+			if teacher.Shortname == old.Short {
+				if listed {
+					continue
+				}
+				teacher.Shortname = replacement.Short
+				teacher.Name = replacement.Longname
+			}
+			teachers = append(teachers, teacher)
+		}
+		if application.SchoolEventDetails.Teachers != nil {
+			application.SchoolEventDetails.Teachers = teachers
+		}
+		if application.TrainingDetails.Filer == old.Longname && old.Longname != "" {
+			application.TrainingDetails.Filer = replacement.Longname
+		}
+		if application.OtherReasonDetails.Filer == old.Longname && old.Longname != "" {
+			application.OtherReasonDetails.Filer = replacement.Longname
+		}
+		if err := m.UpdateApplication(application.UUID, application); err != nil {
+			return 0, err
+		}
+	}
+	return len(applications), nil
+}
+
+// MergeTeachers merges the duplicate source into the teacher target and deletes source
+// the applications, delegations and deputy assignments of source are rewritten first, values target lacks are taken from source and roles are united
+// if the merge fails it can be repeated, as source is only deleted at the end
+// returns the merged teacher and the amount of rewritten applications
+func (m MongoDatabaseConnector) MergeTeachers(source, target Teacher) (Teacher, int, error) {
+	rewritten, err := m.ReplaceParticipant(source, target)
+	if err != nil {
+		return Teacher{}, rewritten, err
+	}
+	if err := m.renameDelegations(source.Short, target.Short); err != nil {
+		return Teacher{}, rewritten, err
+	}
+	if err := m.renameDeputyAssignments(source.Short, target.Short); err != nil {
+		return Teacher{}, rewritten, err
+	}
+	if target.Untis == "" {
+		target.Untis = source.Untis
+	}
+	if target.Mail == "" {
+		target.Mail = source.Mail
+	}
+	if target.Staffnr == 0 {
+		target.Staffnr = source.Staffnr
+	}
+	if target.Degree == "" {
+		target.Degree = source.Degree
+	}
+	if target.Title == "" {
+		target.Title = source.Title
+	}
+	target.Departments = unite(target.Departments, source.Departments)
+	target.StartingAddresses = unite(target.StartingAddresses, source.StartingAddresses)
+	target.TripGoals = unite(target.TripGoals, source.TripGoals)
+	target.GrantedRoles = target.GrantedRoles.Merge(source.GrantedRoles)
+	target.UpdatePermissions()
+	if err := m.UpdateTeacher(target.UUID, target); err != nil {
+		return Teacher{}, rewritten, err
+	}
+	if err := m.DeleteTeacher(source.UUID); err != nil {
+		return Teacher{}, rewritten, err
+	}
+	return target, rewritten, nil
+}
@@ -18,31 +18,23 @@ var doc = `{
     "info": {
         "description": "{{.Description}}",
         "title": "{{.Title}}",
-        "contact": {
-            "name": "Michael Beier - Entwickler",
-            "url": "https://mbeier.at",
-            "email": "admin@mbeier.at"
-        },
-        "license": {
-            "name": "MIT License",
-            "url": "https://github.com/refundable-tgm/huginn/blob/master/LICENSE"
-        },
+        "contact": {},
         "version": "{{.Version}}"
     },
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/createApplication": {
+        "/checkConflicts": {
             "post": {
-                "description": "Creates the provided application in the system",
+                "description": "Returns the conflicts of the provided application with other applications taking away the same teachers or classes and with exams in untis\nBlocking marks the conflicts which would prevent saving the application",
                 "consumes": [
                     "application/json"
                 ],
                 "produces": [
                     "application/json"
                 ],
-                "summary": "Creates a new application",
-                "operationId": "create-application",
+                "summary": "Checks an application for conflicts without saving it",
+                "operationId": "check-conflicts",
                 "parameters": [
                     {
                         "type": "string",
@@ -53,20 +45,29 @@ var doc = `{
                         "required": true
                     },
                     {
-                        "description": "The Application Data",
+                        "description": "The application data to check",
                         "name": "application",
                         "in": "body",
                         "required": true,
                         "schema": {
                             "$ref": "#/definitions/db.Application"
                         }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Identifier of the application if it already exists",
+                        "name": "uuid",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/rest.Information"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/rest.Conflict"
+                            }
                         }
                     },
                     "401": {
@@ -86,21 +87,24 @@ var doc = `{
                         "schema": {
                             "$ref": "#/definitions/rest.Error"
                         }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
                     }
                 }
             }
         },
-        "/deleteApplication": {
+        "/clearLockout": {
             "delete": {
-                "description": "Deletes an application identified by a uuid",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Forgets the failed logins of a username or address, so it can log in again immediately",
                 "produces": [
                     "application/json"
                 ],
-                "summary": "Deletes an existing application",
-                "operationId": "delete-application",
+                "summary": "Clears the lockout of a username or address",
+                "operationId": "clear-lockout",
                 "parameters": [
                     {
                         "type": "string",
@@ -111,9 +115,20 @@ var doc = `{
                         "required": true
                     },
                     {
+                        "enum": [
+                            "user",
+                            "ip"
+                        ],
                         "type": "string",
-                        "description": "Identifier of the application to delete",
-                        "name": "uuid",
+                        "description": "Kind of the lockout",
+                        "name": "kind",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Username or address",
+                        "name": "key",
                         "in": "query",
                         "required": true
                     }
@@ -148,21 +163,27 @@ var doc = `{
                         "schema": {
                             "$ref": "#/definitions/rest.Error"
                         }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
                     }
                 }
             }
         },
-        "/getAbsenceFormForClasses": {
-            "get": {
-                "description": "Generates an absence form for classes and returns it",
+        "/createAPIKey": {
+            "post": {
+                "description": "Creates an API key machine clients present instead of an access token, the key is only returned once and stored hashed\nThe service principal is created if it doesn't exist, its permissions restrict what the key may do together with the listed endpoints\nAt least one endpoint has to be listed, service principals can't be super users or hold roles the requesting administrator lacks\nAPI keys can't be used to manage API keys",
                 "consumes": [
                     "application/json"
                 ],
                 "produces": [
                     "application/json"
                 ],
-                "summary": "Generates an absence form for classes",
-                "operationId": "get-absence-form-for-classes",
+                "summary": "Creates an API key for a service principal",
+                "operationId": "create-api-key",
                 "parameters": [
                     {
                         "type": "string",
@@ -173,28 +194,20 @@ var doc = `{
                         "required": true
                     },
                     {
-                        "type": "string",
-                        "description": "Identifier of the application to generate the pdf from",
-                        "name": "uuid",
-                        "in": "query",
-                        "required": true
-                    },
-                    {
-                        "type": "array",
-                        "items": {
-                            "type": "string"
-                        },
-                        "collectionFormat": "multi",
-                        "description": "Filter for classes",
-                        "name": "classes",
-                        "in": "query"
+                        "description": "Data of the API key",
+                        "name": "key",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/rest.APIKeyRequest"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/rest.PDF"
+                            "$ref": "#/definitions/rest.CreatedAPIKey"
                         }
                     },
                     "401": {
@@ -203,8 +216,8 @@ var doc = `{
                             "$ref": "#/definitions/rest.Error"
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
+                    "409": {
+                        "description": "Conflict",
                         "schema": {
                             "$ref": "#/definitions/rest.Error"
                         }
@@ -220,21 +233,27 @@ var doc = `{
                         "schema": {
                             "$ref": "#/definitions/rest.Error"
                         }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
                     }
                 }
             }
         },
-        "/getAbsenceFormForTeacher": {
-            "get": {
-                "description": "Generates an absence form for a teacher and returns it",
+        "/createApplication": {
+            "post": {
+                "description": "Creates the provided application in the system\nThe application is checked for conflicts with other applications taking away the same teachers or classes and with exams in untis\nConflicts configured as blocking (CONFLICT_BLOCKING) prevent creating the application, all others are returned as warnings\nOnly teachers taking part in the application, their delegates and administrators may create it",
                 "consumes": [
                     "application/json"
                 ],
                 "produces": [
                     "application/json"
                 ],
-                "summary": "Generates an absence form for a teacher",
-                "operationId": "get-absence-form-for-teacher",
+                "summary": "Creates a new application",
+                "operationId": "create-application",
                 "parameters": [
                     {
                         "type": "string",
@@ -245,24 +264,20 @@ var doc = `{
                         "required": true
                     },
                     {
-                        "type": "string",
-                        "description": "Identifier of the application to generate the pdf from",
-                        "name": "uuid",
-                        "in": "query",
-                        "required": true
-                    },
-                    {
-                        "type": "string",
-                        "description": "short name of the teacher, if not provided logged in teacher will be used",
-                        "name": "teacher",
-                        "in": "query"
+                        "description": "The Application Data",
+                        "name": "application",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/db.Application"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/rest.PDF"
+                            "$ref": "#/definitions/rest.SavedApplication"
                         }
                     },
                     "401": {
@@ -271,10 +286,10 @@ var doc = `{
                             "$ref": "#/definitions/rest.Error"
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
+                    "409": {
+                        "description": "Conflict",
                         "schema": {
-                            "$ref": "#/definitions/rest.Error"
+                            "$ref": "#/definitions/rest.ConflictError"
                         }
                     },
                     "422": {
@@ -288,21 +303,27 @@ var doc = `{
                         "schema": {
                             "$ref": "#/definitions/rest.Error"
                         }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
                     }
                 }
             }
         },
-        "/getActiveApplications": {
-            "get": {
-                "description": "Returns all active applications as a list of applications",
+        "/createDelegation": {
+            "post": {
+                "description": "Lets the delegate create and edit the applications and invoices of the delegator until the delegation ends\nEverything the delegate does on behalf of the delegator is audited, generated forms carry the data of the delegator",
                 "consumes": [
                     "application/json"
                 ],
                 "produces": [
                     "application/json"
                 ],
-                "summary": "Returns all active applications",
-                "operationId": "get-all-active-applications",
+                "summary": "Lets another teacher act on behalf of a teacher",
+                "operationId": "create-delegation",
                 "parameters": [
                     {
                         "type": "string",
@@ -313,20 +334,20 @@ var doc = `{
                         "required": true
                     },
                     {
-                        "type": "string",
-                        "description": "Filter to only show applications of this teacher",
-                        "name": "username",
-                        "in": "query"
+                        "description": "Data of the delegation",
+                        "name": "delegation",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/rest.DelegationRequest"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/db.Application"
-                            }
+                            "$ref": "#/definitions/db.Delegation"
                         }
                     },
                     "401": {
@@ -335,26 +356,50 @@ var doc = `{
                             "$ref": "#/definitions/rest.Error"
                         }
                     },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
                             "$ref": "#/definitions/rest.Error"
                         }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
                     }
                 }
             }
         },
-        "/getAdminApplication": {
-            "get": {
-                "description": "Returns all applications currently needing a review by an admin",
+        "/createDeputy": {
+            "post": {
+                "description": "Lets the deputy review and approve applications in place of the approver for a time range of at most a year, e.g. during a school trip\nThe deputy sees the approval queue of the approver, everything the deputy does as deputy is audited",
                 "consumes": [
                     "application/json"
                 ],
                 "produces": [
                     "application/json"
                 ],
-                "summary": "Returns all admin applications",
-                "operationId": "get-admin-applications",
+                "summary": "Names a deputy of an approver",
+                "operationId": "create-deputy",
                 "parameters": [
                     {
                         "type": "string",
@@ -363,16 +408,22 @@ var doc = `{
                         "name": "Authorization",
                         "in": "header",
                         "required": true
+                    },
+                    {
+                        "description": "Data of the deputy assignment",
+                        "name": "deputy",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/rest.DeputyRequest"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/db.Application"
-                            }
+                            "$ref": "#/definitions/db.DeputyAssignment"
                         }
                     },
                     "401": {
@@ -381,26 +432,50 @@ var doc = `{
                             "$ref": "#/definitions/rest.Error"
                         }
                     },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
                             "$ref": "#/definitions/rest.Error"
                         }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
                     }
                 }
             }
         },
-        "/getAllApplications": {
-            "get": {
-                "description": "Returns all applications as a list of applications",
+        "/createTeacher": {
+            "post": {
+                "description": "Creates a teacher before their first login, e.g. to add them as companion\nLocal accounts are created through /setLocalAccount and service principals through /createAPIKey",
                 "consumes": [
                     "application/json"
                 ],
                 "produces": [
                     "application/json"
                 ],
-                "summary": "Returns all applications",
-                "operationId": "get-all-applications",
+                "summary": "Creates a teacher",
+                "operationId": "create-teacher",
                 "parameters": [
                     {
                         "type": "string",
@@ -411,20 +486,20 @@ var doc = `{
                         "required": true
                     },
                     {
-                        "type": "string",
-                        "description": "Filter to only show applications of this teacher",
-                        "name": "username",
-                        "in": "query"
+                        "description": "Data of the teacher",
+                        "name": "teacher",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/rest.TeacherData"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/db.Application"
-                            }
+                            "$ref": "#/definitions/db.Teacher"
                         }
                     },
                     "401": {
@@ -433,26 +508,41 @@ var doc = `{
                             "$ref": "#/definitions/rest.Error"
                         }
                     },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
                             "$ref": "#/definitions/rest.Error"
                         }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
                     }
                 }
             }
         },
-        "/getApplication": {
-            "get": {
-                "description": "Returns the Application matching the given UUID",
-                "consumes": [
-                    "application/json"
-                ],
+        "/deactivateTeacher": {
+            "post": {
+                "description": "Deactivates a teacher who left the school, the teacher is logged out and can't log in anymore but their applications are kept\nTeachers holding roles the requesting administrator lacks (e.g. super users) can't be deactivated by them",
                 "produces": [
                     "application/json"
                 ],
-                "summary": "Returns an Application",
-                "operationId": "get-application",
+                "summary": "Deactivates a teacher",
+                "operationId": "deactivate-teacher",
                 "parameters": [
                     {
                         "type": "string",
@@ -464,7 +554,7 @@ var doc = `{
                     },
                     {
                         "type": "string",
-                        "description": "The UUID of the specifying Application",
+                        "description": "UUID of the teacher",
                         "name": "uuid",
                         "in": "query",
                         "required": true
@@ -474,7 +564,7 @@ var doc = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/db.Application"
+                            "$ref": "#/definitions/db.Teacher"
                         }
                     },
                     "401": {
@@ -489,6 +579,12 @@ var doc = `{
                             "$ref": "#/definitions/rest.Error"
                         }
                     },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    },
                     "422": {
                         "description": "Unprocessable Entity",
                         "schema": {
@@ -500,21 +596,27 @@ var doc = `{
                         "schema": {
                             "$ref": "#/definitions/rest.Error"
                         }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
                     }
                 }
             }
         },
-        "/getBusinessTripApplicationExcel": {
-            "get": {
-                "description": "Generates a business trip application excel for a teacher and returns it",
+        "/deleteApplication": {
+            "delete": {
+                "description": "Deletes an application identified by a uuid",
                 "consumes": [
                     "application/json"
                 ],
                 "produces": [
                     "application/json"
                 ],
-                "summary": "Generates a business trip application excel for a teacher",
-                "operationId": "get-business-trip-application-excel",
+                "summary": "Deletes an existing application",
+                "operationId": "delete-application",
                 "parameters": [
                     {
                         "type": "string",
@@ -526,31 +628,17 @@ var doc = `{
                     },
                     {
                         "type": "string",
-                        "description": "Identifier of the application to generate the excel from",
+                        "description": "Identifier of the application to delete",
                         "name": "uuid",
                         "in": "query",
                         "required": true
-                    },
-                    {
-                        "type": "string",
-                        "description": "Short name of the teacher this should be generated for",
-                        "name": "short",
-                        "in": "query",
-                        "required": true
-                    },
-                    {
-                        "type": "integer",
-                        "description": "ID of the Business Trip Application data",
-                        "name": "bta_id",
-                        "in": "query",
-                        "required": true
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/rest.Excel"
+                            "$ref": "#/definitions/rest.Information"
                         }
                     },
                     "401": {
@@ -576,21 +664,24 @@ var doc = `{
                         "schema": {
                             "$ref": "#/definitions/rest.Error"
                         }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
                     }
                 }
             }
         },
-        "/getBusinessTripApplicationForm": {
+        "/getAPIKeys": {
             "get": {
-                "description": "Generates a business trip application form for a teacher and returns it",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Returns the API keys with their usage, including expired and revoked ones, the newest first",
                 "produces": [
                     "application/json"
                 ],
-                "summary": "Generates a business trip application form for a teacher",
-                "operationId": "get-business-trip-application-form",
+                "summary": "Lists the API keys",
+                "operationId": "get-api-keys",
                 "parameters": [
                     {
                         "type": "string",
@@ -602,31 +693,19 @@ var doc = `{
                     },
                     {
                         "type": "string",
-                        "description": "Identifier of the application to generate the form from",
-                        "name": "uuid",
-                        "in": "query",
-                        "required": true
-                    },
-                    {
-                        "type": "string",
-                        "description": "Short name of the teacher this should be generated for",
-                        "name": "short",
-                        "in": "query",
-                        "required": true
-                    },
-                    {
-                        "type": "integer",
-                        "description": "ID of the Business Trip Application data",
-                        "name": "bta_id",
-                        "in": "query",
-                        "required": true
+                        "description": "Only return the keys of this service principal",
+                        "name": "principal",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/rest.PDF"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/db.APIKey"
+                            }
                         }
                     },
                     "401": {
@@ -635,20 +714,14 @@ var doc = `{
                             "$ref": "#/definitions/rest.Error"
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
-                        "schema": {
-                            "$ref": "#/definitions/rest.Error"
-                        }
-                    },
-                    "422": {
-                        "description": "Unprocessable Entity",
+                    "500": {
+                        "description": "Internal Server Error",
                         "schema": {
                             "$ref": "#/definitions/rest.Error"
                         }
                     },
-                    "500": {
-                        "description": "Internal Server Error",
+                    "503": {
+                        "description": "Service Unavailable",
                         "schema": {
                             "$ref": "#/definitions/rest.Error"
                         }
@@ -656,17 +729,17 @@ var doc = `{
                 }
             }
         },
-        "/getCompensationForEducationalSupportForm": {
+        "/getAbsenceFormForClasses": {
             "get": {
-                "description": "Generates a compensation for educational support form for all teachers and returns it",
+                "description": "Generates an absence form for classes and returns it",
                 "consumes": [
                     "application/json"
                 ],
                 "produces": [
                     "application/json"
                 ],
-                "summary": "Generates a compensation for educational support form for all teachers",
-                "operationId": "get-compensation-for-educational-support-form",
+                "summary": "Generates an absence form for classes",
+                "operationId": "get-absence-form-for-classes",
                 "parameters": [
                     {
                         "type": "string",
@@ -682,6 +755,15 @@ var doc = `{
                         "name": "uuid",
                         "in": "query",
                         "required": true
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "description": "Filter for classes",
+                        "name": "classes",
+                        "in": "query"
                     }
                 ],
                 "responses": {
@@ -714,49 +796,9 @@ var doc = `{
                         "schema": {
                             "$ref": "#/definitions/rest.Error"
                         }
-                    }
-                }
-            }
-        },
-        "/getNews": {
-            "get": {
-                "description": "Returns the 10 last changed applications",
-                "consumes": [
-                    "application/json"
-                ],
-                "produces": [
-                    "application/json"
-                ],
-                "summary": "Returns the news",
-                "operationId": "get-news",
-                "parameters": [
-                    {
-                        "type": "string",
-                        "default": "Bearer \u003cAdd access token here\u003e",
-                        "description": "Access Token",
-                        "name": "Authorization",
-                        "in": "header",
-                        "required": true
-                    }
-                ],
-                "responses": {
-                    "200": {
-                        "description": "OK",
-                        "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/rest.News"
-                            }
-                        }
-                    },
-                    "401": {
-                        "description": "Unauthorized",
-                        "schema": {
-                            "$ref": "#/definitions/rest.Error"
-                        }
                     },
-                    "500": {
-                        "description": "Internal Server Error",
+                    "503": {
+                        "description": "Service Unavailable",
                         "schema": {
                             "$ref": "#/definitions/rest.Error"
                         }
@@ -764,17 +806,17 @@ var doc = `{
                 }
             }
         },
-        "/getTeacher": {
+        "/getAbsenceFormForTeacher": {
             "get": {
-                "description": "Searches for the Teacher with the specified uuid and returns the data",
+                "description": "Generates an absence form for a teacher and returns it",
                 "consumes": [
                     "application/json"
                 ],
                 "produces": [
                     "application/json"
                 ],
-                "summary": "Returns a teacher with the specified UUID",
-                "operationId": "get-teacher",
+                "summary": "Generates an absence form for a teacher",
+                "operationId": "get-absence-form-for-teacher",
                 "parameters": [
                     {
                         "type": "string",
@@ -786,17 +828,23 @@ var doc = `{
                     },
                     {
                         "type": "string",
-                        "description": "UUID of Teacher",
+                        "description": "Identifier of the application to generate the pdf from",
                         "name": "uuid",
                         "in": "query",
                         "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "short name of the teacher, if not provided logged in teacher will be used",
+                        "name": "teacher",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/db.Teacher"
+                            "$ref": "#/definitions/rest.PDF"
                         }
                     },
                     "401": {
@@ -822,21 +870,27 @@ var doc = `{
                         "schema": {
                             "$ref": "#/definitions/rest.Error"
                         }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
                     }
                 }
             }
         },
-        "/getTeacherByShort": {
+        "/getActiveApplications": {
             "get": {
-                "description": "Searches for the Teacher with the specified name and returns the data",
+                "description": "Returns one page of the active applications matching the given filters, sorted by the given field",
                 "consumes": [
                     "application/json"
                 ],
                 "produces": [
                     "application/json"
                 ],
-                "summary": "Returns a teacher with the specified short name",
-                "operationId": "get-teacher-by-short",
+                "summary": "Returns all active applications",
+                "operationId": "get-all-active-applications",
                 "parameters": [
                     {
                         "type": "string",
@@ -848,73 +902,124 @@ var doc = `{
                     },
                     {
                         "type": "string",
-                        "description": "Short Name of Teacher",
-                        "name": "name",
-                        "in": "query",
-                        "required": true
-                    }
-                ],
-                "responses": {
-                    "200": {
-                        "description": "OK",
-                        "schema": {
-                            "$ref": "#/definitions/db.Teacher"
-                        }
+                        "description": "Filter to only show applications of this teacher",
+                        "name": "username",
+                        "in": "query"
                     },
-                    "401": {
-                        "description": "Unauthorized",
-                        "schema": {
-                            "$ref": "#/definitions/rest.Error"
-                        }
+                    {
+                        "type": "integer",
+                        "default": 1,
+                        "description": "Page to return, starting at 1",
+                        "name": "page",
+                        "in": "query"
                     },
-                    "422": {
-                        "description": "Unprocessable Entity",
-                        "schema": {
-                            "$ref": "#/definitions/rest.Error"
-                        }
+                    {
+                        "type": "integer",
+                        "default": 50,
+                        "description": "Amount of applications per page (at most 200)",
+                        "name": "per_page",
+                        "in": "query"
                     },
-                    "500": {
-                        "description": "Internal Server Error",
-                        "schema": {
-                            "$ref": "#/definitions/rest.Error"
-                        }
-                    }
-                }
-            }
-        },
-        "/getTeacherByUntis": {
-            "get": {
-                "description": "Searches for the Teacher with the specified untis abbrevation and returns the data",
-                "consumes": [
-                    "application/json"
-                ],
-                "produces": [
-                    "application/json"
-                ],
-                "summary": "Returns a teacher with the specified untis abbrevation",
-                "operationId": "get-teacher-by-untis",
-                "parameters": [
                     {
+                        "enum": [
+                            "start_time",
+                            "last_changed",
+                            "name"
+                        ],
                         "type": "string",
-                        "default": "Bearer \u003cAdd access token here\u003e",
-                        "description": "Access Token",
-                        "name": "Authorization",
-                        "in": "header",
-                        "required": true
+                        "default": "last_changed",
+                        "description": "Field to sort by",
+                        "name": "sort",
+                        "in": "query"
                     },
                     {
+                        "enum": [
+                            "asc",
+                            "desc"
+                        ],
                         "type": "string",
-                        "description": "Untis abbrevation of Teacher",
-                        "name": "untis",
-                        "in": "query",
-                        "required": true
+                        "default": "desc",
+                        "description": "Order of the sort",
+                        "name": "order",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "integer"
+                        },
+                        "description": "Filter for kinds of applications",
+                        "name": "kind",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "integer"
+                        },
+                        "description": "Filter for progress states",
+                        "name": "progress",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only show applications ending after this time (RFC 3339 or 2006-01-02)",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only show applications starting before this time (RFC 3339 or 2006-01-02)",
+                        "name": "till",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "description": "Filter for participating classes",
+                        "name": "class",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only show applications of teachers of this department",
+                        "name": "department",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "description": "Top level fields to return or 'summary' for the summary representation",
+                        "name": "fields",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/db.Teacher"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/db.Application"
+                            }
+                        },
+                        "headers": {
+                            "X-Page": {
+                                "type": "int",
+                                "description": "The returned page"
+                            },
+                            "X-Per-Page": {
+                                "type": "int",
+                                "description": "Amount of applications per page"
+                            },
+                            "X-Total-Count": {
+                                "type": "int",
+                                "description": "Total amount of matching applications"
+                            }
                         }
                     },
                     "401": {
@@ -940,21 +1045,27 @@ var doc = `{
                         "schema": {
                             "$ref": "#/definitions/rest.Error"
                         }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
                     }
                 }
             }
         },
-        "/getTravelInvoiceExcel": {
+        "/getAdminApplication": {
             "get": {
-                "description": "Generates a travel invoice excel for a teacher and returns it",
+                "description": "Returns one page of the applications currently needing a review by an admin\nApplications in process are shown to av and administration, if APPROVAL_CHAIN is set they are routed along it instead and the following roles see them after APPROVAL_ESCALATION_TIMEOUT passed per position\nDeputies see the queue of the approvers they currently stand in for",
                 "consumes": [
                     "application/json"
                 ],
                 "produces": [
                     "application/json"
                 ],
-                "summary": "Generates a travel invoice excel for a teacher",
-                "operationId": "get-travel-invoice-excel",
+                "summary": "Returns all admin applications",
+                "operationId": "get-admin-applications",
                 "parameters": [
                     {
                         "type": "string",
@@ -965,23 +1076,2504 @@ var doc = `{
                         "required": true
                     },
                     {
-                        "type": "string",
-                        "description": "Identifier of the application to generate the excel from",
-                        "name": "uuid",
+                        "type": "integer",
+                        "default": 1,
+                        "description": "Page to return, starting at 1",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 50,
+                        "description": "Amount of applications per page (at most 200)",
+                        "name": "per_page",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "start_time",
+                            "last_changed",
+                            "name"
+                        ],
+                        "type": "string",
+                        "default": "last_changed",
+                        "description": "Field to sort by",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "asc",
+                            "desc"
+                        ],
+                        "type": "string",
+                        "default": "desc",
+                        "description": "Order of the sort",
+                        "name": "order",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "integer"
+                        },
+                        "description": "Filter for kinds of applications",
+                        "name": "kind",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "integer"
+                        },
+                        "description": "Filter for progress states",
+                        "name": "progress",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only show applications ending after this time (RFC 3339 or 2006-01-02)",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only show applications starting before this time (RFC 3339 or 2006-01-02)",
+                        "name": "till",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "description": "Filter for participating classes",
+                        "name": "class",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only show applications of teachers of this department",
+                        "name": "department",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "description": "Top level fields to return or 'summary' for the summary representation",
+                        "name": "fields",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/db.Application"
+                            }
+                        },
+                        "headers": {
+                            "X-Page": {
+                                "type": "int",
+                                "description": "The returned page"
+                            },
+                            "X-Per-Page": {
+                                "type": "int",
+                                "description": "Amount of applications per page"
+                            },
+                            "X-Total-Count": {
+                                "type": "int",
+                                "description": "Total amount of matching applications"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/getAllApplications": {
+            "get": {
+                "description": "Returns one page of the applications matching the given filters, sorted by the given field",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Returns all applications",
+                "operationId": "get-all-applications",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "default": "Bearer \u003cAdd access token here\u003e",
+                        "description": "Access Token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter to only show applications of this teacher",
+                        "name": "username",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 1,
+                        "description": "Page to return, starting at 1",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 50,
+                        "description": "Amount of applications per page (at most 200)",
+                        "name": "per_page",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "start_time",
+                            "last_changed",
+                            "name"
+                        ],
+                        "type": "string",
+                        "default": "last_changed",
+                        "description": "Field to sort by",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "asc",
+                            "desc"
+                        ],
+                        "type": "string",
+                        "default": "desc",
+                        "description": "Order of the sort",
+                        "name": "order",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "integer"
+                        },
+                        "description": "Filter for kinds of applications",
+                        "name": "kind",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "integer"
+                        },
+                        "description": "Filter for progress states",
+                        "name": "progress",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only show applications ending after this time (RFC 3339 or 2006-01-02)",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only show applications starting before this time (RFC 3339 or 2006-01-02)",
+                        "name": "till",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "description": "Filter for participating classes",
+                        "name": "class",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only show applications of teachers of this department",
+                        "name": "department",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "description": "Top level fields to return or 'summary' for the summary representation",
+                        "name": "fields",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/db.Application"
+                            }
+                        },
+                        "headers": {
+                            "X-Page": {
+                                "type": "int",
+                                "description": "The returned page"
+                            },
+                            "X-Per-Page": {
+                                "type": "int",
+                                "description": "Amount of applications per page"
+                            },
+                            "X-Total-Count": {
+                                "type": "int",
+                                "description": "Total amount of matching applications"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/getApplication": {
+            "get": {
+                "description": "Returns the Application matching the given UUID",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Returns an Application",
+                "operationId": "get-application",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "default": "Bearer \u003cAdd access token here\u003e",
+                        "description": "Access Token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "The UUID of the specifying Application",
+                        "name": "uuid",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/db.Application"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/getAuditEntries": {
+            "get": {
+                "description": "Returns failed logins, lockouts and cleared lockouts, the newest first",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Returns the newest audit entries",
+                "operationId": "get-audit-entries",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "default": "Bearer \u003cAdd access token here\u003e",
+                        "description": "Access Token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only return the entries of this username",
+                        "name": "username",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 100,
+                        "description": "Maximum amount of entries",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/db.AuditEntry"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/getBusinessTripApplicationExcel": {
+            "get": {
+                "description": "Generates a business trip application excel for a teacher and returns it",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Generates a business trip application excel for a teacher",
+                "operationId": "get-business-trip-application-excel",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "default": "Bearer \u003cAdd access token here\u003e",
+                        "description": "Access Token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Identifier of the application to generate the excel from",
+                        "name": "uuid",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Short name of the teacher this should be generated for",
+                        "name": "short",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "ID of the Business Trip Application data",
+                        "name": "bta_id",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Excel"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/getBusinessTripApplicationForm": {
+            "get": {
+                "description": "Generates a business trip application form for a teacher and returns it",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Generates a business trip application form for a teacher",
+                "operationId": "get-business-trip-application-form",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "default": "Bearer \u003cAdd access token here\u003e",
+                        "description": "Access Token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Identifier of the application to generate the form from",
+                        "name": "uuid",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Short name of the teacher this should be generated for",
+                        "name": "short",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "ID of the Business Trip Application data",
+                        "name": "bta_id",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/rest.PDF"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/getCompensationForEducationalSupportForm": {
+            "get": {
+                "description": "Generates a compensation for educational support form for all teachers and returns it",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Generates a compensation for educational support form for all teachers",
+                "operationId": "get-compensation-for-educational-support-form",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "default": "Bearer \u003cAdd access token here\u003e",
+                        "description": "Access Token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Identifier of the application to generate the pdf from",
+                        "name": "uuid",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/rest.PDF"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/getDelegations": {
+            "get": {
+                "description": "Returns the delegations a teacher granted or received, including ended and revoked ones, the newest first",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Lists the delegations of a teacher",
+                "operationId": "get-delegations",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "default": "Bearer \u003cAdd access token here\u003e",
+                        "description": "Access Token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Short name of the teacher, only administrators may list the delegations of other teachers",
+                        "name": "short",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/db.Delegation"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/getDeputies": {
+            "get": {
+                "description": "Returns the deputy assignments a teacher made as approver or received as deputy, including ended and revoked ones, the newest first",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Lists the deputy assignments of a teacher",
+                "operationId": "get-deputies",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "default": "Bearer \u003cAdd access token here\u003e",
+                        "description": "Access Token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Short name of the teacher, only administrators may list the deputy assignments of other teachers",
+                        "name": "short",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/db.DeputyAssignment"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/getLockouts": {
+            "get": {
+                "description": "Returns the usernames and addresses which currently have to wait or are locked out after failed logins",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Lists the throttled usernames and addresses",
+                "operationId": "get-lockouts",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "default": "Bearer \u003cAdd access token here\u003e",
+                        "description": "Access Token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/auth.Lockout"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/getNews": {
+            "get": {
+                "description": "Returns the 10 last changed applications",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Returns the news",
+                "operationId": "get-news",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "default": "Bearer \u003cAdd access token here\u003e",
+                        "description": "Access Token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/rest.News"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/getSubstitutionSuggestions": {
+            "get": {
+                "description": "Lists the lessons the given teachers (or all teachers of a school event) miss during their attendance of the application and suggests free teachers for each of them, ranked by whether they are freed up by the event, teach the class or subject, and by their load",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Suggests substitutes for the lessons missed because of an application",
+                "operationId": "get-substitution-suggestions",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "default": "Bearer \u003cAdd access token here\u003e",
+                        "description": "Access Token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Identifier of the application",
+                        "name": "uuid",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "description": "Short names of the absent teachers, defaults to the teachers of a school event or the logged in teacher",
+                        "name": "teacher",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/rest.SubstitutionSuggestion"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/getTeacher": {
+            "get": {
+                "description": "Searches for the Teacher with the specified uuid and returns the data",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Returns a teacher with the specified UUID",
+                "operationId": "get-teacher",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "default": "Bearer \u003cAdd access token here\u003e",
+                        "description": "Access Token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "UUID of Teacher",
+                        "name": "uuid",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/db.Teacher"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/getTeacherByShort": {
+            "get": {
+                "description": "Searches for the Teacher with the specified name and returns the data",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Returns a teacher with the specified short name",
+                "operationId": "get-teacher-by-short",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "default": "Bearer \u003cAdd access token here\u003e",
+                        "description": "Access Token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Short Name of Teacher",
+                        "name": "name",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/db.Teacher"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/getTeacherByUntis": {
+            "get": {
+                "description": "Searches for the Teacher with the specified untis abbrevation and returns the data",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Returns a teacher with the specified untis abbrevation",
+                "operationId": "get-teacher-by-untis",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "default": "Bearer \u003cAdd access token here\u003e",
+                        "description": "Access Token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Untis abbrevation of Teacher",
+                        "name": "untis",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/db.Teacher"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/getTravelInvoiceExcel": {
+            "get": {
+                "description": "Generates a travel invoice excel for a teacher and returns it",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Generates a travel invoice excel for a teacher",
+                "operationId": "get-travel-invoice-excel",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "default": "Bearer \u003cAdd access token here\u003e",
+                        "description": "Access Token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Identifier of the application to generate the excel from",
+                        "name": "uuid",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Short name of the teacher this should be generated for",
+                        "name": "short",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "ID of the Travel Invoice data",
+                        "name": "ti_id",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Excel"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/getTravelInvoiceForm": {
+            "get": {
+                "description": "Generates a travel invoice form for a teacher and returns it",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Generates a travel invoice for a teacher",
+                "operationId": "get-travel-invoice-form",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "default": "Bearer \u003cAdd access token here\u003e",
+                        "description": "Access Token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Identifier of the application to generate the pdf from",
+                        "name": "uuid",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Short name of the teacher this should be generated for",
+                        "name": "short",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "ID of the Travel Invoice data",
+                        "name": "ti_id",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "If provided the pdf will include all receipt",
+                        "name": "receipts",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/rest.PDF"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/invalidateUntisCache": {
+            "post": {
+                "description": "Drops the cached teachers, rooms, classes, subjects and timegrid, so they are loaded from untis on the next request",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Invalidates the cached untis data",
+                "operationId": "invalidate-untis-cache",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "default": "Bearer \u003cAdd access token here\u003e",
+                        "description": "Access Token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Information"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/login": {
+            "post": {
+                "description": "Login a user using username and password, which are verified by the password providers enabled in AUTH_PROVIDERS\nUsernames and addresses with too many failed logins have to wait or are locked out temporarily, they get 429 with a Retry-After header\nIf the user has to use a second factor, a challenge is returned instead of the token pair, which has to be answered at /login/twoFactor",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Login a user",
+                "operationId": "login",
+                "parameters": [
+                    {
+                        "description": "Account Information",
+                        "name": "user",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/rest.User"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/rest.TokenPair"
+                        }
+                    },
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "$ref": "#/definitions/rest.LoginChallenge"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/login/callback": {
+            "post": {
+                "description": "Exchanges the code the provider returned after the login and creates a session of the teacher it belongs to",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Finishes a login on the page of an external identity provider",
+                "operationId": "finish-redirect-login",
+                "parameters": [
+                    {
+                        "description": "Code and state returned by the provider",
+                        "name": "callback",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/rest.LoginCallback"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/rest.TokenPair"
+                        }
+                    },
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "$ref": "#/definitions/rest.LoginChallenge"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/login/providers": {
+            "get": {
+                "description": "Lists the providers enabled in AUTH_PROVIDERS, ldap and local use the login endpoint, oidc uses the redirect login",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Returns the enabled authentication providers",
+                "operationId": "get-login-providers",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/login/redirect": {
+            "get": {
+                "description": "Returns the url of the login page of the provider the user has to be redirected to\nAfter the login the provider redirects to OIDC_REDIRECT_URL, which has to pass code and state to the callback endpoint",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Starts a login on the page of an external identity provider",
+                "operationId": "begin-redirect-login",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "default": "oidc",
+                        "description": "Name of the redirect provider",
+                        "name": "provider",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/rest.RedirectURL"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/login/refresh": {
+            "post": {
+                "description": "Creates a new token pair when a valid refresh token is provided",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Refreshes the token pair of a session",
+                "operationId": "refresh",
+                "parameters": [
+                    {
+                        "description": "Refresh Token",
+                        "name": "token",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/rest.RefreshToken"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/rest.TokenPair"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/login/twoFactor": {
+            "post": {
+                "description": "Checks the one-time password or recovery code answering the challenge returned by the login and creates the session\nIf the user enrolled a second factor during the login, the enrolment is confirmed and the recovery codes are returned once",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Answers the second step of a login",
+                "operationId": "answer-login-challenge",
+                "parameters": [
+                    {
+                        "description": "Challenge and one-time password or recovery code",
+                        "name": "answer",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/rest.ChallengeAnswer"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/rest.TwoFactorLogin"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/login/twoFactor/enroll": {
+            "post": {
+                "description": "Users whose roles require a second factor but who haven't enrolled one yet get a challenge which isn't enrolled\nThis returns the secret to add to an authenticator app, the challenge is then answered with a one-time password of it",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Enrols a second factor during a login",
+                "operationId": "enroll-login-challenge",
+                "parameters": [
+                    {
+                        "description": "Challenge returned by the login, the code is ignored",
+                        "name": "challenge",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/rest.ChallengeAnswer"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/auth.Provisioning"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/logout": {
+            "post": {
+                "description": "Destroys the session of a user, the untis session is only closed once no other session of the user is left",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Logs out a user",
+                "operationId": "logout",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "default": "Bearer \u003cAdd access token here\u003e",
+                        "description": "Access Token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Information"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/mergeTeachers": {
+            "post": {
+                "description": "Rewrites the applications the duplicate takes part in to the other teacher, copies values the other teacher lacks, unites their roles and deletes the duplicate\nFiled business trip applications and travel invoices are kept unchanged\nDuplicates holding roles the requesting administrator lacks (e.g. super users) can't be merged by them",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Merges a duplicate teacher into another one",
+                "operationId": "merge-teachers",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "default": "Bearer \u003cAdd access token here\u003e",
+                        "description": "Access Token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "UUID of the duplicate, which is deleted",
+                        "name": "source",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "UUID of the teacher which is kept",
+                        "name": "target",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/rest.MergeResult"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/reactivateTeacher": {
+            "post": {
+                "description": "Reactivates a deactivated teacher, so the teacher can log in again\nTeachers holding roles the requesting administrator lacks can't be reactivated by them",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Reactivates a teacher",
+                "operationId": "reactivate-teacher",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "default": "Bearer \u003cAdd access token here\u003e",
+                        "description": "Access Token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "UUID of the teacher",
+                        "name": "uuid",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/db.Teacher"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/resetTwoFactor": {
+            "post": {
+                "description": "Removes the second factor of a teacher who lost it, a teacher whose roles require one has to enrol again at the next login\nTeachers holding roles the administrator lacks, e.g. super users, can only be reset by someone holding these roles too, every reset is audited",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Removes the second factor of a teacher",
+                "operationId": "reset-two-factor",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "default": "Bearer \u003cAdd access token here\u003e",
+                        "description": "Access Token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "UUID of the teacher",
+                        "name": "uuid",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Information"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/revokeAPIKey": {
+            "delete": {
+                "description": "Revokes an API key immediately, it is kept so its usage can still be looked up",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Revokes an API key",
+                "operationId": "revoke-api-key",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "default": "Bearer \u003cAdd access token here\u003e",
+                        "description": "Access Token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ID of the API key",
+                        "name": "id",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Information"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/revokeDelegation": {
+            "delete": {
+                "description": "Ends a delegation immediately, it can be revoked by the delegator, the delegate and administrators",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Revokes a delegation",
+                "operationId": "revoke-delegation",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "default": "Bearer \u003cAdd access token here\u003e",
+                        "description": "Access Token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "UUID of the delegation",
+                        "name": "uuid",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Information"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/revokeDeputy": {
+            "delete": {
+                "description": "Ends a deputy assignment immediately, it can be revoked by the approver, the deputy and administrators",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Revokes a deputy assignment",
+                "operationId": "revoke-deputy",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "default": "Bearer \u003cAdd access token here\u003e",
+                        "description": "Access Token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "UUID of the deputy assignment",
+                        "name": "uuid",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Information"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/saveBillingReceipt": {
+            "post": {
+                "description": "Saves a billing receipt in the context of an application",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Saves a billing receipt",
+                "operationId": "save-billing-receipt",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "default": "Bearer \u003cAdd access token here\u003e",
+                        "description": "Access Token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Identifier of the application to generate the excel from",
+                        "name": "uuid",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Short name of the teacher this should be generated for",
+                        "name": "short",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "description": "The files to save as an array of the base64 decoded file contents",
+                        "name": "files",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/rest.PDFs"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Information"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/search": {
+            "get": {
+                "description": "Searches the name, notes, destination, organizer, classes and teachers of all applications visible to the user, ranked by relevance\nTeachers without approving roles find the applications they or the teachers who delegated to them take part in",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Searches applications",
+                "operationId": "search",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "default": "Bearer \u003cAdd access token here\u003e",
+                        "description": "Access Token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "The search text, quoted phrases and negated terms (-term) are supported",
+                        "name": "q",
                         "in": "query",
                         "required": true
                     },
+                    {
+                        "type": "integer",
+                        "default": 20,
+                        "description": "Maximum amount of results (at most 100)",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/rest.SearchHit"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/setLocalAccount": {
+            "post": {
+                "description": "Creates a teacher logging in with a password stored in huginn (e.g. external companions), or sets the name and password of an existing one\nLocal accounts can only log in if local is enabled in AUTH_PROVIDERS\nThe short name is stored lowercased, accounts holding roles the administrator lacks can only be changed by someone holding these roles too, every change is audited",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Creates or updates a local account",
+                "operationId": "set-local-account",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "default": "Bearer \u003cAdd access token here\u003e",
+                        "description": "Access Token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "Data of the local account",
+                        "name": "account",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/rest.LocalAccount"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/db.Teacher"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/setTeacherPermissions": {
+            "post": {
+                "description": "Sets the manually granted permissions of a Teacher to update their access rights\nPermissions derived from the directory groups of the teacher are kept, the teacher has the union of both\nOnly roles the requester holds can be granted, teachers holding roles the requester lacks can't be changed, every change is audited",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Sets the permissions of a Teacher",
+                "operationId": "set-teacher-permissions",
+                "parameters": [
                     {
                         "type": "string",
-                        "description": "Short name of the teacher this should be generated for",
-                        "name": "short",
-                        "in": "query",
+                        "default": "Bearer \u003cAdd access token here\u003e",
+                        "description": "Access Token",
+                        "name": "Authorization",
+                        "in": "header",
                         "required": true
                     },
                     {
-                        "type": "integer",
-                        "description": "ID of the Travel Invoice data",
-                        "name": "ti_id",
+                        "description": "Permission data of the teacher",
+                        "name": "perm",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/rest.Permissions"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "UUID of the teacher whos permissions will be changed",
+                        "name": "uuid",
                         "in": "query",
                         "required": true
                     }
@@ -990,7 +3582,7 @@ var doc = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/rest.Excel"
+                            "$ref": "#/definitions/db.Teacher"
                         }
                     },
                     "401": {
@@ -1016,21 +3608,27 @@ var doc = `{
                         "schema": {
                             "$ref": "#/definitions/rest.Error"
                         }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
                     }
                 }
             }
         },
-        "/getTravelInvoiceForm": {
-            "get": {
-                "description": "Generates a travel invoice form for a teacher and returns it",
+        "/syncTeachers": {
+            "post": {
+                "description": "Updates the names, titles, departments, staff numbers, mail addresses and groups of all teachers from the directory and reports the teachers which don't exist there anymore",
                 "consumes": [
                     "application/json"
                 ],
                 "produces": [
                     "application/json"
                 ],
-                "summary": "Generates a travel invoice for a teacher",
-                "operationId": "get-travel-invoice-form",
+                "summary": "Synchronizes all teachers with the directory",
+                "operationId": "sync-teachers",
                 "parameters": [
                     {
                         "type": "string",
@@ -1039,32 +3637,101 @@ var doc = `{
                         "name": "Authorization",
                         "in": "header",
                         "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/ldap.SyncReport"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
                     },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/teachers": {
+            "get": {
+                "description": "Returns one page of the teachers ordered by their name, e.g. to pick the companions of a school event\nThe search matches teachers whose short name, name, untis abbrevation or mail address contains every word of it\nDeactivated teachers and service principals are only listed if they are requested",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Lists the teachers",
+                "operationId": "get-teachers",
+                "parameters": [
                     {
                         "type": "string",
-                        "description": "Identifier of the application to generate the pdf from",
-                        "name": "uuid",
-                        "in": "query",
+                        "default": "Bearer \u003cAdd access token here\u003e",
+                        "description": "Access Token",
+                        "name": "Authorization",
+                        "in": "header",
                         "required": true
                     },
                     {
                         "type": "string",
-                        "description": "Short name of the teacher this should be generated for",
-                        "name": "short",
-                        "in": "query",
-                        "required": true
+                        "description": "Words the teachers have to match",
+                        "name": "search",
+                        "in": "query"
                     },
                     {
-                        "type": "integer",
-                        "description": "ID of the Travel Invoice data",
-                        "name": "ti_id",
-                        "in": "query",
-                        "required": true
+                        "type": "string",
+                        "description": "Only list teachers of this department",
+                        "name": "department",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "ldap",
+                            "oidc",
+                            "local",
+                            "service"
+                        ],
+                        "type": "string",
+                        "description": "Only list teachers of this provider",
+                        "name": "provider",
+                        "in": "query"
                     },
                     {
                         "type": "boolean",
-                        "description": "If provided the pdf will include all receipt",
-                        "name": "receipts",
+                        "description": "List deactivated teachers too",
+                        "name": "include_deactivated",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 1,
+                        "description": "Page to return, starting at 1",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 50,
+                        "description": "Amount of teachers per page",
+                        "name": "per_page",
                         "in": "query"
                     }
                 ],
@@ -1072,7 +3739,24 @@ var doc = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/rest.PDF"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/db.Teacher"
+                            }
+                        },
+                        "headers": {
+                            "X-Page": {
+                                "type": "int",
+                                "description": "Returned page"
+                            },
+                            "X-Per-Page": {
+                                "type": "int",
+                                "description": "Amount of teachers per page"
+                            },
+                            "X-Total-Count": {
+                                "type": "int",
+                                "description": "Total amount of matching teachers"
+                            }
                         }
                     },
                     "401": {
@@ -1081,12 +3765,6 @@ var doc = `{
                             "$ref": "#/definitions/rest.Error"
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
-                        "schema": {
-                            "$ref": "#/definitions/rest.Error"
-                        }
-                    },
                     "422": {
                         "description": "Unprocessable Entity",
                         "schema": {
@@ -1098,29 +3776,43 @@ var doc = `{
                         "schema": {
                             "$ref": "#/definitions/rest.Error"
                         }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
                     }
                 }
             }
         },
-        "/login": {
+        "/twoFactor/confirm": {
             "post": {
-                "description": "Login a user using username and password",
+                "description": "Enables the second factor if the one-time password matches the secret of the enrolment, the recovery codes are returned once",
                 "consumes": [
                     "application/json"
                 ],
                 "produces": [
                     "application/json"
                 ],
-                "summary": "Login a user",
-                "operationId": "login",
+                "summary": "Confirms the enrolment of a second factor",
+                "operationId": "confirm-two-factor",
                 "parameters": [
                     {
-                        "description": "Account Information",
-                        "name": "user",
+                        "type": "string",
+                        "default": "Bearer \u003cAdd access token here\u003e",
+                        "description": "Access Token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "One-time password",
+                        "name": "code",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/rest.User"
+                            "$ref": "#/definitions/rest.TwoFactorCode"
                         }
                     }
                 ],
@@ -1128,7 +3820,7 @@ var doc = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/rest.TokenPair"
+                            "$ref": "#/definitions/rest.RecoveryCodes"
                         }
                     },
                     "401": {
@@ -1137,42 +3829,74 @@ var doc = `{
                             "$ref": "#/definitions/rest.Error"
                         }
                     },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    },
                     "422": {
                         "description": "Unprocessable Entity",
                         "schema": {
                             "$ref": "#/definitions/rest.Error"
                         }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
                     }
                 }
             }
         },
-        "/login/refresh": {
+        "/twoFactor/disable": {
             "post": {
-                "description": "Creates a new token pair when a valid refresh token is provided",
+                "description": "Removes the second factor after checking a one-time password or recovery code, it can't be removed if the roles of the user require it",
                 "consumes": [
                     "application/json"
                 ],
                 "produces": [
                     "application/json"
                 ],
-                "summary": "Refreshes the token pair of a session",
-                "operationId": "refresh",
+                "summary": "Disables the second factor",
+                "operationId": "disable-two-factor",
                 "parameters": [
                     {
-                        "description": "Refresh Token",
-                        "name": "token",
+                        "type": "string",
+                        "default": "Bearer \u003cAdd access token here\u003e",
+                        "description": "Access Token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "One-time password or recovery code",
+                        "name": "code",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/rest.RefreshToken"
+                            "$ref": "#/definitions/rest.TwoFactorCode"
                         }
                     }
                 ],
                 "responses": {
-                    "201": {
-                        "description": "Created",
+                    "200": {
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/rest.TokenPair"
+                            "$ref": "#/definitions/rest.Information"
                         }
                     },
                     "401": {
@@ -1187,26 +3911,41 @@ var doc = `{
                             "$ref": "#/definitions/rest.Error"
                         }
                     },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    },
                     "422": {
                         "description": "Unprocessable Entity",
                         "schema": {
                             "$ref": "#/definitions/rest.Error"
                         }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
                     }
                 }
             }
         },
-        "/logout": {
+        "/twoFactor/enroll": {
             "post": {
-                "description": "Destroys the session of a user",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Creates a new secret to add to an authenticator app, it has to be confirmed with a one-time password at /twoFactor/confirm",
                 "produces": [
                     "application/json"
                 ],
-                "summary": "Logs out a user",
-                "operationId": "logout",
+                "summary": "Starts the enrolment of a second factor",
+                "operationId": "enroll-two-factor",
                 "parameters": [
                     {
                         "type": "string",
@@ -1221,7 +3960,7 @@ var doc = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/rest.Information"
+                            "$ref": "#/definitions/auth.Provisioning"
                         }
                     },
                     "401": {
@@ -1229,21 +3968,39 @@ var doc = `{
                         "schema": {
                             "$ref": "#/definitions/rest.Error"
                         }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
                     }
                 }
             }
         },
-        "/saveBillingReceipt": {
+        "/twoFactor/recoveryCodes": {
             "post": {
-                "description": "Saves a billing receipt in the context of an application",
+                "description": "Creates new recovery codes after checking a one-time password or recovery code, the old ones can't be used anymore",
                 "consumes": [
                     "application/json"
                 ],
                 "produces": [
                     "application/json"
                 ],
-                "summary": "Saves a billing receipt",
-                "operationId": "save-billing-receipt",
+                "summary": "Replaces the recovery codes",
+                "operationId": "regenerate-recovery-codes",
                 "parameters": [
                     {
                         "type": "string",
@@ -1254,26 +4011,12 @@ var doc = `{
                         "required": true
                     },
                     {
-                        "type": "string",
-                        "description": "Identifier of the application to generate the excel from",
-                        "name": "uuid",
-                        "in": "query",
-                        "required": true
-                    },
-                    {
-                        "type": "string",
-                        "description": "Short name of the teacher this should be generated for",
-                        "name": "short",
-                        "in": "query",
-                        "required": true
-                    },
-                    {
-                        "description": "The files to save as an array of the base64 decoded file contents",
-                        "name": "files",
+                        "description": "One-time password or recovery code",
+                        "name": "code",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/rest.PDFs"
+                            "$ref": "#/definitions/rest.TwoFactorCode"
                         }
                     }
                 ],
@@ -1281,7 +4024,7 @@ var doc = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/rest.Information"
+                            "$ref": "#/definitions/rest.RecoveryCodes"
                         }
                     },
                     "401": {
@@ -1290,8 +4033,14 @@ var doc = `{
                             "$ref": "#/definitions/rest.Error"
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
                         "schema": {
                             "$ref": "#/definitions/rest.Error"
                         }
@@ -1307,21 +4056,27 @@ var doc = `{
                         "schema": {
                             "$ref": "#/definitions/rest.Error"
                         }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
                     }
                 }
             }
         },
-        "/setTeacherPermissions": {
-            "post": {
-                "description": "Sets the permissions of a Teacher to update their access rights",
+        "/updateApplication": {
+            "put": {
+                "description": "Updates an application identified by a uuid with the data in the body in the system\nThe application is checked for conflicts like on creation, blocking conflicts prevent the update",
                 "consumes": [
                     "application/json"
                 ],
                 "produces": [
                     "application/json"
                 ],
-                "summary": "Sets the permissions of a Teacher",
-                "operationId": "set-teacher-permissions",
+                "summary": "Updates an existing application",
+                "operationId": "update-application",
                 "parameters": [
                     {
                         "type": "string",
@@ -1332,17 +4087,17 @@ var doc = `{
                         "required": true
                     },
                     {
-                        "description": "Permission data of the teacher",
-                        "name": "perm",
+                        "description": "The application data to update",
+                        "name": "application",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/rest.Permissions"
+                            "$ref": "#/definitions/db.Application"
                         }
                     },
                     {
                         "type": "string",
-                        "description": "UUID of the teacher whos permissions will be changed",
+                        "description": "Identifier of the application to update",
                         "name": "uuid",
                         "in": "query",
                         "required": true
@@ -1352,7 +4107,7 @@ var doc = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/db.Teacher"
+                            "$ref": "#/definitions/rest.SavedApplication"
                         }
                     },
                     "401": {
@@ -1361,6 +4116,18 @@ var doc = `{
                             "$ref": "#/definitions/rest.Error"
                         }
                     },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/rest.ConflictError"
+                        }
+                    },
                     "422": {
                         "description": "Unprocessable Entity",
                         "schema": {
@@ -1372,21 +4139,27 @@ var doc = `{
                         "schema": {
                             "$ref": "#/definitions/rest.Error"
                         }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
                     }
                 }
             }
         },
-        "/updateApplication": {
+        "/updateTeacher": {
             "put": {
-                "description": "Updates an application identified by a uuid with the data in the body in the system",
+                "description": "Replaces the data of a teacher, applications filed under the old name of the teacher are rewritten if the name changes\nShort name and provider can't be changed, duplicates are merged through /mergeTeachers\nTeachers holding roles the requesting administrator lacks (e.g. super users) can't be updated by them",
                 "consumes": [
                     "application/json"
                 ],
                 "produces": [
                     "application/json"
                 ],
-                "summary": "Updates an existing application",
-                "operationId": "update-application",
+                "summary": "Updates a teacher",
+                "operationId": "update-teacher",
                 "parameters": [
                     {
                         "type": "string",
@@ -1396,28 +4169,28 @@ var doc = `{
                         "in": "header",
                         "required": true
                     },
-                    {
-                        "description": "The application data to update",
-                        "name": "application",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/db.Application"
-                        }
-                    },
                     {
                         "type": "string",
-                        "description": "Identifier of the application to update",
+                        "description": "UUID of the teacher",
                         "name": "uuid",
                         "in": "query",
                         "required": true
+                    },
+                    {
+                        "description": "Data of the teacher",
+                        "name": "teacher",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/rest.TeacherData"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/rest.Information"
+                            "$ref": "#/definitions/db.Teacher"
                         }
                     },
                     "401": {
@@ -1443,6 +4216,12 @@ var doc = `{
                         "schema": {
                             "$ref": "#/definitions/rest.Error"
                         }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
                     }
                 }
             }
@@ -1497,6 +4276,12 @@ var doc = `{
                             "$ref": "#/definitions/rest.Error"
                         }
                     },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
+                    },
                     "422": {
                         "description": "Unprocessable Entity",
                         "schema": {
@@ -1508,12 +4293,133 @@ var doc = `{
                         "schema": {
                             "$ref": "#/definitions/rest.Error"
                         }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/rest.Error"
+                        }
                     }
                 }
             }
         }
     },
     "definitions": {
+        "auth.Lockout": {
+            "type": "object",
+            "properties": {
+                "failures": {
+                    "description": "Failures is the amount of failed logins since the last success",
+                    "type": "integer",
+                    "example": 10
+                },
+                "key": {
+                    "description": "Key is the username or address",
+                    "type": "string",
+                    "example": "szakall"
+                },
+                "kind": {
+                    "description": "Kind of the key, user or ip",
+                    "type": "string",
+                    "example": "user"
+                },
+                "last_failure": {
+                    "description": "LastFailure is the time of the last failed login",
+                    "type": "string"
+                },
+                "locked": {
+                    "description": "Locked is true if the key is locked out and not only backing off",
+                    "type": "boolean",
+                    "example": true
+                },
+                "until": {
+                    "description": "Until is the time logins are accepted again",
+                    "type": "string"
+                }
+            }
+        },
+        "auth.Provisioning": {
+            "type": "object",
+            "properties": {
+                "qr_code": {
+                    "description": "QRCode is the url encoded as QR code in a data url of a PNG image",
+                    "type": "string",
+                    "example": "data:image/png;base64,iVBORw0KGgo..."
+                },
+                "secret": {
+                    "description": "Secret encoded in base32 to enter it manually",
+                    "type": "string",
+                    "example": "JBSWY3DPEHPK3PXPJBSWY3DPEHPK3PXP"
+                },
+                "url": {
+                    "description": "URL is the otpauth:// url of the secret",
+                    "type": "string",
+                    "example": "otpauth://totp/Refundable:szakall?secret=JBSWY3DPEHPK3PXPJBSWY3DPEHPK3PXP\u0026issuer=Refundable"
+                }
+            }
+        },
+        "db.APIKey": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "description": "CreatedAt is the time the key was created at",
+                    "type": "string"
+                },
+                "created_by": {
+                    "description": "CreatedBy is the short name of the administrator who created the key",
+                    "type": "string",
+                    "example": "mborko"
+                },
+                "endpoints": {
+                    "description": "Endpoints lists the endpoints the key may call (e.g. /getAllApplications), the key may call no endpoint if it is empty",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "/getAllApplications",
+                        "/getTravelInvoiceExcel"
+                    ]
+                },
+                "expires_at": {
+                    "description": "ExpiresAt is the time the key isn't accepted anymore",
+                    "type": "string"
+                },
+                "id": {
+                    "description": "ID identifies the key, it is part of the key itself",
+                    "type": "string",
+                    "example": "3f9c2a7e41b8d605"
+                },
+                "last_used": {
+                    "description": "LastUsed is the time the key was last accepted at",
+                    "type": "string"
+                },
+                "last_used_ip": {
+                    "description": "LastUsedIP is the address the key was last used from",
+                    "type": "string",
+                    "example": "10.2.24.12"
+                },
+                "name": {
+                    "description": "Name describes what the key is used for",
+                    "type": "string",
+                    "example": "PEK monthly export"
+                },
+                "principal": {
+                    "description": "Principal is the short name of the service principal the key acts as",
+                    "type": "string",
+                    "example": "svc-pek-export"
+                },
+                "revoked_at": {
+                    "description": "RevokedAt is the time the key was revoked at, it is zero while the key isn't revoked",
+                    "type": "string"
+                },
+                "uses": {
+                    "description": "Uses is the amount of requests the key was accepted for",
+                    "type": "integer",
+                    "example": 42
+                }
+            }
+        },
         "db.Application": {
             "type": "object",
             "properties": {
@@ -1533,6 +4439,10 @@ var doc = `{
                     "description": "the time the underlying event of this Application ends",
                     "type": "string"
                 },
+                "in_process_since": {
+                    "description": "The timestamp this application entered InProcess, it is set by the server and zero in every other progress state",
+                    "type": "string"
+                },
                 "kind": {
                     "description": "The kind of this Application (for more see the Enum for the kinds of Application on this level only Training, SchoolEvent and OtherReason is applicable, the sub kinds should be used in the further detail section of the corresponding site)",
                     "type": "integer",
@@ -1597,6 +4507,53 @@ var doc = `{
                 }
             }
         },
+        "db.Attendance": {
+            "type": "object",
+            "properties": {
+                "from": {
+                    "description": "the start of the interval",
+                    "type": "string"
+                },
+                "till": {
+                    "description": "the end of the interval",
+                    "type": "string"
+                }
+            }
+        },
+        "db.AuditEntry": {
+            "type": "object",
+            "properties": {
+                "actor": {
+                    "description": "Actor is the short name of the teacher who caused the event, if it isn't the user itself (e.g. an administrator or a delegate)",
+                    "type": "string",
+                    "example": "mborko"
+                },
+                "ip": {
+                    "description": "IP address the event originated from",
+                    "type": "string",
+                    "example": "10.2.24.12"
+                },
+                "kind": {
+                    "description": "Kind of the event",
+                    "type": "string",
+                    "example": "login_failed"
+                },
+                "message": {
+                    "description": "Message describes the event",
+                    "type": "string",
+                    "example": "invalid credentials"
+                },
+                "time": {
+                    "description": "Time the event happened at",
+                    "type": "string"
+                },
+                "username": {
+                    "description": "Username the event concerns",
+                    "type": "string",
+                    "example": "szakall"
+                }
+            }
+        },
         "db.BusinessTripApplication": {
             "type": "object",
             "properties": {
@@ -1783,6 +4740,88 @@ var doc = `{
                 }
             }
         },
+        "db.Delegation": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "description": "CreatedAt is the time the delegation was created at",
+                    "type": "string"
+                },
+                "created_by": {
+                    "description": "CreatedBy is the short name of the teacher who created the delegation",
+                    "type": "string",
+                    "example": "szakall"
+                },
+                "delegate": {
+                    "description": "Delegate is the short name of the teacher acting on behalf of the delegator",
+                    "type": "string",
+                    "example": "ehuber"
+                },
+                "delegator": {
+                    "description": "Delegator is the short name of the teacher whose applications may be edited",
+                    "type": "string",
+                    "example": "szakall"
+                },
+                "from": {
+                    "description": "From is the time the delegation starts",
+                    "type": "string"
+                },
+                "revoked_at": {
+                    "description": "RevokedAt is the time the delegation was revoked at, it is zero while the delegation isn't revoked",
+                    "type": "string"
+                },
+                "till": {
+                    "description": "Till is the time the delegation ends",
+                    "type": "string"
+                },
+                "uuid": {
+                    "description": "UUID identifies the delegation",
+                    "type": "string",
+                    "example": "0b5a3c56-4e0d-4d6c-9a57-3f1b9b1f2c11"
+                }
+            }
+        },
+        "db.DeputyAssignment": {
+            "type": "object",
+            "properties": {
+                "approver": {
+                    "description": "Approver is the short name of the absent approver",
+                    "type": "string",
+                    "example": "mborko"
+                },
+                "created_at": {
+                    "description": "CreatedAt is the time the assignment was created at",
+                    "type": "string"
+                },
+                "created_by": {
+                    "description": "CreatedBy is the short name of the teacher who created the assignment",
+                    "type": "string",
+                    "example": "mborko"
+                },
+                "deputy": {
+                    "description": "Deputy is the short name of the teacher approving in place of the approver",
+                    "type": "string",
+                    "example": "szakall"
+                },
+                "from": {
+                    "description": "From is the time the assignment starts",
+                    "type": "string"
+                },
+                "revoked_at": {
+                    "description": "RevokedAt is the time the assignment was revoked at, it is zero while the assignment isn't revoked",
+                    "type": "string"
+                },
+                "till": {
+                    "description": "Till is the time the assignment ends",
+                    "type": "string"
+                },
+                "uuid": {
+                    "description": "UUID identifies the assignment",
+                    "type": "string",
+                    "example": "5d0c7f4e-2b1a-4c8e-9f3d-6a7b8c9d0e1f"
+                }
+            }
+        },
         "db.OtherReasonDetails": {
             "type": "object",
             "properties": {
@@ -1805,10 +4844,45 @@ var doc = `{
                     "description": "the gz number if the other reason is a ServiceMandate",
                     "type": "integer"
                 },
-                "service_mandate_title": {
-                    "description": "The title if the other reason is a ServiceMandate",
-                    "type": "string",
-                    "example": "Dienstverrichtung"
+                "service_mandate_title": {
+                    "description": "The title if the other reason is a ServiceMandate",
+                    "type": "string",
+                    "example": "Dienstverrichtung"
+                }
+            }
+        },
+        "db.Roles": {
+            "type": "object",
+            "properties": {
+                "administration": {
+                    "description": "Administration rights",
+                    "type": "boolean",
+                    "example": false
+                },
+                "av": {
+                    "description": "AV rights",
+                    "type": "boolean",
+                    "example": false
+                },
+                "department_head": {
+                    "description": "DepartmentHead lists the departments headed by the teacher",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "HIT"
+                    ]
+                },
+                "pek": {
+                    "description": "PEK rights",
+                    "type": "boolean",
+                    "example": true
+                },
+                "super_user": {
+                    "description": "SuperUser (total admin) of this software",
+                    "type": "boolean",
+                    "example": false
                 }
             }
         },
@@ -1936,13 +5010,20 @@ var doc = `{
             "type": "object",
             "properties": {
                 "attendance_from": {
-                    "description": "The teacher will be attending the SchoolEvent from",
+                    "description": "The teacher will be attending the SchoolEvent from (only used if Attendances is empty)",
                     "type": "string"
                 },
                 "attendance_till": {
-                    "description": "The teacher will be attend the SchoolEvent till",
+                    "description": "The teacher will be attend the SchoolEvent till (only used if Attendances is empty)",
                     "type": "string"
                 },
+                "attendances": {
+                    "description": "The intervals the teacher attends the SchoolEvent in, if the teacher doesn't attend the whole event at once",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/db.Attendance"
+                    }
+                },
                 "group": {
                     "description": "The group number",
                     "type": "integer",
@@ -1988,11 +5069,25 @@ var doc = `{
                     "type": "boolean",
                     "example": true
                 },
+                "deactivated_at": {
+                    "description": "The time the teacher was deactivated at (e.g. after leaving the school), it is zero while the teacher is active\ndeactivated teachers can't log in and aren't listed unless requested",
+                    "type": "string"
+                },
                 "degree": {
                     "description": "Degree of the Teacher",
                     "type": "string",
                     "example": "DI"
                 },
+                "department_head": {
+                    "description": "The Departments this Teacher is head of",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "HIT"
+                    ]
+                },
                 "departments": {
                     "description": "The Departments this teacher belongs to",
                     "type": "array",
@@ -2004,21 +5099,61 @@ var doc = `{
                         "HBG"
                     ]
                 },
+                "directory_roles": {
+                    "description": "The roles derived from the directory groups of the Teacher, they are replaced on every synchronization",
+                    "$ref": "#/definitions/db.Roles"
+                },
+                "granted_roles": {
+                    "description": "The roles granted manually (e.g. through the set teacher permissions endpoint)",
+                    "$ref": "#/definitions/db.Roles"
+                },
                 "group": {
                     "description": "The Group number",
                     "type": "integer",
                     "example": 1
                 },
+                "groups": {
+                    "description": "The distinguished names of the directory groups the teacher is a member of (synchronized from the directory)",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "CN=Lehrer",
+                        "OU=Groups",
+                        "DC=tgm",
+                        "DC=ac",
+                        "DC=at"
+                    ]
+                },
+                "last_synced": {
+                    "description": "The time the teacher was last synchronized with the directory",
+                    "type": "string"
+                },
                 "longname": {
                     "description": "the longname (firstname + sirname) of the Teacher",
                     "type": "string",
                     "example": "Stefan Zakall"
                 },
+                "mail": {
+                    "description": "The mail address of the teacher (synchronized from the directory)",
+                    "type": "string",
+                    "example": "szakall@tgm.ac.at"
+                },
+                "missing_since": {
+                    "description": "The time the teacher was first found missing in the directory, it is zero while the teacher exists there",
+                    "type": "string"
+                },
                 "pek": {
                     "description": "whether this Teacher as pek rights",
                     "type": "boolean",
                     "example": true
                 },
+                "provider": {
+                    "description": "The authentication provider the teacher logs in with (ldap if empty)",
+                    "type": "string",
+                    "example": "ldap"
+                },
                 "short": {
                     "description": "the short name of the Teacher",
                     "type": "string",
@@ -2062,6 +5197,10 @@ var doc = `{
                         "Landesgericht St. Pölten"
                     ]
                 },
+                "two_factor": {
+                    "description": "The second factor (TOTP) of the teacher",
+                    "$ref": "#/definitions/db.TwoFactor"
+                },
                 "untis": {
                     "description": "The Untis abbrevation of the teacher",
                     "type": "string",
@@ -2246,17 +5385,337 @@ var doc = `{
                     "type": "boolean",
                     "example": false
                 },
-                "trip_begin_time": {
-                    "description": "The time the trip begins",
+                "trip_begin_time": {
+                    "description": "The time the trip begins",
+                    "type": "string"
+                },
+                "trip_end_time": {
+                    "description": "The time the trip ends",
+                    "type": "string"
+                },
+                "zi": {
+                    "description": "the zi number",
+                    "type": "integer"
+                }
+            }
+        },
+        "db.TwoFactor": {
+            "type": "object",
+            "properties": {
+                "enabled": {
+                    "description": "whether the teacher has to enter a one-time password at login",
+                    "type": "boolean",
+                    "example": true
+                },
+                "enabled_at": {
+                    "description": "the time the second factor was enabled at",
+                    "type": "string"
+                }
+            }
+        },
+        "ldap.SyncReport": {
+            "type": "object",
+            "properties": {
+                "checked": {
+                    "description": "Checked is the amount of teachers compared with the directory",
+                    "type": "integer",
+                    "example": 120
+                },
+                "failed": {
+                    "description": "Failed are the short names of the teachers which couldn't be synchronized",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "ehuber"
+                    ]
+                },
+                "finished": {
+                    "description": "Finished is the time the synchronization finished at",
+                    "type": "string"
+                },
+                "missing": {
+                    "description": "Missing are the short names of the local teachers which don't exist in the directory anymore",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "mborko"
+                    ]
+                },
+                "started": {
+                    "description": "Started is the time the synchronization started at",
+                    "type": "string"
+                },
+                "updated": {
+                    "description": "Updated are the short names of the teachers whose data changed",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "szakall"
+                    ]
+                }
+            }
+        },
+        "rest.APIKeyRequest": {
+            "type": "object",
+            "properties": {
+                "endpoints": {
+                    "description": "Endpoints lists the endpoints the key may call, at least one has to be listed",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "/getAllApplications",
+                        "/getTravelInvoiceExcel"
+                    ]
+                },
+                "expires_at": {
+                    "description": "ExpiresAt is the time the key isn't accepted anymore, at most two years from now",
+                    "type": "string"
+                },
+                "longname": {
+                    "description": "Longname is the name of a service principal which is created",
+                    "type": "string",
+                    "example": "PEK Export"
+                },
+                "name": {
+                    "description": "Name describes what the key is used for",
+                    "type": "string",
+                    "example": "PEK monthly export"
+                },
+                "permissions": {
+                    "description": "Permissions replace the permissions of the service principal if they are given\nthey can't contain super user rights or roles the requesting administrator lacks",
+                    "$ref": "#/definitions/rest.Permissions"
+                },
+                "principal": {
+                    "description": "Principal is the short name of the service principal the key acts as, it is created if it doesn't exist",
+                    "type": "string",
+                    "example": "svc-pek-export"
+                }
+            }
+        },
+        "rest.ApplicationSummary": {
+            "type": "object",
+            "properties": {
+                "classes": {
+                    "description": "Classes participating in the application (only set for school events)",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "5AHIT",
+                        "5BHIT"
+                    ]
+                },
+                "end_time": {
+                    "description": "EndTime is the time the underlying event ends",
+                    "type": "string"
+                },
+                "kind": {
+                    "description": "Kind of the application",
+                    "type": "integer",
+                    "example": 0
+                },
+                "last_changed": {
+                    "description": "LastChanged is the time the application was changed last",
+                    "type": "string"
+                },
+                "name": {
+                    "description": "Name of the application",
+                    "type": "string",
+                    "example": "Sommersportwoche"
+                },
+                "participants": {
+                    "description": "Participants are the short names of the teachers of a school event or the filer of any other application",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "SCHF",
+                        "ZAKS"
+                    ]
+                },
+                "progress": {
+                    "description": "Progress of the application",
+                    "type": "integer",
+                    "example": 3
+                },
+                "start_time": {
+                    "description": "StartTime is the time the underlying event starts",
+                    "type": "string"
+                },
+                "uuid": {
+                    "description": "UUID of the application",
+                    "type": "string",
+                    "example": "3fcf7f67-e0ed-4339-99b4-a6765aaa3dc4"
+                }
+            }
+        },
+        "rest.ChallengeAnswer": {
+            "type": "object",
+            "properties": {
+                "challenge": {
+                    "description": "Challenge returned by the login",
+                    "type": "string",
+                    "example": "4f1c2a9e8b7d6c5a4f1c2a9e8b7d6c5a"
+                },
+                "code": {
+                    "description": "Code is a one-time password or a recovery code",
+                    "type": "string",
+                    "example": "492039"
+                }
+            }
+        },
+        "rest.Conflict": {
+            "type": "object",
+            "properties": {
+                "application": {
+                    "description": "Application is the uuid of the conflicting application, it is empty for conflicts with exams",
+                    "type": "string",
+                    "example": "693aa616-9895-418b-8904-765f0f6d26a4"
+                },
+                "blocking": {
+                    "description": "Blocking whether the conflict prevents saving the application",
+                    "type": "boolean",
+                    "example": false
+                },
+                "classes": {
+                    "description": "Classes affected by the conflict",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "5AHIT"
+                    ]
+                },
+                "end": {
+                    "description": "End of the conflicting application or exam",
+                    "type": "string"
+                },
+                "kind": {
+                    "description": "Kind of the conflict (teacher, class, exam or unchecked)",
+                    "type": "string",
+                    "example": "class"
+                },
+                "message": {
+                    "description": "Message describing the conflict",
+                    "type": "string",
+                    "example": "5AHIT also take part in Sommersportwoche"
+                },
+                "name": {
+                    "description": "Name of the conflicting application",
+                    "type": "string",
+                    "example": "Sommersportwoche"
+                },
+                "start": {
+                    "description": "Start of the conflicting application or exam",
+                    "type": "string"
+                },
+                "subjects": {
+                    "description": "Subjects of the conflicting exam",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "SEW"
+                    ]
+                },
+                "teachers": {
+                    "description": "Teachers are the short names of the teachers affected by the conflict (untis names for exams)",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "szakall"
+                    ]
+                }
+            }
+        },
+        "rest.ConflictError": {
+            "type": "object",
+            "properties": {
+                "conflicts": {
+                    "description": "Conflicts of the application, including the ones which don't block saving it",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/rest.Conflict"
+                    }
+                },
+                "error": {
+                    "description": "the message that should be sent",
+                    "type": "string",
+                    "example": "the application conflicts with other appointments"
+                }
+            }
+        },
+        "rest.CreatedAPIKey": {
+            "type": "object",
+            "properties": {
+                "api_key": {
+                    "description": "APIKey is the stored information about the key",
+                    "$ref": "#/definitions/db.APIKey"
+                },
+                "key": {
+                    "description": "Key is the API key itself, it is only shown once",
+                    "type": "string",
+                    "example": "huginn_3f9c2a7e41b8d605_8d1f..."
+                }
+            }
+        },
+        "rest.DelegationRequest": {
+            "type": "object",
+            "properties": {
+                "delegate": {
+                    "description": "Delegate is the short name of the teacher acting on behalf of the delegator",
+                    "type": "string",
+                    "example": "ehuber"
+                },
+                "delegator": {
+                    "description": "Delegator is the short name of the teacher whose applications may be edited, the requesting teacher if it is empty\nonly administrators may create delegations for other teachers",
+                    "type": "string",
+                    "example": "szakall"
+                },
+                "from": {
+                    "description": "From is the time the delegation starts, now if it is empty",
+                    "type": "string"
+                },
+                "till": {
+                    "description": "Till is the time the delegation ends, at most a year after it starts",
+                    "type": "string"
+                }
+            }
+        },
+        "rest.DeputyRequest": {
+            "type": "object",
+            "properties": {
+                "approver": {
+                    "description": "Approver is the short name of the absent approver, the requesting teacher if it is empty\nonly administrators may name deputies of other approvers",
+                    "type": "string",
+                    "example": "mborko"
+                },
+                "deputy": {
+                    "description": "Deputy is the short name of the teacher approving in place of the approver",
+                    "type": "string",
+                    "example": "szakall"
+                },
+                "from": {
+                    "description": "From is the time the assignment starts, now if it is empty",
                     "type": "string"
                 },
-                "trip_end_time": {
-                    "description": "The time the trip ends",
+                "till": {
+                    "description": "Till is the time the assignment ends, at most a year after it starts",
                     "type": "string"
-                },
-                "zi": {
-                    "description": "the zi number",
-                    "type": "integer"
                 }
             }
         },
@@ -2280,6 +5739,21 @@ var doc = `{
                 }
             }
         },
+        "rest.Highlight": {
+            "type": "object",
+            "properties": {
+                "field": {
+                    "description": "Field is the json path of the matching field",
+                    "type": "string",
+                    "example": "destination_address"
+                },
+                "snippet": {
+                    "description": "Snippet is the highlighted part of the field",
+                    "type": "string",
+                    "example": "Jugendgästehaus \u003cem\u003eWien\u003c/em\u003e Brigittenau"
+                }
+            }
+        },
         "rest.Information": {
             "type": "object",
             "properties": {
@@ -2290,6 +5764,83 @@ var doc = `{
                 }
             }
         },
+        "rest.LocalAccount": {
+            "type": "object",
+            "properties": {
+                "longname": {
+                    "description": "Longname (firstname + sirname) of the account",
+                    "type": "string",
+                    "example": "Maria Muster"
+                },
+                "mail": {
+                    "description": "Mail address of the account",
+                    "type": "string",
+                    "example": "maria.muster@example.com"
+                },
+                "password": {
+                    "description": "Password of the account",
+                    "type": "string",
+                    "example": "correct horse battery"
+                },
+                "short": {
+                    "description": "Short name the account logs in with",
+                    "type": "string",
+                    "example": "gast1"
+                },
+                "untis": {
+                    "description": "Untis abbrevation of the account, if it has one",
+                    "type": "string"
+                }
+            }
+        },
+        "rest.LoginCallback": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "description": "Code returned by the identity provider",
+                    "type": "string",
+                    "example": "0.AQwAsd..."
+                },
+                "state": {
+                    "description": "State returned by the identity provider",
+                    "type": "string",
+                    "example": "9f86d081884c7d659a2feaa0c55ad015"
+                }
+            }
+        },
+        "rest.LoginChallenge": {
+            "type": "object",
+            "properties": {
+                "challenge": {
+                    "description": "Challenge identifies the login",
+                    "type": "string",
+                    "example": "4f1c2a9e8b7d6c5a4f1c2a9e8b7d6c5a"
+                },
+                "enrolled": {
+                    "description": "Enrolled is false if the user has to enrol a second factor at /login/twoFactor/enroll first",
+                    "type": "boolean",
+                    "example": true
+                },
+                "expires": {
+                    "description": "Expires is the time the challenge has to be answered by",
+                    "type": "string"
+                }
+            }
+        },
+        "rest.MergeResult": {
+            "type": "object",
+            "properties": {
+                "applications": {
+                    "description": "Applications is the amount of applications whose participants were rewritten",
+                    "type": "integer",
+                    "example": 3
+                },
+                "teacher": {
+                    "description": "Teacher is the merged teacher",
+                    "$ref": "#/definitions/db.Teacher"
+                }
+            }
+        },
         "rest.News": {
             "type": "object",
             "properties": {
@@ -2349,6 +5900,16 @@ var doc = `{
                     "type": "boolean",
                     "example": true
                 },
+                "department_head": {
+                    "description": "DepartmentHead lists the departments the teacher is head of",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "HIT"
+                    ]
+                },
                 "pek": {
                     "description": "PEK permission",
                     "type": "boolean",
@@ -2361,6 +5922,31 @@ var doc = `{
                 }
             }
         },
+        "rest.RecoveryCodes": {
+            "type": "object",
+            "properties": {
+                "codes": {
+                    "description": "Codes are the recovery codes, they are only shown once",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "7kq2-m9xd-p4tz"
+                    ]
+                }
+            }
+        },
+        "rest.RedirectURL": {
+            "type": "object",
+            "properties": {
+                "url": {
+                    "description": "URL the user has to be redirected to",
+                    "type": "string",
+                    "example": "https://login.microsoftonline.com/\u003ctenant\u003e/oauth2/v2.0/authorize?..."
+                }
+            }
+        },
         "rest.RefreshToken": {
             "type": "object",
             "properties": {
@@ -2371,6 +5957,207 @@ var doc = `{
                 }
             }
         },
+        "rest.SavedApplication": {
+            "type": "object",
+            "properties": {
+                "conflicts": {
+                    "description": "Conflicts of the application which didn't prevent saving it",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/rest.Conflict"
+                    }
+                },
+                "info": {
+                    "description": "the message that should be sent",
+                    "type": "string",
+                    "example": "success; application created"
+                },
+                "uuid": {
+                    "description": "UUID of the saved application",
+                    "type": "string",
+                    "example": "693aa616-9895-418b-8904-765f0f6d26a4"
+                }
+            }
+        },
+        "rest.SearchHit": {
+            "type": "object",
+            "properties": {
+                "application": {
+                    "description": "Application is the summary of the matching application",
+                    "$ref": "#/definitions/rest.ApplicationSummary"
+                },
+                "highlights": {
+                    "description": "Highlights are snippets of the fields matching the search",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/rest.Highlight"
+                    }
+                },
+                "score": {
+                    "description": "Score is the relevance of the application, higher scores are more relevant",
+                    "type": "number",
+                    "example": 1.5
+                }
+            }
+        },
+        "rest.SubstitutionCandidate": {
+            "type": "object",
+            "properties": {
+                "freed_up": {
+                    "description": "FreedUp whether the teacher is only free because their own class takes part in the same event",
+                    "type": "boolean",
+                    "example": false
+                },
+                "load": {
+                    "description": "Load is the amount of lessons the teacher teaches on the day of the lesson",
+                    "type": "integer",
+                    "example": 4
+                },
+                "teacher": {
+                    "description": "Teacher is the untis name of the teacher",
+                    "type": "string",
+                    "example": "BORM"
+                },
+                "teaches_class": {
+                    "description": "TeachesClass whether the teacher teaches one of the classes of the lesson",
+                    "type": "boolean",
+                    "example": true
+                },
+                "teaches_subject": {
+                    "description": "TeachesSubject whether the teacher teaches one of the subjects of the lesson",
+                    "type": "boolean",
+                    "example": false
+                }
+            }
+        },
+        "rest.SubstitutionSuggestion": {
+            "type": "object",
+            "properties": {
+                "candidates": {
+                    "description": "Candidates are the suggested substitutes ordered by their suitability",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/rest.SubstitutionCandidate"
+                    }
+                },
+                "classes": {
+                    "description": "Classes attending the lesson",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "5AHIT"
+                    ]
+                },
+                "date": {
+                    "description": "Date of the lesson",
+                    "type": "string",
+                    "example": "01.03.2021"
+                },
+                "end": {
+                    "description": "End is the number of the last period of the lesson",
+                    "type": "integer",
+                    "example": 4
+                },
+                "rooms": {
+                    "description": "Rooms the lesson takes place in",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "H1101"
+                    ]
+                },
+                "start": {
+                    "description": "Start is the number of the first period of the lesson",
+                    "type": "integer",
+                    "example": 3
+                },
+                "subjects": {
+                    "description": "Subjects taught in the lesson",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "SEW"
+                    ]
+                },
+                "teachers": {
+                    "description": "Teachers are the untis names of the absent teachers of the lesson",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "ZAKS"
+                    ]
+                }
+            }
+        },
+        "rest.TeacherData": {
+            "type": "object",
+            "properties": {
+                "degree": {
+                    "description": "Degree of the teacher",
+                    "type": "string",
+                    "example": "DI"
+                },
+                "departments": {
+                    "description": "Departments the teacher belongs to",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "HIT",
+                        "HBG"
+                    ]
+                },
+                "group": {
+                    "description": "Group number of the teacher",
+                    "type": "integer",
+                    "example": 1
+                },
+                "longname": {
+                    "description": "Longname (firstname + surname) of the teacher, it is read from the directory if it is empty",
+                    "type": "string",
+                    "example": "Stefan Zakall"
+                },
+                "mail": {
+                    "description": "Mail address of the teacher",
+                    "type": "string",
+                    "example": "szakall@tgm.ac.at"
+                },
+                "provider": {
+                    "description": "Provider the teacher logs in with (ldap or oidc), it can't be changed after the teacher was created",
+                    "type": "string",
+                    "example": "ldap"
+                },
+                "short": {
+                    "description": "Short name of the teacher, it can't be changed after the teacher was created",
+                    "type": "string",
+                    "example": "szakall"
+                },
+                "staffnr": {
+                    "description": "Staffnr of the teacher",
+                    "type": "integer",
+                    "example": 938503154
+                },
+                "title": {
+                    "description": "Title of the teacher",
+                    "type": "string",
+                    "example": "Prof"
+                },
+                "untis": {
+                    "description": "Untis abbrevation of the teacher",
+                    "type": "string",
+                    "example": "ZAKS"
+                }
+            }
+        },
         "rest.TeacherInformation": {
             "type": "object",
             "properties": {
@@ -2450,6 +6237,41 @@ var doc = `{
                 }
             }
         },
+        "rest.TwoFactorCode": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "description": "Code is a one-time password or a recovery code",
+                    "type": "string",
+                    "example": "492039"
+                }
+            }
+        },
+        "rest.TwoFactorLogin": {
+            "type": "object",
+            "properties": {
+                "access_token": {
+                    "description": "the access token",
+                    "type": "string",
+                    "example": "\u003cjwt-token\u003e"
+                },
+                "recovery_codes": {
+                    "description": "the recovery codes, only returned once if the second factor was enrolled during the login",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "7kq2-m9xd-p4tz"
+                    ]
+                },
+                "refresh_token": {
+                    "description": "the refresh token",
+                    "type": "string",
+                    "example": "\u003cjwt-token\u003e"
+                }
+            }
+        },
         "rest.User": {
             "type": "object",
             "properties": {
@@ -2465,13 +6287,6 @@ var doc = `{
                 }
             }
         }
-    },
-    "securityDefinitions": {
-        "ApiKeyAuth": {
-            "type": "apiKey",
-            "name": "Authorization",
-            "in": "header"
-        }
     }
 }`
 
@@ -2486,12 +6301,12 @@ type swaggerInfo struct {
 
 // SwaggerInfo holds exported Swagger Info so clients can modify it
 var SwaggerInfo = swaggerInfo{
-	Version:     "1.1",
-	Host:        "localhost:8080",
-	BasePath:    "/api",
+	Version:     "",
+	Host:        "",
+	BasePath:    "",
 	Schemes:     []string{},
-	Title:       "Refundable",
-	Description: "This REST-API provides the backend of Refundable",
+	Title:       "",
+	Description: "",
 }
 
 type s struct{}
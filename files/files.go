@@ -1,6 +1,8 @@
 package files
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"github.com/360EntSecGroup-Skylar/excelize/v2"
 	"github.com/google/uuid"
@@ -69,6 +71,12 @@ const BusinessTripApplicationExcelFileName = "business_trip_application_%v.xlsx"
 // When filling in the wildcards this will result in a final name such as: travel_invoice_szakall.xlsx
 const TravelInvoiceExcelFileName = "travel_invoice_%v.xlsx"
 
+// SuggestedSubstitutes is the amount of suggested substitutes printed for each lesson on the teacher absence form
+const SuggestedSubstitutes = 2
+
+// AbsentClassNote is printed as substitute of lessons which don't need a substitution as their classes are absent as well
+const AbsentClassNote = "Klasse abwesend"
+
 // CheckedCheckBox is the unicode character for a checked check box (used when setting booleans in excel)
 const CheckedCheckBox = "☑"
 
@@ -239,11 +247,14 @@ func GenerateFileEnvironment(app db.Application) (string, error) {
 }
 
 // GenerateAbsenceFormForClass generates the class absence forms for all classes in the given db.Application.
-// It will be saved under path, and the given username is used to log into the untis service
+// It will be saved under path, and untis is accessed on behalf of the given username
 // It will return a string array of paths to all generated pdfs or an error if the operation wasn't successful
 func GenerateAbsenceFormForClass(path, username string, app db.Application) ([]string, error) {
 	paths := make([]string, 0)
-	client := untis.GetClient(username)
+	client, err := untis.ClientFor(username)
+	if err != nil {
+		return nil, err
+	}
 	if app.Kind != db.SchoolEvent {
 		return nil, fmt.Errorf("this pdf can only be generated for school events")
 	}
@@ -546,10 +557,6 @@ func GenerateAbsenceFormForClass(path, username string, app db.Application) ([]s
 		if err != nil {
 			return nil, err
 		}
-		err = client.Close()
-		if err != nil {
-			return nil, err
-		}
 		untisnames := ""
 		for _, t := range untiscomps {
 			untisnames = untisnames + t + ", "
@@ -557,8 +564,7 @@ func GenerateAbsenceFormForClass(path, username string, app db.Application) ([]s
 		if len(untisnames) != 0 {
 			untisnames = untisnames[0 : len(untisnames)-2]
 		}
-		lessons = mergeLessons(lessons)
-		//lessons = groupLessons(lessons)
+		lessons = untis.GroupLessons(untis.MergeParallelLessons(untis.WithoutCancelled(lessons)))
 		for _, lesson := range lessons {
 			date := lesson.Start
 			beginLesson := untis.GetLessonNrByStart(lesson.Start)
@@ -587,6 +593,7 @@ func GenerateAbsenceFormForClass(path, username string, app db.Application) ([]s
 			row := []string{"", class,
 				date.In(loc).Format("02.01.2006"),
 				hourString,
+				lessonSubject(lesson),
 				rooms,
 				untisnames,
 				teachers,
@@ -595,14 +602,14 @@ func GenerateAbsenceFormForClass(path, username string, app db.Application) ([]s
 			tableStrings = append(tableStrings, row)
 		}
 		sortTableByDate(tableStrings)
-		m.TableList([]string{"H/R/E", "Jahrgang", "Datum", "Stunde", "Saal", "LK Supp.", "LK Entf.", "Paraphe"},
+		m.TableList([]string{"H/R/E", "Jahrgang", "Datum", "Stunde", "Fach", "Saal", "LK Supp.", "LK Entf.", "Paraphe"},
 			tableStrings, props.TableList{
 				Align: consts.Center,
 				HeaderProp: props.TableListContent{
-					GridSizes: []uint{1, 1, 2, 1, 2, 1, 1, 1, 2, 2, 2},
+					GridSizes: []uint{1, 1, 2, 1, 1, 1, 2, 1, 2},
 				},
 				ContentProp: props.TableListContent{
-					GridSizes: []uint{1, 1, 2, 1, 2, 1, 1, 1, 2, 2, 2},
+					GridSizes: []uint{1, 1, 2, 1, 1, 1, 2, 1, 2},
 				},
 				Line: true,
 			})
@@ -670,6 +677,10 @@ func GenerateCompensationForEducationalSupport(path string, app db.Application)
 			companions = append(companions, teacher)
 		}
 	}
+	leaderFrom, leaderTill := app.StartTime, app.EndTime
+	if attendance := leader.AttendanceIntervals(app); len(attendance) > 0 {
+		leaderFrom, leaderTill = attendance[0].From, attendance[len(attendance)-1].Till
+	}
 	m := pdf.NewMaroto(consts.Portrait, consts.A4)
 	m.SetPageMargins(10, 15, 10)
 
@@ -802,8 +813,8 @@ func GenerateCompensationForEducationalSupport(path string, app db.Application)
 					Align: consts.Left,
 				})
 			})
-			weekday := getWeekday(int(leader.AttendanceFrom.In(loc).Weekday()))
-			m.Text(fmt.Sprintf("%v, %v", weekday, leader.AttendanceFrom.In(loc).Format("02.01.2006 15:04")),
+			weekday := getWeekday(int(leaderFrom.In(loc).Weekday()))
+			m.Text(fmt.Sprintf("%v, %v", weekday, leaderFrom.In(loc).Format("02.01.2006 15:04")),
 				props.Text{
 					Top:   2.5,
 					Align: consts.Center,
@@ -817,8 +828,8 @@ func GenerateCompensationForEducationalSupport(path string, app db.Application)
 					Align: consts.Left,
 				})
 			})
-			weekday := getWeekday(int(leader.AttendanceTill.In(loc).Weekday()))
-			m.Text(fmt.Sprintf("%v, %v", weekday, leader.AttendanceTill.In(loc).Format("02.01.2006 15:04")),
+			weekday := getWeekday(int(leaderTill.In(loc).Weekday()))
+			m.Text(fmt.Sprintf("%v, %v", weekday, leaderTill.In(loc).Format("02.01.2006 15:04")),
 				props.Text{
 					Top:   2.5,
 					Align: consts.Center,
@@ -840,23 +851,31 @@ func GenerateCompensationForEducationalSupport(path string, app db.Application)
 
 	tableString := make([][]string, 0)
 	for _, teacher := range teachers {
-		sweekday := getWeekday(int(app.StartTime.In(loc).Weekday()))
-		eweekday := getWeekday(int(app.EndTime.In(loc).Weekday()))
-		row := []string{
-			teacher.Name,
-			fmt.Sprintf("L%d", teacher.Group),
-			fmt.Sprintf("%v, %v", sweekday, teacher.AttendanceFrom.In(loc).Format("02.01.2006 15:04")),
-			fmt.Sprintf("%v, %v", eweekday, teacher.AttendanceTill.In(loc).Format("02.01.2006 15:04")),
+		// a teacher attending the event in several intervals gets a row for each of them
+		attendance := teacher.AttendanceIntervals(app)
+		for i, interval := range attendance {
+			sweekday := getWeekday(int(interval.From.In(loc).Weekday()))
+			eweekday := getWeekday(int(interval.Till.In(loc).Weekday()))
+			row := []string{"", "",
+				fmt.Sprintf("%v, %v", sweekday, interval.From.In(loc).Format("02.01.2006 15:04")),
+				fmt.Sprintf("%v, %v", eweekday, interval.Till.In(loc).Format("02.01.2006 15:04")),
+				"",
+			}
+			if i == 0 {
+				row[0] = teacher.Name
+				row[1] = fmt.Sprintf("L%d", teacher.Group)
+				row[4] = fmt.Sprintf("%d", attendedDays(attendance, loc))
+			}
+			tableString = append(tableString, row)
 		}
-		tableString = append(tableString, row)
 	}
-	m.TableList([]string{"Name", "Verwendungsgruppe", "Beginn", "Ende"}, tableString, props.TableList{
+	m.TableList([]string{"Name", "Verwendungsgruppe", "Beginn", "Ende", "Tage"}, tableString, props.TableList{
 		Align: consts.Center,
 		HeaderProp: props.TableListContent{
-			GridSizes: []uint{3, 3, 3, 3},
+			GridSizes: []uint{3, 2, 3, 3, 1},
 		},
 		ContentProp: props.TableListContent{
-			GridSizes: []uint{3, 3, 3, 3},
+			GridSizes: []uint{3, 2, 3, 3, 1},
 		},
 		Line: true,
 	})
@@ -894,16 +913,42 @@ func GenerateCompensationForEducationalSupport(path string, app db.Application)
 }
 
 // GenerateAbsenceFormForTeacher generates the teacher absence form for a teacher in the given db.Application.
-// It will be saved under path, and the given username is used to log into the untis service.
+// It will be saved under path, and untis is accessed on behalf of the given username.
 // The teacher string is the teachers abbrevation for the untis service
+// The given context bounds the database operations needed to resolve the name of the teacher
 // It will return a string array of paths to all generated pdfs or an error if the operation wasn't successful
-func GenerateAbsenceFormForTeacher(path, username, teacher string, app db.Application) (string, error) {
-	client := untis.GetClient(username)
-	defer client.Close()
+func GenerateAbsenceFormForTeacher(ctx context.Context, path, username, teacher string, app db.Application) (string, error) {
+	client, err := untis.ClientFor(username)
+	if err != nil {
+		return "", err
+	}
 	loc, err := time.LoadLocation("Europe/Vienna")
 	if err != nil {
 		return "", fmt.Errorf("couldn't load timezone")
 	}
+	mongo := db.MongoDatabaseConnector{}
+	name := username
+	if mongo.Connect(ctx) == nil {
+		if t, err := mongo.GetTeacherByShort(username); err == nil {
+			name = t.Longname
+		} else if errors.Is(err, db.ErrNotFound) {
+			name, err = ldap.GetLongName(username)
+			if err != nil {
+				name = username
+			} else {
+				_, _ = mongo.CreateTeacher(db.Teacher{
+					UUID:           uuid.NewString(),
+					Short:          username,
+					Longname:       name,
+					SuperUser:      false,
+					AV:             false,
+					Administration: false,
+					PEK:            false,
+				})
+			}
+		}
+		mongo.Close()
+	}
 	m := pdf.NewMaroto(consts.Portrait, consts.A4)
 	m.SetPageMargins(10, 15, 10)
 
@@ -962,29 +1007,6 @@ func GenerateAbsenceFormForTeacher(path, username, teacher string, app db.Applic
 					Align: consts.Left,
 				})
 			})
-			mongo := db.MongoDatabaseConnector{}
-			name := username
-			if mongo.Connect() {
-				if mongo.DoesTeacherExistByShort(username) {
-					name = mongo.GetTeacherByShort(username).Longname
-				} else {
-					name, err = ldap.GetLongName(client.Username, client.Password, username)
-					if err != nil {
-						name = username
-					} else {
-						mongo.CreateTeacher(db.Teacher{
-							UUID:           uuid.NewString(),
-							Short:          username,
-							Longname:       name,
-							SuperUser:      false,
-							AV:             false,
-							Administration: false,
-							PEK:            false,
-						})
-					}
-				}
-				mongo.Close()
-			}
 			m.Text(name, props.Text{
 				Top:   2.5,
 				Align: consts.Center,
@@ -1247,6 +1269,10 @@ func GenerateAbsenceFormForTeacher(path, username, teacher string, app db.Applic
 	tableStrings := make([][]string, 0)
 	var lessons []untis.Lesson
 	var untisname string
+	if teacher == "self" && client.Service {
+		// the service account has no timetable of its own, so the timetable of the user is requested by their name
+		teacher = name
+	}
 	if teacher == "self" {
 		var err error
 		err = client.Authenticate()
@@ -1282,9 +1308,10 @@ func GenerateAbsenceFormForTeacher(path, username, teacher string, app db.Applic
 		}
 		untisname = untisnameArr[0]
 	}
-	lessons = mergeLessons(lessons)
-	//lessons = groupLessons(lessons)
-	for _, lesson := range lessons {
+	attendance := teacherAttendance(ctx, app, untisname, loc)
+	lessons = untis.GroupLessons(untis.MergeParallelLessons(untis.WithoutCancelled(untis.During(lessons, attendance))))
+	substitutes := suggestSubstitutes(ctx, client, app, untisname, attendance, lessons)
+	for i, lesson := range lessons {
 		beginLesson := untis.GetLessonNrByStart(lesson.Start)
 		endLesson := untis.GetLessonNrByEnd(lesson.End)
 		hourString := ""
@@ -1310,22 +1337,23 @@ func GenerateAbsenceFormForTeacher(path, username, teacher string, app db.Applic
 		row := []string{"", classes,
 			fmt.Sprintf("%v", lesson.Start.In(loc).Format("02.01.2006")),
 			hourString,
+			lessonSubject(lesson),
 			rooms,
-			"",
+			substitutes[i],
 			untisname,
 			"",
 		}
 		tableStrings = append(tableStrings, row)
 	}
 	sortTableByDate(tableStrings)
-	m.TableList([]string{"H/R/E", "Jahrgang", "Datum", "Stunde", "Saal", "LK Supp.", "LK Entf.", "Paraphe"},
+	m.TableList([]string{"H/R/E", "Jahrgang", "Datum", "Stunde", "Fach", "Saal", "LK Supp.", "LK Entf.", "Paraphe"},
 		tableStrings, props.TableList{
 			Align: consts.Center,
 			HeaderProp: props.TableListContent{
-				GridSizes: []uint{1, 1, 2, 1, 2, 1, 1, 1, 2, 2, 2},
+				GridSizes: []uint{1, 1, 2, 1, 1, 1, 2, 1, 2},
 			},
 			ContentProp: props.TableListContent{
-				GridSizes: []uint{1, 1, 2, 1, 2, 1, 1, 1, 2, 2, 2},
+				GridSizes: []uint{1, 1, 2, 1, 1, 1, 2, 1, 2},
 			},
 			Line: true,
 		})
@@ -3112,151 +3140,136 @@ func sortTableByDate(table [][]string) {
 	})
 }
 
-// mergeLessons merges parallel lessons based on the beginning and end start times and merges the information in them
-func mergeLessons(lessons []untis.Lesson) []untis.Lesson {
-	dis := make([]untis.Lesson, 0)
-	for _, lesson := range lessons {
-		contains := false
-		for _, n := range dis {
-			if lessonsAreEqual(n, lesson) {
-				contains = true
-				n.Teachers = distinctString(append(n.Teachers, lesson.Teachers...))
-				n.Rooms = distinctString(append(n.Rooms, lesson.Rooms...))
-				n.Classes = distinctString(append(n.Classes, lesson.Classes...))
-				n.TeacherIDs = distinctInt(append(n.TeacherIDs, lesson.TeacherIDs...))
-				n.RoomIDs = distinctInt(append(n.RoomIDs, lesson.RoomIDs...))
-				n.ClassIDs = distinctInt(append(n.ClassIDs, lesson.ClassIDs...))
-			}
-		}
-		if !contains {
-			dis = append(dis, lesson)
-		}
-	}
-	return dis
+// lessonTypeNames are the names of the lesson types printed in the substitution tables
+var lessonTypeNames = map[string]string{
+	untis.LessonTypeOfficeHour:       "Sprechstunde",
+	untis.LessonTypeStandby:          "Bereitschaft",
+	untis.LessonTypeBreakSupervision: "Pausenaufsicht",
+	untis.LessonTypeExamination:      "Prüfung",
 }
 
-// groupLessons groups consecutive lessons which should can be summaried
-// ToDo fix
-func groupLessons(lessons []untis.Lesson) []untis.Lesson {
-	sort.Slice(lessons, func(i, j int) bool {
-		if lessons[i].Start == lessons[j].Start {
-			return untis.GetLessonNrByStart(lessons[i].Start) < untis.GetLessonNrByStart(lessons[j].Start)
+// suggestSubstitutes returns the substitutes suggested for each of the lessons the teacher untisname misses because of the application
+// attendance are the intervals the teacher is away in
+// the other teachers (during their attendance) and the classes of a school event are absent as well, lessons only attended by absent classes are marked with AbsentClassNote
+// if no suggestions can be made, e.g. because untis can't be accessed, no substitutes are returned
+func suggestSubstitutes(ctx context.Context, client *untis.Client, app db.Application, untisname string, attendance []untis.Interval, lessons []untis.Lesson) []string {
+	substitutes := make([]string, len(lessons))
+	loc, err := time.LoadLocation("Europe/Vienna")
+	if err != nil {
+		return substitutes
+	}
+	intervals := eventAttendances(ctx, app, loc)
+	intervals[untisname] = attendance
+	absence := untis.Absence{
+		Start:     app.StartTime.In(loc),
+		End:       app.EndTime.In(loc),
+		Teachers:  make([]string, 0, len(intervals)),
+		Intervals: intervals,
+	}
+	for teacher := range intervals {
+		absence.Teachers = append(absence.Teachers, teacher)
+	}
+	if app.Kind == db.SchoolEvent {
+		absence.Classes = app.SchoolEventDetails.Classes
+	}
+	open := make([]untis.Lesson, 0)
+	indices := make([]int, 0)
+	for i, lesson := range lessons {
+		if absence.OnlyAbsentClasses(lesson) {
+			substitutes[i] = AbsentClassNote
+			continue
 		}
-		return lessons[i].Start.Before(lessons[j].Start)
-	})
-	groups := make([]untis.Lesson, 0)
-	for _, lesson := range lessons {
-		grouped := false
-		for _, n := range groups {
-			if lesson.Start.Year() == n.Start.Year() &&
-				lesson.Start.Month() == n.Start.Month() &&
-				lesson.Start.Day() == n.Start.Day() &&
-				lesson.End.Year() == n.End.Year() &&
-				lesson.End.Month() == n.End.Month() &&
-				lesson.End.Day() == n.End.Day() {
-				if untis.GetLessonNrByStart(lesson.Start)+1 == untis.GetLessonNrByStart(n.Start) {
-					if compareString(lesson.Teachers, n.Teachers) &&
-						compareString(lesson.Rooms, n.Rooms) &&
-						compareString(lesson.Classes, n.Classes) &&
-						compareInt(lesson.TeacherIDs, n.TeacherIDs) &&
-						compareInt(lesson.RoomIDs, n.RoomIDs) &&
-						compareInt(lesson.ClassIDs, n.ClassIDs) {
-						n.End = lesson.End
-						grouped = true
-						break
-					}
-				}
+		open = append(open, lesson)
+		indices = append(indices, i)
+	}
+	suggestions, err := client.SuggestSubstitutions(absence, open)
+	if err != nil {
+		return substitutes
+	}
+	for j, suggestion := range suggestions {
+		names := make([]string, 0, SuggestedSubstitutes)
+		for _, candidate := range suggestion.Candidates {
+			if len(names) == SuggestedSubstitutes {
+				break
 			}
+			names = append(names, candidate.Teacher)
 		}
-		if !grouped {
-			groups = append(groups, lesson)
-		}
+		substitutes[indices[j]] = strings.Join(names, ", ")
 	}
-	return groups
+	return substitutes
 }
 
-// lessonsAreEqual checks whether two lessons start and end at the same time
-func lessonsAreEqual(lesson1, lesson2 untis.Lesson) bool {
-	return lesson1.Start.Year() == lesson2.Start.Year() &&
-		lesson1.Start.Month() == lesson2.Start.Month() &&
-		lesson1.Start.Day() == lesson2.Start.Day() &&
-		lesson1.Start.Hour() == lesson2.Start.Hour() &&
-		lesson1.Start.Minute() == lesson2.Start.Minute() &&
-		lesson1.End.Year() == lesson2.End.Year() &&
-		lesson1.End.Month() == lesson2.End.Month() &&
-		lesson1.End.Day() == lesson2.End.Day() &&
-		lesson1.End.Hour() == lesson2.End.Hour() &&
-		lesson1.End.Minute() == lesson2.End.Minute()
-}
-
-// distinctString removes duplicate entries out of a string array
-func distinctString(arr []string) []string {
-	tmp := make([]string, 0)
-	for _, a := range arr {
-		eq := false
-		for _, b := range arr {
-			if a == b {
-				eq = true
-			}
-		}
-		if !eq {
-			tmp = append(tmp, a)
+// eventAttendances returns the attendance intervals of all teachers of a school event known to the database by their untis names
+func eventAttendances(ctx context.Context, app db.Application, loc *time.Location) map[string][]untis.Interval {
+	attendances := make(map[string][]untis.Interval)
+	if app.Kind != db.SchoolEvent {
+		return attendances
+	}
+	mongo := db.MongoDatabaseConnector{}
+	if err := mongo.Connect(ctx); err != nil {
+		return attendances
+	}
+	defer mongo.Close()
+	for _, t := range app.SchoolEventDetails.Teachers {
+		if teacher, err := mongo.GetTeacherByShort(t.Shortname); err == nil && teacher.Untis != "" {
+			attendances[teacher.Untis] = intervals(t.AttendanceIntervals(app), loc)
 		}
 	}
-	return tmp
+	return attendances
 }
 
-// distinctInt removes duplicate entries out of an int array
-func distinctInt(arr []int) []int {
-	tmp := make([]int, 0)
-	for _, a := range arr {
-		eq := false
-		for _, b := range arr {
-			if a == b {
-				eq = true
-			}
-		}
-		if !eq {
-			tmp = append(tmp, a)
-		}
+// teacherAttendance returns the intervals the teacher with the given untis name is away because of the application
+// if the teacher isn't known to the database, they are away during the whole application
+func teacherAttendance(ctx context.Context, app db.Application, untisname string, loc *time.Location) []untis.Interval {
+	whole := []untis.Interval{{Start: app.StartTime.In(loc), End: app.EndTime.In(loc)}}
+	mongo := db.MongoDatabaseConnector{}
+	if err := mongo.Connect(ctx); err != nil {
+		return whole
+	}
+	defer mongo.Close()
+	teacher, err := mongo.GetTeacherByUntis(untisname)
+	if err != nil {
+		return whole
 	}
-	return tmp
+	return intervals(app.AttendanceOf(teacher.Short), loc)
 }
 
-// compareInt compares two int slices
-func compareInt(a, b []int) bool {
-	if len(a) != len(b) {
-		return false
+// intervals converts attendance intervals into untis intervals in the given time zone
+func intervals(attendances []db.Attendance, loc *time.Location) []untis.Interval {
+	converted := make([]untis.Interval, 0, len(attendances))
+	for _, attendance := range attendances {
+		converted = append(converted, untis.Interval{Start: attendance.From.In(loc), End: attendance.Till.In(loc)})
 	}
-	for _, v := range a {
-		contains := false
-		for _, q := range b {
-			if v == q {
-				contains = true
-			}
+	return converted
+}
+
+// attendedDays returns the amount of calendar days in the given time zone the attendance intervals touch
+func attendedDays(attendances []db.Attendance, loc *time.Location) int {
+	days := make(map[string]bool)
+	for _, attendance := range attendances {
+		last := attendance.Till.In(loc)
+		if !last.After(attendance.From) {
+			continue
 		}
-		if !contains {
-			return false
+		// an interval ending exactly at midnight doesn't touch the following day
+		last = last.Add(-time.Nanosecond)
+		for day := attendance.From.In(loc); !day.After(last); day = day.AddDate(0, 0, 1) {
+			days[day.Format("2006-01-02")] = true
 		}
+		days[last.Format("2006-01-02")] = true
 	}
-	return true
+	return len(days)
 }
 
-// compareString compares two string slices
-func compareString(a, b []string) bool {
-	if len(a) != len(b) {
-		return false
+// lessonSubject describes what takes place in a lesson for the substitution tables
+// it consists of the subjects, the type of the lesson if it isn't a regular one, and the substitution text of untis
+func lessonSubject(lesson untis.Lesson) string {
+	subject := strings.Join(lesson.Subjects, ", ")
+	if name, ok := lessonTypeNames[lesson.Type]; ok {
+		subject = strings.TrimSpace(name + " " + subject)
 	}
-	for _, v := range a {
-		contains := false
-		for _, q := range b {
-			if v == q {
-				contains = true
-			}
-		}
-		if !contains {
-			return false
-		}
+	if lesson.SubstText != "" {
+		subject = strings.TrimSpace(subject + " (" + lesson.SubstText + ")")
 	}
-	return true
+	return subject
 }
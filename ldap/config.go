@@ -0,0 +1,172 @@
+package ldap
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"github.com/go-ldap/ldap/v3"
+	"io/ioutil"
+	"log"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// DefaultURL is the url of the tgm ldap server used if LDAP_URL isn't set
+const DefaultURL = "ldap://10.2.24.151:389"
+
+// DefaultUPNSuffix is the suffix appended to usernames to form their user principal name if LDAP_UPN_SUFFIX isn't set
+const DefaultUPNSuffix = "@tgm.ac.at"
+
+// DefaultBaseDN is the dn teachers are searched in if LDAP_BASE_DN isn't set
+const DefaultBaseDN = "DC=tgm,DC=ac,DC=at"
+
+// DefaultDialTimeout is the maximum time establishing a connection may take if LDAP_DIAL_TIMEOUT isn't set
+const DefaultDialTimeout = 10 * time.Second
+
+// DefaultRequestTimeout is the maximum time a single request may take if LDAP_TIMEOUT isn't set
+const DefaultRequestTimeout = 30 * time.Second
+
+// Environment variables configuring the connection to the ldap server
+const (
+	// URLEnv contains the url of the ldap server, ldaps:// urls are encrypted using TLS
+	URLEnv = "LDAP_URL"
+	// StartTLSEnv enables StartTLS on ldap:// urls if set to true
+	StartTLSEnv = "LDAP_STARTTLS"
+	// CAFileEnv contains the path to a PEM bundle of the certificate authorities trusted in addition to the ones of the system
+	CAFileEnv = "LDAP_CA_FILE"
+	// DialTimeoutEnv contains the maximum time establishing a connection may take (e.g. 10s)
+	DialTimeoutEnv = "LDAP_DIAL_TIMEOUT"
+	// TimeoutEnv contains the maximum time a single request may take (e.g. 30s)
+	TimeoutEnv = "LDAP_TIMEOUT"
+	// UPNSuffixEnv contains the suffix appended to usernames to form their user principal name
+	UPNSuffixEnv = "LDAP_UPN_SUFFIX"
+	// BaseDNEnv contains the dn teachers are searched in
+	BaseDNEnv = "LDAP_BASE_DN"
+)
+
+// ErrInvalidConfig is returned if the configuration of the ldap connection is invalid
+var ErrInvalidConfig = errors.New("invalid ldap configuration")
+
+// plaintextWarning makes sure the warning about unencrypted connections is only logged once
+var plaintextWarning sync.Once
+
+// config is the configuration of the connection to the ldap server
+type config struct {
+	// url of the ldap server
+	url *url.URL
+	// startTLS whether the connection is upgraded using StartTLS
+	startTLS bool
+	// tls is the configuration of encrypted connections
+	tls *tls.Config
+	// dialTimeout is the maximum time establishing a connection may take
+	dialTimeout time.Duration
+	// timeout is the maximum time a single request may take
+	timeout time.Duration
+	// upnSuffix is appended to usernames to form their user principal name
+	upnSuffix string
+	// baseDN is the dn teachers are searched in
+	baseDN string
+}
+
+// loadConfig reads the configuration of the ldap connection from the environment
+func loadConfig() (config, error) {
+	c := config{
+		dialTimeout: DefaultDialTimeout,
+		timeout:     DefaultRequestTimeout,
+		upnSuffix:   envOr(UPNSuffixEnv, DefaultUPNSuffix),
+		baseDN:      envOr(BaseDNEnv, DefaultBaseDN),
+	}
+	var err error
+	if c.url, err = url.Parse(envOr(URLEnv, DefaultURL)); err != nil || (c.url.Scheme != "ldap" && c.url.Scheme != "ldaps") {
+		return c, fmt.Errorf("%w: %v has to be an ldap:// or ldaps:// url", ErrInvalidConfig, URLEnv)
+	}
+	if value := os.Getenv(StartTLSEnv); value != "" {
+		if c.startTLS, err = strconv.ParseBool(value); err != nil {
+			return c, fmt.Errorf("%w: %v has to be true or false", ErrInvalidConfig, StartTLSEnv)
+		}
+	}
+	if c.startTLS && c.url.Scheme == "ldaps" {
+		return c, fmt.Errorf("%w: StartTLS can't be used with ldaps:// urls", ErrInvalidConfig)
+	}
+	if c.dialTimeout, err = durationEnv(DialTimeoutEnv, DefaultDialTimeout); err != nil {
+		return c, err
+	}
+	if c.timeout, err = durationEnv(TimeoutEnv, DefaultRequestTimeout); err != nil {
+		return c, err
+	}
+	c.tls = &tls.Config{ServerName: c.url.Hostname(), MinVersion: tls.VersionTLS12}
+	if caFile := os.Getenv(CAFileEnv); caFile != "" {
+		pem, err := ioutil.ReadFile(caFile)
+		if err != nil {
+			return c, err
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return c, fmt.Errorf("%w: %v doesn't contain any PEM certificates", ErrInvalidConfig, CAFileEnv)
+		}
+		c.tls.RootCAs = pool
+	}
+	return c, nil
+}
+
+// encrypted checks whether connections using the configuration are encrypted
+func (c config) encrypted() bool {
+	return c.startTLS || c.url.Scheme == "ldaps"
+}
+
+// dial opens a connection to the configured ldap server, which is encrypted if ldaps:// or StartTLS is configured
+// returns ErrUnavailable if the server can't be reached or the connection can't be encrypted
+func dial() (*ldap.Conn, config, error) {
+	c, err := loadConfig()
+	if err != nil {
+		return nil, c, err
+	}
+	l, err := ldap.DialURL(c.url.String(),
+		ldap.DialWithDialer(&net.Dialer{Timeout: c.dialTimeout}),
+		ldap.DialWithTLSConfig(c.tls))
+	if err != nil {
+		return nil, c, fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+	l.SetTimeout(c.timeout)
+	if c.startTLS {
+		if err := l.StartTLS(c.tls); err != nil {
+			l.Close()
+			return nil, c, fmt.Errorf("%w: %v", ErrUnavailable, err)
+		}
+	}
+	if !c.encrypted() {
+		plaintextWarning.Do(func() {
+			log.Println("The connection to the ldap server isn't encrypted, configure an ldaps:// url or StartTLS")
+		})
+	}
+	return l, c, nil
+}
+
+// envOr returns the value of the environment variable, or fallback if it isn't set
+func envOr(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
+
+// durationEnv returns the duration in the environment variable, or fallback if it isn't set
+func durationEnv(key string, fallback time.Duration) (time.Duration, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("%w: %v has to be a positive duration like 10s", ErrInvalidConfig, key)
+	}
+	return d, nil
+}
@@ -1,111 +1,165 @@
 package ldap
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"github.com/go-ldap/ldap/v3"
 	"github.com/google/uuid"
 	"github.com/refundable-tgm/huginn/db"
 	"github.com/refundable-tgm/huginn/untis"
+	"io/ioutil"
+	"log"
+	"os"
 	"strings"
+	"time"
 )
 
-// URL is the ip address of the tgm ldap server to connect to
-const URL = "10.2.24.151"
+// BindUserEnv is the environment variable containing the user principal name of the ldap service account
+const BindUserEnv = "LDAP_BIND_USER"
 
-// Port of the tgm ldap server. In this case it is the default port
-const Port = 389
+// BindPasswordFileEnv is the environment variable containing the path to the file holding the password of the ldap service account
+const BindPasswordFileEnv = "LDAP_BIND_PASSWORD_FILE"
 
-// AuthenticateUserCredentials authenicates a user given by username and password through the tgm ldap server.
-// Furthermore if it is the first login of a user it will create a new Teacher instance and save it to the local database.
-// It will return true if the credentials are valid and able to produce a successful login operation on the ldap server
-// Otherwise if any connection error occurs or the credentials aren't valid this method will return false
-func AuthenticateUserCredentials(username, password string) bool {
-	cred := username + "@tgm.ac.at"
-	l, err := ldap.Dial("tcp", fmt.Sprintf("%s:%d", URL, Port))
+// ErrInvalidCredentials is returned if the ldap server rejects the given credentials
+var ErrInvalidCredentials = errors.New("invalid credentials")
+
+// ErrUnavailable is returned if the ldap server can't be reached
+var ErrUnavailable = errors.New("ldap server unavailable")
+
+// ErrNoServiceAccount is returned if a lookup needs the ldap service account but none is configured
+var ErrNoServiceAccount = errors.New("no ldap service account configured")
+
+// AuthenticateUserCredentials authenicates a user given by username and password through the configured ldap server.
+// Furthermore if it is the first login of a user it will create a new Teacher instance from their directory entry and save it to the local database,
+// otherwise the stored teacher is synchronized with the directory entry.
+// In both cases the directory roles of the teacher are derived from its groups using LDAP_GROUP_ROLES.
+// If no untis service account is configured an untis session is opened for the user, the password isn't kept afterwards.
+// It will return nil if the credentials are valid and able to produce a successful login operation on the ldap server
+// If the credentials aren't valid ErrInvalidCredentials is returned, otherwise any error occurred during the login is returned
+// The given context bounds all database operations during the login
+func AuthenticateUserCredentials(ctx context.Context, username, password string) error {
+	l, c, err := dial()
 	if err != nil {
-		return false
+		return err
+	}
+	defer l.Close()
+	err = l.Bind(username+c.upnSuffix, password)
+	if ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) || ldap.IsErrorWithCode(err, ldap.ErrorEmptyPassword) {
+		return ErrInvalidCredentials
 	}
-	err = l.Bind(cred, password)
 	if err != nil {
-		return false
+		return fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+	if !untis.HasServiceAccount() {
+		if _, err := untis.Login(username, password); err != nil {
+			log.Println("Couldn't open untis session of ", username, ": ", err)
+		}
 	}
 	mongo := db.MongoDatabaseConnector{}
-	if !mongo.Connect() {
-		return false
+	if err := mongo.Connect(ctx); err != nil {
+		return err
 	}
 	defer mongo.Close()
-	longname, err := GetLongName(username, password, username)
+	entry, found, searchErr := searchEntry(l, c.baseDN, loadAttributes(), username)
+	mappings, err := loadRoleMappings()
 	if err != nil {
-		return false
-	}
-	client := untis.CreateClient(username, password)
-	if !mongo.DoesTeacherExistByShort(username) {
-		err = client.Authenticate()
-		if err != nil {
-			_ = client.Close()
-			return false
-		}
-		id, err := client.ResolveTeacherID(longname)
-		if err != nil {
-			return false
-		}
-		untisAb, err := client.ResolveTeachers([]int{id})
-		if err != nil {
-			return false
-		}
-		if !mongo.CreateTeacher(db.Teacher{
-			UUID:           uuid.NewString(),
-			Short:          username,
-			Longname:       longname,
-			SuperUser:      false,
-			AV:             false,
-			Administration: false,
-			PEK:            false,
-			Untis:          untisAb[0],
-		}) {
-			_ = client.Close()
-			return false
+		// the directory roles are kept until the mappings are fixed
+		log.Println("Couldn't map the directory groups of ", username, " onto roles: ", err)
+	}
+	teacher, err := mongo.GetTeacherByShort(username)
+	if err == nil && (teacher.Provider == db.LocalProvider || teacher.Provider == db.ServiceProvider) {
+		// local accounts and service principals can't be taken over by a directory account with the same name
+		return ErrInvalidCredentials
+	}
+	if err == nil {
+		// the teacher is synchronized with the directory on every login, but a failure doesn't prevent the login
+		if searchErr != nil || !found {
+			log.Println("Couldn't synchronize ", username, " with the directory: ", searchErr)
+			return nil
 		}
-		err = client.Close()
-		if err != nil {
-			return false
+		entry.Apply(&teacher, mappings)
+		teacher.LastSynced = time.Now()
+		if err := mongo.UpdateTeacher(teacher.UUID, teacher); err != nil {
+			log.Println("Couldn't synchronize ", username, " with the directory: ", err)
 		}
+		return nil
+	}
+	if !errors.Is(err, db.ErrNotFound) {
+		return err
+	}
+	if searchErr != nil {
+		return searchErr
+	}
+	if !found {
+		return fmt.Errorf("user does not exist in the directory")
 	}
-	return true
+	client, err := untis.ClientFor(username)
+	if err != nil {
+		return err
+	}
+	id, err := client.ResolveTeacherID(entry.LongName())
+	if err != nil {
+		return err
+	}
+	untisAb, err := client.ResolveTeachers([]int{id})
+	if err != nil {
+		return err
+	}
+	teacher = db.Teacher{
+		UUID:           uuid.NewString(),
+		Short:          username,
+		Provider:       db.LDAPProvider,
+		SuperUser:      false,
+		AV:             false,
+		Administration: false,
+		PEK:            false,
+		Untis:          untisAb[0],
+		LastSynced:     time.Now(),
+	}
+	entry.Apply(&teacher, mappings)
+	_, err = mongo.CreateTeacher(teacher)
+	return err
 }
 
-// GetLongName will find out the full name (name + surname) of a teacher identified by key through their saved file on the active directory
-// ldap server. If the search operation was successful the full name is returned. Otherwise any error occurred will be
+// GetLongName will find out the full name (name + surname) of a teacher identified by key through their entry in the active directory
+// ldap server using the service account. If the search operation was successful the full name is returned. Otherwise any error occurred will be
 // returned.
-func GetLongName(username, password, key string) (string, error) {
-	cred := username + "@tgm.ac.at"
-	l, err := ldap.Dial("tcp", fmt.Sprintf("%s:%d", URL, Port))
+func GetLongName(key string) (string, error) {
+	l, c, err := serviceConnection()
 	if err != nil {
 		return "", err
 	}
-	err = l.Bind(cred, password)
+	defer l.Close()
+	entry, found, err := searchEntry(l, c.baseDN, loadAttributes(), key)
 	if err != nil {
 		return "", err
 	}
-	search := ldap.NewSearchRequest("DC=tgm,DC=ac,DC=at",
-		ldap.ScopeWholeSubtree,
-		ldap.NeverDerefAliases,
-		0,
-		0,
-		false,
-		fmt.Sprintf("(&(mailNickname=%s))", key),
-		[]string{"dn"},
-		nil,
-	)
-	res, err := l.Search(search)
+	if !found {
+		return "", fmt.Errorf("user does not exist in the directory")
+	}
+	return entry.LongName(), nil
+}
+
+// serviceConnection opens a connection to the ldap server bound to the service account
+// configured by LDAP_BIND_USER and LDAP_BIND_PASSWORD_FILE
+func serviceConnection() (*ldap.Conn, config, error) {
+	user := os.Getenv(BindUserEnv)
+	passwordFile := os.Getenv(BindPasswordFileEnv)
+	if user == "" || passwordFile == "" {
+		return nil, config{}, ErrNoServiceAccount
+	}
+	password, err := ioutil.ReadFile(passwordFile)
 	if err != nil {
-		return "", err
+		return nil, config{}, err
+	}
+	l, c, err := dial()
+	if err != nil {
+		return nil, c, err
 	}
-	if len(res.Entries) != 1 {
-		return "", fmt.Errorf("user does not exist or too many entries returned")
+	if err := l.Bind(user, strings.TrimSuffix(string(password), "\n")); err != nil {
+		l.Close()
+		return nil, c, err
 	}
-	userdn := res.Entries[0]
-	cn := strings.Split(userdn.DN, ",")[0]
-	name := strings.Split(cn, "=")[1]
-	return name, nil
+	return l, c, nil
 }
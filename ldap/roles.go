@@ -0,0 +1,145 @@
+package ldap
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/go-ldap/ldap/v3"
+	"github.com/refundable-tgm/huginn/db"
+	"os"
+	"strings"
+)
+
+// GroupRolesEnv is the environment variable mapping directory groups onto roles as json object,
+// e.g. {"CN=PEK,OU=Groups,DC=tgm,DC=ac,DC=at": ["pek"], "CN=Abteilungsvorstand-HIT": ["department_head:HIT"]}
+// groups are given as distinguished name, or only as their first RDN to match every group with this RDN
+const GroupRolesEnv = "LDAP_GROUP_ROLES"
+
+// Roles which can be mapped onto directory groups
+const (
+	// SuperUserRole grants the super user permission
+	SuperUserRole = "superuser"
+	// AVRole grants the av permission
+	AVRole = "av"
+	// AdministrationRole grants the administration permission
+	AdministrationRole = "administration"
+	// PEKRole grants the pek permission
+	PEKRole = "pek"
+	// DepartmentHeadRole makes the members head of the department following the colon, e.g. department_head:HIT
+	DepartmentHeadRole = "department_head"
+)
+
+// RoleMapping grants roles to the members of a directory group
+type RoleMapping struct {
+	// Group is the distinguished name of the group, or only its first RDN
+	Group *ldap.DN
+	// Roles granted to the members of the group
+	Roles db.Roles
+}
+
+// matches checks whether the group with the given distinguished name is the mapped group
+func (m RoleMapping) matches(group string) bool {
+	dn, err := ldap.ParseDN(group)
+	if err != nil || len(dn.RDNs) == 0 {
+		return false
+	}
+	if len(m.Group.RDNs) == 1 {
+		return equalRDN(m.Group.RDNs[0], dn.RDNs[0])
+	}
+	if len(m.Group.RDNs) != len(dn.RDNs) {
+		return false
+	}
+	for i := range dn.RDNs {
+		if !equalRDN(m.Group.RDNs[i], dn.RDNs[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// equalRDN compares two RDNs ignoring the case of types and values like the active directory does
+func equalRDN(a, b *ldap.RelativeDN) bool {
+	if len(a.Attributes) != len(b.Attributes) {
+		return false
+	}
+	for _, x := range a.Attributes {
+		found := false
+		for _, y := range b.Attributes {
+			if strings.EqualFold(x.Type, y.Type) && strings.EqualFold(x.Value, y.Value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// loadRoleMappings reads the mappings of directory groups onto roles from LDAP_GROUP_ROLES
+// returns an empty list if no mappings are configured
+func loadRoleMappings() ([]RoleMapping, error) {
+	mappings := make([]RoleMapping, 0)
+	value := os.Getenv(GroupRolesEnv)
+	if value == "" {
+		return mappings, nil
+	}
+	groups := make(map[string][]string)
+	if err := json.Unmarshal([]byte(value), &groups); err != nil {
+		return nil, fmt.Errorf("%w: %v has to be a json object mapping groups onto lists of roles", ErrInvalidConfig, GroupRolesEnv)
+	}
+	for group, roles := range groups {
+		dn, err := ldap.ParseDN(group)
+		if err != nil || len(dn.RDNs) == 0 {
+			return nil, fmt.Errorf("%w: %v contains the invalid group %v", ErrInvalidConfig, GroupRolesEnv, group)
+		}
+		mapping := RoleMapping{Group: dn, Roles: db.Roles{DepartmentHead: make([]string, 0)}}
+		for _, role := range roles {
+			if err := grant(&mapping.Roles, role); err != nil {
+				return nil, err
+			}
+		}
+		mappings = append(mappings, mapping)
+	}
+	return mappings, nil
+}
+
+// grant adds the role with the given name to the roles
+func grant(roles *db.Roles, role string) error {
+	name, department := strings.TrimSpace(role), ""
+	if i := strings.Index(name, ":"); i >= 0 {
+		name, department = strings.TrimSpace(name[:i]), strings.TrimSpace(name[i+1:])
+	}
+	switch strings.ToLower(name) {
+	case SuperUserRole:
+		roles.SuperUser = true
+	case AVRole:
+		roles.AV = true
+	case AdministrationRole:
+		roles.Administration = true
+	case PEKRole:
+		roles.PEK = true
+	case DepartmentHeadRole:
+		if department == "" {
+			return fmt.Errorf("%w: %v requires a department like %v:HIT", ErrInvalidConfig, DepartmentHeadRole, DepartmentHeadRole)
+		}
+		roles.DepartmentHead = append(roles.DepartmentHead, department)
+	default:
+		return fmt.Errorf("%w: %v contains the unknown role %v", ErrInvalidConfig, GroupRolesEnv, role)
+	}
+	return nil
+}
+
+// rolesOf returns the roles granted by the mappings to a member of the given groups
+func rolesOf(groups []string, mappings []RoleMapping) db.Roles {
+	roles := db.Roles{DepartmentHead: make([]string, 0)}
+	for _, mapping := range mappings {
+		for _, group := range groups {
+			if mapping.matches(group) {
+				roles = roles.Merge(mapping.Roles)
+				break
+			}
+		}
+	}
+	return roles
+}
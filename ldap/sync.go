@@ -0,0 +1,301 @@
+package ldap
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/go-ldap/ldap/v3"
+	"github.com/refundable-tgm/huginn/db"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultSyncInterval is the time between two full synchronizations if LDAP_SYNC_INTERVAL isn't set
+const DefaultSyncInterval = 24 * time.Hour
+
+// SyncIntervalEnv is the environment variable containing the time between two full synchronizations (e.g. 12h), 0 disables them
+const SyncIntervalEnv = "LDAP_SYNC_INTERVAL"
+
+// Environment variables containing the names of the directory attributes mapped onto a teacher
+const (
+	// UsernameAttributeEnv is the attribute containing the username (short name) of a teacher
+	UsernameAttributeEnv = "LDAP_ATTR_USERNAME"
+	// GivenNameAttributeEnv is the attribute containing the first name of a teacher
+	GivenNameAttributeEnv = "LDAP_ATTR_GIVEN_NAME"
+	// SurnameAttributeEnv is the attribute containing the last name of a teacher
+	SurnameAttributeEnv = "LDAP_ATTR_SURNAME"
+	// TitleAttributeEnv is the attribute containing the title of a teacher
+	TitleAttributeEnv = "LDAP_ATTR_TITLE"
+	// DepartmentAttributeEnv is the attribute containing the departments of a teacher (separated by commas if there are several)
+	DepartmentAttributeEnv = "LDAP_ATTR_DEPARTMENT"
+	// StaffnrAttributeEnv is the attribute containing the staff number of a teacher
+	StaffnrAttributeEnv = "LDAP_ATTR_STAFFNR"
+	// MailAttributeEnv is the attribute containing the mail address of a teacher
+	MailAttributeEnv = "LDAP_ATTR_MAIL"
+	// GroupsAttributeEnv is the attribute containing the groups a teacher is a member of
+	GroupsAttributeEnv = "LDAP_ATTR_GROUPS"
+)
+
+// attributes are the names of the directory attributes mapped onto a teacher
+type attributes struct {
+	username   string
+	givenName  string
+	surname    string
+	title      string
+	department string
+	staffnr    string
+	mail       string
+	groups     string
+}
+
+// loadAttributes reads the names of the mapped attributes from the environment
+func loadAttributes() attributes {
+	return attributes{
+		username:   envOr(UsernameAttributeEnv, "mailNickname"),
+		givenName:  envOr(GivenNameAttributeEnv, "givenName"),
+		surname:    envOr(SurnameAttributeEnv, "sn"),
+		title:      envOr(TitleAttributeEnv, "title"),
+		department: envOr(DepartmentAttributeEnv, "department"),
+		staffnr:    envOr(StaffnrAttributeEnv, "employeeID"),
+		mail:       envOr(MailAttributeEnv, "mail"),
+		groups:     envOr(GroupsAttributeEnv, "memberOf"),
+	}
+}
+
+// list returns the names of all mapped attributes
+func (a attributes) list() []string {
+	return []string{a.username, a.givenName, a.surname, a.title, a.department, a.staffnr, a.mail, a.groups}
+}
+
+// Entry is the directory entry of a teacher
+type Entry struct {
+	// DN is the distinguished name of the entry
+	DN string
+	// Username is the username (short name) of the teacher
+	Username string
+	// GivenName is the first name of the teacher
+	GivenName string
+	// Surname is the last name of the teacher
+	Surname string
+	// Title of the teacher
+	Title string
+	// Departments the teacher belongs to
+	Departments []string
+	// Staffnr is the staff number of the teacher, it is 0 if the directory doesn't contain a valid one
+	Staffnr int
+	// Mail address of the teacher
+	Mail string
+	// Groups are the distinguished names of the groups the teacher is a member of
+	Groups []string
+}
+
+// LongName returns the full name (first name + last name) of the teacher
+// if the directory doesn't contain both names, the value of the first RDN of the DN is used
+func (e Entry) LongName() string {
+	if e.GivenName != "" && e.Surname != "" {
+		return e.GivenName + " " + e.Surname
+	}
+	dn, err := ldap.ParseDN(e.DN)
+	if err != nil || len(dn.RDNs) == 0 || len(dn.RDNs[0].Attributes) == 0 {
+		return ""
+	}
+	return dn.RDNs[0].Attributes[0].Value
+}
+
+// Apply copies the attributes of the entry onto the teacher, attributes missing in the directory keep their stored value
+// the directory roles of the teacher are derived from its groups using mappings, they are kept if mappings is nil
+// returns true if the teacher was changed
+func (e Entry) Apply(teacher *db.Teacher, mappings []RoleMapping) bool {
+	before := fmt.Sprint(*teacher)
+	if name := e.LongName(); name != "" {
+		teacher.Longname = name
+	}
+	if e.Title != "" {
+		teacher.Title = e.Title
+	}
+	if len(e.Departments) > 0 {
+		teacher.Departments = e.Departments
+	}
+	if e.Staffnr != 0 {
+		teacher.Staffnr = e.Staffnr
+	}
+	if e.Mail != "" {
+		teacher.Mail = e.Mail
+	}
+	if e.Groups != nil {
+		teacher.Groups = e.Groups
+		if mappings != nil {
+			teacher.DirectoryRoles = rolesOf(e.Groups, mappings)
+		}
+	}
+	teacher.UpdatePermissions()
+	teacher.MissingSince = time.Time{}
+	return fmt.Sprint(*teacher) != before
+}
+
+// SyncReport is the result of a full synchronization of the teachers with the directory
+type SyncReport struct {
+	// Started is the time the synchronization started at
+	Started time.Time `json:"started"`
+	// Finished is the time the synchronization finished at
+	Finished time.Time `json:"finished"`
+	// Checked is the amount of teachers compared with the directory
+	Checked int `json:"checked" example:"120"`
+	// Updated are the short names of the teachers whose data changed
+	Updated []string `json:"updated" example:"szakall"`
+	// Missing are the short names of the local teachers which don't exist in the directory anymore
+	Missing []string `json:"missing" example:"mborko"`
+	// Failed are the short names of the teachers which couldn't be synchronized
+	Failed []string `json:"failed" example:"ehuber"`
+}
+
+// startSync ensures the scheduled synchronization is only started once
+var startSync sync.Once
+
+// StartScheduledSync starts synchronizing all teachers with the directory every LDAP_SYNC_INTERVAL in the background
+// nothing is scheduled if the interval is 0 or no ldap service account is configured
+func StartScheduledSync() {
+	startSync.Do(func() {
+		if envOr(SyncIntervalEnv, "") == "0" {
+			return
+		}
+		interval, err := durationEnv(SyncIntervalEnv, DefaultSyncInterval)
+		if err != nil {
+			log.Println(err)
+			interval = DefaultSyncInterval
+		}
+		go func() {
+			for {
+				time.Sleep(interval)
+				report, err := SyncTeachers(context.Background())
+				if errors.Is(err, ErrNoServiceAccount) {
+					return
+				}
+				if err != nil {
+					log.Println("Couldn't synchronize teachers with the directory: ", err)
+				}
+				logReport(report)
+			}
+		}()
+	})
+}
+
+// SyncTeachers compares all stored teachers with the directory using the service account
+// teachers found in the directory are updated, teachers missing there are reported and marked with MissingSince
+// the directory roles of the teachers are derived from their groups using LDAP_GROUP_ROLES, local accounts are left out
+// the given context bounds all database operations
+func SyncTeachers(ctx context.Context) (SyncReport, error) {
+	report := SyncReport{Started: time.Now(), Updated: make([]string, 0), Missing: make([]string, 0), Failed: make([]string, 0)}
+	l, c, err := serviceConnection()
+	if err != nil {
+		return report, err
+	}
+	defer l.Close()
+	mongo := db.MongoDatabaseConnector{}
+	if err := mongo.Connect(ctx); err != nil {
+		return report, err
+	}
+	defer mongo.Close()
+	teachers, err := mongo.GetAllTeachers()
+	if err != nil {
+		return report, err
+	}
+	mappings, err := loadRoleMappings()
+	if err != nil {
+		return report, err
+	}
+	attrs := loadAttributes()
+	for _, teacher := range teachers {
+		if teacher.Provider == db.LocalProvider || teacher.Provider == db.ServiceProvider {
+			continue
+		}
+		report.Checked++
+		entry, found, err := searchEntry(l, c.baseDN, attrs, teacher.Short)
+		if err != nil {
+			report.Failed = append(report.Failed, teacher.Short)
+			continue
+		}
+		changed := false
+		if !found {
+			report.Missing = append(report.Missing, teacher.Short)
+			if teacher.MissingSince.IsZero() {
+				teacher.MissingSince = time.Now()
+				changed = true
+			}
+		} else if entry.Apply(&teacher, mappings) {
+			report.Updated = append(report.Updated, teacher.Short)
+			changed = true
+		}
+		if found {
+			teacher.LastSynced = time.Now()
+			changed = true
+		}
+		if changed {
+			if err := mongo.UpdateTeacher(teacher.UUID, teacher); err != nil {
+				report.Failed = append(report.Failed, teacher.Short)
+			}
+		}
+	}
+	report.Finished = time.Now()
+	return report, nil
+}
+
+// logReport logs the result of a synchronization
+func logReport(report SyncReport) {
+	log.Println("Synchronized ", report.Checked, " teachers with the directory, ", len(report.Updated), " updated")
+	if len(report.Missing) > 0 {
+		log.Println("Teachers missing in the directory: ", strings.Join(report.Missing, ", "))
+	}
+	if len(report.Failed) > 0 {
+		log.Println("Teachers which couldn't be synchronized: ", strings.Join(report.Failed, ", "))
+	}
+}
+
+// searchEntry searches the directory entry of the teacher with the given username below baseDN using the given bound connection
+// returns false if the directory doesn't contain the teacher
+func searchEntry(l *ldap.Conn, baseDN string, attrs attributes, username string) (Entry, bool, error) {
+	search := ldap.NewSearchRequest(baseDN,
+		ldap.ScopeWholeSubtree,
+		ldap.NeverDerefAliases,
+		0,
+		0,
+		false,
+		fmt.Sprintf("(&(%s=%s))", ldap.EscapeFilter(attrs.username), ldap.EscapeFilter(username)),
+		attrs.list(),
+		nil,
+	)
+	res, err := l.Search(search)
+	if err != nil {
+		return Entry{}, false, err
+	}
+	if len(res.Entries) == 0 {
+		return Entry{}, false, nil
+	}
+	if len(res.Entries) > 1 {
+		return Entry{}, false, fmt.Errorf("too many entries returned for %v", username)
+	}
+	e := res.Entries[0]
+	entry := Entry{
+		DN:        e.DN,
+		Username:  e.GetAttributeValue(attrs.username),
+		GivenName: e.GetAttributeValue(attrs.givenName),
+		Surname:   e.GetAttributeValue(attrs.surname),
+		Title:     e.GetAttributeValue(attrs.title),
+		Mail:      e.GetAttributeValue(attrs.mail),
+		Groups:    e.GetAttributeValues(attrs.groups),
+	}
+	for _, department := range e.GetAttributeValues(attrs.department) {
+		for _, d := range strings.Split(department, ",") {
+			if d = strings.TrimSpace(d); d != "" {
+				entry.Departments = append(entry.Departments, d)
+			}
+		}
+	}
+	if staffnr, err := strconv.Atoi(strings.TrimSpace(e.GetAttributeValue(attrs.staffnr))); err == nil {
+		entry.Staffnr = staffnr
+	}
+	return entry, true, nil
+}
@@ -0,0 +1,165 @@
+package rest
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	mongo "github.com/refundable-tgm/huginn/db"
+	"github.com/refundable-tgm/huginn/ldap"
+	"log"
+	"os"
+	"strings"
+	"time"
+)
+
+// ApprovalChainEnv is the environment variable listing the roles (comma separated) applications in process are escalated through
+// the first role reviews them at once, every following one after another ApprovalTimeoutEnv passed without a decision
+// escalation is opt-in, without a chain av and administration review applications in process at once
+const ApprovalChainEnv = "APPROVAL_CHAIN"
+
+// ApprovalTimeoutEnv is the environment variable containing the time after which an application in process is escalated to the next role
+const ApprovalTimeoutEnv = "APPROVAL_ESCALATION_TIMEOUT"
+
+// DefaultApprovalTimeout is the time after which an application in process is escalated if APPROVAL_ESCALATION_TIMEOUT isn't set
+const DefaultApprovalTimeout = 72 * time.Hour
+
+// approvalChain returns the roles configured by APPROVAL_CHAIN, unknown roles are logged and skipped
+// the chain is empty if APPROVAL_CHAIN isn't set
+func approvalChain() []string {
+	chain := make([]string, 0)
+	value := os.Getenv(ApprovalChainEnv)
+	if value == "" {
+		return chain
+	}
+	for _, role := range splitList([]string{value}) {
+		switch role = strings.ToLower(role); role {
+		case ldap.AVRole, ldap.AdministrationRole, ldap.PEKRole, ldap.SuperUserRole:
+			chain = append(chain, role)
+		default:
+			log.Println(ApprovalChainEnv, " contains the unknown role ", role, ", skipping it")
+		}
+	}
+	return chain
+}
+
+// approvalTimeout returns the time configured by APPROVAL_ESCALATION_TIMEOUT
+func approvalTimeout() time.Duration {
+	value := os.Getenv(ApprovalTimeoutEnv)
+	if value == "" {
+		return DefaultApprovalTimeout
+	}
+	timeout, err := time.ParseDuration(value)
+	if err != nil || timeout <= 0 {
+		log.Println(ApprovalTimeoutEnv, " has to be a positive duration like 72h, using ", DefaultApprovalTimeout)
+		return DefaultApprovalTimeout
+	}
+	return timeout
+}
+
+// hasRole checks whether the roles contain the role with the given name
+func hasRole(roles mongo.Roles, role string) bool {
+	switch role {
+	case ldap.AVRole:
+		return roles.AV
+	case ldap.AdministrationRole:
+		return roles.Administration
+	case ldap.PEKRole:
+		return roles.PEK
+	case ldap.SuperUserRole:
+		return roles.SuperUser
+	}
+	return false
+}
+
+// escalatedBefore returns the time applications have to be in process since to be reviewed with the given roles
+// super users review every application, the other roles once the application was escalated to their position in APPROVAL_CHAIN
+// without a chain av and administration review every application in process
+// a zero time means every application in process is reviewed, false means none of the roles reviews applications in process
+func escalatedBefore(roles mongo.Roles) (time.Time, bool) {
+	if roles.SuperUser {
+		return time.Time{}, true
+	}
+	chain := approvalChain()
+	if len(chain) == 0 {
+		return time.Time{}, roles.AV || roles.Administration
+	}
+	for position, role := range chain {
+		if !hasRole(roles, role) {
+			continue
+		}
+		if position == 0 {
+			return time.Time{}, true
+		}
+		return time.Now().Add(-time.Duration(position) * approvalTimeout()), true
+	}
+	return time.Time{}, false
+}
+
+// deputizedFor returns the active approvers the requester currently approves in place of
+func deputizedFor(db mongo.MongoDatabaseConnector, requester mongo.Teacher) ([]mongo.Teacher, error) {
+	approvers := make([]mongo.Teacher, 0)
+	assignments, err := db.GetActiveDeputyAssignments(requester.Short, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	for _, assignment := range assignments {
+		approver, err := db.GetTeacherByShort(assignment.Approver)
+		if errors.Is(err, mongo.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if approver.DeactivatedAt.IsZero() {
+			approvers = append(approvers, approver)
+		}
+	}
+	return approvers, nil
+}
+
+// approvalRoles returns the roles the requester reviews applications with
+// these are their own roles and the approving roles of the approvers they are deputy of, super user rights aren't passed on
+func approvalRoles(requester mongo.Teacher, approvers []mongo.Teacher) mongo.Roles {
+	roles := mongo.Roles{
+		SuperUser:      requester.SuperUser,
+		AV:             requester.AV,
+		Administration: requester.Administration,
+		PEK:            requester.PEK,
+	}
+	for _, approver := range approvers {
+		roles.AV = roles.AV || approver.AV
+		roles.Administration = roles.Administration || approver.Administration
+		roles.PEK = roles.PEK || approver.PEK
+	}
+	return roles
+}
+
+// isApprover checks whether the roles allow reviewing applications
+func isApprover(roles mongo.Roles) bool {
+	return roles.AV || roles.Administration || roles.PEK || roles.SuperUser
+}
+
+// stampInProcess sets the time the application entered InProcess
+// the time of the stored version is kept while the application stays in process, it is cleared in every other progress state
+func stampInProcess(application *mongo.Application, stored mongo.Application) {
+	switch {
+	case application.Progress != mongo.InProcess:
+		application.InProcessSince = time.Time{}
+	case stored.Progress == mongo.InProcess && !stored.InProcessSince.IsZero():
+		application.InProcessSince = stored.InProcessSince
+	default:
+		application.InProcessSince = time.Now()
+	}
+}
+
+// auditAsDeputy records that the requester accessed an application only as deputy of the approvers
+// subject identifies what the requester acted on, e.g. the uuid of an application
+func auditAsDeputy(con *gin.Context, db mongo.MongoDatabaseConnector, requester mongo.Teacher, approvers []mongo.Teacher, subject string) {
+	for _, approver := range approvers {
+		auditActingFor(con, db, mongo.AuditAsDeputy, "as deputy of", requester, approver, subject)
+	}
+}
+
+// auditDeputyAssignment records that the requester created or revoked the deputy assignment
+func auditDeputyAssignment(con *gin.Context, db mongo.MongoDatabaseConnector, kind string, requester mongo.Teacher, assignment mongo.DeputyAssignment) {
+	auditAssignment(con, db, kind, "may approve in place of", requester, assignment.Approver, assignment.Deputy, assignment.From, assignment.Till)
+}
@@ -0,0 +1,75 @@
+package rest
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	mongo "github.com/refundable-tgm/huginn/db"
+	"log"
+	"strings"
+	"time"
+)
+
+// storeAuditEntry stores the entry stamped with the current time and the ip of the request, failures are only logged
+func storeAuditEntry(con *gin.Context, db mongo.MongoDatabaseConnector, entry mongo.AuditEntry) {
+	entry.Time = time.Now()
+	entry.IP = clientIP(con)
+	if err := db.CreateAuditEntry(entry); err != nil {
+		log.Println("Couldn't store audit entry: ", err)
+	}
+}
+
+// auditActingFor records that the requester used the rights of teacher for the request
+// relation describes how the requester acted for the teacher, e.g. "on behalf of"
+// subject identifies what the requester acted on, e.g. the uuid of an application
+func auditActingFor(con *gin.Context, db mongo.MongoDatabaseConnector, kind, relation string, requester, teacher mongo.Teacher, subject string) {
+	storeAuditEntry(con, db, mongo.AuditEntry{
+		Kind:     kind,
+		Username: teacher.Short,
+		Actor:    requester.Short,
+		Message:  fmt.Sprintf("%v %v %v: %v %v %v", requester.Short, relation, teacher.Short, con.Request.Method, con.FullPath(), subject),
+	})
+}
+
+// auditAssignment records that the requester created or revoked an assignment passing rights of granter to receiver between from and till
+// relation describes the rights, e.g. "may act on behalf of", the requester is only stored as actor if they aren't the granter
+func auditAssignment(con *gin.Context, db mongo.MongoDatabaseConnector, kind, relation string, requester mongo.Teacher, granter, receiver string, from, till time.Time) {
+	entry := mongo.AuditEntry{
+		Kind:     kind,
+		Username: granter,
+		Message:  fmt.Sprintf("%v %v %v from %v till %v", receiver, relation, granter, from.Format(time.RFC3339), till.Format(time.RFC3339)),
+	}
+	if requester.Short != granter {
+		entry.Actor = requester.Short
+	}
+	storeAuditEntry(con, db, entry)
+}
+
+// auditAdministration records that the requester changed the account of the teacher as administrator
+func auditAdministration(con *gin.Context, db mongo.MongoDatabaseConnector, kind string, requester, teacher mongo.Teacher, message string) {
+	storeAuditEntry(con, db, mongo.AuditEntry{
+		Kind:     kind,
+		Username: teacher.Short,
+		Actor:    requester.Short,
+		Message:  fmt.Sprintf("%v: %v", requester.Short, message),
+	})
+}
+
+// describeRoles lists the roles for audit messages, e.g. "av, department_head:HIT"
+func describeRoles(roles mongo.Roles) string {
+	names := make([]string, 0)
+	for _, role := range []struct {
+		held bool
+		name string
+	}{{roles.SuperUser, "superuser"}, {roles.AV, "av"}, {roles.Administration, "administration"}, {roles.PEK, "pek"}} {
+		if role.held {
+			names = append(names, role.name)
+		}
+	}
+	for _, department := range roles.DepartmentHead {
+		names = append(names, "department_head:"+department)
+	}
+	if len(names) == 0 {
+		return "none"
+	}
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,86 @@
+package rest
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// TrustedProxiesEnv is the environment variable listing the reverse proxies (comma separated addresses or CIDRs) whose X-Forwarded-For header is trusted
+// if it isn't set no proxy is trusted and the address of the connection is used as client address
+const TrustedProxiesEnv = "TRUSTED_PROXIES"
+
+// trustedProxies are the networks of the reverse proxies configured by TRUSTED_PROXIES
+var trustedProxies []*net.IPNet
+
+// loadTrustedProxies parses the addresses and networks configured by TRUSTED_PROXIES
+func loadTrustedProxies() ([]*net.IPNet, error) {
+	proxies := make([]*net.IPNet, 0)
+	for _, proxy := range splitList([]string{os.Getenv(TrustedProxiesEnv)}) {
+		if !strings.Contains(proxy, "/") {
+			ip := net.ParseIP(proxy)
+			if ip == nil {
+				return nil, fmt.Errorf("%v contains the invalid address %v", TrustedProxiesEnv, proxy)
+			}
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			proxies = append(proxies, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, network, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("%v contains the invalid network %v", TrustedProxiesEnv, proxy)
+		}
+		proxies = append(proxies, network)
+	}
+	return proxies, nil
+}
+
+// clientIP returns the address of the client of the request, see clientIPOf
+func clientIP(con *gin.Context) string {
+	return clientIPOf(con.Request, trustedProxies)
+}
+
+// clientIPOf returns the address of the client of the request
+// X-Forwarded-For is only read if the connection comes from a trusted proxy, then the last address added by an untrusted hop is the client
+// as everything in front of it can be set by the client itself
+func clientIPOf(r *http.Request, proxies []*net.IPNet) string {
+	remote, _, err := net.SplitHostPort(strings.TrimSpace(r.RemoteAddr))
+	if err != nil {
+		remote = strings.TrimSpace(r.RemoteAddr)
+	}
+	if !trusted(net.ParseIP(remote), proxies) {
+		return remote
+	}
+	hops := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		ip := net.ParseIP(strings.TrimSpace(hops[i]))
+		if ip == nil {
+			// a malformed hop can't be attributed to anyone, so the last trusted address is used
+			return remote
+		}
+		if !trusted(ip, proxies) {
+			return ip.String()
+		}
+		remote = ip.String()
+	}
+	return remote
+}
+
+// trusted checks whether the address belongs to one of the trusted proxies
+func trusted(ip net.IP, proxies []*net.IPNet) bool {
+	if ip == nil {
+		return false
+	}
+	for _, proxy := range proxies {
+		if proxy.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,82 @@
+package rest
+
+import (
+	"net/http"
+	"os"
+	"testing"
+)
+
+func TestLoadTrustedProxies(t *testing.T) {
+	tests := []struct {
+		value   string
+		count   int
+		invalid bool
+	}{
+		{value: "", count: 0},
+		{value: "10.0.0.1", count: 1},
+		{value: "10.0.0.1, 192.168.0.0/16,::1", count: 3},
+		{value: "proxy.tgm.ac.at", invalid: true},
+		{value: "10.0.0.0/33", invalid: true},
+	}
+	previous, ok := os.LookupEnv(TrustedProxiesEnv)
+	defer func() {
+		if ok {
+			_ = os.Setenv(TrustedProxiesEnv, previous)
+		} else {
+			_ = os.Unsetenv(TrustedProxiesEnv)
+		}
+	}()
+	for _, test := range tests {
+		_ = os.Setenv(TrustedProxiesEnv, test.value)
+		proxies, err := loadTrustedProxies()
+		if test.invalid {
+			if err == nil {
+				t.Errorf("%q was accepted", test.value)
+			}
+			continue
+		}
+		if err != nil || len(proxies) != test.count {
+			t.Errorf("loadTrustedProxies() with %q = %v, %v, want %v proxies", test.value, proxies, err, test.count)
+		}
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	_ = os.Setenv(TrustedProxiesEnv, "10.0.0.1,172.16.0.0/12")
+	proxies, err := loadTrustedProxies()
+	_ = os.Unsetenv(TrustedProxiesEnv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name      string
+		remote    string
+		forwarded []string
+		proxies   bool
+		want      string
+	}{
+		{name: "no proxies trusted", remote: "10.0.0.1:4242", forwarded: []string{"1.2.3.4"}, want: "10.0.0.1"},
+		{name: "untrusted connection", remote: "8.8.8.8:4242", forwarded: []string{"1.2.3.4"}, proxies: true, want: "8.8.8.8"},
+		{name: "trusted proxy", remote: "10.0.0.1:4242", forwarded: []string{"1.2.3.4"}, proxies: true, want: "1.2.3.4"},
+		{name: "header forged by the client", remote: "10.0.0.1:4242", forwarded: []string{"6.6.6.6, 1.2.3.4"}, proxies: true, want: "1.2.3.4"},
+		{name: "chain of trusted proxies", remote: "10.0.0.1:4242", forwarded: []string{"6.6.6.6", "1.2.3.4, 172.16.5.5"}, proxies: true, want: "1.2.3.4"},
+		{name: "malformed hop", remote: "10.0.0.1:4242", forwarded: []string{"1.2.3.4, garbage"}, proxies: true, want: "10.0.0.1"},
+		{name: "without header", remote: "10.0.0.1:4242", proxies: true, want: "10.0.0.1"},
+		{name: "ipv6", remote: "[2001:db8::1]:4242", forwarded: []string{"1.2.3.4"}, proxies: true, want: "2001:db8::1"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := &http.Request{RemoteAddr: test.remote, Header: http.Header{}}
+			for _, value := range test.forwarded {
+				r.Header.Add("X-Forwarded-For", value)
+			}
+			trusted := proxies
+			if !test.proxies {
+				trusted = nil
+			}
+			if got := clientIPOf(r, trusted); got != test.want {
+				t.Errorf("clientIPOf() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
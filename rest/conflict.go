@@ -0,0 +1,263 @@
+package rest
+
+import (
+	"errors"
+	"fmt"
+	mongo "github.com/refundable-tgm/huginn/db"
+	"github.com/refundable-tgm/huginn/untis"
+	"os"
+	"strings"
+	"time"
+)
+
+// ConflictBlockingEnv is the environment variable listing the kinds of conflicts (comma separated) which prevent saving an application
+// conflicts of all other kinds are returned as warnings
+const ConflictBlockingEnv = "CONFLICT_BLOCKING"
+
+// Kinds of conflicts of an application
+const (
+	// TeacherConflict a teacher takes part in another application at the same time
+	TeacherConflict = "teacher"
+	// ClassConflict a class takes part in another school event at the same time
+	ClassConflict = "class"
+	// ExamConflict a class or teacher has an exam in untis during the application
+	ExamConflict = "exam"
+	// UncheckedConflict the exams couldn't be checked, because untis wasn't reachable
+	UncheckedConflict = "unchecked"
+)
+
+// conflictingProgress lists the progress states of applications other applications can conflict with
+var conflictingProgress = []int{
+	mongo.InSubmission,
+	mongo.InProcess,
+	mongo.Confirmed,
+	mongo.Running,
+	mongo.CostsPending,
+	mongo.CostsInProcess,
+	mongo.Done,
+}
+
+// blockingConflicts returns the kinds of conflicts configured as blocking by CONFLICT_BLOCKING
+func blockingConflicts() map[string]bool {
+	blocking := make(map[string]bool)
+	for _, kind := range splitList([]string{os.Getenv(ConflictBlockingEnv)}) {
+		blocking[strings.ToLower(kind)] = true
+	}
+	return blocking
+}
+
+// isBlocked checks whether one of the conflicts prevents saving the application
+func isBlocked(conflicts []Conflict) bool {
+	for _, conflict := range conflicts {
+		if conflict.Blocking {
+			return true
+		}
+	}
+	return false
+}
+
+// findConflicts checks the application for conflicts with other applications and with exams in untis
+// teachers only conflict if the intervals they attend both applications in overlap
+// uuid identifies the application itself, so it doesn't conflict with its stored version
+// untis is accessed on behalf of username, if it can't be reached an UncheckedConflict is returned instead of the exams
+func findConflicts(db mongo.MongoDatabaseConnector, username, uuid string, application mongo.Application) ([]Conflict, error) {
+	conflicts := make([]Conflict, 0)
+	if application.StartTime.IsZero() || !application.EndTime.After(application.StartTime) {
+		return conflicts, nil
+	}
+	involved := involvement{db: db, shorts: make(map[string]string)}
+	teachers, classes, err := involved.of(application)
+	if err != nil {
+		return nil, err
+	}
+	if len(teachers) == 0 && len(classes) == 0 {
+		return conflicts, nil
+	}
+	blocking := blockingConflicts()
+	others, err := db.FindApplications(mongo.ApplicationFilter{
+		Progress: conflictingProgress,
+		From:     application.StartTime,
+		Till:     application.EndTime,
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, other := range others {
+		if other.UUID == uuid || !other.EndTime.After(application.StartTime) || !other.StartTime.Before(application.EndTime) {
+			continue
+		}
+		otherTeachers, otherClasses, err := involved.of(other)
+		if err != nil {
+			return nil, err
+		}
+		common := make([]string, 0)
+		for _, teacher := range intersect(teachers, otherTeachers) {
+			if mongo.AttendancesOverlap(application.AttendanceOf(teacher), other.AttendanceOf(teacher)) {
+				common = append(common, teacher)
+			}
+		}
+		if len(common) > 0 {
+			conflicts = append(conflicts, Conflict{
+				Kind:        TeacherConflict,
+				Blocking:    blocking[TeacherConflict],
+				Message:     fmt.Sprintf("%v also take part in %v", strings.Join(common, ", "), other.Name),
+				Application: other.UUID,
+				Name:        other.Name,
+				Teachers:    common,
+				Classes:     make([]string, 0),
+				Subjects:    make([]string, 0),
+				Start:       other.StartTime,
+				End:         other.EndTime,
+			})
+		}
+		if common := intersect(classes, otherClasses); len(common) > 0 {
+			conflicts = append(conflicts, Conflict{
+				Kind:        ClassConflict,
+				Blocking:    blocking[ClassConflict],
+				Message:     fmt.Sprintf("%v also take part in %v", strings.Join(common, ", "), other.Name),
+				Application: other.UUID,
+				Name:        other.Name,
+				Teachers:    make([]string, 0),
+				Classes:     common,
+				Subjects:    make([]string, 0),
+				Start:       other.StartTime,
+				End:         other.EndTime,
+			})
+		}
+	}
+	exams, err := findExams(db, username, application, teachers, classes, blocking)
+	if err != nil {
+		return nil, err
+	}
+	return append(conflicts, exams...), nil
+}
+
+// findExams returns a conflict for every exam of the given teachers (short names) and classes in untis during the application
+// exams of teachers are only considered during the intervals the teachers attend the application in
+func findExams(db mongo.MongoDatabaseConnector, username string, application mongo.Application, teachers, classes []string, blocking map[string]bool) ([]Conflict, error) {
+	attendances := make(map[string][]mongo.Attendance, len(teachers))
+	for _, short := range teachers {
+		teacher, err := db.GetTeacherByShort(short)
+		if errors.Is(err, mongo.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if teacher.Untis == "" {
+			continue
+		}
+		attendances[teacher.Untis] = application.AttendanceOf(short)
+	}
+	conflicts := make([]Conflict, 0)
+	if len(attendances) == 0 && len(classes) == 0 {
+		return conflicts, nil
+	}
+	absence, err := absenceOf(application, attendances, classes)
+	if err != nil {
+		return nil, err
+	}
+	unchecked := func(err error) []Conflict {
+		return append(conflicts, Conflict{
+			Kind:     UncheckedConflict,
+			Blocking: blocking[UncheckedConflict],
+			Message:  "exams couldn't be checked: " + err.Error(),
+			Teachers: make([]string, 0),
+			Classes:  make([]string, 0),
+			Subjects: make([]string, 0),
+			Start:    application.StartTime,
+			End:      application.EndTime,
+		})
+	}
+	client, err := untis.ClientFor(username)
+	if err != nil {
+		return unchecked(err), nil
+	}
+	exams, err := client.Exams(absence)
+	if err != nil {
+		return unchecked(err), nil
+	}
+	loc := absence.Start.Location()
+	for _, exam := range exams {
+		conflicts = append(conflicts, Conflict{
+			Kind:     ExamConflict,
+			Blocking: blocking[ExamConflict],
+			Message:  fmt.Sprintf("exam in %v of %v on %v", strings.Join(exam.Subjects, ", "), strings.Join(exam.Classes, ", "), exam.Start.Format("02.01.2006 15:04")),
+			Teachers: nonNil(exam.Teachers),
+			Classes:  nonNil(exam.Classes),
+			Subjects: nonNil(exam.Subjects),
+			Start:    schoolTime(exam.Start, loc),
+			End:      schoolTime(exam.End, loc),
+		})
+	}
+	return conflicts, nil
+}
+
+// involvement resolves the teachers and classes taking part in applications
+type involvement struct {
+	// db is the connection the filers are looked up with
+	db mongo.MongoDatabaseConnector
+	// shorts caches the short names of filers by their long names
+	shorts map[string]string
+}
+
+// of returns the short names of the teachers and the classes taking part in the application
+// filers which aren't stored as teachers are left out
+func (i involvement) of(application mongo.Application) ([]string, []string, error) {
+	teachers := make([]string, 0)
+	classes := make([]string, 0)
+	switch application.Kind {
+	case mongo.SchoolEvent:
+		for _, teacher := range application.SchoolEventDetails.Teachers {
+			teachers = append(teachers, teacher.Shortname)
+		}
+		classes = append(classes, application.SchoolEventDetails.Classes...)
+		return teachers, classes, nil
+	case mongo.Training:
+		return i.filer(application.TrainingDetails.Filer, teachers, classes)
+	case mongo.OtherReason:
+		return i.filer(application.OtherReasonDetails.Filer, teachers, classes)
+	}
+	return teachers, classes, nil
+}
+
+// filer appends the short name of the filer with the given long name to the teachers
+func (i involvement) filer(longname string, teachers, classes []string) ([]string, []string, error) {
+	if longname == "" {
+		return teachers, classes, nil
+	}
+	short, ok := i.shorts[longname]
+	if !ok {
+		teacher, err := i.db.GetTeacherByLongname(longname)
+		if err != nil && !errors.Is(err, mongo.ErrNotFound) {
+			return nil, nil, err
+		}
+		short = teacher.Short
+		i.shorts[longname] = short
+	}
+	if short != "" {
+		teachers = append(teachers, short)
+	}
+	return teachers, classes, nil
+}
+
+// intersect returns the values contained in both lists without duplicates
+func intersect(a, b []string) []string {
+	contained := make(map[string]bool, len(b))
+	for _, value := range b {
+		contained[value] = true
+	}
+	common := make([]string, 0)
+	for _, value := range a {
+		if contained[value] {
+			common = append(common, value)
+			delete(contained, value)
+		}
+	}
+	return common
+}
+
+// schoolTime converts a wall clock time of untis stored as UTC into the given time zone of the school
+func schoolTime(t time.Time, loc *time.Location) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), 0, loc)
+}
@@ -0,0 +1,79 @@
+package rest
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	mongo "github.com/refundable-tgm/huginn/db"
+	"time"
+)
+
+// MaxDelegationDuration is the longest time a delegation can last
+const MaxDelegationDuration = 365 * 24 * time.Hour
+
+// actingFor returns the teacher the requester acts for in the application
+// this is the requester if they take part in it, otherwise a teacher taking part in it who delegated to the requester
+// returns false if the requester neither takes part in the application nor acts for someone who does
+func actingFor(db mongo.MongoDatabaseConnector, application mongo.Application, requester mongo.Teacher) (mongo.Teacher, bool, error) {
+	if participates(application, requester) {
+		return requester, true, nil
+	}
+	delegators, err := delegatorsOf(db, requester)
+	if err != nil {
+		return mongo.Teacher{}, false, err
+	}
+	for _, delegator := range delegators {
+		if participates(application, delegator) {
+			return delegator, true, nil
+		}
+	}
+	return requester, false, nil
+}
+
+// delegatorsOf returns the active teachers who currently let the requester act on their behalf
+func delegatorsOf(db mongo.MongoDatabaseConnector, requester mongo.Teacher) ([]mongo.Teacher, error) {
+	delegators := make([]mongo.Teacher, 0)
+	delegations, err := db.GetActiveDelegations(requester.Short, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	for _, delegation := range delegations {
+		delegator, err := db.GetTeacherByShort(delegation.Delegator)
+		if errors.Is(err, mongo.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if delegator.DeactivatedAt.IsZero() {
+			delegators = append(delegators, delegator)
+		}
+	}
+	return delegators, nil
+}
+
+// delegatedBy checks whether the teacher with the short name delegator currently lets the requester act on their behalf
+func delegatedBy(db mongo.MongoDatabaseConnector, requester mongo.Teacher, delegator string) (bool, error) {
+	delegations, err := db.GetActiveDelegations(requester.Short, time.Now())
+	if err != nil {
+		return false, err
+	}
+	for _, delegation := range delegations {
+		if delegation.Delegator == delegator {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// auditOnBehalf records that the requester acted on behalf of another teacher, nothing is recorded if they acted for themselves
+// subject identifies what the requester acted on, e.g. the uuid of an application
+func auditOnBehalf(con *gin.Context, db mongo.MongoDatabaseConnector, requester, onBehalfOf mongo.Teacher, subject string) {
+	if requester.Short != onBehalfOf.Short {
+		auditActingFor(con, db, mongo.AuditOnBehalf, "on behalf of", requester, onBehalfOf, subject)
+	}
+}
+
+// auditDelegation records that the requester created or revoked the delegation
+func auditDelegation(con *gin.Context, db mongo.MongoDatabaseConnector, kind string, requester mongo.Teacher, delegation mongo.Delegation) {
+	auditAssignment(con, db, kind, "may act on behalf of", requester, delegation.Delegator, delegation.Delegate, delegation.From, delegation.Till)
+}
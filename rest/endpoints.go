@@ -2,12 +2,14 @@ package rest
 
 import (
 	"encoding/base64"
+	"errors"
 	"fmt"
 	"github.com/dgrijalva/jwt-go"
 	"github.com/gin-gonic/gin"
 	uuidG "github.com/google/uuid"
 	"github.com/pdfcpu/pdfcpu/pkg/api"
 	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu"
+	authn "github.com/refundable-tgm/huginn/auth"
 	mongo "github.com/refundable-tgm/huginn/db"
 	"github.com/refundable-tgm/huginn/files"
 	"github.com/refundable-tgm/huginn/ldap"
@@ -16,14 +18,37 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
-	"sort"
 	"strconv"
 	"strings"
+	"time"
 )
 
+// NewsLimit is the maximum amount of applications returned as news
+const NewsLimit = 10
+
+// AuditLimit is the default amount of audit entries returned
+const AuditLimit = 100
+
 // AuthWall drops every token which doesnt provide a valid token
+// API keys are accepted instead of access tokens for the endpoints they permit
 func AuthWall() gin.HandlerFunc {
 	return func(con *gin.Context) {
+		if token := ExtractToken(con.Request); authn.IsAPIKey(token) {
+			key, err := authn.VerifyAPIKey(con.Request.Context(), token, clientIP(con))
+			if err != nil {
+				respondAuthError(con, err)
+				con.Abort()
+				return
+			}
+			if !authn.PermitsEndpoint(key, strings.TrimPrefix(con.FullPath(), "/api")) {
+				con.JSON(http.StatusUnauthorized, Error{"the API key doesn't permit this endpoint"})
+				con.Abort()
+				return
+			}
+			con.Request = withAPIKey(con.Request, key)
+			con.Next()
+			return
+		}
 		ok, err := TokenValid(con.Request)
 		if !ok && err != nil {
 			con.JSON(http.StatusUnauthorized, Error{"present a valid token"})
@@ -47,14 +72,19 @@ func AuthWall() gin.HandlerFunc {
 
 // Login represents the login endpoint
 // @Summary Login a user
-// @Description Login a user using username and password
+// @Description Login a user using username and password, which are verified by the password providers enabled in AUTH_PROVIDERS
+// @Description Usernames and addresses with too many failed logins have to wait or are locked out temporarily, they get 429 with a Retry-After header
+// @Description If the user has to use a second factor, a challenge is returned instead of the token pair, which has to be answered at /login/twoFactor
 // @ID login
 // @Accept json
 // @Produce json
 // @Param user body User true "Account Information"
 // @Success 200 {object} TokenPair
+// @Success 202 {object} LoginChallenge
 // @Failure 401 {object} Error
 // @Failure 422 {object} Error
+// @Failure 429 {object} Error
+// @Failure 503 {object} Error
 // @Router /login [post]
 func Login(con *gin.Context) {
 	u := User{}
@@ -62,26 +92,379 @@ func Login(con *gin.Context) {
 		con.JSON(http.StatusUnprocessableEntity, Error{"invalid request structure provided"})
 		return
 	}
-	if !ldap.AuthenticateUserCredentials(u.Username, u.Password) {
-		con.JSON(http.StatusUnauthorized, Error{"this credentials do not resolve into an authorized login"})
+	teacher, err := authn.Authenticate(con.Request.Context(), u.Username, u.Password, clientIP(con))
+	if err != nil {
+		respondAuthError(con, err)
+		return
+	}
+	completeLogin(con, teacher)
+}
+
+// GetLoginProviders represents the get login providers endpoint
+// @Summary Returns the enabled authentication providers
+// @Description Lists the providers enabled in AUTH_PROVIDERS, ldap and local use the login endpoint, oidc uses the redirect login
+// @ID get-login-providers
+// @Produce json
+// @Success 200 {array} string
+// @Failure 500 {object} Error
+// @Router /login/providers [get]
+func GetLoginProviders(con *gin.Context) {
+	names, err := authn.Names()
+	if err != nil {
+		respondAuthError(con, err)
+		return
+	}
+	con.JSON(http.StatusOK, names)
+}
+
+// BeginRedirectLogin represents the begin redirect login endpoint
+// @Summary Starts a login on the page of an external identity provider
+// @Description Returns the url of the login page of the provider the user has to be redirected to
+// @Description After the login the provider redirects to OIDC_REDIRECT_URL, which has to pass code and state to the callback endpoint
+// @ID begin-redirect-login
+// @Produce json
+// @Param provider query string false "Name of the redirect provider" default(oidc)
+// @Success 200 {object} RedirectURL
+// @Failure 404 {object} Error
+// @Failure 500 {object} Error
+// @Failure 503 {object} Error
+// @Router /login/redirect [get]
+func BeginRedirectLogin(con *gin.Context) {
+	provider := con.Request.URL.Query().Get("provider")
+	if provider == "" {
+		provider = mongo.OIDCProvider
+	}
+	u, err := authn.BeginLogin(provider)
+	if err != nil {
+		respondAuthError(con, err)
+		return
+	}
+	con.JSON(http.StatusOK, RedirectURL{u})
+}
+
+// FinishRedirectLogin represents the finish redirect login endpoint
+// @Summary Finishes a login on the page of an external identity provider
+// @Description Exchanges the code the provider returned after the login and creates a session of the teacher it belongs to
+// @ID finish-redirect-login
+// @Accept json
+// @Produce json
+// @Param callback body LoginCallback true "Code and state returned by the provider"
+// @Success 200 {object} TokenPair
+// @Success 202 {object} LoginChallenge
+// @Failure 401 {object} Error
+// @Failure 404 {object} Error
+// @Failure 422 {object} Error
+// @Failure 503 {object} Error
+// @Router /login/callback [post]
+func FinishRedirectLogin(con *gin.Context) {
+	callback := LoginCallback{}
+	if err := con.ShouldBindJSON(&callback); err != nil || callback.Code == "" || callback.State == "" {
+		con.JSON(http.StatusUnprocessableEntity, Error{"invalid request structure provided"})
+		return
+	}
+	teacher, err := authn.FinishLogin(con.Request.Context(), callback.State, callback.Code)
+	if err != nil {
+		respondAuthError(con, err)
+		return
+	}
+	completeLogin(con, teacher)
+}
+
+// AnswerLoginChallenge represents the answer login challenge endpoint
+// @Summary Answers the second step of a login
+// @Description Checks the one-time password or recovery code answering the challenge returned by the login and creates the session
+// @Description If the user enrolled a second factor during the login, the enrolment is confirmed and the recovery codes are returned once
+// @ID answer-login-challenge
+// @Accept json
+// @Produce json
+// @Param answer body ChallengeAnswer true "Challenge and one-time password or recovery code"
+// @Success 200 {object} TwoFactorLogin
+// @Failure 401 {object} Error
+// @Failure 422 {object} Error
+// @Failure 429 {object} Error
+// @Failure 500 {object} Error
+// @Failure 503 {object} Error
+// @Router /login/twoFactor [post]
+func AnswerLoginChallenge(con *gin.Context) {
+	answer := ChallengeAnswer{}
+	if err := con.ShouldBindJSON(&answer); err != nil || answer.Challenge == "" {
+		con.JSON(http.StatusUnprocessableEntity, Error{"invalid request structure provided"})
+		return
+	}
+	teacher, codes, err := authn.AnswerChallenge(con.Request.Context(), answer.Challenge, answer.Code, clientIP(con))
+	if err != nil {
+		respondAuthError(con, err)
+		return
+	}
+	pair, err := createSession(teacher.Short)
+	if err != nil {
+		con.JSON(http.StatusInternalServerError, Error{"couldn't sign token"})
+		return
+	}
+	con.JSON(http.StatusOK, TwoFactorLogin{
+		AccessToken:   pair.AccessToken,
+		RefreshToken:  pair.RefreshToken,
+		RecoveryCodes: codes,
+	})
+}
+
+// EnrollLoginChallenge represents the enroll login challenge endpoint
+// @Summary Enrols a second factor during a login
+// @Description Users whose roles require a second factor but who haven't enrolled one yet get a challenge which isn't enrolled
+// @Description This returns the secret to add to an authenticator app, the challenge is then answered with a one-time password of it
+// @ID enroll-login-challenge
+// @Accept json
+// @Produce json
+// @Param challenge body ChallengeAnswer true "Challenge returned by the login, the code is ignored"
+// @Success 200 {object} auth.Provisioning
+// @Failure 401 {object} Error
+// @Failure 409 {object} Error
+// @Failure 422 {object} Error
+// @Failure 500 {object} Error
+// @Failure 503 {object} Error
+// @Router /login/twoFactor/enroll [post]
+func EnrollLoginChallenge(con *gin.Context) {
+	answer := ChallengeAnswer{}
+	if err := con.ShouldBindJSON(&answer); err != nil || answer.Challenge == "" {
+		con.JSON(http.StatusUnprocessableEntity, Error{"invalid request structure provided"})
+		return
+	}
+	provisioning, err := authn.EnrollChallenge(con.Request.Context(), answer.Challenge)
+	if err != nil {
+		respondAuthError(con, err)
+		return
+	}
+	con.JSON(http.StatusOK, provisioning)
+}
+
+// completeLogin answers a login whose first step succeeded with a challenge if the teacher needs a second factor, otherwise with the token pair
+func completeLogin(con *gin.Context, teacher mongo.Teacher) {
+	challenge, err := authn.NewChallenge(teacher)
+	if err != nil {
+		respondAuthError(con, err)
+		return
+	}
+	if challenge != nil {
+		con.JSON(http.StatusAccepted, LoginChallenge{
+			Challenge: challenge.ID,
+			Enrolled:  challenge.Enrolled,
+			Expires:   challenge.Expires,
+		})
 		return
 	}
-	token, err := CreateToken(u.Username)
+	pair, err := createSession(teacher.Short)
 	if err != nil {
 		con.JSON(http.StatusInternalServerError, Error{"couldn't sign token"})
 		return
 	}
-	SaveToken(u.Username, token)
-	out := TokenPair{
+	con.JSON(http.StatusOK, pair)
+}
+
+// createSession creates a session of the user and returns its token pair
+func createSession(username string) (TokenPair, error) {
+	token, err := CreateToken(username)
+	if err != nil {
+		return TokenPair{}, err
+	}
+	SaveToken(username, token)
+	return TokenPair{
 		AccessToken:  token.AccessToken,
 		RefreshToken: token.RefreshToken,
+	}, nil
+}
+
+// EnrollTwoFactor represents the enroll two factor endpoint
+// @Summary Starts the enrolment of a second factor
+// @Description Creates a new secret to add to an authenticator app, it has to be confirmed with a one-time password at /twoFactor/confirm
+// @ID enroll-two-factor
+// @Produce json
+// @Param Authorization header string true "Access Token" default(Bearer <Add access token here>)
+// @Success 200 {object} auth.Provisioning
+// @Failure 401 {object} Error
+// @Failure 409 {object} Error
+// @Failure 500 {object} Error
+// @Failure 503 {object} Error
+// @Router /twoFactor/enroll [post]
+func EnrollTwoFactor(con *gin.Context) {
+	auth, err := ExtractTokenMeta(con.Request)
+	if err != nil {
+		con.JSON(http.StatusUnauthorized, Error{"you are not logged in"})
+		return
+	}
+	provisioning, err := authn.BeginEnrolment(con.Request.Context(), auth.Username)
+	if err != nil {
+		respondAuthError(con, err)
+		return
+	}
+	con.JSON(http.StatusOK, provisioning)
+}
+
+// ConfirmTwoFactor represents the confirm two factor endpoint
+// @Summary Confirms the enrolment of a second factor
+// @Description Enables the second factor if the one-time password matches the secret of the enrolment, the recovery codes are returned once
+// @ID confirm-two-factor
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Access Token" default(Bearer <Add access token here>)
+// @Param code body TwoFactorCode true "One-time password"
+// @Success 200 {object} RecoveryCodes
+// @Failure 401 {object} Error
+// @Failure 403 {object} Error
+// @Failure 409 {object} Error
+// @Failure 422 {object} Error
+// @Failure 500 {object} Error
+// @Failure 503 {object} Error
+// @Router /twoFactor/confirm [post]
+func ConfirmTwoFactor(con *gin.Context) {
+	auth, err := ExtractTokenMeta(con.Request)
+	if err != nil {
+		con.JSON(http.StatusUnauthorized, Error{"you are not logged in"})
+		return
+	}
+	code := TwoFactorCode{}
+	if err := con.ShouldBindJSON(&code); err != nil {
+		con.JSON(http.StatusUnprocessableEntity, Error{"invalid request structure provided"})
+		return
+	}
+	codes, err := authn.ConfirmEnrolment(con.Request.Context(), auth.Username, code.Code)
+	if err != nil {
+		respondAuthError(con, err)
+		return
+	}
+	con.JSON(http.StatusOK, RecoveryCodes{codes})
+}
+
+// DisableTwoFactor represents the disable two factor endpoint
+// @Summary Disables the second factor
+// @Description Removes the second factor after checking a one-time password or recovery code, it can't be removed if the roles of the user require it
+// @ID disable-two-factor
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Access Token" default(Bearer <Add access token here>)
+// @Param code body TwoFactorCode true "One-time password or recovery code"
+// @Success 200 {object} Information
+// @Failure 401 {object} Error
+// @Failure 403 {object} Error
+// @Failure 409 {object} Error
+// @Failure 422 {object} Error
+// @Failure 500 {object} Error
+// @Failure 503 {object} Error
+// @Router /twoFactor/disable [post]
+func DisableTwoFactor(con *gin.Context) {
+	auth, err := ExtractTokenMeta(con.Request)
+	if err != nil {
+		con.JSON(http.StatusUnauthorized, Error{"you are not logged in"})
+		return
+	}
+	code := TwoFactorCode{}
+	if err := con.ShouldBindJSON(&code); err != nil {
+		con.JSON(http.StatusUnprocessableEntity, Error{"invalid request structure provided"})
+		return
+	}
+	if err := authn.DisableTwoFactor(con.Request.Context(), auth.Username, code.Code); err != nil {
+		respondAuthError(con, err)
+		return
+	}
+	con.JSON(http.StatusOK, Information{"two-factor authentication disabled"})
+}
+
+// RegenerateRecoveryCodes represents the regenerate recovery codes endpoint
+// @Summary Replaces the recovery codes
+// @Description Creates new recovery codes after checking a one-time password or recovery code, the old ones can't be used anymore
+// @ID regenerate-recovery-codes
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Access Token" default(Bearer <Add access token here>)
+// @Param code body TwoFactorCode true "One-time password or recovery code"
+// @Success 200 {object} RecoveryCodes
+// @Failure 401 {object} Error
+// @Failure 403 {object} Error
+// @Failure 409 {object} Error
+// @Failure 422 {object} Error
+// @Failure 500 {object} Error
+// @Failure 503 {object} Error
+// @Router /twoFactor/recoveryCodes [post]
+func RegenerateRecoveryCodes(con *gin.Context) {
+	auth, err := ExtractTokenMeta(con.Request)
+	if err != nil {
+		con.JSON(http.StatusUnauthorized, Error{"you are not logged in"})
+		return
+	}
+	code := TwoFactorCode{}
+	if err := con.ShouldBindJSON(&code); err != nil {
+		con.JSON(http.StatusUnprocessableEntity, Error{"invalid request structure provided"})
+		return
+	}
+	codes, err := authn.RegenerateRecoveryCodes(con.Request.Context(), auth.Username, code.Code)
+	if err != nil {
+		respondAuthError(con, err)
+		return
+	}
+	con.JSON(http.StatusOK, RecoveryCodes{codes})
+}
+
+// ResetTwoFactor represents the reset two factor endpoint
+// @Summary Removes the second factor of a teacher
+// @Description Removes the second factor of a teacher who lost it, a teacher whose roles require one has to enrol again at the next login
+// @Description Teachers holding roles the administrator lacks, e.g. super users, can only be reset by someone holding these roles too, every reset is audited
+// @ID reset-two-factor
+// @Produce json
+// @Param Authorization header string true "Access Token" default(Bearer <Add access token here>)
+// @Param uuid query string true "UUID of the teacher"
+// @Success 200 {object} Information
+// @Failure 401 {object} Error
+// @Failure 404 {object} Error
+// @Failure 422 {object} Error
+// @Failure 500 {object} Error
+// @Failure 503 {object} Error
+// @Router /resetTwoFactor [post]
+func ResetTwoFactor(con *gin.Context) {
+	auth, err := ExtractTokenMeta(con.Request)
+	if err != nil {
+		con.JSON(http.StatusUnauthorized, Error{"you are not logged in"})
+		return
+	}
+	uuid := con.Request.URL.Query().Get("uuid")
+	if uuid == "" {
+		con.JSON(http.StatusUnprocessableEntity, Error{"invalid request structure provided"})
+		return
+	}
+	db := mongo.MongoDatabaseConnector{}
+	if err := db.Connect(con.Request.Context()); err != nil {
+		respondError(con, err, "database")
+		return
+	}
+	defer db.Close()
+	requester, err := db.GetTeacherByShort(auth.Username)
+	if err != nil {
+		respondError(con, err, "teacher")
+		return
 	}
-	con.JSON(http.StatusOK, out)
+	if !(requester.Administration || requester.SuperUser) {
+		con.JSON(http.StatusUnauthorized, Error{"unauthorized"})
+		return
+	}
+	teacher, err := db.GetTeacherByUUID(uuid)
+	if err != nil {
+		respondError(con, err, "teacher")
+		return
+	}
+	if !requester.Permissions().Covers(teacher.Permissions()) {
+		con.JSON(http.StatusUnauthorized, Error{"the second factor of teachers holding roles you lack can't be reset by you"})
+		return
+	}
+	teacher.TwoFactor = mongo.TwoFactor{}
+	if err := db.UpdateTeacher(uuid, teacher); err != nil {
+		respondError(con, err, "teacher")
+		return
+	}
+	auditAdministration(con, db, mongo.AuditTwoFactorReset, requester, teacher, "reset the second factor of "+teacher.Short)
+	con.JSON(http.StatusOK, Information{"two-factor authentication reset"})
 }
 
 // Logout represents the logout endpoint
 // @Summary Logs out a user
-// @Description Destroys the session of a user
+// @Description Destroys the session of a user, the untis session is only closed once no other session of the user is left
 // @ID logout
 // @Accept json
 // @Produce json
@@ -96,7 +479,9 @@ func Logout(con *gin.Context) {
 		return
 	}
 	DeleteToken(auth.AccessUUID)
-	untis.GetClient(auth.Username).DeleteClient()
+	if !hasTokens(auth.Username) {
+		untis.RemoveClient(auth.Username)
+	}
 	con.JSON(http.StatusOK, Information{"logged out"})
 }
 
@@ -178,8 +563,10 @@ func Refresh(con *gin.Context) {
 // @Param name query string true "Short Name of Teacher"
 // @Success 200 {object} db.Teacher
 // @Failure 401 {object} Error
+// @Failure 404 {object} Error
 // @Failure 422 {object} Error
 // @Failure 500 {object} Error
+// @Failure 503 {object} Error
 // @Router /getTeacherByShort [get]
 func GetTeacherByShort(con *gin.Context) {
 	auth, err := ExtractTokenMeta(con.Request)
@@ -194,55 +581,14 @@ func GetTeacherByShort(con *gin.Context) {
 	}
 	name := query.Get("name")
 	db := mongo.MongoDatabaseConnector{}
-	if !db.Connect() {
-		con.JSON(http.StatusInternalServerError, Error{"database didn't respond"})
+	if err := db.Connect(con.Request.Context()); err != nil {
+		respondError(con, err, "database")
 		return
 	}
 	defer db.Close()
-	if db.DoesTeacherExistByShort(name) {
-		teacher := db.GetTeacherByShort(name)
-		con.JSON(http.StatusOK, teacher)
-		return
-	}
-	client := untis.GetClient(auth.Username)
-	longname, err := ldap.GetLongName(client.Username, client.Password, name)
-	if err != nil {
-		con.JSON(http.StatusInternalServerError, Error{"couldn't read longname of new teacher"})
-		return
-	}
-	err = client.Authenticate()
-	defer func() {
-		err = client.Close()
-		if err != nil {
-			con.JSON(http.StatusInternalServerError, Error{"couldn't logout out off untis API"})
-		}
-	}()
-	if err != nil {
-		con.JSON(http.StatusInternalServerError, Error{"couldn't authenticate with untis API"})
-		return
-	}
-	id, err := client.ResolveTeacherID(longname)
-	if err != nil {
-		con.JSON(http.StatusInternalServerError, Error{"couldn't resolve untis id of new teacher"})
-		return
-	}
-	untisAb, err := client.ResolveTeachers([]int{id})
+	teacher, err := resolveTeacher(db, auth.Username, name)
 	if err != nil {
-		con.JSON(http.StatusInternalServerError, Error{"couldn't resolve untis abbrevation of new teacher"})
-		return
-	}
-	teacher := mongo.Teacher{
-		UUID:           uuidG.NewString(),
-		Short:          name,
-		Longname:       longname,
-		SuperUser:      false,
-		AV:             false,
-		Administration: false,
-		PEK:            false,
-		Untis:          untisAb[0],
-	}
-	if !db.CreateTeacher(teacher) {
-		con.JSON(http.StatusInternalServerError, Error{"couldn't create new teacher based on this"})
+		respondError(con, err, "teacher")
 		return
 	}
 	con.JSON(http.StatusOK, teacher)
@@ -261,6 +607,7 @@ func GetTeacherByShort(con *gin.Context) {
 // @Failure 404 {object} Error
 // @Failure 422 {object} Error
 // @Failure 500 {object} Error
+// @Failure 503 {object} Error
 // @Router /getTeacher [get]
 func GetTeacher(con *gin.Context) {
 	_, err := ExtractTokenMeta(con.Request)
@@ -275,16 +622,16 @@ func GetTeacher(con *gin.Context) {
 	}
 	uuid := query.Get("uuid")
 	db := mongo.MongoDatabaseConnector{}
-	if !db.Connect() {
-		con.JSON(http.StatusInternalServerError, Error{"database didn't respond"})
+	if err := db.Connect(con.Request.Context()); err != nil {
+		respondError(con, err, "database")
 		return
 	}
 	defer db.Close()
-	if !db.DoesTeacherExistByUUID(uuid) {
-		con.JSON(http.StatusNotFound, Error{"teacher not found"})
+	teacher, err := db.GetTeacherByUUID(uuid)
+	if err != nil {
+		respondError(con, err, "teacher")
 		return
 	}
-	teacher := db.GetTeacherByUUID(uuid)
 	con.JSON(http.StatusOK, teacher)
 }
 
@@ -301,6 +648,7 @@ func GetTeacher(con *gin.Context) {
 // @Failure 404 {object} Error
 // @Failure 422 {object} Error
 // @Failure 500 {object} Error
+// @Failure 503 {object} Error
 // @Router /getTeacherByUntis [get]
 func GetTeacherByUntis(con *gin.Context) {
 	_, err := ExtractTokenMeta(con.Request)
@@ -315,22 +663,24 @@ func GetTeacherByUntis(con *gin.Context) {
 	}
 	untisAb := query.Get("untis")
 	db := mongo.MongoDatabaseConnector{}
-	if !db.Connect() {
-		con.JSON(http.StatusInternalServerError, Error{"database didn't respond"})
+	if err := db.Connect(con.Request.Context()); err != nil {
+		respondError(con, err, "database")
 		return
 	}
 	defer db.Close()
-	if !db.DoesTeacherExistByUntis(untisAb) {
-		con.JSON(http.StatusNotFound, Error{"teacher not found"})
+	teacher, err := db.GetTeacherByUntis(untisAb)
+	if err != nil {
+		respondError(con, err, "teacher")
 		return
 	}
-	teacher := db.GetTeacherByUntis(untisAb)
 	con.JSON(http.StatusOK, teacher)
 }
 
 // SetTeacherPermissions represents the set teacher permissions endpoint
 // @Summary Sets the permissions of a Teacher
-// @Description Sets the permissions of a Teacher to update their access rights
+// @Description Sets the manually granted permissions of a Teacher to update their access rights
+// @Description Permissions derived from the directory groups of the teacher are kept, the teacher has the union of both
+// @Description Only roles the requester holds can be granted, teachers holding roles the requester lacks can't be changed, every change is audited
 // @ID set-teacher-permissions
 // @Accept json
 // @Produce json
@@ -339,8 +689,10 @@ func GetTeacherByUntis(con *gin.Context) {
 // @Param uuid query string true "UUID of the teacher whos permissions will be changed"
 // @Success 200 {object} db.Teacher
 // @Failure 401 {object} Error
+// @Failure 404 {object} Error
 // @Failure 422 {object} Error
 // @Failure 500 {object} Error
+// @Failure 503 {object} Error
 // @Router /setTeacherPermissions [post]
 func SetTeacherPermissions(con *gin.Context) {
 	auth, err := ExtractTokenMeta(con.Request)
@@ -360,72 +712,890 @@ func SetTeacherPermissions(con *gin.Context) {
 	}
 	uuid := query.Get("uuid")
 	db := mongo.MongoDatabaseConnector{}
-	if !db.Connect() {
-		con.JSON(http.StatusInternalServerError, Error{"database didn't respond"})
+	if err := db.Connect(con.Request.Context()); err != nil {
+		respondError(con, err, "database")
 		return
 	}
 	defer db.Close()
-	requester := db.GetTeacherByShort(auth.Username)
+	requester, err := db.GetTeacherByShort(auth.Username)
+	if err != nil {
+		respondError(con, err, "teacher")
+		return
+	}
 	if !(requester.PEK || requester.Administration || requester.AV || requester.SuperUser) {
 		con.JSON(http.StatusUnauthorized, Error{"unauthorized"})
 		return
 	}
-	teacher := db.GetTeacherByUUID(uuid)
-	teacher.SuperUser = perm.SuperUser
-	teacher.Administration = perm.Administration
-	teacher.PEK = perm.PEK
-	teacher.Administration = perm.Administration
-	if db.UpdateTeacher(uuid, teacher) {
-		con.JSON(http.StatusOK, Information{"permissions updated"})
-	} else {
-		con.JSON(http.StatusInternalServerError, Error{"permissions couldn't be updated"})
+	teacher, err := db.GetTeacherByUUID(uuid)
+	if err != nil {
+		respondError(con, err, "teacher")
+		return
 	}
+	if !requester.Permissions().Covers(teacher.Permissions()) {
+		con.JSON(http.StatusUnauthorized, Error{"permissions of teachers holding roles you lack can't be changed by you"})
+		return
+	}
+	roles := mongo.Roles{
+		SuperUser:      perm.SuperUser,
+		AV:             perm.AV,
+		Administration: perm.Administration,
+		PEK:            perm.PEK,
+		DepartmentHead: splitList(perm.DepartmentHead),
+	}
+	// Covers keeps the super user role to super users as well
+	if !requester.Permissions().Covers(roles) {
+		con.JSON(http.StatusUnauthorized, Error{"roles you lack can't be granted by you"})
+		return
+	}
+	// only the manually granted roles are replaced, the roles derived from the directory are kept
+	teacher.GrantedRoles = roles
+	teacher.UpdatePermissions()
+	if err := db.UpdateTeacher(uuid, teacher); err != nil {
+		respondError(con, err, "teacher")
+		return
+	}
+	auditAdministration(con, db, mongo.AuditPermissionsSet, requester, teacher, fmt.Sprintf("granted %v the roles %v", teacher.Short, describeRoles(roles)))
+	con.JSON(http.StatusOK, Information{"permissions updated"})
 }
 
-// UpdateTeacherInformation represents the update teacher information endpoint
-// @Summary Updates the information of an existing teacher
-// @Description Updates a teacher identified by a uuid with the data in the body in the system
-// @ID update-teacher-information
+// SetLocalAccount represents the set local account endpoint
+// @Summary Creates or updates a local account
+// @Description Creates a teacher logging in with a password stored in huginn (e.g. external companions), or sets the name and password of an existing one
+// @Description Local accounts can only log in if local is enabled in AUTH_PROVIDERS
+// @Description The short name is stored lowercased, accounts holding roles the administrator lacks can only be changed by someone holding these roles too, every change is audited
+// @ID set-local-account
 // @Accept json
 // @Produce json
 // @Param Authorization header string true "Access Token" default(Bearer <Add access token here>)
-// @Param teacher_information body rest.TeacherInformation true "The teacher information data to set"
-// @Param uuid query string true "Identifier of the teacher to update"
-// @Success 200 {object} Information
+// @Param account body LocalAccount true "Data of the local account"
+// @Success 200 {object} db.Teacher
 // @Failure 401 {object} Error
+// @Failure 409 {object} Error
 // @Failure 422 {object} Error
 // @Failure 500 {object} Error
-// @Router /updateTeacherInformation [put]
-func UpdateTeacherInformation(con *gin.Context) {
-	ti := TeacherInformation{}
-	if err := con.ShouldBindJSON(&ti); err != nil {
-		con.JSON(http.StatusUnprocessableEntity, Error{"invalid request structure provided"})
-		return
-	}
+// @Failure 503 {object} Error
+// @Router /setLocalAccount [post]
+func SetLocalAccount(con *gin.Context) {
 	auth, err := ExtractTokenMeta(con.Request)
 	if err != nil {
 		con.JSON(http.StatusUnauthorized, Error{"you are not logged in"})
 		return
 	}
-	db := mongo.MongoDatabaseConnector{}
-	if !db.Connect() {
-		con.JSON(http.StatusInternalServerError, Error{"database didn't respond"})
+	account := LocalAccount{}
+	if err := con.ShouldBindJSON(&account); err != nil {
+		con.JSON(http.StatusUnprocessableEntity, Error{"invalid request structure provided"})
 		return
 	}
-	defer db.Close()
-	query := con.Request.URL.Query()
-	uuid := query.Get("uuid")
-	if query.Get("uuid") == "" {
+	// logins are looked up by the lowercased short name
+	account.Short = strings.ToLower(strings.TrimSpace(account.Short))
+	if account.Short == "" {
 		con.JSON(http.StatusUnprocessableEntity, Error{"invalid request structure provided"})
 		return
 	}
-	requestTeacher := db.GetTeacherByShort(auth.Username)
-	teacherToUpdate := db.GetTeacherByUUID(uuid)
-	if !(requestTeacher.UUID == teacherToUpdate.UUID) {
-		con.JSON(http.StatusUnauthorized, Error{"teacher are only allowed to update themselves"})
+	db := mongo.MongoDatabaseConnector{}
+	if err := db.Connect(con.Request.Context()); err != nil {
+		respondError(con, err, "database")
 		return
 	}
-	teacherToUpdate.Degree = ti.Degree
+	defer db.Close()
+	requester, err := db.GetTeacherByShort(auth.Username)
+	if err != nil {
+		respondError(con, err, "teacher")
+		return
+	}
+	if !(requester.Administration || requester.SuperUser) {
+		con.JSON(http.StatusUnauthorized, Error{"unauthorized"})
+		return
+	}
+	hash, err := authn.HashPassword(account.Password)
+	if errors.Is(err, authn.ErrWeakPassword) {
+		con.JSON(http.StatusUnprocessableEntity, Error{err.Error()})
+		return
+	}
+	if err != nil {
+		respondAuthError(con, err)
+		return
+	}
+	teacher, err := db.GetTeacherByShort(account.Short)
+	if errors.Is(err, mongo.ErrNotFound) {
+		teacher, err = db.CreateTeacher(mongo.Teacher{
+			UUID:         uuidG.NewString(),
+			Short:        account.Short,
+			Longname:     account.Longname,
+			Mail:         account.Mail,
+			Untis:        account.Untis,
+			Provider:     mongo.LocalProvider,
+			PasswordHash: hash,
+		})
+		if err != nil {
+			respondError(con, err, "teacher")
+			return
+		}
+		auditAdministration(con, db, mongo.AuditLocalAccountSet, requester, teacher, "created the local account "+teacher.Short)
+		con.JSON(http.StatusOK, teacher)
+		return
+	}
+	if err != nil {
+		respondError(con, err, "teacher")
+		return
+	}
+	if teacher.Provider != mongo.LocalProvider {
+		con.JSON(http.StatusConflict, Error{"a teacher of another provider already has this short name"})
+		return
+	}
+	if !requester.Permissions().Covers(teacher.Permissions()) {
+		con.JSON(http.StatusUnauthorized, Error{"local accounts holding roles you lack can't be changed by you"})
+		return
+	}
+	teacher.PasswordHash = hash
+	if account.Longname != "" {
+		teacher.Longname = account.Longname
+	}
+	if account.Mail != "" {
+		teacher.Mail = account.Mail
+	}
+	if account.Untis != "" {
+		teacher.Untis = account.Untis
+	}
+	if err := db.UpdateTeacher(teacher.UUID, teacher); err != nil {
+		respondError(con, err, "teacher")
+		return
+	}
+	auditAdministration(con, db, mongo.AuditLocalAccountSet, requester, teacher, "set the password of the local account "+teacher.Short)
+	con.JSON(http.StatusOK, teacher)
+}
+
+// GetLockouts represents the get lockouts endpoint
+// @Summary Lists the throttled usernames and addresses
+// @Description Returns the usernames and addresses which currently have to wait or are locked out after failed logins
+// @ID get-lockouts
+// @Produce json
+// @Param Authorization header string true "Access Token" default(Bearer <Add access token here>)
+// @Success 200 {array} auth.Lockout
+// @Failure 401 {object} Error
+// @Failure 500 {object} Error
+// @Failure 503 {object} Error
+// @Router /getLockouts [get]
+func GetLockouts(con *gin.Context) {
+	if _, ok := requireAdministration(con); !ok {
+		return
+	}
+	con.JSON(http.StatusOK, authn.Lockouts())
+}
+
+// ClearLockout represents the clear lockout endpoint
+// @Summary Clears the lockout of a username or address
+// @Description Forgets the failed logins of a username or address, so it can log in again immediately
+// @ID clear-lockout
+// @Produce json
+// @Param Authorization header string true "Access Token" default(Bearer <Add access token here>)
+// @Param kind query string true "Kind of the lockout" Enums(user, ip)
+// @Param key query string true "Username or address"
+// @Success 200 {object} Information
+// @Failure 401 {object} Error
+// @Failure 404 {object} Error
+// @Failure 422 {object} Error
+// @Failure 500 {object} Error
+// @Failure 503 {object} Error
+// @Router /clearLockout [delete]
+func ClearLockout(con *gin.Context) {
+	query := con.Request.URL.Query()
+	kind, key := query.Get("kind"), query.Get("key")
+	if (kind != authn.UserThrottle && kind != authn.IPThrottle) || key == "" {
+		con.JSON(http.StatusUnprocessableEntity, Error{"invalid request structure provided"})
+		return
+	}
+	requester, ok := requireAdministration(con)
+	if !ok {
+		return
+	}
+	if !authn.ClearLockout(con.Request.Context(), kind, key, requester.Short) {
+		con.JSON(http.StatusNotFound, Error{"lockout not found"})
+		return
+	}
+	con.JSON(http.StatusOK, Information{"lockout cleared"})
+}
+
+// GetAuditEntries represents the get audit entries endpoint
+// @Summary Returns the newest audit entries
+// @Description Returns failed logins, lockouts and cleared lockouts, the newest first
+// @ID get-audit-entries
+// @Produce json
+// @Param Authorization header string true "Access Token" default(Bearer <Add access token here>)
+// @Param username query string false "Only return the entries of this username"
+// @Param limit query int false "Maximum amount of entries" default(100)
+// @Success 200 {array} db.AuditEntry
+// @Failure 401 {object} Error
+// @Failure 422 {object} Error
+// @Failure 500 {object} Error
+// @Failure 503 {object} Error
+// @Router /getAuditEntries [get]
+func GetAuditEntries(con *gin.Context) {
+	query := con.Request.URL.Query()
+	limit := int64(AuditLimit)
+	if value := query.Get("limit"); value != "" {
+		parsed, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || parsed <= 0 {
+			con.JSON(http.StatusUnprocessableEntity, Error{"invalid request structure provided"})
+			return
+		}
+		limit = parsed
+	}
+	if _, ok := requireAdministration(con); !ok {
+		return
+	}
+	db := mongo.MongoDatabaseConnector{}
+	if err := db.Connect(con.Request.Context()); err != nil {
+		respondError(con, err, "database")
+		return
+	}
+	defer db.Close()
+	entries, err := db.GetAuditEntries(strings.ToLower(query.Get("username")), limit)
+	if err != nil {
+		respondError(con, err, "audit entries")
+		return
+	}
+	con.JSON(http.StatusOK, entries)
+}
+
+// CreateAPIKey represents the create api key endpoint
+// @Summary Creates an API key for a service principal
+// @Description Creates an API key machine clients present instead of an access token, the key is only returned once and stored hashed
+// @Description The service principal is created if it doesn't exist, its permissions restrict what the key may do together with the listed endpoints
+// @Description At least one endpoint has to be listed, service principals can't be super users or hold roles the requesting administrator lacks
+// @Description API keys can't be used to manage API keys
+// @ID create-api-key
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Access Token" default(Bearer <Add access token here>)
+// @Param key body APIKeyRequest true "Data of the API key"
+// @Success 200 {object} CreatedAPIKey
+// @Failure 401 {object} Error
+// @Failure 409 {object} Error
+// @Failure 422 {object} Error
+// @Failure 500 {object} Error
+// @Failure 503 {object} Error
+// @Router /createAPIKey [post]
+func CreateAPIKey(con *gin.Context) {
+	req := APIKeyRequest{}
+	if err := con.ShouldBindJSON(&req); err != nil || strings.TrimSpace(req.Principal) == "" || strings.TrimSpace(req.Name) == "" {
+		con.JSON(http.StatusUnprocessableEntity, Error{"invalid request structure provided"})
+		return
+	}
+	endpoints := splitList(req.Endpoints)
+	if len(endpoints) == 0 {
+		respondAuthError(con, authn.ErrNoEndpoints)
+		return
+	}
+	if rejectAPIKey(con) {
+		return
+	}
+	requester, ok := requireAdministration(con)
+	if !ok {
+		return
+	}
+	var roles *mongo.Roles
+	if req.Permissions != nil {
+		roles = &mongo.Roles{
+			SuperUser:      req.Permissions.SuperUser,
+			AV:             req.Permissions.AV,
+			Administration: req.Permissions.Administration,
+			PEK:            req.Permissions.PEK,
+			DepartmentHead: splitList(req.Permissions.DepartmentHead),
+		}
+		if roles.SuperUser {
+			respondAuthError(con, authn.ErrPrivilegedPrincipal)
+			return
+		}
+		if !requester.Permissions().Covers(*roles) {
+			con.JSON(http.StatusUnauthorized, Error{"service principals can't be granted roles you lack"})
+			return
+		}
+	}
+	db := mongo.MongoDatabaseConnector{}
+	if err := db.Connect(con.Request.Context()); err != nil {
+		respondError(con, err, "database")
+		return
+	}
+	defer db.Close()
+	short := strings.ToLower(strings.TrimSpace(req.Principal))
+	principal, err := db.GetTeacherByShort(short)
+	if errors.Is(err, mongo.ErrNotFound) {
+		principal, err = db.CreateTeacher(mongo.Teacher{
+			UUID:     uuidG.NewString(),
+			Short:    short,
+			Longname: req.Longname,
+			Provider: mongo.ServiceProvider,
+		})
+	}
+	if err != nil {
+		respondError(con, err, "service principal")
+		return
+	}
+	if principal.Provider != mongo.ServiceProvider {
+		respondAuthError(con, authn.ErrNoServicePrincipal)
+		return
+	}
+	if roles == nil && !requester.Permissions().Covers(principal.Permissions()) {
+		con.JSON(http.StatusUnauthorized, Error{"keys of service principals holding roles you lack can't be created by you"})
+		return
+	}
+	if roles != nil {
+		principal.GrantedRoles = *roles
+		principal.UpdatePermissions()
+		if err := db.UpdateTeacher(principal.UUID, principal); err != nil {
+			respondError(con, err, "service principal")
+			return
+		}
+	}
+	key, plain, err := authn.NewAPIKey(con.Request.Context(), mongo.APIKey{
+		Name:      strings.TrimSpace(req.Name),
+		Principal: principal.Short,
+		Endpoints: endpoints,
+		CreatedBy: requester.Short,
+		ExpiresAt: req.ExpiresAt,
+	})
+	if err != nil {
+		respondAuthError(con, err)
+		return
+	}
+	con.JSON(http.StatusOK, CreatedAPIKey{Key: plain, APIKey: key})
+}
+
+// GetAPIKeys represents the get api keys endpoint
+// @Summary Lists the API keys
+// @Description Returns the API keys with their usage, including expired and revoked ones, the newest first
+// @ID get-api-keys
+// @Produce json
+// @Param Authorization header string true "Access Token" default(Bearer <Add access token here>)
+// @Param principal query string false "Only return the keys of this service principal"
+// @Success 200 {array} db.APIKey
+// @Failure 401 {object} Error
+// @Failure 500 {object} Error
+// @Failure 503 {object} Error
+// @Router /getAPIKeys [get]
+func GetAPIKeys(con *gin.Context) {
+	if rejectAPIKey(con) {
+		return
+	}
+	if _, ok := requireAdministration(con); !ok {
+		return
+	}
+	db := mongo.MongoDatabaseConnector{}
+	if err := db.Connect(con.Request.Context()); err != nil {
+		respondError(con, err, "database")
+		return
+	}
+	defer db.Close()
+	keys, err := db.GetAPIKeys(strings.ToLower(con.Request.URL.Query().Get("principal")))
+	if err != nil {
+		respondError(con, err, "API keys")
+		return
+	}
+	con.JSON(http.StatusOK, keys)
+}
+
+// RevokeAPIKey represents the revoke api key endpoint
+// @Summary Revokes an API key
+// @Description Revokes an API key immediately, it is kept so its usage can still be looked up
+// @ID revoke-api-key
+// @Produce json
+// @Param Authorization header string true "Access Token" default(Bearer <Add access token here>)
+// @Param id query string true "ID of the API key"
+// @Success 200 {object} Information
+// @Failure 401 {object} Error
+// @Failure 404 {object} Error
+// @Failure 422 {object} Error
+// @Failure 500 {object} Error
+// @Failure 503 {object} Error
+// @Router /revokeAPIKey [delete]
+func RevokeAPIKey(con *gin.Context) {
+	id := con.Request.URL.Query().Get("id")
+	if id == "" {
+		con.JSON(http.StatusUnprocessableEntity, Error{"invalid request structure provided"})
+		return
+	}
+	if rejectAPIKey(con) {
+		return
+	}
+	if _, ok := requireAdministration(con); !ok {
+		return
+	}
+	db := mongo.MongoDatabaseConnector{}
+	if err := db.Connect(con.Request.Context()); err != nil {
+		respondError(con, err, "database")
+		return
+	}
+	defer db.Close()
+	if err := db.RevokeAPIKey(id); err != nil {
+		respondError(con, err, "API key")
+		return
+	}
+	con.JSON(http.StatusOK, Information{"API key revoked"})
+}
+
+// rejectAPIKey answers the request with 401 if it was authenticated with an API key
+// returns whether the request was rejected
+func rejectAPIKey(con *gin.Context) bool {
+	if auth, err := ExtractTokenMeta(con.Request); err == nil && auth.APIKey != "" {
+		con.JSON(http.StatusUnauthorized, Error{"API keys can't be used for this endpoint"})
+		return true
+	}
+	return false
+}
+
+// CreateDelegation represents the create delegation endpoint
+// @Summary Lets another teacher act on behalf of a teacher
+// @Description Lets the delegate create and edit the applications and invoices of the delegator until the delegation ends
+// @Description Everything the delegate does on behalf of the delegator is audited, generated forms carry the data of the delegator
+// @ID create-delegation
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Access Token" default(Bearer <Add access token here>)
+// @Param delegation body DelegationRequest true "Data of the delegation"
+// @Success 200 {object} db.Delegation
+// @Failure 401 {object} Error
+// @Failure 404 {object} Error
+// @Failure 409 {object} Error
+// @Failure 422 {object} Error
+// @Failure 500 {object} Error
+// @Failure 503 {object} Error
+// @Router /createDelegation [post]
+func CreateDelegation(con *gin.Context) {
+	req := DelegationRequest{}
+	if err := con.ShouldBindJSON(&req); err != nil || strings.TrimSpace(req.Delegate) == "" {
+		con.JSON(http.StatusUnprocessableEntity, Error{"invalid request structure provided"})
+		return
+	}
+	if req.From.IsZero() {
+		req.From = time.Now()
+	}
+	if !req.Till.After(req.From) || !req.Till.After(time.Now()) || req.Till.Sub(req.From) > MaxDelegationDuration {
+		con.JSON(http.StatusUnprocessableEntity, Error{"a delegation has to end in the future and at most a year after it starts"})
+		return
+	}
+	if rejectAPIKey(con) {
+		return
+	}
+	auth, err := ExtractTokenMeta(con.Request)
+	if err != nil {
+		con.JSON(http.StatusUnauthorized, Error{"you are not logged in"})
+		return
+	}
+	db := mongo.MongoDatabaseConnector{}
+	if err := db.Connect(con.Request.Context()); err != nil {
+		respondError(con, err, "database")
+		return
+	}
+	defer db.Close()
+	requester, err := db.GetTeacherByShort(auth.Username)
+	if err != nil {
+		respondError(con, err, "teacher")
+		return
+	}
+	delegator := requester
+	if short := strings.ToLower(strings.TrimSpace(req.Delegator)); short != "" && short != requester.Short {
+		if !(requester.Administration || requester.SuperUser) {
+			con.JSON(http.StatusUnauthorized, Error{"only administrators can create delegations for other teachers"})
+			return
+		}
+		if delegator, err = db.GetTeacherByShort(short); err != nil {
+			respondError(con, err, "delegator")
+			return
+		}
+	}
+	delegate, err := db.GetTeacherByShort(strings.ToLower(strings.TrimSpace(req.Delegate)))
+	if err != nil {
+		respondError(con, err, "delegate")
+		return
+	}
+	if delegate.Short == delegator.Short || delegate.Provider == mongo.ServiceProvider || !delegate.DeactivatedAt.IsZero() {
+		con.JSON(http.StatusConflict, Error{"teachers can only delegate to other active teachers"})
+		return
+	}
+	delegation, err := db.CreateDelegation(mongo.Delegation{
+		UUID:      uuidG.NewString(),
+		Delegator: delegator.Short,
+		Delegate:  delegate.Short,
+		From:      req.From,
+		Till:      req.Till,
+		CreatedAt: time.Now(),
+		CreatedBy: requester.Short,
+	})
+	if err != nil {
+		respondError(con, err, "delegation")
+		return
+	}
+	auditDelegation(con, db, mongo.AuditDelegationCreated, requester, delegation)
+	con.JSON(http.StatusOK, delegation)
+}
+
+// GetDelegations represents the get delegations endpoint
+// @Summary Lists the delegations of a teacher
+// @Description Returns the delegations a teacher granted or received, including ended and revoked ones, the newest first
+// @ID get-delegations
+// @Produce json
+// @Param Authorization header string true "Access Token" default(Bearer <Add access token here>)
+// @Param short query string false "Short name of the teacher, only administrators may list the delegations of other teachers"
+// @Success 200 {array} db.Delegation
+// @Failure 401 {object} Error
+// @Failure 500 {object} Error
+// @Failure 503 {object} Error
+// @Router /getDelegations [get]
+func GetDelegations(con *gin.Context) {
+	auth, err := ExtractTokenMeta(con.Request)
+	if err != nil {
+		con.JSON(http.StatusUnauthorized, Error{"you are not logged in"})
+		return
+	}
+	db := mongo.MongoDatabaseConnector{}
+	if err := db.Connect(con.Request.Context()); err != nil {
+		respondError(con, err, "database")
+		return
+	}
+	defer db.Close()
+	requester, err := db.GetTeacherByShort(auth.Username)
+	if err != nil {
+		respondError(con, err, "teacher")
+		return
+	}
+	short := strings.ToLower(con.Request.URL.Query().Get("short"))
+	if short == "" {
+		short = requester.Short
+	}
+	if short != requester.Short && !(requester.Administration || requester.SuperUser) {
+		con.JSON(http.StatusUnauthorized, Error{"unauthorized"})
+		return
+	}
+	delegations, err := db.GetDelegations(short)
+	if err != nil {
+		respondError(con, err, "delegations")
+		return
+	}
+	con.JSON(http.StatusOK, delegations)
+}
+
+// RevokeDelegation represents the revoke delegation endpoint
+// @Summary Revokes a delegation
+// @Description Ends a delegation immediately, it can be revoked by the delegator, the delegate and administrators
+// @ID revoke-delegation
+// @Produce json
+// @Param Authorization header string true "Access Token" default(Bearer <Add access token here>)
+// @Param uuid query string true "UUID of the delegation"
+// @Success 200 {object} Information
+// @Failure 401 {object} Error
+// @Failure 404 {object} Error
+// @Failure 422 {object} Error
+// @Failure 500 {object} Error
+// @Failure 503 {object} Error
+// @Router /revokeDelegation [delete]
+func RevokeDelegation(con *gin.Context) {
+	uuid := con.Request.URL.Query().Get("uuid")
+	if uuid == "" {
+		con.JSON(http.StatusUnprocessableEntity, Error{"invalid request structure provided"})
+		return
+	}
+	auth, err := ExtractTokenMeta(con.Request)
+	if err != nil {
+		con.JSON(http.StatusUnauthorized, Error{"you are not logged in"})
+		return
+	}
+	db := mongo.MongoDatabaseConnector{}
+	if err := db.Connect(con.Request.Context()); err != nil {
+		respondError(con, err, "database")
+		return
+	}
+	defer db.Close()
+	requester, err := db.GetTeacherByShort(auth.Username)
+	if err != nil {
+		respondError(con, err, "teacher")
+		return
+	}
+	delegation, err := db.GetDelegation(uuid)
+	if err != nil {
+		respondError(con, err, "delegation")
+		return
+	}
+	if !(requester.Short == delegation.Delegator || requester.Short == delegation.Delegate || requester.Administration || requester.SuperUser) {
+		con.JSON(http.StatusUnauthorized, Error{"unauthorized"})
+		return
+	}
+	if err := db.RevokeDelegation(uuid); err != nil {
+		respondError(con, err, "delegation")
+		return
+	}
+	auditDelegation(con, db, mongo.AuditDelegationRevoked, requester, delegation)
+	con.JSON(http.StatusOK, Information{"delegation revoked"})
+}
+
+// CreateDeputy represents the create deputy endpoint
+// @Summary Names a deputy of an approver
+// @Description Lets the deputy review and approve applications in place of the approver for a time range of at most a year, e.g. during a school trip
+// @Description The deputy sees the approval queue of the approver, everything the deputy does as deputy is audited
+// @ID create-deputy
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Access Token" default(Bearer <Add access token here>)
+// @Param deputy body DeputyRequest true "Data of the deputy assignment"
+// @Success 200 {object} db.DeputyAssignment
+// @Failure 401 {object} Error
+// @Failure 404 {object} Error
+// @Failure 409 {object} Error
+// @Failure 422 {object} Error
+// @Failure 500 {object} Error
+// @Failure 503 {object} Error
+// @Router /createDeputy [post]
+func CreateDeputy(con *gin.Context) {
+	req := DeputyRequest{}
+	if err := con.ShouldBindJSON(&req); err != nil || strings.TrimSpace(req.Deputy) == "" {
+		con.JSON(http.StatusUnprocessableEntity, Error{"invalid request structure provided"})
+		return
+	}
+	if req.From.IsZero() {
+		req.From = time.Now()
+	}
+	if !req.Till.After(req.From) || !req.Till.After(time.Now()) || req.Till.Sub(req.From) > MaxDelegationDuration {
+		con.JSON(http.StatusUnprocessableEntity, Error{"a deputy assignment has to end in the future and at most a year after it starts"})
+		return
+	}
+	if rejectAPIKey(con) {
+		return
+	}
+	auth, err := ExtractTokenMeta(con.Request)
+	if err != nil {
+		con.JSON(http.StatusUnauthorized, Error{"you are not logged in"})
+		return
+	}
+	db := mongo.MongoDatabaseConnector{}
+	if err := db.Connect(con.Request.Context()); err != nil {
+		respondError(con, err, "database")
+		return
+	}
+	defer db.Close()
+	requester, err := db.GetTeacherByShort(auth.Username)
+	if err != nil {
+		respondError(con, err, "teacher")
+		return
+	}
+	approver := requester
+	if short := strings.ToLower(strings.TrimSpace(req.Approver)); short != "" && short != requester.Short {
+		if !(requester.Administration || requester.SuperUser) {
+			con.JSON(http.StatusUnauthorized, Error{"only administrators can name deputies of other approvers"})
+			return
+		}
+		if approver, err = db.GetTeacherByShort(short); err != nil {
+			respondError(con, err, "approver")
+			return
+		}
+	}
+	if !isApprover(approvalRoles(approver, nil)) {
+		con.JSON(http.StatusConflict, Error{"only approvers can have deputies"})
+		return
+	}
+	deputy, err := db.GetTeacherByShort(strings.ToLower(strings.TrimSpace(req.Deputy)))
+	if err != nil {
+		respondError(con, err, "deputy")
+		return
+	}
+	if deputy.Short == approver.Short || deputy.Provider == mongo.ServiceProvider || !deputy.DeactivatedAt.IsZero() {
+		con.JSON(http.StatusConflict, Error{"approvers can only name other active teachers as deputies"})
+		return
+	}
+	assignment, err := db.CreateDeputyAssignment(mongo.DeputyAssignment{
+		UUID:      uuidG.NewString(),
+		Approver:  approver.Short,
+		Deputy:    deputy.Short,
+		From:      req.From,
+		Till:      req.Till,
+		CreatedAt: time.Now(),
+		CreatedBy: requester.Short,
+	})
+	if err != nil {
+		respondError(con, err, "deputy assignment")
+		return
+	}
+	auditDeputyAssignment(con, db, mongo.AuditDeputyCreated, requester, assignment)
+	con.JSON(http.StatusOK, assignment)
+}
+
+// GetDeputies represents the get deputies endpoint
+// @Summary Lists the deputy assignments of a teacher
+// @Description Returns the deputy assignments a teacher made as approver or received as deputy, including ended and revoked ones, the newest first
+// @ID get-deputies
+// @Produce json
+// @Param Authorization header string true "Access Token" default(Bearer <Add access token here>)
+// @Param short query string false "Short name of the teacher, only administrators may list the deputy assignments of other teachers"
+// @Success 200 {array} db.DeputyAssignment
+// @Failure 401 {object} Error
+// @Failure 500 {object} Error
+// @Failure 503 {object} Error
+// @Router /getDeputies [get]
+func GetDeputies(con *gin.Context) {
+	auth, err := ExtractTokenMeta(con.Request)
+	if err != nil {
+		con.JSON(http.StatusUnauthorized, Error{"you are not logged in"})
+		return
+	}
+	db := mongo.MongoDatabaseConnector{}
+	if err := db.Connect(con.Request.Context()); err != nil {
+		respondError(con, err, "database")
+		return
+	}
+	defer db.Close()
+	requester, err := db.GetTeacherByShort(auth.Username)
+	if err != nil {
+		respondError(con, err, "teacher")
+		return
+	}
+	short := strings.ToLower(con.Request.URL.Query().Get("short"))
+	if short == "" {
+		short = requester.Short
+	}
+	if short != requester.Short && !(requester.Administration || requester.SuperUser) {
+		con.JSON(http.StatusUnauthorized, Error{"unauthorized"})
+		return
+	}
+	assignments, err := db.GetDeputyAssignments(short)
+	if err != nil {
+		respondError(con, err, "deputy assignments")
+		return
+	}
+	con.JSON(http.StatusOK, assignments)
+}
+
+// RevokeDeputy represents the revoke deputy endpoint
+// @Summary Revokes a deputy assignment
+// @Description Ends a deputy assignment immediately, it can be revoked by the approver, the deputy and administrators
+// @ID revoke-deputy
+// @Produce json
+// @Param Authorization header string true "Access Token" default(Bearer <Add access token here>)
+// @Param uuid query string true "UUID of the deputy assignment"
+// @Success 200 {object} Information
+// @Failure 401 {object} Error
+// @Failure 404 {object} Error
+// @Failure 422 {object} Error
+// @Failure 500 {object} Error
+// @Failure 503 {object} Error
+// @Router /revokeDeputy [delete]
+func RevokeDeputy(con *gin.Context) {
+	uuid := con.Request.URL.Query().Get("uuid")
+	if uuid == "" {
+		con.JSON(http.StatusUnprocessableEntity, Error{"invalid request structure provided"})
+		return
+	}
+	auth, err := ExtractTokenMeta(con.Request)
+	if err != nil {
+		con.JSON(http.StatusUnauthorized, Error{"you are not logged in"})
+		return
+	}
+	db := mongo.MongoDatabaseConnector{}
+	if err := db.Connect(con.Request.Context()); err != nil {
+		respondError(con, err, "database")
+		return
+	}
+	defer db.Close()
+	requester, err := db.GetTeacherByShort(auth.Username)
+	if err != nil {
+		respondError(con, err, "teacher")
+		return
+	}
+	assignment, err := db.GetDeputyAssignment(uuid)
+	if err != nil {
+		respondError(con, err, "deputy assignment")
+		return
+	}
+	if !(requester.Short == assignment.Approver || requester.Short == assignment.Deputy || requester.Administration || requester.SuperUser) {
+		con.JSON(http.StatusUnauthorized, Error{"unauthorized"})
+		return
+	}
+	if err := db.RevokeDeputyAssignment(uuid); err != nil {
+		respondError(con, err, "deputy assignment")
+		return
+	}
+	auditDeputyAssignment(con, db, mongo.AuditDeputyRevoked, requester, assignment)
+	con.JSON(http.StatusOK, Information{"deputy assignment revoked"})
+}
+
+// requireAdministration answers the request with 401 unless the requesting teacher has administration or super user rights
+// returns the requesting teacher and whether the request may continue
+func requireAdministration(con *gin.Context) (mongo.Teacher, bool) {
+	auth, err := ExtractTokenMeta(con.Request)
+	if err != nil {
+		con.JSON(http.StatusUnauthorized, Error{"you are not logged in"})
+		return mongo.Teacher{}, false
+	}
+	db := mongo.MongoDatabaseConnector{}
+	if err := db.Connect(con.Request.Context()); err != nil {
+		respondError(con, err, "database")
+		return mongo.Teacher{}, false
+	}
+	defer db.Close()
+	requester, err := db.GetTeacherByShort(auth.Username)
+	if err != nil {
+		respondError(con, err, "teacher")
+		return mongo.Teacher{}, false
+	}
+	if !(requester.Administration || requester.SuperUser) {
+		con.JSON(http.StatusUnauthorized, Error{"unauthorized"})
+		return mongo.Teacher{}, false
+	}
+	return requester, true
+}
+
+// UpdateTeacherInformation represents the update teacher information endpoint
+// @Summary Updates the information of an existing teacher
+// @Description Updates a teacher identified by a uuid with the data in the body in the system
+// @ID update-teacher-information
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Access Token" default(Bearer <Add access token here>)
+// @Param teacher_information body rest.TeacherInformation true "The teacher information data to set"
+// @Param uuid query string true "Identifier of the teacher to update"
+// @Success 200 {object} Information
+// @Failure 401 {object} Error
+// @Failure 404 {object} Error
+// @Failure 422 {object} Error
+// @Failure 500 {object} Error
+// @Failure 503 {object} Error
+// @Router /updateTeacherInformation [put]
+func UpdateTeacherInformation(con *gin.Context) {
+	ti := TeacherInformation{}
+	if err := con.ShouldBindJSON(&ti); err != nil {
+		con.JSON(http.StatusUnprocessableEntity, Error{"invalid request structure provided"})
+		return
+	}
+	auth, err := ExtractTokenMeta(con.Request)
+	if err != nil {
+		con.JSON(http.StatusUnauthorized, Error{"you are not logged in"})
+		return
+	}
+	db := mongo.MongoDatabaseConnector{}
+	if err := db.Connect(con.Request.Context()); err != nil {
+		respondError(con, err, "database")
+		return
+	}
+	defer db.Close()
+	query := con.Request.URL.Query()
+	uuid := query.Get("uuid")
+	if query.Get("uuid") == "" {
+		con.JSON(http.StatusUnprocessableEntity, Error{"invalid request structure provided"})
+		return
+	}
+	requestTeacher, err := db.GetTeacherByShort(auth.Username)
+	if err != nil {
+		respondError(con, err, "teacher")
+		return
+	}
+	teacherToUpdate, err := db.GetTeacherByUUID(uuid)
+	if err != nil {
+		respondError(con, err, "teacher")
+		return
+	}
+	if !(requestTeacher.UUID == teacherToUpdate.UUID) {
+		con.JSON(http.StatusUnauthorized, Error{"teacher are only allowed to update themselves"})
+		return
+	}
+	teacherToUpdate.Degree = ti.Degree
 	teacherToUpdate.Title = ti.Title
 	teacherToUpdate.Departments = ti.Departments
 	teacherToUpdate.Group = ti.Group
@@ -433,24 +1603,401 @@ func UpdateTeacherInformation(con *gin.Context) {
 	teacherToUpdate.StartingAddresses = ti.StartingAddresses
 	teacherToUpdate.TripGoals = ti.TripGoals
 	teacherToUpdate.Untis = ti.Untis
-	if db.UpdateTeacher(uuid, teacherToUpdate) {
-		con.JSON(http.StatusOK, Information{"success; teacher updated"})
-	} else {
-		con.JSON(http.StatusInternalServerError, Error{"error; teacher not updated"})
+	if err := db.UpdateTeacher(uuid, teacherToUpdate); err != nil {
+		respondError(con, err, "teacher")
+		return
+	}
+	con.JSON(http.StatusOK, Information{"success; teacher updated"})
+}
+
+// GetTeachers represents the get teachers endpoint
+// @Summary Lists the teachers
+// @Description Returns one page of the teachers ordered by their name, e.g. to pick the companions of a school event
+// @Description The search matches teachers whose short name, name, untis abbrevation or mail address contains every word of it
+// @Description Deactivated teachers and service principals are only listed if they are requested
+// @ID get-teachers
+// @Produce json
+// @Param Authorization header string true "Access Token" default(Bearer <Add access token here>)
+// @Param search query string false "Words the teachers have to match"
+// @Param department query string false "Only list teachers of this department"
+// @Param provider query string false "Only list teachers of this provider" Enums(ldap, oidc, local, service)
+// @Param include_deactivated query bool false "List deactivated teachers too"
+// @Param page query int false "Page to return, starting at 1" default(1)
+// @Param per_page query int false "Amount of teachers per page" default(50)
+// @Success 200 {array} db.Teacher
+// @Header 200 {int} X-Total-Count "Total amount of matching teachers"
+// @Header 200 {int} X-Page "Returned page"
+// @Header 200 {int} X-Per-Page "Amount of teachers per page"
+// @Failure 401 {object} Error
+// @Failure 422 {object} Error
+// @Failure 500 {object} Error
+// @Failure 503 {object} Error
+// @Router /teachers [get]
+func GetTeachers(con *gin.Context) {
+	query := con.Request.URL.Query()
+	page, perPage, err := parsePaging(query)
+	if err != nil {
+		con.JSON(http.StatusUnprocessableEntity, Error{err.Error()})
+		return
+	}
+	q := mongo.TeacherQuery{
+		Search:     query.Get("search"),
+		Department: query.Get("department"),
+		Provider:   query.Get("provider"),
+		Skip:       (page - 1) * perPage,
+		Limit:      perPage,
+	}
+	switch q.Provider {
+	case "", mongo.LDAPProvider, mongo.OIDCProvider, mongo.LocalProvider, mongo.ServiceProvider:
+	default:
+		con.JSON(http.StatusUnprocessableEntity, Error{"invalid provider: " + q.Provider})
+		return
+	}
+	if value := query.Get("include_deactivated"); value != "" {
+		if q.IncludeDeactivated, err = strconv.ParseBool(value); err != nil {
+			con.JSON(http.StatusUnprocessableEntity, Error{"invalid include_deactivated: " + value})
+			return
+		}
+	}
+	if _, err := ExtractTokenMeta(con.Request); err != nil {
+		con.JSON(http.StatusUnauthorized, Error{"you are not logged in"})
+		return
+	}
+	db := mongo.MongoDatabaseConnector{}
+	if err := db.Connect(con.Request.Context()); err != nil {
+		respondError(con, err, "database")
+		return
+	}
+	defer db.Close()
+	teachers, total, err := db.QueryTeachers(q)
+	if err != nil {
+		respondError(con, err, "teachers")
+		return
+	}
+	writePaging(con, total, page, perPage)
+	con.JSON(http.StatusOK, teachers)
+}
+
+// CreateTeacher represents the create teacher endpoint
+// @Summary Creates a teacher
+// @Description Creates a teacher before their first login, e.g. to add them as companion
+// @Description Local accounts are created through /setLocalAccount and service principals through /createAPIKey
+// @ID create-teacher
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Access Token" default(Bearer <Add access token here>)
+// @Param teacher body TeacherData true "Data of the teacher"
+// @Success 200 {object} db.Teacher
+// @Failure 401 {object} Error
+// @Failure 409 {object} Error
+// @Failure 422 {object} Error
+// @Failure 500 {object} Error
+// @Failure 503 {object} Error
+// @Router /createTeacher [post]
+func CreateTeacher(con *gin.Context) {
+	data := TeacherData{}
+	if err := con.ShouldBindJSON(&data); err != nil || strings.TrimSpace(data.Short) == "" {
+		con.JSON(http.StatusUnprocessableEntity, Error{"invalid request structure provided"})
+		return
+	}
+	switch data.Provider {
+	case "":
+		data.Provider = mongo.LDAPProvider
+	case mongo.LDAPProvider, mongo.OIDCProvider:
+	default:
+		con.JSON(http.StatusUnprocessableEntity, Error{"teachers can only be created for the providers ldap and oidc"})
+		return
+	}
+	if _, ok := requireAdministration(con); !ok {
+		return
+	}
+	short := strings.ToLower(strings.TrimSpace(data.Short))
+	if data.Longname == "" && data.Provider == mongo.LDAPProvider {
+		// the name is completed from the directory if possible, otherwise it is left for the first login
+		if longname, err := ldap.GetLongName(short); err == nil {
+			data.Longname = longname
+		}
+	}
+	db := mongo.MongoDatabaseConnector{}
+	if err := db.Connect(con.Request.Context()); err != nil {
+		respondError(con, err, "database")
+		return
+	}
+	defer db.Close()
+	teacher := mongo.Teacher{UUID: uuidG.NewString(), Short: short, Provider: data.Provider}
+	applyTeacherData(&teacher, data)
+	teacher, err := db.CreateTeacher(teacher)
+	if err != nil {
+		respondError(con, err, "teacher")
+		return
+	}
+	con.JSON(http.StatusOK, teacher)
+}
+
+// UpdateTeacher represents the update teacher endpoint
+// @Summary Updates a teacher
+// @Description Replaces the data of a teacher, applications filed under the old name of the teacher are rewritten if the name changes
+// @Description Short name and provider can't be changed, duplicates are merged through /mergeTeachers
+// @Description Teachers holding roles the requesting administrator lacks (e.g. super users) can't be updated by them
+// @ID update-teacher
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Access Token" default(Bearer <Add access token here>)
+// @Param uuid query string true "UUID of the teacher"
+// @Param teacher body TeacherData true "Data of the teacher"
+// @Success 200 {object} db.Teacher
+// @Failure 401 {object} Error
+// @Failure 404 {object} Error
+// @Failure 422 {object} Error
+// @Failure 500 {object} Error
+// @Failure 503 {object} Error
+// @Router /updateTeacher [put]
+func UpdateTeacher(con *gin.Context) {
+	data := TeacherData{}
+	uuid := con.Request.URL.Query().Get("uuid")
+	if err := con.ShouldBindJSON(&data); err != nil || uuid == "" {
+		con.JSON(http.StatusUnprocessableEntity, Error{"invalid request structure provided"})
+		return
+	}
+	requester, ok := requireAdministration(con)
+	if !ok {
+		return
+	}
+	db := mongo.MongoDatabaseConnector{}
+	if err := db.Connect(con.Request.Context()); err != nil {
+		respondError(con, err, "database")
+		return
+	}
+	defer db.Close()
+	teacher, err := db.GetTeacherByUUID(uuid)
+	if err != nil {
+		respondError(con, err, "teacher")
+		return
+	}
+	if !requester.Permissions().Covers(teacher.Permissions()) {
+		con.JSON(http.StatusUnauthorized, Error{"teachers holding roles you lack can't be updated by you"})
+		return
+	}
+	if data.Short != "" && !strings.EqualFold(strings.TrimSpace(data.Short), teacher.Short) ||
+		data.Provider != "" && data.Provider != teacher.Provider {
+		con.JSON(http.StatusUnprocessableEntity, Error{"short name and provider can't be changed, merge the teachers instead"})
+		return
+	}
+	old := teacher
+	applyTeacherData(&teacher, data)
+	if err := db.UpdateTeacher(uuid, teacher); err != nil {
+		respondError(con, err, "teacher")
+		return
+	}
+	if old.Longname != teacher.Longname {
+		if _, err := db.ReplaceParticipant(old, teacher); err != nil {
+			respondError(con, err, "applications")
+			return
+		}
+	}
+	con.JSON(http.StatusOK, teacher)
+}
+
+// DeactivateTeacher represents the deactivate teacher endpoint
+// @Summary Deactivates a teacher
+// @Description Deactivates a teacher who left the school, the teacher is logged out and can't log in anymore but their applications are kept
+// @Description Teachers holding roles the requesting administrator lacks (e.g. super users) can't be deactivated by them
+// @ID deactivate-teacher
+// @Produce json
+// @Param Authorization header string true "Access Token" default(Bearer <Add access token here>)
+// @Param uuid query string true "UUID of the teacher"
+// @Success 200 {object} db.Teacher
+// @Failure 401 {object} Error
+// @Failure 404 {object} Error
+// @Failure 409 {object} Error
+// @Failure 422 {object} Error
+// @Failure 500 {object} Error
+// @Failure 503 {object} Error
+// @Router /deactivateTeacher [post]
+func DeactivateTeacher(con *gin.Context) {
+	setTeacherActive(con, false)
+}
+
+// ReactivateTeacher represents the reactivate teacher endpoint
+// @Summary Reactivates a teacher
+// @Description Reactivates a deactivated teacher, so the teacher can log in again
+// @Description Teachers holding roles the requesting administrator lacks can't be reactivated by them
+// @ID reactivate-teacher
+// @Produce json
+// @Param Authorization header string true "Access Token" default(Bearer <Add access token here>)
+// @Param uuid query string true "UUID of the teacher"
+// @Success 200 {object} db.Teacher
+// @Failure 401 {object} Error
+// @Failure 404 {object} Error
+// @Failure 409 {object} Error
+// @Failure 422 {object} Error
+// @Failure 500 {object} Error
+// @Failure 503 {object} Error
+// @Router /reactivateTeacher [post]
+func ReactivateTeacher(con *gin.Context) {
+	setTeacherActive(con, true)
+}
+
+// MergeTeachers represents the merge teachers endpoint
+// @Summary Merges a duplicate teacher into another one
+// @Description Rewrites the applications the duplicate takes part in to the other teacher, copies values the other teacher lacks, unites their roles and deletes the duplicate
+// @Description Filed business trip applications and travel invoices are kept unchanged
+// @Description Duplicates holding roles the requesting administrator lacks (e.g. super users) can't be merged by them
+// @ID merge-teachers
+// @Produce json
+// @Param Authorization header string true "Access Token" default(Bearer <Add access token here>)
+// @Param source query string true "UUID of the duplicate, which is deleted"
+// @Param target query string true "UUID of the teacher which is kept"
+// @Success 200 {object} MergeResult
+// @Failure 401 {object} Error
+// @Failure 404 {object} Error
+// @Failure 409 {object} Error
+// @Failure 422 {object} Error
+// @Failure 500 {object} Error
+// @Failure 503 {object} Error
+// @Router /mergeTeachers [post]
+func MergeTeachers(con *gin.Context) {
+	query := con.Request.URL.Query()
+	sourceUUID, targetUUID := query.Get("source"), query.Get("target")
+	if sourceUUID == "" || targetUUID == "" || sourceUUID == targetUUID {
+		con.JSON(http.StatusUnprocessableEntity, Error{"invalid request structure provided"})
+		return
+	}
+	requester, ok := requireAdministration(con)
+	if !ok {
+		return
+	}
+	db := mongo.MongoDatabaseConnector{}
+	if err := db.Connect(con.Request.Context()); err != nil {
+		respondError(con, err, "database")
+		return
+	}
+	defer db.Close()
+	source, err := db.GetTeacherByUUID(sourceUUID)
+	if err != nil {
+		respondError(con, err, "teacher")
+		return
+	}
+	target, err := db.GetTeacherByUUID(targetUUID)
+	if err != nil {
+		respondError(con, err, "teacher")
+		return
+	}
+	if source.UUID == requester.UUID {
+		con.JSON(http.StatusConflict, Error{"teachers can't merge themselves into another teacher"})
+		return
+	}
+	if source.Provider == mongo.ServiceProvider || target.Provider == mongo.ServiceProvider {
+		con.JSON(http.StatusConflict, Error{"service principals can't be merged"})
+		return
+	}
+	if !requester.Permissions().Covers(source.Permissions()) {
+		con.JSON(http.StatusUnauthorized, Error{"teachers holding roles you lack can't be merged by you"})
+		return
 	}
+	merged, rewritten, err := db.MergeTeachers(source, target)
+	if err != nil {
+		respondError(con, err, "teacher")
+		return
+	}
+	DeleteTokensOf(source.Short)
+	con.JSON(http.StatusOK, MergeResult{Teacher: merged, Applications: rewritten})
+}
+
+// setTeacherActive deactivates or reactivates the teacher with the uuid of the request
+func setTeacherActive(con *gin.Context, active bool) {
+	uuid := con.Request.URL.Query().Get("uuid")
+	if uuid == "" {
+		con.JSON(http.StatusUnprocessableEntity, Error{"invalid request structure provided"})
+		return
+	}
+	requester, ok := requireAdministration(con)
+	if !ok {
+		return
+	}
+	if !active && requester.UUID == uuid {
+		con.JSON(http.StatusConflict, Error{"teachers can't deactivate themselves"})
+		return
+	}
+	db := mongo.MongoDatabaseConnector{}
+	if err := db.Connect(con.Request.Context()); err != nil {
+		respondError(con, err, "database")
+		return
+	}
+	defer db.Close()
+	teacher, err := db.GetTeacherByUUID(uuid)
+	if err != nil {
+		respondError(con, err, "teacher")
+		return
+	}
+	if !requester.Permissions().Covers(teacher.Permissions()) {
+		con.JSON(http.StatusUnauthorized, Error{"teachers holding roles you lack can't be deactivated or reactivated by you"})
+		return
+	}
+	if active && teacher.DeactivatedAt.IsZero() {
+		con.JSON(http.StatusConflict, Error{"teacher is already active"})
+		return
+	}
+	if !active && !teacher.DeactivatedAt.IsZero() {
+		con.JSON(http.StatusConflict, Error{"teacher is already deactivated"})
+		return
+	}
+	teacher.DeactivatedAt = time.Time{}
+	if !active {
+		teacher.DeactivatedAt = time.Now()
+	}
+	if err := db.UpdateTeacher(uuid, teacher); err != nil {
+		respondError(con, err, "teacher")
+		return
+	}
+	if !active {
+		DeleteTokensOf(teacher.Short)
+	}
+	con.JSON(http.StatusOK, teacher)
+}
+
+// applyTeacherData sets the data administrators may change of the teacher, an empty name keeps the current one
+func applyTeacherData(teacher *mongo.Teacher, data TeacherData) {
+	if data.Longname != "" {
+		teacher.Longname = data.Longname
+	}
+	teacher.Untis = data.Untis
+	teacher.Mail = data.Mail
+	teacher.Departments = splitList(data.Departments)
+	teacher.Degree = data.Degree
+	teacher.Title = data.Title
+	teacher.Staffnr = data.Staffnr
+	teacher.Group = data.Group
 }
 
 // GetActiveApplications represents the get active applications endpoint
 // @Summary Returns all active applications
-// @Description Returns all active applications as a list of applications
+// @Description Returns one page of the active applications matching the given filters, sorted by the given field
 // @ID get-all-active-applications
 // @Accept json
 // @Produce json
 // @Param Authorization header string true "Access Token" default(Bearer <Add access token here>)
 // @Param username query string false "Filter to only show applications of this teacher"
+// @Param page query int false "Page to return, starting at 1" default(1)
+// @Param per_page query int false "Amount of applications per page (at most 200)" default(50)
+// @Param sort query string false "Field to sort by" Enums(start_time, last_changed, name) default(last_changed)
+// @Param order query string false "Order of the sort" Enums(asc, desc) default(desc)
+// @Param kind query []int false "Filter for kinds of applications"
+// @Param progress query []int false "Filter for progress states"
+// @Param from query string false "Only show applications ending after this time (RFC 3339 or 2006-01-02)"
+// @Param till query string false "Only show applications starting before this time (RFC 3339 or 2006-01-02)"
+// @Param class query []string false "Filter for participating classes"
+// @Param department query string false "Only show applications of teachers of this department"
+// @Param fields query []string false "Top level fields to return or 'summary' for the summary representation"
 // @Success 200 {array} db.Application
+// @Header 200 {int} X-Total-Count "Total amount of matching applications"
+// @Header 200 {int} X-Page "The returned page"
+// @Header 200 {int} X-Per-Page "Amount of applications per page"
 // @Failure 401 {object} Error
+// @Failure 404 {object} Error
+// @Failure 422 {object} Error
 // @Failure 500 {object} Error
+// @Failure 503 {object} Error
 // @Router /getActiveApplications [get]
 func GetActiveApplications(con *gin.Context) {
 	auth, err := ExtractTokenMeta(con.Request)
@@ -458,107 +2005,77 @@ func GetActiveApplications(con *gin.Context) {
 		con.JSON(http.StatusUnauthorized, Error{"you are not logged in"})
 		return
 	}
+	req, err := parseListRequest(con)
+	if err != nil {
+		con.JSON(http.StatusUnprocessableEntity, Error{err.Error()})
+		return
+	}
 	db := mongo.MongoDatabaseConnector{}
-	if !db.Connect() {
-		con.JSON(http.StatusInternalServerError, Error{"database didn't respond"})
+	if err := db.Connect(con.Request.Context()); err != nil {
+		respondError(con, err, "database")
 		return
 	}
 	defer db.Close()
-	_ = con.Request.ParseForm()
-	query := con.Request.URL.Query()
-	_, applyFilter := con.Request.Form["username"]
-	filter := query.Get("username")
-	requestTeacher := db.GetTeacherByShort(auth.Username)
-	if !(requestTeacher.Administration || requestTeacher.AV || requestTeacher.SuperUser || requestTeacher.PEK || (applyFilter && requestTeacher.Short == filter)) {
-		con.JSON(http.StatusUnauthorized, "unauthorized")
+	requestTeacher, err := db.GetTeacherByShort(auth.Username)
+	if err != nil {
+		respondError(con, err, "teacher")
 		return
 	}
-	applications := db.GetActiveApplications()
-	var teacher mongo.Teacher
-	if db.DoesTeacherExistByShort(filter) {
-		teacher = db.GetTeacherByShort(filter)
-	} else {
-		client := untis.GetClient(auth.Username)
-		longname, err := ldap.GetLongName(client.Username, client.Password, filter)
-		if err != nil {
-			con.JSON(http.StatusInternalServerError, Error{"couldn't read longname of new teacher"})
-			return
-		}
-		err = client.Authenticate()
-		defer func() {
-			err = client.Close()
-			if err != nil {
-				con.JSON(http.StatusInternalServerError, Error{"couldn't logout out off untis API"})
-			}
-		}()
-		if err != nil {
-			con.JSON(http.StatusInternalServerError, Error{"couldn't authenticate with untis API"})
+	allowed := requestTeacher.Administration || requestTeacher.AV || requestTeacher.SuperUser || requestTeacher.PEK || (req.FilterUser && requestTeacher.Short == req.Username)
+	if !allowed && req.FilterUser {
+		// delegates may list the applications of the teachers who delegated to them
+		if allowed, err = delegatedBy(db, requestTeacher, req.Username); err != nil {
+			respondError(con, err, "delegations")
 			return
 		}
-		id, err := client.ResolveTeacherID(longname)
-		if err != nil {
-			con.JSON(http.StatusInternalServerError, Error{"couldn't resolve untis id of new teacher"})
-			return
-		}
-		untisAb, err := client.ResolveTeachers([]int{id})
+	}
+	if !allowed {
+		con.JSON(http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	if req.FilterUser {
+		teacher, err := resolveTeacher(db, auth.Username, req.Username)
 		if err != nil {
-			con.JSON(http.StatusInternalServerError, Error{"couldn't resolve untis abbrevation of new teacher"})
-			return
-		}
-		teacher = mongo.Teacher{
-			UUID:           uuidG.NewString(),
-			Short:          filter,
-			Longname:       longname,
-			SuperUser:      false,
-			AV:             false,
-			Administration: false,
-			PEK:            false,
-			Untis:          untisAb[0],
-		}
-		if !db.CreateTeacher(teacher) {
-			con.JSON(http.StatusInternalServerError, Error{"couldn't create new teacher based on this"})
+			respondError(con, err, "teacher")
 			return
 		}
+		req.Query.Filter.Participant = &teacher
 	}
-
-	if applyFilter {
-		res := make([]mongo.Application, 0)
-		for _, app := range applications {
-			if app.Kind == mongo.SchoolEvent {
-				teachers := app.SchoolEventDetails.Teachers
-				for _, t := range teachers {
-					if t.Shortname == teacher.Short {
-						res = append(res, app)
-						break
-					}
-				}
-			} else if app.Kind == mongo.Training {
-				if app.TrainingDetails.Filer == teacher.Longname {
-					res = append(res, app)
-				}
-			} else if app.Kind == mongo.OtherReason {
-				if app.OtherReasonDetails.Filer == teacher.Longname {
-					res = append(res, app)
-				}
-			}
-		}
-		con.JSON(http.StatusOK, res)
+	if !req.restrictProgress(mongo.ActiveProgress) {
+		writeApplications(con, req, nil, 0)
 		return
 	}
-	con.JSON(http.StatusOK, applications)
+	respondApplications(con, db, req)
 }
 
 // GetAllApplications represents the get all applications endpoint
 // @Summary Returns all applications
-// @Description Returns all applications as a list of applications
+// @Description Returns one page of the applications matching the given filters, sorted by the given field
 // @ID get-all-applications
 // @Accept json
 // @Produce json
 // @Param Authorization header string true "Access Token" default(Bearer <Add access token here>)
 // @Param username query string false "Filter to only show applications of this teacher"
+// @Param page query int false "Page to return, starting at 1" default(1)
+// @Param per_page query int false "Amount of applications per page (at most 200)" default(50)
+// @Param sort query string false "Field to sort by" Enums(start_time, last_changed, name) default(last_changed)
+// @Param order query string false "Order of the sort" Enums(asc, desc) default(desc)
+// @Param kind query []int false "Filter for kinds of applications"
+// @Param progress query []int false "Filter for progress states"
+// @Param from query string false "Only show applications ending after this time (RFC 3339 or 2006-01-02)"
+// @Param till query string false "Only show applications starting before this time (RFC 3339 or 2006-01-02)"
+// @Param class query []string false "Filter for participating classes"
+// @Param department query string false "Only show applications of teachers of this department"
+// @Param fields query []string false "Top level fields to return or 'summary' for the summary representation"
 // @Success 200 {array} db.Application
+// @Header 200 {int} X-Total-Count "Total amount of matching applications"
+// @Header 200 {int} X-Page "The returned page"
+// @Header 200 {int} X-Per-Page "Amount of applications per page"
 // @Failure 401 {object} Error
+// @Failure 404 {object} Error
+// @Failure 422 {object} Error
 // @Failure 500 {object} Error
+// @Failure 503 {object} Error
 // @Router /getAllApplications [get]
 func GetAllApplications(con *gin.Context) {
 	auth, err := ExtractTokenMeta(con.Request)
@@ -566,93 +2083,43 @@ func GetAllApplications(con *gin.Context) {
 		con.JSON(http.StatusUnauthorized, Error{"you are not logged in"})
 		return
 	}
+	req, err := parseListRequest(con)
+	if err != nil {
+		con.JSON(http.StatusUnprocessableEntity, Error{err.Error()})
+		return
+	}
 	db := mongo.MongoDatabaseConnector{}
-	if !db.Connect() {
-		con.JSON(http.StatusInternalServerError, Error{"database didn't respond"})
+	if err := db.Connect(con.Request.Context()); err != nil {
+		respondError(con, err, "database")
 		return
 	}
 	defer db.Close()
-	_ = con.Request.ParseForm()
-	query := con.Request.URL.Query()
-	_, applyFilter := con.Request.Form["username"]
-	filter := query.Get("username")
-	requestTeacher := db.GetTeacherByShort(auth.Username)
-	if !(requestTeacher.Administration || requestTeacher.AV || requestTeacher.SuperUser || requestTeacher.PEK || (applyFilter && requestTeacher.Short == filter)) {
-		con.JSON(http.StatusUnauthorized, Error{"unauthorized"})
+	requestTeacher, err := db.GetTeacherByShort(auth.Username)
+	if err != nil {
+		respondError(con, err, "teacher")
 		return
 	}
-	applications := db.GetAllApplications()
-	var teacher mongo.Teacher
-	if db.DoesTeacherExistByShort(filter) {
-		teacher = db.GetTeacherByShort(filter)
-	} else {
-		client := untis.GetClient(auth.Username)
-		longname, err := ldap.GetLongName(client.Username, client.Password, filter)
-		if err != nil {
-			con.JSON(http.StatusInternalServerError, Error{"couldn't read longname of new teacher"})
-			return
-		}
-		err = client.Authenticate()
-		defer func() {
-			err = client.Close()
-			if err != nil {
-				con.JSON(http.StatusInternalServerError, Error{"couldn't logout out off untis API"})
-			}
-		}()
-		if err != nil {
-			con.JSON(http.StatusInternalServerError, Error{"couldn't authenticate with untis API"})
-			return
-		}
-		id, err := client.ResolveTeacherID(longname)
-		if err != nil {
-			con.JSON(http.StatusInternalServerError, Error{"couldn't resolve untis id of new teacher"})
+	allowed := requestTeacher.Administration || requestTeacher.AV || requestTeacher.SuperUser || requestTeacher.PEK || (req.FilterUser && requestTeacher.Short == req.Username)
+	if !allowed && req.FilterUser {
+		// delegates may list the applications of the teachers who delegated to them
+		if allowed, err = delegatedBy(db, requestTeacher, req.Username); err != nil {
+			respondError(con, err, "delegations")
 			return
 		}
-		untisAb, err := client.ResolveTeachers([]int{id})
+	}
+	if !allowed {
+		con.JSON(http.StatusUnauthorized, Error{"unauthorized"})
+		return
+	}
+	if req.FilterUser {
+		teacher, err := resolveTeacher(db, auth.Username, req.Username)
 		if err != nil {
-			con.JSON(http.StatusInternalServerError, Error{"couldn't resolve untis abbrevation of new teacher"})
+			respondError(con, err, "teacher")
 			return
 		}
-		teacher = mongo.Teacher{
-			UUID:           uuidG.NewString(),
-			Short:          filter,
-			Longname:       longname,
-			SuperUser:      false,
-			AV:             false,
-			Administration: false,
-			PEK:            false,
-			Untis:          untisAb[0],
-		}
-		if !db.CreateTeacher(teacher) {
-			con.JSON(http.StatusInternalServerError, Error{"couldn't create new teacher based on this"})
-			return
-		}
-	}
-	if applyFilter {
-		res := make([]mongo.Application, 0)
-		for _, app := range applications {
-			if app.Kind == mongo.SchoolEvent {
-				teachers := app.SchoolEventDetails.Teachers
-				for _, t := range teachers {
-					if t.Shortname == teacher.Short {
-						res = append(res, app)
-						break
-					}
-				}
-			} else if app.Kind == mongo.Training {
-				if app.TrainingDetails.Filer == teacher.Longname {
-					res = append(res, app)
-				}
-			} else if app.Kind == mongo.OtherReason {
-				if app.OtherReasonDetails.Filer == teacher.Longname {
-					res = append(res, app)
-				}
-			}
-		}
-		con.JSON(http.StatusOK, res)
-		return
+		req.Query.Filter.Participant = &teacher
 	}
-	con.JSON(http.StatusOK, applications)
+	respondApplications(con, db, req)
 }
 
 // GetNews represents the get news endpoint
@@ -664,7 +2131,9 @@ func GetAllApplications(con *gin.Context) {
 // @Param Authorization header string true "Access Token" default(Bearer <Add access token here>)
 // @Success 200 {array} News
 // @Failure 401 {object} Error
+// @Failure 404 {object} Error
 // @Failure 500 {object} Error
+// @Failure 503 {object} Error
 // @Router /getNews [get]
 func GetNews(con *gin.Context) {
 	auth, err := ExtractTokenMeta(con.Request)
@@ -673,38 +2142,23 @@ func GetNews(con *gin.Context) {
 		return
 	}
 	db := mongo.MongoDatabaseConnector{}
-	if !db.Connect() {
-		con.JSON(http.StatusInternalServerError, Error{"database didn't respond"})
+	if err := db.Connect(con.Request.Context()); err != nil {
+		respondError(con, err, "database")
 		return
 	}
 	defer db.Close()
-	applications := db.GetActiveApplications()
-	teacher := db.GetTeacherByShort(auth.Username)
-	res := make([]mongo.Application, 0)
-	for _, app := range applications {
-		if app.Kind == mongo.SchoolEvent {
-			teachers := app.SchoolEventDetails.Teachers
-			for _, t := range teachers {
-				if t.Shortname == teacher.Short {
-					res = append(res, app)
-					break
-				}
-			}
-		} else if app.Kind == mongo.Training {
-			if app.TrainingDetails.Filer == teacher.Longname {
-				res = append(res, app)
-			}
-		} else if app.Kind == mongo.OtherReason {
-			if app.OtherReasonDetails.Filer == teacher.Longname {
-				res = append(res, app)
-			}
-		}
+	teacher, err := db.GetTeacherByShort(auth.Username)
+	if err != nil {
+		respondError(con, err, "teacher")
+		return
 	}
-	sort.Slice(res, func(i, j int) bool {
-		return res[i].LastChanged.After(res[j].LastChanged)
-	})
-	if len(res) > 10 {
-		res = res[0:10]
+	res, err := db.GetLatestApplications(mongo.ApplicationFilter{
+		Participant: &teacher,
+		Progress:    mongo.ActiveProgress,
+	}, NewsLimit)
+	if err != nil {
+		respondError(con, err, "applications")
+		return
 	}
 	news := make([]News, 0)
 	for _, app := range res {
@@ -720,99 +2174,414 @@ func GetNews(con *gin.Context) {
 // @Accept json
 // @Produce json
 // @Param Authorization header string true "Access Token" default(Bearer <Add access token here>)
-// @Param uuid query string true "The UUID of the specifying Application"
-// @Success 200 {object} db.Application
+// @Param uuid query string true "The UUID of the specifying Application"
+// @Success 200 {object} db.Application
+// @Failure 401 {object} Error
+// @Failure 404 {object} Error
+// @Failure 422 {object} Error
+// @Failure 500 {object} Error
+// @Failure 503 {object} Error
+// @Router /getApplication [get]
+func GetApplication(con *gin.Context) {
+	auth, err := ExtractTokenMeta(con.Request)
+	if err != nil {
+		con.JSON(http.StatusUnauthorized, Error{"you are not logged in"})
+		return
+	}
+	db := mongo.MongoDatabaseConnector{}
+	if err := db.Connect(con.Request.Context()); err != nil {
+		respondError(con, err, "database")
+		return
+	}
+	defer db.Close()
+	query := con.Request.URL.Query()
+	uuid := query.Get("uuid")
+	if query.Get("uuid") == "" {
+		con.JSON(http.StatusUnprocessableEntity, Error{"invalid request structure provided"})
+		return
+	}
+	requestTeacher, err := db.GetTeacherByShort(auth.Username)
+	if err != nil {
+		respondError(con, err, "teacher")
+		return
+	}
+	application, err := db.GetApplication(uuid)
+	if err != nil {
+		respondError(con, err, "application")
+		return
+	}
+	onBehalfOf, in, err := actingFor(db, application, requestTeacher)
+	if err != nil {
+		respondError(con, err, "delegations")
+		return
+	}
+	approvers, err := deputizedFor(db, requestTeacher)
+	if err != nil {
+		respondError(con, err, "deputies")
+		return
+	}
+	if !(in || isApprover(approvalRoles(requestTeacher, approvers))) {
+		con.JSON(http.StatusUnauthorized, Error{"unauthorized"})
+		return
+	}
+	if in {
+		auditOnBehalf(con, db, requestTeacher, onBehalfOf, application.UUID)
+	} else if !isApprover(approvalRoles(requestTeacher, nil)) {
+		auditAsDeputy(con, db, requestTeacher, approvers, application.UUID)
+	}
+	con.JSON(http.StatusOK, application)
+}
+
+// GetAdminApplications represents the get admin applications endpoint
+// @Summary Returns all admin applications
+// @Description Returns one page of the applications currently needing a review by an admin
+// @Description Applications in process are shown to av and administration, if APPROVAL_CHAIN is set they are routed along it instead and the following roles see them after APPROVAL_ESCALATION_TIMEOUT passed per position
+// @Description Deputies see the queue of the approvers they currently stand in for
+// @ID get-admin-applications
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Access Token" default(Bearer <Add access token here>)
+// @Param page query int false "Page to return, starting at 1" default(1)
+// @Param per_page query int false "Amount of applications per page (at most 200)" default(50)
+// @Param sort query string false "Field to sort by" Enums(start_time, last_changed, name) default(last_changed)
+// @Param order query string false "Order of the sort" Enums(asc, desc) default(desc)
+// @Param kind query []int false "Filter for kinds of applications"
+// @Param progress query []int false "Filter for progress states"
+// @Param from query string false "Only show applications ending after this time (RFC 3339 or 2006-01-02)"
+// @Param till query string false "Only show applications starting before this time (RFC 3339 or 2006-01-02)"
+// @Param class query []string false "Filter for participating classes"
+// @Param department query string false "Only show applications of teachers of this department"
+// @Param fields query []string false "Top level fields to return or 'summary' for the summary representation"
+// @Success 200 {array} db.Application
+// @Header 200 {int} X-Total-Count "Total amount of matching applications"
+// @Header 200 {int} X-Page "The returned page"
+// @Header 200 {int} X-Per-Page "Amount of applications per page"
+// @Failure 401 {object} Error
+// @Failure 404 {object} Error
+// @Failure 422 {object} Error
+// @Failure 500 {object} Error
+// @Failure 503 {object} Error
+// @Router /getAdminApplication [get]
+func GetAdminApplications(con *gin.Context) {
+	auth, err := ExtractTokenMeta(con.Request)
+	if err != nil {
+		con.JSON(http.StatusUnauthorized, Error{"you are not logged in"})
+		return
+	}
+	req, err := parseListRequest(con)
+	if err != nil {
+		con.JSON(http.StatusUnprocessableEntity, Error{err.Error()})
+		return
+	}
+	db := mongo.MongoDatabaseConnector{}
+	if err := db.Connect(con.Request.Context()); err != nil {
+		respondError(con, err, "database")
+		return
+	}
+	defer db.Close()
+	teacher, err := db.GetTeacherByShort(auth.Username)
+	if err != nil {
+		respondError(con, err, "teacher")
+		return
+	}
+	approvers, err := deputizedFor(db, teacher)
+	if err != nil {
+		respondError(con, err, "deputies")
+		return
+	}
+	roles := approvalRoles(teacher, approvers)
+	if !isApprover(roles) {
+		con.JSON(http.StatusUnauthorized, Error{"unauthorized"})
+		return
+	}
+	progress := []int{mongo.CostsInProcess}
+	if before, ok := escalatedBefore(roles); ok {
+		progress = append(progress, mongo.InProcess)
+		req.Query.Filter.EscalatedBefore = before
+	}
+	if !req.restrictProgress(progress) {
+		writeApplications(con, req, nil, 0)
+		return
+	}
+	if req.FilterUser {
+		filterTeacher, err := resolveTeacher(db, auth.Username, req.Username)
+		if err != nil {
+			respondError(con, err, "teacher")
+			return
+		}
+		req.Query.Filter.Participant = &filterTeacher
+	}
+	respondApplications(con, db, req)
+}
+
+// Search represents the search endpoint
+// @Summary Searches applications
+// @Description Searches the name, notes, destination, organizer, classes and teachers of all applications visible to the user, ranked by relevance
+// @Description Teachers without approving roles find the applications they or the teachers who delegated to them take part in
+// @ID search
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Access Token" default(Bearer <Add access token here>)
+// @Param q query string true "The search text, quoted phrases and negated terms (-term) are supported"
+// @Param limit query int false "Maximum amount of results (at most 100)" default(20)
+// @Success 200 {array} SearchHit
+// @Failure 401 {object} Error
+// @Failure 404 {object} Error
+// @Failure 422 {object} Error
+// @Failure 500 {object} Error
+// @Failure 503 {object} Error
+// @Router /search [get]
+func Search(con *gin.Context) {
+	auth, err := ExtractTokenMeta(con.Request)
+	if err != nil {
+		con.JSON(http.StatusUnauthorized, Error{"you are not logged in"})
+		return
+	}
+	query := con.Request.URL.Query()
+	text := strings.TrimSpace(query.Get("q"))
+	if text == "" {
+		con.JSON(http.StatusUnprocessableEntity, Error{"invalid request structure provided"})
+		return
+	}
+	limit := int64(SearchLimit)
+	if l := query.Get("limit"); l != "" {
+		limit, err = strconv.ParseInt(l, 10, 64)
+		if err != nil || limit < 1 || limit > MaxSearchLimit {
+			con.JSON(http.StatusUnprocessableEntity, Error{fmt.Sprintf("invalid limit, it has to be between 1 and %v: %v", MaxSearchLimit, l)})
+			return
+		}
+	}
+	db := mongo.MongoDatabaseConnector{}
+	if err := db.Connect(con.Request.Context()); err != nil {
+		respondError(con, err, "database")
+		return
+	}
+	defer db.Close()
+	requestTeacher, err := db.GetTeacherByShort(auth.Username)
+	if err != nil {
+		respondError(con, err, "teacher")
+		return
+	}
+	approvers, err := deputizedFor(db, requestTeacher)
+	if err != nil {
+		respondError(con, err, "deputies")
+		return
+	}
+	filter := mongo.ApplicationFilter{}
+	if !isApprover(approvalRoles(requestTeacher, approvers)) {
+		// the same rules as in GetApplication apply, teachers find their own applications and the ones of their delegators
+		delegators, err := delegatorsOf(db, requestTeacher)
+		if err != nil {
+			respondError(con, err, "delegations")
+			return
+		}
+		filter.AnyParticipant = append(delegators, requestTeacher)
+	}
+	results, err := db.SearchApplications(text, filter, limit)
+	if err != nil {
+		respondError(con, err, "applications")
+		return
+	}
+	terms := searchTerms(text)
+	hits := make([]SearchHit, 0, len(results))
+	for _, result := range results {
+		hits = append(hits, SearchHit{
+			Application: summarize(result.Application),
+			Score:       result.Score,
+			Highlights:  highlight(result.Application, terms),
+		})
+	}
+	con.JSON(http.StatusOK, hits)
+}
+
+// InvalidateUntisCache represents the invalidate untis cache endpoint
+// @Summary Invalidates the cached untis data
+// @Description Drops the cached teachers, rooms, classes, subjects and timegrid, so they are loaded from untis on the next request
+// @ID invalidate-untis-cache
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Access Token" default(Bearer <Add access token here>)
+// @Success 200 {object} Information
+// @Failure 401 {object} Error
+// @Failure 404 {object} Error
+// @Failure 500 {object} Error
+// @Failure 503 {object} Error
+// @Router /invalidateUntisCache [post]
+func InvalidateUntisCache(con *gin.Context) {
+	auth, err := ExtractTokenMeta(con.Request)
+	if err != nil {
+		con.JSON(http.StatusUnauthorized, Error{"you are not logged in"})
+		return
+	}
+	db := mongo.MongoDatabaseConnector{}
+	if err := db.Connect(con.Request.Context()); err != nil {
+		respondError(con, err, "database")
+		return
+	}
+	defer db.Close()
+	teacher, err := db.GetTeacherByShort(auth.Username)
+	if err != nil {
+		respondError(con, err, "teacher")
+		return
+	}
+	if !(teacher.Administration || teacher.SuperUser) {
+		con.JSON(http.StatusUnauthorized, Error{"unauthorized"})
+		return
+	}
+	untis.InvalidateMetadata()
+	untis.InvalidateTimegrid()
+	con.JSON(http.StatusOK, Information{"success; untis cache invalidated"})
+}
+
+// SyncTeachers represents the sync teachers endpoint
+// @Summary Synchronizes all teachers with the directory
+// @Description Updates the names, titles, departments, staff numbers, mail addresses and groups of all teachers from the directory and reports the teachers which don't exist there anymore
+// @ID sync-teachers
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Access Token" default(Bearer <Add access token here>)
+// @Success 200 {object} ldap.SyncReport
+// @Failure 401 {object} Error
+// @Failure 404 {object} Error
+// @Failure 500 {object} Error
+// @Failure 503 {object} Error
+// @Router /syncTeachers [post]
+func SyncTeachers(con *gin.Context) {
+	auth, err := ExtractTokenMeta(con.Request)
+	if err != nil {
+		con.JSON(http.StatusUnauthorized, Error{"you are not logged in"})
+		return
+	}
+	db := mongo.MongoDatabaseConnector{}
+	if err := db.Connect(con.Request.Context()); err != nil {
+		respondError(con, err, "database")
+		return
+	}
+	defer db.Close()
+	teacher, err := db.GetTeacherByShort(auth.Username)
+	if err != nil {
+		respondError(con, err, "teacher")
+		return
+	}
+	if !(teacher.Administration || teacher.SuperUser) {
+		con.JSON(http.StatusUnauthorized, Error{"unauthorized"})
+		return
+	}
+	report, err := ldap.SyncTeachers(con.Request.Context())
+	if errors.Is(err, ldap.ErrUnavailable) || errors.Is(err, ldap.ErrNoServiceAccount) {
+		con.JSON(http.StatusServiceUnavailable, Error{err.Error()})
+		return
+	}
+	if err != nil {
+		respondError(con, err, "teachers")
+		return
+	}
+	con.JSON(http.StatusOK, report)
+}
+
+// GetSubstitutionSuggestions represents the get substitution suggestions endpoint
+// @Summary Suggests substitutes for the lessons missed because of an application
+// @Description Lists the lessons the given teachers (or all teachers of a school event) miss during their attendance of the application and suggests free teachers for each of them, ranked by whether they are freed up by the event, teach the class or subject, and by their load
+// @ID get-substitution-suggestions
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Access Token" default(Bearer <Add access token here>)
+// @Param uuid query string true "Identifier of the application"
+// @Param teacher query []string false "Short names of the absent teachers, defaults to the teachers of a school event or the logged in teacher"
+// @Success 200 {array} SubstitutionSuggestion
 // @Failure 401 {object} Error
 // @Failure 404 {object} Error
 // @Failure 422 {object} Error
 // @Failure 500 {object} Error
-// @Router /getApplication [get]
-func GetApplication(con *gin.Context) {
+// @Failure 503 {object} Error
+// @Router /getSubstitutionSuggestions [get]
+func GetSubstitutionSuggestions(con *gin.Context) {
 	auth, err := ExtractTokenMeta(con.Request)
 	if err != nil {
 		con.JSON(http.StatusUnauthorized, Error{"you are not logged in"})
 		return
 	}
+	query := con.Request.URL.Query()
+	uuid := query.Get("uuid")
+	if uuid == "" {
+		con.JSON(http.StatusUnprocessableEntity, Error{"invalid request structure provided"})
+		return
+	}
 	db := mongo.MongoDatabaseConnector{}
-	if !db.Connect() {
-		con.JSON(http.StatusInternalServerError, Error{"database didn't respond"})
+	if err := db.Connect(con.Request.Context()); err != nil {
+		respondError(con, err, "database")
 		return
 	}
 	defer db.Close()
-	query := con.Request.URL.Query()
-	uuid := query.Get("uuid")
-	if query.Get("uuid") == "" {
-		con.JSON(http.StatusUnprocessableEntity, Error{"invalid request structure provided"})
+	requestTeacher, err := db.GetTeacherByShort(auth.Username)
+	if err != nil {
+		respondError(con, err, "teacher")
+		return
+	}
+	application, err := db.GetApplication(uuid)
+	if err != nil {
+		respondError(con, err, "application")
 		return
 	}
-	requestTeacher := db.GetTeacherByShort(auth.Username)
-	if !db.DoesApplicationExist(uuid) {
-		con.JSON(http.StatusNotFound, Error{"application not found"})
+	onBehalfOf, in, err := actingFor(db, application, requestTeacher)
+	if err != nil {
+		respondError(con, err, "delegations")
 		return
 	}
-	application := db.GetApplication(uuid)
-	var in bool
-	if application.Kind == mongo.SchoolEvent {
-		teachers := application.SchoolEventDetails.Teachers
-		for _, t := range teachers {
-			if t.Shortname == requestTeacher.Short {
-				in = true
-				break
-			}
+	if !(in || requestTeacher.Administration || requestTeacher.AV || requestTeacher.SuperUser) {
+		con.JSON(http.StatusUnauthorized, Error{"unauthorized"})
+		return
+	}
+	auditOnBehalf(con, db, requestTeacher, onBehalfOf, application.UUID)
+	shorts := splitList(query["teacher"])
+	if len(shorts) == 0 && application.Kind == mongo.SchoolEvent {
+		for _, t := range application.SchoolEventDetails.Teachers {
+			shorts = append(shorts, t.Shortname)
 		}
-	} else if application.Kind == mongo.Training {
-		if application.TrainingDetails.Filer == requestTeacher.Longname {
-			in = true
+	}
+	if len(shorts) == 0 {
+		shorts = append(shorts, requestTeacher.Short)
+	}
+	attendances := make(map[string][]mongo.Attendance, len(shorts))
+	for _, short := range shorts {
+		teacher, err := db.GetTeacherByShort(short)
+		if err != nil {
+			respondError(con, err, "teacher")
+			return
 		}
-	} else if application.Kind == mongo.OtherReason {
-		if application.OtherReasonDetails.Filer == requestTeacher.Longname {
-			in = true
+		if teacher.Untis == "" {
+			con.JSON(http.StatusUnprocessableEntity, Error{"the untis abbrevation of " + short + " is unknown"})
+			return
 		}
+		attendances[teacher.Untis] = application.AttendanceOf(short)
 	}
-	if !(in || requestTeacher.Administration || requestTeacher.AV || requestTeacher.PEK || requestTeacher.SuperUser) {
-		con.JSON(http.StatusUnauthorized, Error{"unauthorized"})
+	classes := make([]string, 0)
+	if application.Kind == mongo.SchoolEvent {
+		classes = application.SchoolEventDetails.Classes
+	}
+	absence, err := absenceOf(application, attendances, classes)
+	if err != nil {
+		con.JSON(http.StatusInternalServerError, Error{"couldn't load timezone"})
 		return
 	}
-	con.JSON(http.StatusOK, application)
-}
-
-// GetAdminApplications represents the get admin applications endpoint
-// @Summary Returns all admin applications
-// @Description Returns all applications currently needing a review by an admin
-// @ID get-admin-applications
-// @Accept json
-// @Produce json
-// @Param Authorization header string true "Access Token" default(Bearer <Add access token here>)
-// @Success 200 {array} db.Application
-// @Failure 401 {object} Error
-// @Failure 500 {object} Error
-// @Router /getAdminApplication [get]
-func GetAdminApplications(con *gin.Context) {
-	auth, err := ExtractTokenMeta(con.Request)
+	client, err := untis.ClientFor(auth.Username)
 	if err != nil {
-		con.JSON(http.StatusUnauthorized, Error{"you are not logged in"})
+		con.JSON(http.StatusServiceUnavailable, Error{err.Error()})
 		return
 	}
-	db := mongo.MongoDatabaseConnector{}
-	if !db.Connect() {
-		con.JSON(http.StatusInternalServerError, Error{"database didn't respond"})
+	lessons, err := client.AffectedLessons(absence)
+	if err != nil {
+		con.JSON(http.StatusInternalServerError, Error{"couldn't read the timetables from untis"})
 		return
 	}
-	defer db.Close()
-	teacher := db.GetTeacherByShort(auth.Username)
-	if !(teacher.PEK || teacher.Administration || teacher.AV || teacher.SuperUser) {
-		con.JSON(http.StatusUnauthorized, Error{"unauthorized"})
+	suggestions, err := client.SuggestSubstitutions(absence, lessons)
+	if err != nil {
+		con.JSON(http.StatusInternalServerError, Error{"couldn't read the timetables from untis"})
 		return
 	}
-	applications := db.GetAllApplications()
-	res := make([]mongo.Application, 0)
-	for _, app := range applications {
-		if app.Progress == mongo.InProcess && (teacher.Administration || teacher.AV || teacher.SuperUser) {
-			res = append(res, app)
-		}
-		if app.Progress == mongo.CostsInProcess {
-			res = append(res, app)
-		}
+	res := make([]SubstitutionSuggestion, 0, len(suggestions))
+	for _, suggestion := range suggestions {
+		res = append(res, toSubstitutionSuggestion(suggestion))
 	}
 	con.JSON(http.StatusOK, res)
 }
@@ -820,15 +2589,20 @@ func GetAdminApplications(con *gin.Context) {
 // CreateApplication represents the create applications endpoint
 // @Summary Creates a new application
 // @Description Creates the provided application in the system
+// @Description The application is checked for conflicts with other applications taking away the same teachers or classes and with exams in untis
+// @Description Conflicts configured as blocking (CONFLICT_BLOCKING) prevent creating the application, all others are returned as warnings
+// @Description Only teachers taking part in the application, their delegates and administrators may create it
 // @ID create-application
 // @Accept json
 // @Produce json
 // @Param Authorization header string true "Access Token" default(Bearer <Add access token here>)
 // @Param application body db.Application true "The Application Data"
-// @Success 200 {object} Information
+// @Success 200 {object} SavedApplication
 // @Failure 401 {object} Error
+// @Failure 409 {object} ConflictError
 // @Failure 422 {object} Error
 // @Failure 500 {object} Error
+// @Failure 503 {object} Error
 // @Router /createApplication [post]
 func CreateApplication(con *gin.Context) {
 	app := mongo.Application{}
@@ -837,38 +2611,66 @@ func CreateApplication(con *gin.Context) {
 		return
 	}
 	app.UUID = uuidG.NewString()
-	_, err := ExtractTokenMeta(con.Request)
+	auth, err := ExtractTokenMeta(con.Request)
 	if err != nil {
 		con.JSON(http.StatusUnauthorized, Error{"you are not logged in"})
 		return
 	}
 	db := mongo.MongoDatabaseConnector{}
-	if !db.Connect() {
-		con.JSON(http.StatusInternalServerError, Error{"database didn't respond"})
+	if err := db.Connect(con.Request.Context()); err != nil {
+		respondError(con, err, "database")
 		return
 	}
 	defer db.Close()
-	if db.CreateApplication(app) {
-		con.JSON(http.StatusOK, Information{"success; application created"})
-	} else {
-		con.JSON(http.StatusInternalServerError, Error{"error; application not created"})
+	requestTeacher, err := db.GetTeacherByShort(auth.Username)
+	if err != nil {
+		respondError(con, err, "teacher")
+		return
 	}
+	onBehalfOf, in, err := actingFor(db, app, requestTeacher)
+	if err != nil {
+		respondError(con, err, "delegations")
+		return
+	}
+	if !(in || requestTeacher.Administration || requestTeacher.AV || requestTeacher.PEK || requestTeacher.SuperUser) {
+		con.JSON(http.StatusUnauthorized, Error{"unauthorized"})
+		return
+	}
+	conflicts, err := findConflicts(db, auth.Username, app.UUID, app)
+	if err != nil {
+		respondError(con, err, "applications")
+		return
+	}
+	if isBlocked(conflicts) {
+		con.JSON(http.StatusConflict, ConflictError{"the application conflicts with other appointments", conflicts})
+		return
+	}
+	stampInProcess(&app, mongo.Application{})
+	if _, err := db.CreateApplication(app); err != nil {
+		respondError(con, err, "application")
+		return
+	}
+	auditOnBehalf(con, db, requestTeacher, onBehalfOf, app.UUID)
+	con.JSON(http.StatusOK, SavedApplication{"success; application created", app.UUID, conflicts})
 }
 
 // UpdateApplication represents the update applications endpoint
 // @Summary Updates an existing application
 // @Description Updates an application identified by a uuid with the data in the body in the system
+// @Description The application is checked for conflicts like on creation, blocking conflicts prevent the update
 // @ID update-application
 // @Accept json
 // @Produce json
 // @Param Authorization header string true "Access Token" default(Bearer <Add access token here>)
 // @Param application body db.Application true "The application data to update"
 // @Param uuid query string true "Identifier of the application to update"
-// @Success 200 {object} Information
+// @Success 200 {object} SavedApplication
 // @Failure 401 {object} Error
 // @Failure 404 {object} Error
+// @Failure 409 {object} ConflictError
 // @Failure 422 {object} Error
 // @Failure 500 {object} Error
+// @Failure 503 {object} Error
 // @Router /updateApplication [put]
 func UpdateApplication(con *gin.Context) {
 	app := mongo.Application{}
@@ -882,8 +2684,8 @@ func UpdateApplication(con *gin.Context) {
 		return
 	}
 	db := mongo.MongoDatabaseConnector{}
-	if !db.Connect() {
-		con.JSON(http.StatusInternalServerError, Error{"database didn't respond"})
+	if err := db.Connect(con.Request.Context()); err != nil {
+		respondError(con, err, "database")
 		return
 	}
 	defer db.Close()
@@ -893,39 +2695,91 @@ func UpdateApplication(con *gin.Context) {
 		con.JSON(http.StatusUnprocessableEntity, Error{"invalid request structure provided"})
 		return
 	}
-	requestTeacher := db.GetTeacherByShort(auth.Username)
-	if !db.DoesApplicationExist(uuid) {
-		con.JSON(http.StatusNotFound, Error{"application not found"})
+	requestTeacher, err := db.GetTeacherByShort(auth.Username)
+	if err != nil {
+		respondError(con, err, "teacher")
+		return
+	}
+	application, err := db.GetApplication(uuid)
+	if err != nil {
+		respondError(con, err, "application")
 		return
 	}
-	application := db.GetApplication(uuid)
-	var in bool
-	if application.Kind == mongo.SchoolEvent {
-		teachers := application.SchoolEventDetails.Teachers
-		for _, t := range teachers {
-			if t.Shortname == requestTeacher.Short {
-				in = true
-				break
-			}
-		}
-	} else if application.Kind == mongo.Training {
-		if application.TrainingDetails.Filer == requestTeacher.Longname {
-			in = true
-		}
-	} else if application.Kind == mongo.OtherReason {
-		if application.OtherReasonDetails.Filer == requestTeacher.Longname {
-			in = true
-		}
+	onBehalfOf, in, err := actingFor(db, application, requestTeacher)
+	if err != nil {
+		respondError(con, err, "delegations")
+		return
 	}
-	if !(in || requestTeacher.Administration || requestTeacher.AV || requestTeacher.PEK || requestTeacher.SuperUser) {
+	approvers, err := deputizedFor(db, requestTeacher)
+	if err != nil {
+		respondError(con, err, "deputies")
+		return
+	}
+	if !(in || isApprover(approvalRoles(requestTeacher, approvers))) {
 		con.JSON(http.StatusUnauthorized, Error{"unauthorized"})
 		return
 	}
-	if db.UpdateApplication(uuid, app) {
-		con.JSON(http.StatusOK, Information{"success; application updated"})
-	} else {
-		con.JSON(http.StatusInternalServerError, Error{"error; application not updated"})
+	conflicts, err := findConflicts(db, auth.Username, uuid, app)
+	if err != nil {
+		respondError(con, err, "applications")
+		return
+	}
+	if isBlocked(conflicts) {
+		con.JSON(http.StatusConflict, ConflictError{"the application conflicts with other appointments", conflicts})
+		return
+	}
+	stampInProcess(&app, application)
+	if err := db.UpdateApplication(uuid, app); err != nil {
+		respondError(con, err, "application")
+		return
+	}
+	if in {
+		auditOnBehalf(con, db, requestTeacher, onBehalfOf, application.UUID)
+	} else if !isApprover(approvalRoles(requestTeacher, nil)) {
+		auditAsDeputy(con, db, requestTeacher, approvers, application.UUID)
+	}
+	con.JSON(http.StatusOK, SavedApplication{"success; application updated", uuid, conflicts})
+}
+
+// CheckConflicts represents the check conflicts endpoint
+// @Summary Checks an application for conflicts without saving it
+// @Description Returns the conflicts of the provided application with other applications taking away the same teachers or classes and with exams in untis
+// @Description Blocking marks the conflicts which would prevent saving the application
+// @ID check-conflicts
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Access Token" default(Bearer <Add access token here>)
+// @Param application body db.Application true "The application data to check"
+// @Param uuid query string false "Identifier of the application if it already exists"
+// @Success 200 {array} Conflict
+// @Failure 401 {object} Error
+// @Failure 422 {object} Error
+// @Failure 500 {object} Error
+// @Failure 503 {object} Error
+// @Router /checkConflicts [post]
+func CheckConflicts(con *gin.Context) {
+	app := mongo.Application{}
+	if err := con.ShouldBindJSON(&app); err != nil {
+		con.JSON(http.StatusUnprocessableEntity, Error{"invalid request structure provided"})
+		return
+	}
+	auth, err := ExtractTokenMeta(con.Request)
+	if err != nil {
+		con.JSON(http.StatusUnauthorized, Error{"you are not logged in"})
+		return
 	}
+	db := mongo.MongoDatabaseConnector{}
+	if err := db.Connect(con.Request.Context()); err != nil {
+		respondError(con, err, "database")
+		return
+	}
+	defer db.Close()
+	conflicts, err := findConflicts(db, auth.Username, con.Request.URL.Query().Get("uuid"), app)
+	if err != nil {
+		respondError(con, err, "applications")
+		return
+	}
+	con.JSON(http.StatusOK, conflicts)
 }
 
 // DeleteApplication represents the delete applications endpoint
@@ -941,6 +2795,7 @@ func UpdateApplication(con *gin.Context) {
 // @Failure 404 {object Error
 // @Failure 422 {object} Error
 // @Failure 500 {object} Error
+// @Failure 503 {object} Error
 // @Router /deleteApplication [delete]
 func DeleteApplication(con *gin.Context) {
 	auth, err := ExtractTokenMeta(con.Request)
@@ -949,8 +2804,8 @@ func DeleteApplication(con *gin.Context) {
 		return
 	}
 	db := mongo.MongoDatabaseConnector{}
-	if !db.Connect() {
-		con.JSON(http.StatusInternalServerError, Error{"database didn't respond"})
+	if err := db.Connect(con.Request.Context()); err != nil {
+		respondError(con, err, "database")
 		return
 	}
 	defer db.Close()
@@ -960,39 +2815,31 @@ func DeleteApplication(con *gin.Context) {
 		con.JSON(http.StatusUnprocessableEntity, Error{"invalid request structure provided"})
 		return
 	}
-	requestTeacher := db.GetTeacherByShort(auth.Username)
-	if !db.DoesApplicationExist(uuid) {
-		con.JSON(http.StatusNotFound, Error{"application not found"})
+	requestTeacher, err := db.GetTeacherByShort(auth.Username)
+	if err != nil {
+		respondError(con, err, "teacher")
+		return
+	}
+	application, err := db.GetApplication(uuid)
+	if err != nil {
+		respondError(con, err, "application")
 		return
 	}
-	application := db.GetApplication(uuid)
-	var in bool
-	if application.Kind == mongo.SchoolEvent {
-		teachers := application.SchoolEventDetails.Teachers
-		for _, t := range teachers {
-			if t.Shortname == requestTeacher.Short {
-				in = true
-				break
-			}
-		}
-	} else if application.Kind == mongo.Training {
-		if application.TrainingDetails.Filer == requestTeacher.Longname {
-			in = true
-		}
-	} else if application.Kind == mongo.OtherReason {
-		if application.OtherReasonDetails.Filer == requestTeacher.Longname {
-			in = true
-		}
+	onBehalfOf, in, err := actingFor(db, application, requestTeacher)
+	if err != nil {
+		respondError(con, err, "delegations")
+		return
 	}
 	if !(in || requestTeacher.Administration || requestTeacher.AV || requestTeacher.PEK || requestTeacher.SuperUser) {
 		con.JSON(http.StatusUnauthorized, Error{"unauthorized"})
 		return
 	}
-	if db.DeleteApplication(uuid) {
-		con.JSON(http.StatusOK, Information{"success; application deleted"})
-	} else {
-		con.JSON(http.StatusInternalServerError, Error{"error; application not deleted"})
+	if err := db.DeleteApplication(uuid); err != nil {
+		respondError(con, err, "application")
+		return
 	}
+	auditOnBehalf(con, db, requestTeacher, onBehalfOf, application.UUID)
+	con.JSON(http.StatusOK, Information{"success; application deleted"})
 }
 
 // GetAbsenceFormForClasses represents get absence form for classes endpoint
@@ -1009,6 +2856,7 @@ func DeleteApplication(con *gin.Context) {
 // @Failure 404 {object} Error
 // @Failure 422 {object} Error
 // @Failure 500 {object} Error
+// @Failure 503 {object} Error
 // @Router /getAbsenceFormForClasses [get]
 func GetAbsenceFormForClasses(con *gin.Context) {
 	auth, err := ExtractTokenMeta(con.Request)
@@ -1016,8 +2864,8 @@ func GetAbsenceFormForClasses(con *gin.Context) {
 		con.JSON(http.StatusUnauthorized, Error{"you are not logged in"})
 	}
 	db := mongo.MongoDatabaseConnector{}
-	if !db.Connect() {
-		con.JSON(http.StatusInternalServerError, Error{"database didn't respond"})
+	if err := db.Connect(con.Request.Context()); err != nil {
+		respondError(con, err, "database")
 		return
 	}
 	defer db.Close()
@@ -1033,34 +2881,26 @@ func GetAbsenceFormForClasses(con *gin.Context) {
 	if applyClassFilter {
 		classes = query["classes"]
 	}
-	if !db.DoesApplicationExist(uuid) {
-		con.JSON(http.StatusNotFound, Error{"application not found"})
+	application, err := db.GetApplication(uuid)
+	if err != nil {
+		respondError(con, err, "application")
 		return
 	}
-	application := db.GetApplication(uuid)
-	requestTeacher := db.GetTeacherByShort(auth.Username)
-	var in bool
-	if application.Kind == mongo.SchoolEvent {
-		teachers := application.SchoolEventDetails.Teachers
-		for _, t := range teachers {
-			if t.Shortname == requestTeacher.Short {
-				in = true
-				break
-			}
-		}
-	} else if application.Kind == mongo.Training {
-		if application.TrainingDetails.Filer == requestTeacher.Longname {
-			in = true
-		}
-	} else if application.Kind == mongo.OtherReason {
-		if application.OtherReasonDetails.Filer == requestTeacher.Longname {
-			in = true
-		}
+	requestTeacher, err := db.GetTeacherByShort(auth.Username)
+	if err != nil {
+		respondError(con, err, "teacher")
+		return
+	}
+	onBehalfOf, in, err := actingFor(db, application, requestTeacher)
+	if err != nil {
+		respondError(con, err, "delegations")
+		return
 	}
 	if !(in || requestTeacher.Administration || requestTeacher.AV || requestTeacher.PEK || requestTeacher.SuperUser) {
 		con.JSON(http.StatusUnauthorized, Error{"you have no permission to do this"})
 		return
 	}
+	auditOnBehalf(con, db, requestTeacher, onBehalfOf, application.UUID)
 	path, err := files.GenerateFileEnvironment(application)
 	if err != nil {
 		con.JSON(http.StatusInternalServerError, Error{"couldn't create directories"})
@@ -1137,6 +2977,7 @@ func GetAbsenceFormForClasses(con *gin.Context) {
 // @Failure 404 {object} Error
 // @Failure 422 {object} Error
 // @Failure 500 {object} Error
+// @Failure 503 {object} Error
 // @Router /getAbsenceFormForTeacher [get]
 func GetAbsenceFormForTeacher(con *gin.Context) {
 	auth, err := ExtractTokenMeta(con.Request)
@@ -1144,8 +2985,8 @@ func GetAbsenceFormForTeacher(con *gin.Context) {
 		con.JSON(http.StatusUnauthorized, Error{"you are not logged in"})
 	}
 	db := mongo.MongoDatabaseConnector{}
-	if !db.Connect() {
-		con.JSON(http.StatusInternalServerError, Error{"database didn't respond"})
+	if err := db.Connect(con.Request.Context()); err != nil {
+		respondError(con, err, "database")
 		return
 	}
 	defer db.Close()
@@ -1161,44 +3002,54 @@ func GetAbsenceFormForTeacher(con *gin.Context) {
 	if applyTeacher {
 		teacher = query.Get("teacher")
 	}
-	if !db.DoesApplicationExist(uuid) {
-		con.JSON(http.StatusNotFound, Error{"application not found"})
+	application, err := db.GetApplication(uuid)
+	if err != nil {
+		respondError(con, err, "application")
+		return
+	}
+	requestTeacher, err := db.GetTeacherByShort(auth.Username)
+	if err != nil {
+		respondError(con, err, "teacher")
 		return
 	}
-	application := db.GetApplication(uuid)
-	requestTeacher := db.GetTeacherByShort(auth.Username)
-	var in bool
-	if application.Kind == mongo.SchoolEvent {
-		teachers := application.SchoolEventDetails.Teachers
-		for _, t := range teachers {
-			if t.Shortname == requestTeacher.Short {
-				in = true
-				break
-			}
-		}
-	} else if application.Kind == mongo.Training {
-		if application.TrainingDetails.Filer == requestTeacher.Longname {
-			in = true
+	in := participates(application, requestTeacher)
+	allowed := requestTeacher.Administration || requestTeacher.AV || requestTeacher.PEK || requestTeacher.SuperUser
+	onBehalfOf := requestTeacher
+	if applyTeacher && !allowed {
+		// delegates may generate the form of a teacher taking part in the application who delegated to them
+		delegated, err := delegatedBy(db, requestTeacher, teacher)
+		if err != nil {
+			respondError(con, err, "delegations")
+			return
 		}
-	} else if application.Kind == mongo.OtherReason {
-		if application.OtherReasonDetails.Filer == requestTeacher.Longname {
-			in = true
+		if delegated {
+			if onBehalfOf, err = db.GetTeacherByShort(teacher); err != nil {
+				respondError(con, err, "teacher")
+				return
+			}
+			delegated = participates(application, onBehalfOf)
 		}
+		allowed = delegated
 	}
-	if !((!applyTeacher && in) || (applyTeacher && (requestTeacher.Administration || requestTeacher.AV || requestTeacher.PEK || requestTeacher.SuperUser))) {
+	if !((!applyTeacher && in) || (applyTeacher && allowed)) {
 		con.JSON(http.StatusUnauthorized, Error{"you have no permission to do this"})
 		return
 	}
+	auditOnBehalf(con, db, requestTeacher, onBehalfOf, application.UUID)
 	path, err := files.GenerateFileEnvironment(application)
 	if err != nil {
 		con.JSON(http.StatusInternalServerError, Error{"couldn't create directories"})
 		return
 	}
 	if applyTeacher {
-		reqTeacher := db.GetTeacherByShort(teacher)
-		path, err = files.GenerateAbsenceFormForTeacher(path, auth.Username, reqTeacher.Longname, application)
+		reqTeacher, dbErr := db.GetTeacherByShort(teacher)
+		if dbErr != nil {
+			respondError(con, dbErr, "teacher")
+			return
+		}
+		path, err = files.GenerateAbsenceFormForTeacher(con.Request.Context(), path, auth.Username, reqTeacher.Longname, application)
 	} else {
-		path, err = files.GenerateAbsenceFormForTeacher(path, auth.Username, "self", application)
+		path, err = files.GenerateAbsenceFormForTeacher(con.Request.Context(), path, auth.Username, "self", application)
 	}
 	if err != nil {
 		con.JSON(http.StatusInternalServerError, Error{"couldn't create pdf"})
@@ -1232,6 +3083,7 @@ func GetAbsenceFormForTeacher(con *gin.Context) {
 // @Failure 404 {object} Error
 // @Failure 422 {object} Error
 // @Failure 500 {object} Error
+// @Failure 503 {object} Error
 // @Router /getCompensationForEducationalSupportForm [get]
 func GetCompensationForEducationalSupportForm(con *gin.Context) {
 	auth, err := ExtractTokenMeta(con.Request)
@@ -1239,8 +3091,8 @@ func GetCompensationForEducationalSupportForm(con *gin.Context) {
 		con.JSON(http.StatusUnauthorized, Error{"you are not logged in"})
 	}
 	db := mongo.MongoDatabaseConnector{}
-	if !db.Connect() {
-		con.JSON(http.StatusInternalServerError, Error{"database didn't respond"})
+	if err := db.Connect(con.Request.Context()); err != nil {
+		respondError(con, err, "database")
 		return
 	}
 	defer db.Close()
@@ -1251,34 +3103,26 @@ func GetCompensationForEducationalSupportForm(con *gin.Context) {
 		return
 	}
 	uuid := query.Get("uuid")
-	if !db.DoesApplicationExist(uuid) {
-		con.JSON(http.StatusNotFound, Error{"application not found"})
+	application, err := db.GetApplication(uuid)
+	if err != nil {
+		respondError(con, err, "application")
 		return
 	}
-	application := db.GetApplication(uuid)
-	requestTeacher := db.GetTeacherByShort(auth.Username)
-	var in bool
-	if application.Kind == mongo.SchoolEvent {
-		teachers := application.SchoolEventDetails.Teachers
-		for _, t := range teachers {
-			if t.Shortname == requestTeacher.Short {
-				in = true
-				break
-			}
-		}
-	} else if application.Kind == mongo.Training {
-		if application.TrainingDetails.Filer == requestTeacher.Longname {
-			in = true
-		}
-	} else if application.Kind == mongo.OtherReason {
-		if application.OtherReasonDetails.Filer == requestTeacher.Longname {
-			in = true
-		}
+	requestTeacher, err := db.GetTeacherByShort(auth.Username)
+	if err != nil {
+		respondError(con, err, "teacher")
+		return
+	}
+	onBehalfOf, in, err := actingFor(db, application, requestTeacher)
+	if err != nil {
+		respondError(con, err, "delegations")
+		return
 	}
 	if !(in || requestTeacher.Administration || requestTeacher.AV || requestTeacher.PEK || requestTeacher.SuperUser) {
 		con.JSON(http.StatusUnauthorized, Error{"you have no permission to do this"})
 		return
 	}
+	auditOnBehalf(con, db, requestTeacher, onBehalfOf, application.UUID)
 	path, err := files.GenerateFileEnvironment(application)
 	if err != nil {
 		con.JSON(http.StatusInternalServerError, Error{"couldn't create directories"})
@@ -1320,6 +3164,7 @@ func GetCompensationForEducationalSupportForm(con *gin.Context) {
 // @Failure 404 {object} Error
 // @Failure 422 {object} Error
 // @Failure 500 {object} Error
+// @Failure 503 {object} Error
 // @Router /getTravelInvoiceForm [get]
 func GetTravelInvoiceForm(con *gin.Context) {
 	auth, err := ExtractTokenMeta(con.Request)
@@ -1327,8 +3172,8 @@ func GetTravelInvoiceForm(con *gin.Context) {
 		con.JSON(http.StatusUnauthorized, Error{"you are not logged in"})
 	}
 	db := mongo.MongoDatabaseConnector{}
-	if !db.Connect() {
-		con.JSON(http.StatusInternalServerError, Error{"database didn't respond"})
+	if err := db.Connect(con.Request.Context()); err != nil {
+		respondError(con, err, "database")
 		return
 	}
 	defer db.Close()
@@ -1354,34 +3199,26 @@ func GetTravelInvoiceForm(con *gin.Context) {
 		return
 	}
 	_, applyMergeReceipts := con.Request.Form["receipts"]
-	if !db.DoesApplicationExist(uuid) {
-		con.JSON(http.StatusNotFound, Error{"application not found"})
+	application, err := db.GetApplication(uuid)
+	if err != nil {
+		respondError(con, err, "application")
 		return
 	}
-	application := db.GetApplication(uuid)
-	requestTeacher := db.GetTeacherByShort(auth.Username)
-	var in bool
-	if application.Kind == mongo.SchoolEvent {
-		teachers := application.SchoolEventDetails.Teachers
-		for _, t := range teachers {
-			if t.Shortname == requestTeacher.Short {
-				in = true
-				break
-			}
-		}
-	} else if application.Kind == mongo.Training {
-		if application.TrainingDetails.Filer == requestTeacher.Longname {
-			in = true
-		}
-	} else if application.Kind == mongo.OtherReason {
-		if application.OtherReasonDetails.Filer == requestTeacher.Longname {
-			in = true
-		}
+	requestTeacher, err := db.GetTeacherByShort(auth.Username)
+	if err != nil {
+		respondError(con, err, "teacher")
+		return
+	}
+	onBehalfOf, in, err := actingFor(db, application, requestTeacher)
+	if err != nil {
+		respondError(con, err, "delegations")
+		return
 	}
 	if !(in || requestTeacher.Administration || requestTeacher.AV || requestTeacher.PEK || requestTeacher.SuperUser) {
 		con.JSON(http.StatusUnauthorized, Error{"you have no permission to do this"})
 		return
 	}
+	auditOnBehalf(con, db, requestTeacher, onBehalfOf, application.UUID)
 	path, err := files.GenerateFileEnvironment(application)
 	if err != nil {
 		con.JSON(http.StatusInternalServerError, Error{"couldn't create directories"})
@@ -1470,6 +3307,7 @@ func GetTravelInvoiceForm(con *gin.Context) {
 // @Failure 404 {object} Error
 // @Failure 422 {object} Error
 // @Failure 500 {object} Error
+// @Failure 503 {object} Error
 // @Router /getBusinessTripApplicationForm [get]
 func GetBusinessTripApplicationForm(con *gin.Context) {
 	auth, err := ExtractTokenMeta(con.Request)
@@ -1477,8 +3315,8 @@ func GetBusinessTripApplicationForm(con *gin.Context) {
 		con.JSON(http.StatusUnauthorized, Error{"you are not logged in"})
 	}
 	db := mongo.MongoDatabaseConnector{}
-	if !db.Connect() {
-		con.JSON(http.StatusInternalServerError, Error{"database didn't respond"})
+	if err := db.Connect(con.Request.Context()); err != nil {
+		respondError(con, err, "database")
 		return
 	}
 	defer db.Close()
@@ -1503,34 +3341,26 @@ func GetBusinessTripApplicationForm(con *gin.Context) {
 		con.JSON(http.StatusUnprocessableEntity, Error{"invalid bta_id provided"})
 		return
 	}
-	if !db.DoesApplicationExist(uuid) {
-		con.JSON(http.StatusNotFound, Error{"application not found"})
+	application, err := db.GetApplication(uuid)
+	if err != nil {
+		respondError(con, err, "application")
 		return
 	}
-	application := db.GetApplication(uuid)
-	requestTeacher := db.GetTeacherByShort(auth.Username)
-	var in bool
-	if application.Kind == mongo.SchoolEvent {
-		teachers := application.SchoolEventDetails.Teachers
-		for _, t := range teachers {
-			if t.Shortname == requestTeacher.Short {
-				in = true
-				break
-			}
-		}
-	} else if application.Kind == mongo.Training {
-		if application.TrainingDetails.Filer == requestTeacher.Longname {
-			in = true
-		}
-	} else if application.Kind == mongo.OtherReason {
-		if application.OtherReasonDetails.Filer == requestTeacher.Longname {
-			in = true
-		}
+	requestTeacher, err := db.GetTeacherByShort(auth.Username)
+	if err != nil {
+		respondError(con, err, "teacher")
+		return
+	}
+	onBehalfOf, in, err := actingFor(db, application, requestTeacher)
+	if err != nil {
+		respondError(con, err, "delegations")
+		return
 	}
 	if !(in || requestTeacher.Administration || requestTeacher.AV || requestTeacher.PEK || requestTeacher.SuperUser) {
 		con.JSON(http.StatusUnauthorized, Error{"you have no permission to do this"})
 		return
 	}
+	auditOnBehalf(con, db, requestTeacher, onBehalfOf, application.UUID)
 	path, err := files.GenerateFileEnvironment(application)
 	if err != nil {
 		con.JSON(http.StatusInternalServerError, Error{"couldn't create directories"})
@@ -1578,6 +3408,7 @@ func GetBusinessTripApplicationForm(con *gin.Context) {
 // @Failure 404 {object} Error
 // @Failure 422 {object} Error
 // @Failure 500 {object} Error
+// @Failure 503 {object} Error
 // @Router /getTravelInvoiceExcel [get]
 func GetTravelInvoiceExcel(con *gin.Context) {
 	auth, err := ExtractTokenMeta(con.Request)
@@ -1585,8 +3416,8 @@ func GetTravelInvoiceExcel(con *gin.Context) {
 		con.JSON(http.StatusUnauthorized, Error{"you are not logged in"})
 	}
 	db := mongo.MongoDatabaseConnector{}
-	if !db.Connect() {
-		con.JSON(http.StatusInternalServerError, Error{"database didn't respond"})
+	if err := db.Connect(con.Request.Context()); err != nil {
+		respondError(con, err, "database")
 		return
 	}
 	defer db.Close()
@@ -1611,34 +3442,26 @@ func GetTravelInvoiceExcel(con *gin.Context) {
 		con.JSON(http.StatusUnprocessableEntity, Error{"invalid ti_id provided"})
 		return
 	}
-	if !db.DoesApplicationExist(uuid) {
-		con.JSON(http.StatusNotFound, Error{"application not found"})
+	application, err := db.GetApplication(uuid)
+	if err != nil {
+		respondError(con, err, "application")
 		return
 	}
-	application := db.GetApplication(uuid)
-	requestTeacher := db.GetTeacherByShort(auth.Username)
-	var in bool
-	if application.Kind == mongo.SchoolEvent {
-		teachers := application.SchoolEventDetails.Teachers
-		for _, t := range teachers {
-			if t.Shortname == requestTeacher.Short {
-				in = true
-				break
-			}
-		}
-	} else if application.Kind == mongo.Training {
-		if application.TrainingDetails.Filer == requestTeacher.Longname {
-			in = true
-		}
-	} else if application.Kind == mongo.OtherReason {
-		if application.OtherReasonDetails.Filer == requestTeacher.Longname {
-			in = true
-		}
+	requestTeacher, err := db.GetTeacherByShort(auth.Username)
+	if err != nil {
+		respondError(con, err, "teacher")
+		return
+	}
+	onBehalfOf, in, err := actingFor(db, application, requestTeacher)
+	if err != nil {
+		respondError(con, err, "delegations")
+		return
 	}
 	if !(in || requestTeacher.Administration || requestTeacher.AV || requestTeacher.PEK || requestTeacher.SuperUser) {
 		con.JSON(http.StatusUnauthorized, Error{"you have no permission to do this"})
 		return
 	}
+	auditOnBehalf(con, db, requestTeacher, onBehalfOf, application.UUID)
 	path, err := files.GenerateFileEnvironment(application)
 	if err != nil {
 		con.JSON(http.StatusInternalServerError, Error{"couldn't create directories"})
@@ -1681,6 +3504,7 @@ func GetTravelInvoiceExcel(con *gin.Context) {
 // @Failure 404 {object} Error
 // @Failure 422 {object} Error
 // @Failure 500 {object} Error
+// @Failure 503 {object} Error
 // @Router /getBusinessTripApplicationExcel [get]
 func GetBusinessTripApplicationExcel(con *gin.Context) {
 	auth, err := ExtractTokenMeta(con.Request)
@@ -1688,8 +3512,8 @@ func GetBusinessTripApplicationExcel(con *gin.Context) {
 		con.JSON(http.StatusUnauthorized, Error{"you are not logged in"})
 	}
 	db := mongo.MongoDatabaseConnector{}
-	if !db.Connect() {
-		con.JSON(http.StatusInternalServerError, Error{"database didn't respond"})
+	if err := db.Connect(con.Request.Context()); err != nil {
+		respondError(con, err, "database")
 		return
 	}
 	defer db.Close()
@@ -1714,34 +3538,26 @@ func GetBusinessTripApplicationExcel(con *gin.Context) {
 		con.JSON(http.StatusUnprocessableEntity, Error{"invalid bta_id provided"})
 		return
 	}
-	if !db.DoesApplicationExist(uuid) {
-		con.JSON(http.StatusNotFound, Error{"application not found"})
+	application, err := db.GetApplication(uuid)
+	if err != nil {
+		respondError(con, err, "application")
+		return
+	}
+	requestTeacher, err := db.GetTeacherByShort(auth.Username)
+	if err != nil {
+		respondError(con, err, "teacher")
 		return
 	}
-	application := db.GetApplication(uuid)
-	requestTeacher := db.GetTeacherByShort(auth.Username)
-	var in bool
-	if application.Kind == mongo.SchoolEvent {
-		teachers := application.SchoolEventDetails.Teachers
-		for _, t := range teachers {
-			if t.Shortname == requestTeacher.Short {
-				in = true
-				break
-			}
-		}
-	} else if application.Kind == mongo.Training {
-		if application.TrainingDetails.Filer == requestTeacher.Longname {
-			in = true
-		}
-	} else if application.Kind == mongo.OtherReason {
-		if application.OtherReasonDetails.Filer == requestTeacher.Longname {
-			in = true
-		}
+	onBehalfOf, in, err := actingFor(db, application, requestTeacher)
+	if err != nil {
+		respondError(con, err, "delegations")
+		return
 	}
 	if !(in || requestTeacher.Administration || requestTeacher.AV || requestTeacher.PEK || requestTeacher.SuperUser) {
 		con.JSON(http.StatusUnauthorized, Error{"you have no permission to do this"})
 		return
 	}
+	auditOnBehalf(con, db, requestTeacher, onBehalfOf, application.UUID)
 	path, err := files.GenerateFileEnvironment(application)
 	if err != nil {
 		con.JSON(http.StatusInternalServerError, Error{"couldn't create directories"})
@@ -1784,6 +3600,7 @@ func GetBusinessTripApplicationExcel(con *gin.Context) {
 // @Failure 404 {object} Error
 // @Failure 422 {object} Error
 // @Failure 500 {object} Error
+// @Failure 503 {object} Error
 // @Router /saveBillingReceipt [post]
 func SaveBillingReceipt(con *gin.Context) {
 	auth, err := ExtractTokenMeta(con.Request)
@@ -1796,8 +3613,8 @@ func SaveBillingReceipt(con *gin.Context) {
 		return
 	}
 	db := mongo.MongoDatabaseConnector{}
-	if !db.Connect() {
-		con.JSON(http.StatusInternalServerError, Error{"database didn't respond"})
+	if err := db.Connect(con.Request.Context()); err != nil {
+		respondError(con, err, "database")
 		return
 	}
 	defer db.Close()
@@ -1813,29 +3630,20 @@ func SaveBillingReceipt(con *gin.Context) {
 		return
 	}
 	short := query.Get("short")
-	if !db.DoesApplicationExist(uuid) {
-		con.JSON(http.StatusNotFound, Error{"application not found"})
+	application, err := db.GetApplication(uuid)
+	if err != nil {
+		respondError(con, err, "application")
 		return
 	}
-	application := db.GetApplication(uuid)
-	requestTeacher := db.GetTeacherByShort(auth.Username)
-	var in bool
-	if application.Kind == mongo.SchoolEvent {
-		teachers := application.SchoolEventDetails.Teachers
-		for _, t := range teachers {
-			if t.Shortname == requestTeacher.Short {
-				in = true
-				break
-			}
-		}
-	} else if application.Kind == mongo.Training {
-		if application.TrainingDetails.Filer == requestTeacher.Longname {
-			in = true
-		}
-	} else if application.Kind == mongo.OtherReason {
-		if application.OtherReasonDetails.Filer == requestTeacher.Longname {
-			in = true
-		}
+	requestTeacher, err := db.GetTeacherByShort(auth.Username)
+	if err != nil {
+		respondError(con, err, "teacher")
+		return
+	}
+	onBehalfOf, in, err := actingFor(db, application, requestTeacher)
+	if err != nil {
+		respondError(con, err, "delegations")
+		return
 	}
 	if !in {
 		con.JSON(http.StatusUnauthorized, Error{"you have no permission to do this"})
@@ -1880,5 +3688,64 @@ func SaveBillingReceipt(con *gin.Context) {
 		}
 		_ = file.Close()
 	}
+	auditOnBehalf(con, db, requestTeacher, onBehalfOf, application.UUID)
 	con.JSON(http.StatusOK, Information{"saving successful"})
 }
+
+// participates checks whether a teacher takes part in an application
+// a teacher takes part in a school event if listed as one of its teachers, otherwise if filing the application
+func participates(application mongo.Application, teacher mongo.Teacher) bool {
+	switch application.Kind {
+	case mongo.SchoolEvent:
+		for _, t := range application.SchoolEventDetails.Teachers {
+			if t.Shortname == teacher.Short {
+				return true
+			}
+		}
+	case mongo.Training:
+		return application.TrainingDetails.Filer == teacher.Longname
+	case mongo.OtherReason:
+		return application.OtherReasonDetails.Filer == teacher.Longname
+	}
+	return false
+}
+
+// resolveTeacher returns the teacher identified by the given short name
+// if the teacher isn't stored yet, their data is read from the ldap service account and from untis on behalf of username
+// and the teacher is created in the database
+func resolveTeacher(db mongo.MongoDatabaseConnector, username, short string) (mongo.Teacher, error) {
+	teacher, err := db.GetTeacherByShort(short)
+	if !errors.Is(err, mongo.ErrNotFound) {
+		return teacher, err
+	}
+	longname, err := ldap.GetLongName(short)
+	if err != nil {
+		return mongo.Teacher{}, errors.New("couldn't read longname of new teacher")
+	}
+	client, err := untis.ClientFor(username)
+	if err != nil {
+		return mongo.Teacher{}, err
+	}
+	err = client.Authenticate()
+	if err != nil {
+		return mongo.Teacher{}, errors.New("couldn't authenticate with untis API")
+	}
+	id, err := client.ResolveTeacherID(longname)
+	if err != nil {
+		return mongo.Teacher{}, errors.New("couldn't resolve untis id of new teacher")
+	}
+	untisAb, err := client.ResolveTeachers([]int{id})
+	if err != nil || len(untisAb) == 0 {
+		return mongo.Teacher{}, errors.New("couldn't resolve untis abbrevation of new teacher")
+	}
+	return db.CreateTeacher(mongo.Teacher{
+		UUID:           uuidG.NewString(),
+		Short:          short,
+		Longname:       longname,
+		SuperUser:      false,
+		AV:             false,
+		Administration: false,
+		PEK:            false,
+		Untis:          untisAb[0],
+	})
+}
@@ -0,0 +1,71 @@
+package rest
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	authn "github.com/refundable-tgm/huginn/auth"
+	mongo "github.com/refundable-tgm/huginn/db"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// databaseStatus maps an error returned by the db package to the http status code it should be answered with
+func databaseStatus(err error) int {
+	switch {
+	case errors.Is(err, mongo.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, mongo.ErrConflict):
+		return http.StatusConflict
+	case errors.Is(err, mongo.ErrUnavailable):
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusInternalServerError
+}
+
+// respondError answers a request whose operation failed
+// errors of the db package are mapped to their status codes, any other error is answered with its message and status code 500
+// subject names the entity the operation was performed on and is used in the error message
+func respondError(con *gin.Context, err error, subject string) {
+	status := databaseStatus(err)
+	switch status {
+	case http.StatusNotFound:
+		con.JSON(status, Error{subject + " not found"})
+	case http.StatusConflict:
+		con.JSON(status, Error{subject + " conflicts with an existing one"})
+	case http.StatusServiceUnavailable:
+		con.JSON(status, Error{"database didn't respond"})
+	default:
+		con.JSON(status, Error{err.Error()})
+	}
+}
+
+// respondAuthError answers a request whose authentication failed
+// errors of the auth package are mapped to their status codes, any other error is passed to respondError
+func respondAuthError(con *gin.Context, err error) {
+	throttled := &authn.ThrottledError{}
+	switch {
+	case errors.As(err, &throttled):
+		con.Header("Retry-After", strconv.Itoa(int(math.Ceil(time.Until(throttled.Until).Seconds()))))
+		con.JSON(http.StatusTooManyRequests, Error{throttled.Error()})
+	case errors.Is(err, authn.ErrInvalidAPIKey):
+		con.JSON(http.StatusUnauthorized, Error{"API key presented is invalid"})
+	case errors.Is(err, authn.ErrInvalidCredentials):
+		con.JSON(http.StatusUnauthorized, Error{"this credentials do not resolve into an authorized login"})
+	case errors.Is(err, authn.ErrUnavailable):
+		con.JSON(http.StatusServiceUnavailable, Error{"authentication provider didn't respond"})
+	case errors.Is(err, authn.ErrUnknownProvider):
+		con.JSON(http.StatusNotFound, Error{err.Error()})
+	case errors.Is(err, authn.ErrInvalidCode), errors.Is(err, authn.ErrDeactivated):
+		con.JSON(http.StatusForbidden, Error{err.Error()})
+	case errors.Is(err, authn.ErrInvalidExpiry), errors.Is(err, authn.ErrNoEndpoints):
+		con.JSON(http.StatusUnprocessableEntity, Error{err.Error()})
+	case errors.Is(err, authn.ErrTwoFactorEnabled), errors.Is(err, authn.ErrTwoFactorDisabled),
+		errors.Is(err, authn.ErrTwoFactorRequired), errors.Is(err, authn.ErrNoEnrolment),
+		errors.Is(err, authn.ErrNoServicePrincipal), errors.Is(err, authn.ErrPrivilegedPrincipal):
+		con.JSON(http.StatusConflict, Error{err.Error()})
+	default:
+		respondError(con, err, "teacher")
+	}
+}
@@ -0,0 +1,277 @@
+package rest
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	mongo "github.com/refundable-tgm/huginn/db"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultPageSize is the amount of entries returned per page if the request doesn't specify one
+const DefaultPageSize = 50
+
+// MaxPageSize is the maximum amount of entries a single page may contain
+const MaxPageSize = 200
+
+// SummaryFields is the value of the fields parameter requesting the summary representation of applications
+const SummaryFields = "summary"
+
+// summaryFields are the fields loaded from the database to build an ApplicationSummary
+var summaryFields = []string{
+	"uuid", "name", "kind", "progress", "start_time", "end_time", "last_changed",
+	"school_event_details", "training_details", "other_reason_details",
+}
+
+// listRequest is a parsed request to one of the application list endpoints
+type listRequest struct {
+	// Query is the query sent to the database
+	Query mongo.ApplicationQuery
+	// Page is the requested page, starting at 1
+	Page int64
+	// PerPage is the amount of applications per page
+	PerPage int64
+	// Summary is true if the applications should be returned as ApplicationSummary
+	Summary bool
+	// Username is the teacher the applications are filtered by, it is empty if no teacher filter was requested
+	Username string
+	// FilterUser is true if the username parameter was provided
+	FilterUser bool
+}
+
+// parseListRequest reads the paging, sorting, filter and projection parameters of an application list request
+func parseListRequest(con *gin.Context) (listRequest, error) {
+	_ = con.Request.ParseForm()
+	query := con.Request.URL.Query()
+	req := listRequest{}
+	req.Username = query.Get("username")
+	_, req.FilterUser = con.Request.Form["username"]
+	var err error
+	if req.Page, req.PerPage, err = parsePaging(query); err != nil {
+		return req, err
+	}
+	req.Query.Skip = (req.Page - 1) * req.PerPage
+	req.Query.Limit = req.PerPage
+	req.Query.SortBy = mongo.SortByLastChanged
+	if sortBy := query.Get("sort"); sortBy != "" {
+		if sortBy != mongo.SortByStartTime && sortBy != mongo.SortByLastChanged && sortBy != mongo.SortByName {
+			return req, fmt.Errorf("invalid sort field: %v", sortBy)
+		}
+		req.Query.SortBy = sortBy
+	}
+	switch order := query.Get("order"); order {
+	case "", "desc":
+		req.Query.Descending = true
+	case "asc":
+		req.Query.Descending = false
+	default:
+		return req, fmt.Errorf("invalid order: %v", order)
+	}
+	if req.Query.Filter.Kinds, err = parseIntList(query["kind"]); err != nil {
+		return req, fmt.Errorf("invalid kind: %v", err)
+	}
+	if req.Query.Filter.Progress, err = parseIntList(query["progress"]); err != nil {
+		return req, fmt.Errorf("invalid progress: %v", err)
+	}
+	if req.Query.Filter.From, err = parseTime(query.Get("from"), false); err != nil {
+		return req, fmt.Errorf("invalid from: %v", err)
+	}
+	if req.Query.Filter.Till, err = parseTime(query.Get("till"), true); err != nil {
+		return req, fmt.Errorf("invalid till: %v", err)
+	}
+	req.Query.Filter.Classes = splitList(query["class"])
+	req.Query.Filter.Department = query.Get("department")
+	fields := splitList(query["fields"])
+	if len(fields) == 1 && fields[0] == SummaryFields {
+		req.Summary = true
+		req.Query.Fields = summaryFields
+	} else if len(fields) > 0 {
+		names := mongo.ApplicationFieldNames()
+		for _, field := range fields {
+			if _, ok := names[field]; !ok {
+				return req, fmt.Errorf("invalid field: %v", field)
+			}
+		}
+		req.Query.Fields = fields
+	}
+	return req, nil
+}
+
+// parsePaging reads the page and per_page parameters of a list request
+func parsePaging(query url.Values) (int64, int64, error) {
+	page, perPage := int64(1), int64(DefaultPageSize)
+	var err error
+	if value := query.Get("page"); value != "" {
+		if page, err = strconv.ParseInt(value, 10, 64); err != nil || page < 1 {
+			return 0, 0, fmt.Errorf("invalid page: %v", value)
+		}
+	}
+	if value := query.Get("per_page"); value != "" {
+		if perPage, err = strconv.ParseInt(value, 10, 64); err != nil || perPage < 1 || perPage > MaxPageSize {
+			return 0, 0, fmt.Errorf("invalid per_page, it has to be between 1 and %v: %v", MaxPageSize, value)
+		}
+	}
+	return page, perPage, nil
+}
+
+// writePaging sends the total amount of matching entries and the paging in the X-Total-Count, X-Page and X-Per-Page headers
+func writePaging(con *gin.Context, total, page, perPage int64) {
+	con.Header("X-Total-Count", strconv.FormatInt(total, 10))
+	con.Header("X-Page", strconv.FormatInt(page, 10))
+	con.Header("X-Per-Page", strconv.FormatInt(perPage, 10))
+}
+
+// restrictProgress restricts the requested progress states to the allowed ones
+// returns false if none of the requested progress states is allowed, so no application can match the request
+func (req *listRequest) restrictProgress(allowed []int) bool {
+	if len(req.Query.Filter.Progress) == 0 {
+		req.Query.Filter.Progress = allowed
+		return true
+	}
+	progress := make([]int, 0)
+	for _, requested := range req.Query.Filter.Progress {
+		for _, a := range allowed {
+			if requested == a {
+				progress = append(progress, requested)
+				break
+			}
+		}
+	}
+	req.Query.Filter.Progress = progress
+	return len(progress) > 0
+}
+
+// respondApplications queries the requested page of applications and answers the request with it
+// the total amount of matching applications and the paging are sent in the X-Total-Count, X-Page and X-Per-Page headers
+func respondApplications(con *gin.Context, db mongo.MongoDatabaseConnector, req listRequest) {
+	applications, total, err := db.QueryApplications(req.Query)
+	if err != nil {
+		respondError(con, err, "applications")
+		return
+	}
+	writeApplications(con, req, applications, total)
+}
+
+// writeApplications answers the request with the given page of applications in the requested representation
+func writeApplications(con *gin.Context, req listRequest, applications []mongo.Application, total int64) {
+	if applications == nil {
+		applications = make([]mongo.Application, 0)
+	}
+	writePaging(con, total, req.Page, req.PerPage)
+	switch {
+	case req.Summary:
+		summaries := make([]ApplicationSummary, 0, len(applications))
+		for _, application := range applications {
+			summaries = append(summaries, summarize(application))
+		}
+		con.JSON(http.StatusOK, summaries)
+	case len(req.Query.Fields) > 0:
+		projected, err := projectApplications(applications, req.Query.Fields)
+		if err != nil {
+			con.JSON(http.StatusInternalServerError, Error{"couldn't project applications"})
+			return
+		}
+		con.JSON(http.StatusOK, projected)
+	default:
+		con.JSON(http.StatusOK, applications)
+	}
+}
+
+// summarize converts an application into its summary representation
+func summarize(application mongo.Application) ApplicationSummary {
+	summary := ApplicationSummary{
+		UUID:         application.UUID,
+		Name:         application.Name,
+		Kind:         application.Kind,
+		Progress:     application.Progress,
+		StartTime:    application.StartTime,
+		EndTime:      application.EndTime,
+		LastChanged:  application.LastChanged,
+		Classes:      application.SchoolEventDetails.Classes,
+		Participants: make([]string, 0),
+	}
+	switch application.Kind {
+	case mongo.SchoolEvent:
+		for _, teacher := range application.SchoolEventDetails.Teachers {
+			summary.Participants = append(summary.Participants, teacher.Shortname)
+		}
+	case mongo.Training:
+		summary.Participants = append(summary.Participants, application.TrainingDetails.Filer)
+	case mongo.OtherReason:
+		summary.Participants = append(summary.Participants, application.OtherReasonDetails.Filer)
+	}
+	if summary.Classes == nil {
+		summary.Classes = make([]string, 0)
+	}
+	return summary
+}
+
+// projectApplications reduces the applications to the given json fields, the uuid is always kept
+func projectApplications(applications []mongo.Application, fields []string) ([]map[string]json.RawMessage, error) {
+	projected := make([]map[string]json.RawMessage, 0, len(applications))
+	for _, application := range applications {
+		data, err := json.Marshal(application)
+		if err != nil {
+			return nil, err
+		}
+		all := make(map[string]json.RawMessage)
+		if err := json.Unmarshal(data, &all); err != nil {
+			return nil, err
+		}
+		reduced := map[string]json.RawMessage{"uuid": all["uuid"]}
+		for _, field := range fields {
+			reduced[field] = all[field]
+		}
+		projected = append(projected, reduced)
+	}
+	return projected, nil
+}
+
+// splitList splits every value of a repeatable query parameter at commas and drops empty entries
+func splitList(values []string) []string {
+	list := make([]string, 0)
+	for _, value := range values {
+		for _, entry := range strings.Split(value, ",") {
+			if entry = strings.TrimSpace(entry); entry != "" {
+				list = append(list, entry)
+			}
+		}
+	}
+	return list
+}
+
+// parseIntList parses a repeatable query parameter of comma separated integers
+func parseIntList(values []string) ([]int, error) {
+	list := make([]int, 0)
+	for _, entry := range splitList(values) {
+		i, err := strconv.Atoi(entry)
+		if err != nil {
+			return nil, fmt.Errorf("%v is not a number", entry)
+		}
+		list = append(list, i)
+	}
+	return list, nil
+}
+
+// parseTime parses a point in time given either as RFC 3339 timestamp or as date (2006-01-02)
+// a date is converted to its start, or to its end if endOfDay is set, an empty value results in the zero time
+func parseTime(value string, endOfDay bool) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	t, err := time.ParseInLocation("2006-01-02", value, time.Local)
+	if err != nil {
+		return t, err
+	}
+	if endOfDay {
+		t = t.AddDate(0, 0, 1).Add(-time.Nanosecond)
+	}
+	return t, nil
+}
@@ -1,8 +1,11 @@
 package rest
 
 import (
+	"context"
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
+	mongo "github.com/refundable-tgm/huginn/db"
+	"github.com/refundable-tgm/huginn/ldap"
 	// import to make swagger docs accessible
 	_ "github.com/refundable-tgm/huginn/docs"
 	ginSwagger "github.com/swaggo/gin-swagger"   // gin swagger middleware
@@ -10,7 +13,10 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
 	"strconv"
+	"syscall"
+	"time"
 )
 
 // Port is the port this api will listen to
@@ -19,6 +25,9 @@ const Port = 8080
 // DebugFilePath to where a .debug file lies
 const DebugFilePath = "/vol/files/.debug"
 
+// ShutdownTimeout is the time running requests get to finish when the service is stopped
+const ShutdownTimeout = 15 * time.Second
+
 // StartService starts the rest service
 // @title Refundable
 // @version 1.1
@@ -38,6 +47,17 @@ func StartService() {
 	// initializing Token Manager
 	InitTokenManager()
 
+	// initializing the shared database connection pool, migrations and indexes
+	if err := mongo.InitPool(); err != nil {
+		log.Fatal(err)
+	}
+	if err := mongo.PrepareDatabase(context.Background()); err != nil {
+		log.Fatal(err)
+	}
+
+	// synchronizing the teachers with the directory periodically
+	ldap.StartScheduledSync()
+
 	// Setting Mode of API
 	if debugMode() {
 		gin.SetMode(gin.DebugMode)
@@ -47,6 +67,13 @@ func StartService() {
 
 	// Creating new Router
 	router := gin.Default()
+	// client addresses are resolved by clientIP, which only trusts X-Forwarded-For of the proxies in TRUSTED_PROXIES
+	router.ForwardedByClientIP = false
+	proxies, err := loadTrustedProxies()
+	if err != nil {
+		log.Fatal(err)
+	}
+	trustedProxies = proxies
 
 	// Handling CORS Requests
 	config := cors.DefaultConfig()
@@ -61,21 +88,55 @@ func StartService() {
 		api.POST("/login", Login)
 		api.POST("/logout", AuthWall(), Logout)
 		api.POST("/login/refresh", Refresh)
+		api.GET("/login/providers", GetLoginProviders)
+		api.GET("/login/redirect", BeginRedirectLogin)
+		api.POST("/login/callback", FinishRedirectLogin)
+		api.POST("/login/twoFactor", AnswerLoginChallenge)
+		api.POST("/login/twoFactor/enroll", EnrollLoginChallenge)
+		api.POST("/twoFactor/enroll", AuthWall(), EnrollTwoFactor)
+		api.POST("/twoFactor/confirm", AuthWall(), ConfirmTwoFactor)
+		api.POST("/twoFactor/disable", AuthWall(), DisableTwoFactor)
+		api.POST("/twoFactor/recoveryCodes", AuthWall(), RegenerateRecoveryCodes)
 		api.GET("/getTeacherByShort", AuthWall(), GetTeacherByShort)
 		api.GET("/getTeacher", AuthWall(), GetTeacher)
 		api.GET("/getTeacherByUntis", AuthWall(), GetTeacherByUntis)
 		api.POST("/setTeacherPermissions", AuthWall(), SetTeacherPermissions)
+		api.POST("/setLocalAccount", AuthWall(), SetLocalAccount)
+		api.POST("/resetTwoFactor", AuthWall(), ResetTwoFactor)
+		api.GET("/getLockouts", AuthWall(), GetLockouts)
+		api.DELETE("/clearLockout", AuthWall(), ClearLockout)
+		api.GET("/getAuditEntries", AuthWall(), GetAuditEntries)
+		api.POST("/createAPIKey", AuthWall(), CreateAPIKey)
+		api.GET("/getAPIKeys", AuthWall(), GetAPIKeys)
+		api.DELETE("/revokeAPIKey", AuthWall(), RevokeAPIKey)
 		api.PUT("/updateTeacherInformation", AuthWall(), UpdateTeacherInformation)
+		api.GET("/teachers", AuthWall(), GetTeachers)
+		api.POST("/createTeacher", AuthWall(), CreateTeacher)
+		api.PUT("/updateTeacher", AuthWall(), UpdateTeacher)
+		api.POST("/deactivateTeacher", AuthWall(), DeactivateTeacher)
+		api.POST("/reactivateTeacher", AuthWall(), ReactivateTeacher)
+		api.POST("/mergeTeachers", AuthWall(), MergeTeachers)
+		api.POST("/createDelegation", AuthWall(), CreateDelegation)
+		api.GET("/getDelegations", AuthWall(), GetDelegations)
+		api.DELETE("/revokeDelegation", AuthWall(), RevokeDelegation)
+		api.POST("/createDeputy", AuthWall(), CreateDeputy)
+		api.GET("/getDeputies", AuthWall(), GetDeputies)
+		api.DELETE("/revokeDeputy", AuthWall(), RevokeDeputy)
 		api.GET("/getActiveApplications", AuthWall(), GetActiveApplications)
 		api.GET("/getAllApplications", AuthWall(), GetAllApplications)
 		api.GET("/getNews", AuthWall(), GetNews)
 		api.GET("/getAdminApplications", AuthWall(), GetAdminApplications)
 		api.GET("/getApplication", AuthWall(), GetApplication)
+		api.GET("/search", AuthWall(), Search)
+		api.POST("/invalidateUntisCache", AuthWall(), InvalidateUntisCache)
+		api.POST("/syncTeachers", AuthWall(), SyncTeachers)
 		api.POST("/createApplication", AuthWall(), CreateApplication)
 		api.PUT("/updateApplication", AuthWall(), UpdateApplication)
+		api.POST("/checkConflicts", AuthWall(), CheckConflicts)
 		api.DELETE("/deleteApplication", AuthWall(), DeleteApplication)
 		api.GET("/getAbsenceFormForClasses", AuthWall(), GetAbsenceFormForClasses)
 		api.GET("/getAbsenceFormForTeacher", AuthWall(), GetAbsenceFormForTeacher)
+		api.GET("/getSubstitutionSuggestions", AuthWall(), GetSubstitutionSuggestions)
 		api.GET("/getCompensationForEducationalSupportForm", AuthWall(), GetCompensationForEducationalSupportForm)
 		api.GET("/getTravelInvoiceForm", AuthWall(), GetTravelInvoiceForm)
 		api.GET("/getBusinessTripApplicationForm", AuthWall(), GetBusinessTripApplicationForm)
@@ -96,7 +157,28 @@ func StartService() {
 	})
 
 	// Starting
-	log.Fatal(router.Run(":" + strconv.Itoa(Port)))
+	server := &http.Server{
+		Addr:    ":" + strconv.Itoa(Port),
+		Handler: router,
+	}
+	go func() {
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Fatal(err)
+		}
+	}()
+
+	// Stopping gracefully on SIGINT or SIGTERM
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	<-quit
+	ctx, cancel := context.WithTimeout(context.Background(), ShutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		log.Println(err)
+	}
+	if err := mongo.ClosePool(ctx); err != nil {
+		log.Println(err)
+	}
 }
 
 // setDebugMode analyzes whether a .debug File is present (DebugFilePath)
@@ -0,0 +1,162 @@
+package rest
+
+import (
+	mongo "github.com/refundable-tgm/huginn/db"
+	"html"
+	"strings"
+	"unicode"
+)
+
+// SearchLimit is the amount of search results returned if the request doesn't specify a limit
+const SearchLimit = 20
+
+// MaxSearchLimit is the maximum amount of search results returned
+const MaxSearchLimit = 100
+
+// SnippetLength is the maximum amount of characters of a highlighted snippet
+const SnippetLength = 160
+
+// HighlightStart and HighlightEnd enclose every matching part of a snippet
+const (
+	HighlightStart = "<em>"
+	HighlightEnd   = "</em>"
+)
+
+// germanSuffixes are stripped from search terms, so inflected forms of a term are highlighted as well
+var germanSuffixes = []string{"ern", "en", "er", "es", "em", "e", "n", "s"}
+
+// searchTerms extracts the terms of a search text that should be highlighted
+// negated terms (starting with -) are dropped, every term is lower cased and stemmed
+func searchTerms(text string) []string {
+	terms := make([]string, 0)
+	for _, word := range strings.Fields(strings.ReplaceAll(text, "\"", " ")) {
+		if strings.HasPrefix(word, "-") {
+			continue
+		}
+		parts := strings.FieldsFunc(strings.ToLower(word), func(r rune) bool {
+			return !isWordRune(r)
+		})
+		for _, part := range parts {
+			terms = append(terms, stem(part))
+		}
+	}
+	return terms
+}
+
+// stem strips the first matching german suffix of a term as long as at least four characters remain
+func stem(term string) string {
+	for _, suffix := range germanSuffixes {
+		if strings.HasSuffix(term, suffix) && len([]rune(term))-len([]rune(suffix)) >= 4 {
+			return strings.TrimSuffix(term, suffix)
+		}
+	}
+	return term
+}
+
+// isWordRune checks whether a rune is part of a word
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// highlight returns a highlighted snippet of every searched field of the application containing one of the terms
+func highlight(application mongo.Application, terms []string) []Highlight {
+	fields := []struct {
+		name   string
+		values []string
+	}{
+		{"name", []string{application.Name}},
+		{"notes", []string{application.Notes}},
+		{"destination_address", []string{application.DestinationAddress}},
+		{"training_details.organizer", []string{application.TrainingDetails.Organizer}},
+		{"school_event_details.classes", application.SchoolEventDetails.Classes},
+		{"school_event_details.teachers.name", teacherNames(application)},
+		{"training_details.filer", []string{application.TrainingDetails.Filer}},
+		{"other_reason_details.filer", []string{application.OtherReasonDetails.Filer}},
+	}
+	highlights := make([]Highlight, 0)
+	for _, field := range fields {
+		for _, value := range field.values {
+			if snippet, ok := snippet(value, terms); ok {
+				highlights = append(highlights, Highlight{field.name, snippet})
+			}
+		}
+	}
+	return highlights
+}
+
+// teacherNames returns the names of all teachers of a school event
+func teacherNames(application mongo.Application) []string {
+	names := make([]string, 0)
+	for _, teacher := range application.SchoolEventDetails.Teachers {
+		names = append(names, teacher.Name)
+	}
+	return names
+}
+
+// snippet marks every word of the text starting with one of the terms and cuts it to SnippetLength characters around the first one
+// the text is html escaped, so the snippet can be rendered as html
+// returns false if none of the terms occurs in the text
+func snippet(text string, terms []string) (string, bool) {
+	runes := []rune(text)
+	lower := []rune(strings.Map(unicode.ToLower, text))
+	marked := make([]bool, len(runes))
+	found := false
+	for _, term := range terms {
+		t := []rune(term)
+		if len(t) == 0 {
+			continue
+		}
+		for i := 0; i+len(t) <= len(lower); i++ {
+			if (i > 0 && isWordRune(lower[i-1])) || string(lower[i:i+len(t)]) != term {
+				continue
+			}
+			// the whole word starting with the term is marked, so inflected forms are highlighted completely
+			for j := i; j < len(lower) && (j < i+len(t) || isWordRune(lower[j])); j++ {
+				marked[j] = true
+			}
+			found = true
+		}
+	}
+	if !found {
+		return "", false
+	}
+	start, end := 0, len(runes)
+	if len(runes) > SnippetLength {
+		first := 0
+		for first < len(marked) && !marked[first] {
+			first++
+		}
+		start = first - SnippetLength/4
+		if start < 0 {
+			start = 0
+		}
+		end = start + SnippetLength
+		if end > len(runes) {
+			end = len(runes)
+			start = end - SnippetLength
+		}
+	}
+	var b strings.Builder
+	if start > 0 {
+		b.WriteString("…")
+	}
+	// the text is escaped run by run, so only the inserted markers are markup
+	for i := start; i < end; {
+		j := i
+		for j < end && marked[j] == marked[i] {
+			j++
+		}
+		if marked[i] {
+			b.WriteString(HighlightStart)
+		}
+		b.WriteString(html.EscapeString(string(runes[i:j])))
+		if marked[i] {
+			b.WriteString(HighlightEnd)
+		}
+		i = j
+	}
+	if end < len(runes) {
+		b.WriteString("…")
+	}
+	return b.String(), true
+}
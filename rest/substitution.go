@@ -0,0 +1,59 @@
+package rest
+
+import (
+	mongo "github.com/refundable-tgm/huginn/db"
+	"github.com/refundable-tgm/huginn/untis"
+	"sort"
+	"time"
+)
+
+// toSubstitutionSuggestion converts a suggestion of the untis package into its api representation
+func toSubstitutionSuggestion(suggestion untis.Suggestion) SubstitutionSuggestion {
+	lesson := suggestion.Lesson
+	candidates := make([]SubstitutionCandidate, 0, len(suggestion.Candidates))
+	for _, c := range suggestion.Candidates {
+		candidates = append(candidates, SubstitutionCandidate{c.Teacher, c.Load, c.TeachesClass, c.TeachesSubject, c.FreedUp})
+	}
+	return SubstitutionSuggestion{
+		Date:       lesson.Start.Format("02.01.2006"),
+		Start:      untis.GetLessonNrByStart(lesson.Start),
+		End:        untis.GetLessonNrByEnd(lesson.End),
+		Classes:    nonNil(lesson.Classes),
+		Subjects:   nonNil(lesson.Subjects),
+		Rooms:      nonNil(lesson.Rooms),
+		Teachers:   nonNil(lesson.Teachers),
+		Candidates: candidates,
+	}
+}
+
+// absenceOf returns the absence caused by the application in the time zone of the school
+// attendances are the intervals the absent teachers are away in by their untis names
+func absenceOf(application mongo.Application, attendances map[string][]mongo.Attendance, classes []string) (untis.Absence, error) {
+	loc, err := time.LoadLocation("Europe/Vienna")
+	if err != nil {
+		return untis.Absence{}, err
+	}
+	absence := untis.Absence{
+		Start:     application.StartTime.In(loc),
+		End:       application.EndTime.In(loc),
+		Teachers:  make([]string, 0, len(attendances)),
+		Intervals: make(map[string][]untis.Interval, len(attendances)),
+		Classes:   classes,
+	}
+	for teacher, intervals := range attendances {
+		absence.Teachers = append(absence.Teachers, teacher)
+		for _, interval := range intervals {
+			absence.Intervals[teacher] = append(absence.Intervals[teacher], untis.Interval{Start: interval.From.In(loc), End: interval.Till.In(loc)})
+		}
+	}
+	sort.Strings(absence.Teachers)
+	return absence, nil
+}
+
+// nonNil returns an empty slice instead of nil, so it is encoded as empty json array
+func nonNil(values []string) []string {
+	if values == nil {
+		return make([]string, 0)
+	}
+	return values
+}
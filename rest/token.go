@@ -2,9 +2,12 @@ package rest
 
 import (
 	"bufio"
+	"context"
 	"fmt"
 	"github.com/dgrijalva/jwt-go"
 	"github.com/google/uuid"
+	mongo "github.com/refundable-tgm/huginn/db"
+	"github.com/refundable-tgm/huginn/untis"
 	"log"
 	"math/rand"
 	"net/http"
@@ -62,8 +65,13 @@ type AccessToken struct {
 	AccessUUID string
 	// Username is the username of the user this token belongs to
 	Username string
+	// APIKey is the id of the API key the request was authenticated with, it is empty for access tokens
+	APIKey string
 }
 
+// apiKeyContextKey is the key the AuthWall stores the meta information of an accepted API key at in the request context
+type apiKeyContextKey struct{}
+
 // EntityInformation represents information about tokens
 type EntityInformation struct {
 	// Username identifies the user this token belongs to
@@ -164,7 +172,11 @@ func TokenValid(r *http.Request) (bool, error) {
 }
 
 // ExtractTokenMeta extracts the meta information encoded in the token and returns both uuid and username
+// requests authenticated with an API key return the service principal of the key as username
 func ExtractTokenMeta(r *http.Request) (*AccessToken, error) {
+	if meta, ok := r.Context().Value(apiKeyContextKey{}).(*AccessToken); ok {
+		return meta, nil
+	}
 	token, err := VerifyToken(r)
 	if err != nil {
 		return nil, err
@@ -187,11 +199,37 @@ func ExtractTokenMeta(r *http.Request) (*AccessToken, error) {
 	return nil, err
 }
 
+// withAPIKey returns the request with the meta information of the accepted API key stored in its context
+func withAPIKey(r *http.Request, key mongo.APIKey) *http.Request {
+	meta := &AccessToken{AccessUUID: key.ID, Username: key.Principal, APIKey: key.ID}
+	return r.WithContext(context.WithValue(r.Context(), apiKeyContextKey{}, meta))
+}
+
 // DeleteToken deletes a token
 func DeleteToken(uuid string) {
 	delete(activeTokens, uuid)
 }
 
+// hasTokens checks whether the user has any active token left
+func hasTokens(username string) bool {
+	for _, value := range activeTokens {
+		if value.Username == username {
+			return true
+		}
+	}
+	return false
+}
+
+// DeleteTokensOf deletes all tokens of the user, so every session of the user ends
+func DeleteTokensOf(username string) {
+	for key, value := range activeTokens {
+		if value.Username == username {
+			delete(activeTokens, key)
+		}
+	}
+	untis.RemoveClient(username)
+}
+
 // readAccessSecret manages the refresh secret generation
 func readAccessSecret() {
 	if _, err := os.Stat(pathAccessSecret); os.IsNotExist(err) {
@@ -263,14 +301,23 @@ func readRefreshSecret() {
 }
 
 // ttlCheck checks whether tokens expired and removes them
+// the untis session of a user is removed as soon as the last token of the user expired
 func ttlCheck() {
 	for {
 		now := time.Now()
+		expired := make(map[string]bool)
 		for key, value := range activeTokens {
 			if value.ExpiresAt.Before(now) {
 				delete(activeTokens, key)
+				expired[value.Username] = true
 			}
 		}
+		for _, value := range activeTokens {
+			delete(expired, value.Username)
+		}
+		for username := range expired {
+			untis.RemoveClient(username)
+		}
 		time.Sleep(time.Minute)
 	}
 }
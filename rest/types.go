@@ -1,5 +1,10 @@
 package rest
 
+import (
+	mongo "github.com/refundable-tgm/huginn/db"
+	"time"
+)
+
 // User data input
 type User struct {
 	// Username of the user
@@ -16,6 +21,74 @@ type TokenPair struct {
 	RefreshToken string `json:"refresh_token" example:"<jwt-token>"`
 }
 
+// RedirectURL is the url of the login page of an external identity provider
+type RedirectURL struct {
+	// URL the user has to be redirected to
+	URL string `json:"url" example:"https://login.microsoftonline.com/<tenant>/oauth2/v2.0/authorize?..."`
+}
+
+// LoginCallback is the result of a login on the page of an external identity provider
+type LoginCallback struct {
+	// Code returned by the identity provider
+	Code string `json:"code" example:"0.AQwAsd..."`
+	// State returned by the identity provider
+	State string `json:"state" example:"9f86d081884c7d659a2feaa0c55ad015"`
+}
+
+// LoginChallenge is the second step of a login, which has to be answered with a one-time password or recovery code
+type LoginChallenge struct {
+	// Challenge identifies the login
+	Challenge string `json:"challenge" example:"4f1c2a9e8b7d6c5a4f1c2a9e8b7d6c5a"`
+	// Enrolled is false if the user has to enrol a second factor at /login/twoFactor/enroll first
+	Enrolled bool `json:"enrolled" example:"true"`
+	// Expires is the time the challenge has to be answered by
+	Expires time.Time `json:"expires"`
+}
+
+// ChallengeAnswer answers the second step of a login
+type ChallengeAnswer struct {
+	// Challenge returned by the login
+	Challenge string `json:"challenge" example:"4f1c2a9e8b7d6c5a4f1c2a9e8b7d6c5a"`
+	// Code is a one-time password or a recovery code
+	Code string `json:"code" example:"492039"`
+}
+
+// TwoFactorLogin is the token pair of a login with a second factor
+type TwoFactorLogin struct {
+	// the access token
+	AccessToken string `json:"access_token" example:"<jwt-token>"`
+	// the refresh token
+	RefreshToken string `json:"refresh_token" example:"<jwt-token>"`
+	// the recovery codes, only returned once if the second factor was enrolled during the login
+	RecoveryCodes []string `json:"recovery_codes,omitempty" example:"7kq2-m9xd-p4tz"`
+}
+
+// TwoFactorCode is a one-time password or a recovery code
+type TwoFactorCode struct {
+	// Code is a one-time password or a recovery code
+	Code string `json:"code" example:"492039"`
+}
+
+// RecoveryCodes are the codes which can be used once instead of a one-time password
+type RecoveryCodes struct {
+	// Codes are the recovery codes, they are only shown once
+	Codes []string `json:"codes" example:"7kq2-m9xd-p4tz"`
+}
+
+// LocalAccount is the data of a teacher logging in with a password stored in huginn
+type LocalAccount struct {
+	// Short name the account logs in with
+	Short string `json:"short" example:"gast1"`
+	// Longname (firstname + sirname) of the account
+	Longname string `json:"longname" example:"Maria Muster"`
+	// Mail address of the account
+	Mail string `json:"mail" example:"maria.muster@example.com"`
+	// Untis abbrevation of the account, if it has one
+	Untis string `json:"untis" example:""`
+	// Password of the account
+	Password string `json:"password" example:"correct horse battery"`
+}
+
 // Error maps an error message
 type Error struct {
 	// the message that should be sent
@@ -64,6 +137,8 @@ type Permissions struct {
 	AV bool `json:"av" example:"true"`
 	// PEK permission
 	PEK bool `json:"pek" example:"true"`
+	// DepartmentHead lists the departments the teacher is head of
+	DepartmentHead []string `json:"department_head" example:"HIT"`
 }
 
 // News is a news object for applications
@@ -94,3 +169,203 @@ type Excel struct {
 	// Content is the content of the excel file
 	Content string `json:"excel" example:"<base64>"`
 }
+
+// ApplicationSummary is the reduced representation of an application used in lists
+type ApplicationSummary struct {
+	// UUID of the application
+	UUID string `json:"uuid" example:"3fcf7f67-e0ed-4339-99b4-a6765aaa3dc4"`
+	// Name of the application
+	Name string `json:"name" example:"Sommersportwoche"`
+	// Kind of the application
+	Kind int `json:"kind" example:"0"`
+	// Progress of the application
+	Progress int `json:"progress" example:"3"`
+	// StartTime is the time the underlying event starts
+	StartTime time.Time `json:"start_time"`
+	// EndTime is the time the underlying event ends
+	EndTime time.Time `json:"end_time"`
+	// LastChanged is the time the application was changed last
+	LastChanged time.Time `json:"last_changed"`
+	// Classes participating in the application (only set for school events)
+	Classes []string `json:"classes" example:"5AHIT,5BHIT"`
+	// Participants are the short names of the teachers of a school event or the filer of any other application
+	Participants []string `json:"participants" example:"SCHF,ZAKS"`
+}
+
+// SearchHit is an application matching a search
+type SearchHit struct {
+	// Application is the summary of the matching application
+	Application ApplicationSummary `json:"application"`
+	// Score is the relevance of the application, higher scores are more relevant
+	Score float64 `json:"score" example:"1.5"`
+	// Highlights are snippets of the fields matching the search
+	Highlights []Highlight `json:"highlights"`
+}
+
+// Highlight is a snippet of a field matching a search, matching parts are enclosed in <em> tags
+// the text of the field is html escaped, so the em tags are the only markup
+type Highlight struct {
+	// Field is the json path of the matching field
+	Field string `json:"field" example:"destination_address"`
+	// Snippet is the highlighted part of the field
+	Snippet string `json:"snippet" example:"Jugendgästehaus <em>Wien</em> Brigittenau"`
+}
+
+// SubstitutionSuggestion lists the teachers suggested to substitute a lesson missed because of an application
+type SubstitutionSuggestion struct {
+	// Date of the lesson
+	Date string `json:"date" example:"01.03.2021"`
+	// Start is the number of the first period of the lesson
+	Start int `json:"start" example:"3"`
+	// End is the number of the last period of the lesson
+	End int `json:"end" example:"4"`
+	// Classes attending the lesson
+	Classes []string `json:"classes" example:"5AHIT"`
+	// Subjects taught in the lesson
+	Subjects []string `json:"subjects" example:"SEW"`
+	// Rooms the lesson takes place in
+	Rooms []string `json:"rooms" example:"H1101"`
+	// Teachers are the untis names of the absent teachers of the lesson
+	Teachers []string `json:"teachers" example:"ZAKS"`
+	// Candidates are the suggested substitutes ordered by their suitability
+	Candidates []SubstitutionCandidate `json:"candidates"`
+}
+
+// SubstitutionCandidate is a teacher suggested to substitute a lesson
+type SubstitutionCandidate struct {
+	// Teacher is the untis name of the teacher
+	Teacher string `json:"teacher" example:"BORM"`
+	// Load is the amount of lessons the teacher teaches on the day of the lesson
+	Load int `json:"load" example:"4"`
+	// TeachesClass whether the teacher teaches one of the classes of the lesson
+	TeachesClass bool `json:"teaches_class" example:"true"`
+	// TeachesSubject whether the teacher teaches one of the subjects of the lesson
+	TeachesSubject bool `json:"teaches_subject" example:"false"`
+	// FreedUp whether the teacher is only free because their own class takes part in the same event
+	FreedUp bool `json:"freed_up" example:"false"`
+}
+
+// Conflict of an application with another application or with an exam in untis
+type Conflict struct {
+	// Kind of the conflict (teacher, class, exam or unchecked)
+	Kind string `json:"kind" example:"class"`
+	// Blocking whether the conflict prevents saving the application
+	Blocking bool `json:"blocking" example:"false"`
+	// Message describing the conflict
+	Message string `json:"message" example:"5AHIT also take part in Sommersportwoche"`
+	// Application is the uuid of the conflicting application, it is empty for conflicts with exams
+	Application string `json:"application" example:"693aa616-9895-418b-8904-765f0f6d26a4"`
+	// Name of the conflicting application
+	Name string `json:"name" example:"Sommersportwoche"`
+	// Teachers are the short names of the teachers affected by the conflict (untis names for exams)
+	Teachers []string `json:"teachers" example:"szakall"`
+	// Classes affected by the conflict
+	Classes []string `json:"classes" example:"5AHIT"`
+	// Subjects of the conflicting exam
+	Subjects []string `json:"subjects" example:"SEW"`
+	// Start of the conflicting application or exam
+	Start time.Time `json:"start"`
+	// End of the conflicting application or exam
+	End time.Time `json:"end"`
+}
+
+// SavedApplication is the response to saving an application
+type SavedApplication struct {
+	// the message that should be sent
+	Message string `json:"info" example:"success; application created"`
+	// UUID of the saved application
+	UUID string `json:"uuid" example:"693aa616-9895-418b-8904-765f0f6d26a4"`
+	// Conflicts of the application which didn't prevent saving it
+	Conflicts []Conflict `json:"conflicts"`
+}
+
+// ConflictError is the response if an application couldn't be saved because of blocking conflicts
+type ConflictError struct {
+	// the message that should be sent
+	Message string `json:"error" example:"the application conflicts with other appointments"`
+	// Conflicts of the application, including the ones which don't block saving it
+	Conflicts []Conflict `json:"conflicts"`
+}
+
+// APIKeyRequest is the data needed to create an API key
+type APIKeyRequest struct {
+	// Principal is the short name of the service principal the key acts as, it is created if it doesn't exist
+	Principal string `json:"principal" example:"svc-pek-export"`
+	// Longname is the name of a service principal which is created
+	Longname string `json:"longname" example:"PEK Export"`
+	// Name describes what the key is used for
+	Name string `json:"name" example:"PEK monthly export"`
+	// Endpoints lists the endpoints the key may call, at least one has to be listed
+	Endpoints []string `json:"endpoints" example:"/getAllApplications,/getTravelInvoiceExcel"`
+	// ExpiresAt is the time the key isn't accepted anymore, at most two years from now
+	ExpiresAt time.Time `json:"expires_at"`
+	// Permissions replace the permissions of the service principal if they are given
+	// they can't contain super user rights or roles the requesting administrator lacks
+	Permissions *Permissions `json:"permissions"`
+}
+
+// CreatedAPIKey is a newly created API key
+type CreatedAPIKey struct {
+	// Key is the API key itself, it is only shown once
+	Key string `json:"key" example:"huginn_3f9c2a7e41b8d605_8d1f..."`
+	// APIKey is the stored information about the key
+	APIKey mongo.APIKey `json:"api_key"`
+}
+
+// TeacherData is the data administrators create or update a teacher with
+type TeacherData struct {
+	// Short name of the teacher, it can't be changed after the teacher was created
+	Short string `json:"short" example:"szakall"`
+	// Longname (firstname + surname) of the teacher, it is read from the directory if it is empty
+	Longname string `json:"longname" example:"Stefan Zakall"`
+	// Untis abbrevation of the teacher
+	Untis string `json:"untis" example:"ZAKS"`
+	// Mail address of the teacher
+	Mail string `json:"mail" example:"szakall@tgm.ac.at"`
+	// Departments the teacher belongs to
+	Departments []string `json:"departments" example:"HIT,HBG"`
+	// Degree of the teacher
+	Degree string `json:"degree" example:"DI"`
+	// Title of the teacher
+	Title string `json:"title" example:"Prof"`
+	// Staffnr of the teacher
+	Staffnr int `json:"staffnr" example:"938503154"`
+	// Group number of the teacher
+	Group int `json:"group" example:"1"`
+	// Provider the teacher logs in with (ldap or oidc), it can't be changed after the teacher was created
+	Provider string `json:"provider" example:"ldap"`
+}
+
+// MergeResult is the teacher duplicates were merged into
+type MergeResult struct {
+	// Teacher is the merged teacher
+	Teacher mongo.Teacher `json:"teacher"`
+	// Applications is the amount of applications whose participants were rewritten
+	Applications int `json:"applications" example:"3"`
+}
+
+// DelegationRequest is the data needed to let another teacher act on behalf of a teacher
+type DelegationRequest struct {
+	// Delegator is the short name of the teacher whose applications may be edited, the requesting teacher if it is empty
+	// only administrators may create delegations for other teachers
+	Delegator string `json:"delegator" example:"szakall"`
+	// Delegate is the short name of the teacher acting on behalf of the delegator
+	Delegate string `json:"delegate" example:"ehuber"`
+	// From is the time the delegation starts, now if it is empty
+	From time.Time `json:"from"`
+	// Till is the time the delegation ends, at most a year after it starts
+	Till time.Time `json:"till"`
+}
+
+// DeputyRequest is the data needed to let a teacher approve applications in place of an approver
+type DeputyRequest struct {
+	// Approver is the short name of the absent approver, the requesting teacher if it is empty
+	// only administrators may name deputies of other approvers
+	Approver string `json:"approver" example:"mborko"`
+	// Deputy is the short name of the teacher approving in place of the approver
+	Deputy string `json:"deputy" example:"szakall"`
+	// From is the time the assignment starts, now if it is empty
+	From time.Time `json:"from"`
+	// Till is the time the assignment ends, at most a year after it starts
+	Till time.Time `json:"till"`
+}
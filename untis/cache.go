@@ -0,0 +1,116 @@
+package untis
+
+import (
+	"sync"
+	"time"
+)
+
+// MetadataTTL is the time the cached teachers, rooms, classes and subjects are used before they are loaded again
+const MetadataTTL = time.Hour
+
+// methods of the untis api returning the metadata of a school
+const (
+	teachersMethod = "getTeachers"
+	roomsMethod    = "getRooms"
+	classesMethod  = "getKlassen"
+	subjectsMethod = "getSubjects"
+)
+
+// Element is a teacher, room, class or subject as returned by the untis api
+type Element struct {
+	// ID of the element
+	ID int `json:"id"`
+	// Name is the short name of the element
+	Name string `json:"name"`
+	// ForeName is the first name of a teacher
+	ForeName string `json:"foreName"`
+	// LongName is the full name of the element
+	LongName string `json:"longName"`
+}
+
+// elementRef is a reference to an element inside of a timetable
+type elementRef struct {
+	ID int `json:"id"`
+}
+
+// cachedElements are the elements returned by one method of the untis api
+type cachedElements struct {
+	// elements returned by the method
+	elements []Element
+	// loaded is the time the elements were loaded at
+	loaded time.Time
+}
+
+// metadata caches the elements of each method of the untis api
+var metadata = struct {
+	sync.RWMutex
+	methods map[string]cachedElements
+}{methods: make(map[string]cachedElements)}
+
+// Elements returns the elements returned by the given method of the untis api
+// they are only requested from the service if they aren't cached or are older than MetadataTTL
+func (client *Client) Elements(method string) ([]Element, error) {
+	metadata.RLock()
+	cached, ok := metadata.methods[method]
+	metadata.RUnlock()
+	if ok && time.Since(cached.loaded) < MetadataTTL {
+		return cached.elements, nil
+	}
+	elements := make([]Element, 0)
+	if err := client.call(method, map[string]interface{}{}, &elements); err != nil {
+		return nil, err
+	}
+	metadata.Lock()
+	defer metadata.Unlock()
+	metadata.methods[method] = cachedElements{elements, time.Now()}
+	return elements, nil
+}
+
+// InvalidateMetadata drops all cached teachers, rooms, classes and subjects
+func InvalidateMetadata() {
+	metadata.Lock()
+	defer metadata.Unlock()
+	metadata.methods = make(map[string]cachedElements)
+}
+
+// names returns the names of the elements returned by the given method mapped by their ids
+func (client *Client) names(method string) (map[int]string, error) {
+	elements, err := client.Elements(method)
+	if err != nil {
+		return nil, err
+	}
+	names := make(map[int]string, len(elements))
+	for _, element := range elements {
+		names[element.ID] = element.Name
+	}
+	return names, nil
+}
+
+// resolve converts the ids of elements returned by the given method into their names
+func (client *Client) resolve(method string, ids []int) ([]string, error) {
+	names, err := client.names(method)
+	if err != nil {
+		return nil, err
+	}
+	return lookup(names, ids), nil
+}
+
+// lookup returns the names of the given ids in their order, unknown ids are skipped
+func lookup(names map[int]string, ids []int) []string {
+	resolved := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if name, ok := names[id]; ok {
+			resolved = append(resolved, name)
+		}
+	}
+	return resolved
+}
+
+// ids extracts the ids out of the element references of a lesson
+func ids(refs []elementRef) []int {
+	ids := make([]int, 0, len(refs))
+	for _, ref := range refs {
+		ids = append(ids, ref.ID)
+	}
+	return ids
+}
@@ -0,0 +1,50 @@
+package untis
+
+// Exams returns the exams of the absent classes and teachers taking place during the absence
+// exams of teachers are only returned if they take place during the intervals the teacher is absent in
+// cancelled exams are left out, an exam listed in the timetables of several classes or teachers is only returned once
+func (client *Client) Exams(absence Absence) ([]Lesson, error) {
+	classIDs, err := client.idsByName(classesMethod)
+	if err != nil {
+		return nil, err
+	}
+	teacherIDs, err := client.idsByName(teachersMethod)
+	if err != nil {
+		return nil, err
+	}
+	// the times of lessons are the wall clock times of the school stored as UTC
+	start, end := wallClock(absence.Start), wallClock(absence.End)
+	exams := make([]Lesson, 0)
+	seen := make(map[int]bool)
+	collect := func(id, elementType int, absent func(Lesson) bool) error {
+		timetable, err := client.getTimetable(id, elementType, absence.Start, absence.End)
+		if err != nil {
+			return err
+		}
+		for _, lesson := range timetable {
+			if lesson.Type == LessonTypeExamination && !lesson.Cancelled() &&
+				lesson.End.After(start) && lesson.Start.Before(end) && absent(lesson) && !seen[lesson.ID] {
+				seen[lesson.ID] = true
+				exams = append(exams, lesson)
+			}
+		}
+		return nil
+	}
+	for _, class := range absence.Classes {
+		if id, ok := classIDs[class]; ok {
+			if err := collect(id, ClassType, func(Lesson) bool { return true }); err != nil {
+				return nil, err
+			}
+		}
+	}
+	for _, teacher := range absence.Teachers {
+		teacher := teacher
+		absent := func(lesson Lesson) bool { return absence.AbsentDuring(teacher, lesson) }
+		if id, ok := teacherIDs[teacher]; ok {
+			if err := collect(id, TeacherType, absent); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return GroupLessons(exams), nil
+}
@@ -0,0 +1,186 @@
+package untis
+
+import (
+	"sort"
+	"time"
+)
+
+// WithoutCancelled removes all cancelled lessons, as they don't need a substitution
+func WithoutCancelled(lessons []Lesson) []Lesson {
+	remaining := make([]Lesson, 0, len(lessons))
+	for _, lesson := range lessons {
+		if !lesson.Cancelled() {
+			remaining = append(remaining, lesson)
+		}
+	}
+	return remaining
+}
+
+// During returns the lessons overlapping at least one of the intervals, which have to be given in the time zone of the school
+func During(lessons []Lesson, intervals []Interval) []Lesson {
+	remaining := make([]Lesson, 0, len(lessons))
+	for _, lesson := range lessons {
+		for _, interval := range intervals {
+			// the times of lessons are the wall clock times of the school stored as UTC
+			if lesson.End.After(wallClock(interval.Start)) && lesson.Start.Before(wallClock(interval.End)) {
+				remaining = append(remaining, lesson)
+				break
+			}
+		}
+	}
+	return remaining
+}
+
+// MergeParallelLessons merges all lessons taking place at the same time into one lesson
+// the classes, teachers, rooms and subjects of the merged lessons are combined without duplicates
+// the returned lessons are sorted by their start and end
+func MergeParallelLessons(lessons []Lesson) []Lesson {
+	merged := make([]Lesson, 0, len(lessons))
+	for _, lesson := range sortedLessons(lessons) {
+		last := len(merged) - 1
+		if last >= 0 && merged[last].Start.Equal(lesson.Start) && merged[last].End.Equal(lesson.End) {
+			merged[last] = combine(merged[last], lesson)
+			continue
+		}
+		merged = append(merged, copyLesson(lesson))
+	}
+	return merged
+}
+
+// GroupLessons merges consecutive periods into one lesson spanning all of them
+// periods are consecutive if they take place on the same day and the second one starts in the period right after the first one
+// (according to the timegrid, or directly at the end of the first one if the timegrid doesn't know them)
+// only periods of the same type with the same classes, teachers, rooms and subjects are grouped
+// parallel lessons have to be merged with MergeParallelLessons beforehand, the returned lessons are sorted by their start and end
+func GroupLessons(lessons []Lesson) []Lesson {
+	groups := make([]Lesson, 0, len(lessons))
+	for _, lesson := range sortedLessons(lessons) {
+		grouped := false
+		for i := range groups {
+			if consecutive(groups[i], lesson) && sameParticipants(groups[i], lesson) {
+				groups[i].End = lesson.End
+				grouped = true
+				break
+			}
+		}
+		if !grouped {
+			groups = append(groups, copyLesson(lesson))
+		}
+	}
+	return groups
+}
+
+// sortedLessons returns a copy of the lessons sorted by their start and end
+func sortedLessons(lessons []Lesson) []Lesson {
+	sorted := make([]Lesson, len(lessons))
+	copy(sorted, lessons)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].Start.Equal(sorted[j].Start) {
+			return sorted[i].End.Before(sorted[j].End)
+		}
+		return sorted[i].Start.Before(sorted[j].Start)
+	})
+	return sorted
+}
+
+// consecutive checks whether next takes place in the period directly following the lesson first
+func consecutive(first, next Lesson) bool {
+	if !sameDay(first.End, next.Start) || !next.Start.After(first.Start) {
+		return false
+	}
+	end := GetLessonNrByEnd(first.End)
+	start := GetLessonNrByStart(next.Start)
+	if end != -1 && start != -1 {
+		return end+1 == start
+	}
+	return next.Start.Equal(first.End)
+}
+
+// sameDay checks whether both points in time are on the same day
+func sameDay(a, b time.Time) bool {
+	ay, am, ad := a.Date()
+	by, bm, bd := b.Date()
+	return ay == by && am == bm && ad == bd
+}
+
+// sameParticipants checks whether both lessons are of the same type and have the same classes, teachers, rooms and subjects
+func sameParticipants(a, b Lesson) bool {
+	return a.Type == b.Type &&
+		sameStrings(a.Classes, b.Classes) &&
+		sameStrings(a.Teachers, b.Teachers) &&
+		sameStrings(a.Rooms, b.Rooms) &&
+		sameStrings(a.Subjects, b.Subjects)
+}
+
+// combine returns lesson a extended by the classes, teachers, rooms and subjects of lesson b
+func combine(a, b Lesson) Lesson {
+	a.ClassIDs = distinctInts(append(a.ClassIDs, b.ClassIDs...))
+	a.Classes = distinctStrings(append(a.Classes, b.Classes...))
+	a.TeacherIDs = distinctInts(append(a.TeacherIDs, b.TeacherIDs...))
+	a.Teachers = distinctStrings(append(a.Teachers, b.Teachers...))
+	a.RoomIDs = distinctInts(append(a.RoomIDs, b.RoomIDs...))
+	a.Rooms = distinctStrings(append(a.Rooms, b.Rooms...))
+	a.SubjectIDs = distinctInts(append(a.SubjectIDs, b.SubjectIDs...))
+	a.Subjects = distinctStrings(append(a.Subjects, b.Subjects...))
+	if a.SubstText == "" {
+		a.SubstText = b.SubstText
+	}
+	return a
+}
+
+// copyLesson copies a lesson including its slices, so appending to the copy doesn't change the original
+func copyLesson(lesson Lesson) Lesson {
+	lesson.ClassIDs = append([]int(nil), lesson.ClassIDs...)
+	lesson.Classes = append([]string(nil), lesson.Classes...)
+	lesson.TeacherIDs = append([]int(nil), lesson.TeacherIDs...)
+	lesson.Teachers = append([]string(nil), lesson.Teachers...)
+	lesson.RoomIDs = append([]int(nil), lesson.RoomIDs...)
+	lesson.Rooms = append([]string(nil), lesson.Rooms...)
+	lesson.SubjectIDs = append([]int(nil), lesson.SubjectIDs...)
+	lesson.Subjects = append([]string(nil), lesson.Subjects...)
+	return lesson
+}
+
+// distinctStrings removes duplicate entries out of a string slice keeping the order of their first occurrence
+func distinctStrings(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	distinct := make([]string, 0, len(values))
+	for _, value := range values {
+		if !seen[value] {
+			seen[value] = true
+			distinct = append(distinct, value)
+		}
+	}
+	return distinct
+}
+
+// distinctInts removes duplicate entries out of an int slice keeping the order of their first occurrence
+func distinctInts(values []int) []int {
+	seen := make(map[int]bool, len(values))
+	distinct := make([]int, 0, len(values))
+	for _, value := range values {
+		if !seen[value] {
+			seen[value] = true
+			distinct = append(distinct, value)
+		}
+	}
+	return distinct
+}
+
+// sameStrings checks whether both slices contain the same strings regardless of their order and duplicates
+func sameStrings(a, b []string) bool {
+	a, b = distinctStrings(a), distinctStrings(b)
+	if len(a) != len(b) {
+		return false
+	}
+	contained := make(map[string]bool, len(a))
+	for _, value := range a {
+		contained[value] = true
+	}
+	for _, value := range b {
+		if !contained[value] {
+			return false
+		}
+	}
+	return true
+}
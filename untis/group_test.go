@@ -0,0 +1,259 @@
+package untis
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// monday is a day of the test timetables, lessons store the wall clock time of the school as UTC
+var monday = time.Date(2021, time.March, 1, 0, 0, 0, 0, time.UTC)
+
+// at returns the time hh:mm on the test monday, days later
+func at(days, hour, minute int) time.Time {
+	return monday.AddDate(0, 0, days).Add(time.Duration(hour)*time.Hour + time.Duration(minute)*time.Minute)
+}
+
+// lesson returns a regular lesson of the class 5AHIT with the teacher in the given room
+func lesson(start, end time.Time, teacher, room string) Lesson {
+	return Lesson{
+		Start:    start,
+		End:      end,
+		Classes:  []string{"5AHIT"},
+		Teachers: []string{teacher},
+		Rooms:    []string{room},
+		Subjects: []string{"SEW"},
+		Type:     LessonTypeLesson,
+	}
+}
+
+// useTimegrid replaces the cached timegrid for the duration of the test
+// the first three units of every school day are 08:00-08:50, 08:50-09:40 and, after a break, 09:55-10:45
+func useTimegrid(t *testing.T) {
+	units := []TimeUnit{
+		{Number: 1, Name: "1", Start: 800, End: 850},
+		{Number: 2, Name: "2", Start: 850, End: 940},
+		{Number: 3, Name: "3", Start: 955, End: 1045},
+	}
+	timegrid.Lock()
+	previous := timegrid.days
+	timegrid.days = make(map[time.Weekday][]TimeUnit)
+	for day := time.Monday; day <= time.Friday; day++ {
+		timegrid.days[day] = units
+	}
+	timegrid.Unlock()
+	t.Cleanup(func() {
+		timegrid.Lock()
+		defer timegrid.Unlock()
+		timegrid.days = previous
+	})
+}
+
+func TestGroupLessons(t *testing.T) {
+	first := lesson(at(0, 8, 0), at(0, 8, 50), "SZA", "H1101")
+	second := lesson(at(0, 8, 50), at(0, 9, 40), "SZA", "H1101")
+	afterBreak := lesson(at(0, 9, 55), at(0, 10, 45), "SZA", "H1101")
+	tests := []struct {
+		name     string
+		timegrid bool
+		lessons  []Lesson
+		want     []Lesson
+	}{
+		{
+			name:     "double period",
+			timegrid: true,
+			lessons:  []Lesson{second, first},
+			want:     []Lesson{lesson(at(0, 8, 0), at(0, 9, 40), "SZA", "H1101")},
+		},
+		{
+			name:     "periods around a break",
+			timegrid: true,
+			lessons:  []Lesson{second, afterBreak},
+			want:     []Lesson{lesson(at(0, 8, 50), at(0, 10, 45), "SZA", "H1101")},
+		},
+		{
+			name:     "triple period across a break",
+			timegrid: true,
+			lessons:  []Lesson{afterBreak, first, second},
+			want:     []Lesson{lesson(at(0, 8, 0), at(0, 10, 45), "SZA", "H1101")},
+		},
+		{
+			name:     "break without timegrid",
+			timegrid: false,
+			lessons:  []Lesson{second, afterBreak},
+			want:     []Lesson{second, afterBreak},
+		},
+		{
+			name:     "double period without timegrid",
+			timegrid: false,
+			lessons:  []Lesson{first, second},
+			want:     []Lesson{lesson(at(0, 8, 0), at(0, 9, 40), "SZA", "H1101")},
+		},
+		{
+			name:     "free period in between",
+			timegrid: true,
+			lessons:  []Lesson{first, afterBreak},
+			want:     []Lesson{first, afterBreak},
+		},
+		{
+			name:     "other teacher",
+			timegrid: true,
+			lessons:  []Lesson{first, lesson(at(0, 8, 50), at(0, 9, 40), "EHU", "H1101")},
+			want:     []Lesson{first, lesson(at(0, 8, 50), at(0, 9, 40), "EHU", "H1101")},
+		},
+		{
+			name:     "other day",
+			timegrid: true,
+			lessons:  []Lesson{first, lesson(at(1, 8, 50), at(1, 9, 40), "SZA", "H1101")},
+			want:     []Lesson{first, lesson(at(1, 8, 50), at(1, 9, 40), "SZA", "H1101")},
+		},
+		{
+			name:     "parallel lessons grouped separately",
+			timegrid: true,
+			lessons: []Lesson{
+				first, lesson(at(0, 8, 0), at(0, 8, 50), "EHU", "H1102"),
+				second, lesson(at(0, 8, 50), at(0, 9, 40), "EHU", "H1102"),
+			},
+			want: []Lesson{
+				lesson(at(0, 8, 0), at(0, 9, 40), "SZA", "H1101"),
+				lesson(at(0, 8, 0), at(0, 9, 40), "EHU", "H1102"),
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if test.timegrid {
+				useTimegrid(t)
+			} else {
+				InvalidateTimegrid()
+			}
+			if got := GroupLessons(test.lessons); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("GroupLessons() = %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestMergeParallelLessons(t *testing.T) {
+	sza := lesson(at(0, 8, 0), at(0, 8, 50), "SZA", "H1101")
+	sza.TeacherIDs = []int{1}
+	ehu := lesson(at(0, 8, 0), at(0, 8, 50), "EHU", "H1102")
+	ehu.TeacherIDs = []int{2}
+	ehu.Classes = []string{"5AHIT", "5BHIT"}
+	later := lesson(at(0, 8, 50), at(0, 9, 40), "SZA", "H1101")
+	tests := []struct {
+		name    string
+		lessons []Lesson
+		want    []Lesson
+	}{
+		{
+			name:    "parallel lessons",
+			lessons: []Lesson{sza, ehu},
+			want: []Lesson{{
+				Start:      at(0, 8, 0),
+				End:        at(0, 8, 50),
+				Classes:    []string{"5AHIT", "5BHIT"},
+				TeacherIDs: []int{1, 2},
+				Teachers:   []string{"SZA", "EHU"},
+				ClassIDs:   []int{},
+				RoomIDs:    []int{},
+				Rooms:      []string{"H1101", "H1102"},
+				SubjectIDs: []int{},
+				Subjects:   []string{"SEW"},
+				Type:       LessonTypeLesson,
+			}},
+		},
+		{
+			name:    "consecutive lessons",
+			lessons: []Lesson{later, sza},
+			want:    []Lesson{copyLesson(sza), copyLesson(later)},
+		},
+		{
+			name: "same start but longer",
+			lessons: []Lesson{
+				lesson(at(0, 8, 0), at(0, 9, 40), "EHU", "H1102"), sza,
+			},
+			want: []Lesson{copyLesson(sza), copyLesson(lesson(at(0, 8, 0), at(0, 9, 40), "EHU", "H1102"))},
+		},
+		{
+			name:    "no lessons",
+			lessons: []Lesson{},
+			want:    []Lesson{},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := MergeParallelLessons(test.lessons); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("MergeParallelLessons() = %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestDuring(t *testing.T) {
+	vienna := time.FixedZone("CET", 60*60)
+	first := lesson(at(0, 8, 0), at(0, 8, 50), "SZA", "H1101")
+	second := lesson(at(0, 8, 50), at(0, 9, 40), "SZA", "H1101")
+	afterBreak := lesson(at(0, 9, 55), at(0, 10, 45), "SZA", "H1101")
+	lessons := []Lesson{first, second, afterBreak}
+	tests := []struct {
+		name      string
+		intervals []Interval
+		want      []Lesson
+	}{
+		{
+			name:      "interval in the time zone of the school",
+			intervals: []Interval{{time.Date(2021, time.March, 1, 8, 30, 0, 0, vienna), time.Date(2021, time.March, 1, 9, 0, 0, 0, vienna)}},
+			want:      []Lesson{first, second},
+		},
+		{
+			name:      "interval ending when a lesson starts",
+			intervals: []Interval{{time.Date(2021, time.March, 1, 7, 0, 0, 0, vienna), time.Date(2021, time.March, 1, 8, 0, 0, 0, vienna)}},
+			want:      []Lesson{},
+		},
+		{
+			name:      "interval within the break",
+			intervals: []Interval{{time.Date(2021, time.March, 1, 9, 40, 0, 0, vienna), time.Date(2021, time.March, 1, 9, 55, 0, 0, vienna)}},
+			want:      []Lesson{},
+		},
+		{
+			name: "several intervals",
+			intervals: []Interval{
+				{time.Date(2021, time.March, 1, 8, 0, 0, 0, vienna), time.Date(2021, time.March, 1, 8, 10, 0, 0, vienna)},
+				{time.Date(2021, time.March, 1, 10, 0, 0, 0, vienna), time.Date(2021, time.March, 1, 12, 0, 0, 0, vienna)},
+			},
+			want: []Lesson{first, afterBreak},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := During(lessons, test.intervals); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("During() = %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestWithoutCancelled(t *testing.T) {
+	regular := lesson(at(0, 8, 0), at(0, 8, 50), "SZA", "H1101")
+	cancelled := lesson(at(0, 8, 50), at(0, 9, 40), "SZA", "H1101")
+	cancelled.Code = CodeCancelled
+	irregular := lesson(at(0, 9, 55), at(0, 10, 45), "EHU", "H1101")
+	irregular.Code = CodeIrregular
+	tests := []struct {
+		name    string
+		lessons []Lesson
+		want    []Lesson
+	}{
+		{"mixed", []Lesson{regular, cancelled, irregular}, []Lesson{regular, irregular}},
+		{"only cancelled", []Lesson{cancelled}, []Lesson{}},
+		{"none", []Lesson{}, []Lesson{}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := WithoutCancelled(test.lessons); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("WithoutCancelled() = %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
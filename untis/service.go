@@ -0,0 +1,71 @@
+package untis
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+)
+
+// UsernameEnv is the environment variable containing the username of the untis service account
+const UsernameEnv = "UNTIS_USERNAME"
+
+// PasswordFileEnv is the environment variable containing the path to the file holding the password of the untis service account
+const PasswordFileEnv = "UNTIS_PASSWORD_FILE"
+
+// ErrNoServiceAccount is returned if no untis service account is configured
+var ErrNoServiceAccount = errors.New("no untis service account configured")
+
+// ErrNoSession is returned if a user without an active untis session needs to access untis
+var ErrNoSession = errors.New("no active untis session, please log in again")
+
+// service is the client of the service account, it is created on first use
+var service = struct {
+	sync.Mutex
+	client *Client
+}{}
+
+// HasServiceAccount checks whether an untis service account is configured
+func HasServiceAccount() bool {
+	return os.Getenv(UsernameEnv) != "" && os.Getenv(PasswordFileEnv) != ""
+}
+
+// ServiceClient returns the client of the untis service account configured by UNTIS_USERNAME and UNTIS_PASSWORD_FILE
+// it is used for all lookups which don't need the identity of a user
+func ServiceClient() (*Client, error) {
+	service.Lock()
+	defer service.Unlock()
+	if service.client != nil {
+		return service.client, nil
+	}
+	if !HasServiceAccount() {
+		return nil, ErrNoServiceAccount
+	}
+	password, err := ioutil.ReadFile(os.Getenv(PasswordFileEnv))
+	if err != nil {
+		return nil, err
+	}
+	service.client = &Client{
+		Username:   os.Getenv(UsernameEnv),
+		Password:   strings.TrimSuffix(string(password), "\n"),
+		PersonType: -1,
+		PersonID:   -1,
+		Service:    true,
+	}
+	return service.client, nil
+}
+
+// ClientFor returns the client to access untis on behalf of username
+// this is the service client if a service account is configured, otherwise the client of the session of the user
+func ClientFor(username string) (*Client, error) {
+	if HasServiceAccount() {
+		return ServiceClient()
+	}
+	sessions.Lock()
+	defer sessions.Unlock()
+	if client, ok := sessions.clients[username]; ok {
+		return client, nil
+	}
+	return nil, ErrNoSession
+}
@@ -0,0 +1,100 @@
+package untis
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// SessionIdleTimeout is the time a session may stay unused before it is closed and removed
+const SessionIdleTimeout = 30 * time.Minute
+
+// sessionCheckInterval is the interval in which idle sessions are looked for
+const sessionCheckInterval = time.Minute
+
+// sessions maps a user (the username) to the client of their active session
+var sessions = struct {
+	sync.Mutex
+	clients map[string]*Client
+}{clients: make(map[string]*Client)}
+
+// startExpiry ensures idle sessions are expired only by one goroutine
+var startExpiry sync.Once
+
+// Login authenticates username at untis and stores the client as the session of the user
+// the password is only used to open the session and isn't kept, once the session expires the user has to log in again
+func Login(username, password string) (*Client, error) {
+	startExpiry.Do(func() {
+		go expireSessions()
+	})
+	client := &Client{
+		Username:   username,
+		Password:   password,
+		PersonType: -1,
+		PersonID:   -1,
+	}
+	err := client.Authenticate()
+	client.Password = ""
+	if err != nil {
+		return nil, err
+	}
+	sessions.Lock()
+	existing, ok := sessions.clients[username]
+	sessions.clients[username] = client
+	sessions.Unlock()
+	if ok {
+		go existing.Close()
+	}
+	return client, nil
+}
+
+// GetClient returns the client of the active session of username
+// if the user has no active session an unauthenticated client without credentials is returned
+func GetClient(username string) *Client {
+	sessions.Lock()
+	defer sessions.Unlock()
+	if client, ok := sessions.clients[username]; ok {
+		return client
+	}
+	return &Client{Username: username, PersonType: -1, PersonID: -1}
+}
+
+// DeleteClient closes the session of the client and removes it from the active sessions
+func (client *Client) DeleteClient() {
+	RemoveClient(client.Username)
+}
+
+// RemoveClient closes the active session of username and removes it
+func RemoveClient(username string) {
+	sessions.Lock()
+	client, ok := sessions.clients[username]
+	delete(sessions.clients, username)
+	sessions.Unlock()
+	if ok {
+		_ = client.Close()
+	}
+}
+
+// expireSessions periodically closes and removes all sessions unused for longer than SessionIdleTimeout
+func expireSessions() {
+	for {
+		time.Sleep(sessionCheckInterval)
+		expired := make([]*Client, 0)
+		sessions.Lock()
+		for username, client := range sessions.clients {
+			if client.idleSince() > SessionIdleTimeout {
+				expired = append(expired, client)
+				delete(sessions.clients, username)
+			}
+		}
+		sessions.Unlock()
+		for _, client := range expired {
+			_ = client.Close()
+		}
+	}
+}
+
+// idleSince returns the time passed since the client sent its last request
+func (client *Client) idleSince() time.Duration {
+	return time.Since(time.Unix(0, atomic.LoadInt64(&client.lastUsed)))
+}
@@ -0,0 +1,276 @@
+package untis
+
+import (
+	"sort"
+	"time"
+)
+
+// MaxCandidates is the maximum amount of candidates suggested for a single lesson
+const MaxCandidates = 5
+
+// Absence describes teachers and classes which are absent at the same time, e.g. because of a school event
+type Absence struct {
+	// Start of the absence, it has to be given in the time zone of the school
+	Start time.Time
+	// End of the absence, it has to be given in the time zone of the school
+	End time.Time
+	// Teachers are the untis names of the absent teachers
+	Teachers []string
+	// Intervals are the intervals the absent teachers are absent in by their untis names, they have to be given in the time zone of the school
+	// teachers without intervals are absent from Start till End
+	Intervals map[string][]Interval
+	// Classes are the names of the absent classes
+	Classes []string
+}
+
+// Interval is a period of time
+type Interval struct {
+	// Start of the interval
+	Start time.Time
+	// End of the interval
+	End time.Time
+}
+
+// Candidate is a teacher who could substitute a lesson
+type Candidate struct {
+	// Teacher is the untis name of the teacher
+	Teacher string
+	// Load is the amount of lessons the teacher has to teach on the day of the lesson
+	Load int
+	// TeachesClass whether the teacher teaches one of the classes of the lesson
+	TeachesClass bool
+	// TeachesSubject whether the teacher teaches one of the subjects of the lesson
+	TeachesSubject bool
+	// FreedUp whether the teacher is only free because their own class is absent as well
+	FreedUp bool
+}
+
+// Suggestion lists the candidates for the substitution of a lesson ordered by their suitability
+type Suggestion struct {
+	// Lesson is the lesson which has to be substituted
+	Lesson Lesson
+	// Candidates which are free during the lesson
+	Candidates []Candidate
+}
+
+// AffectedLessons returns the lessons of the absent teachers during the absence which have to be substituted
+// cancelled lessons and lessons only attended by absent classes are left out, parallel and consecutive lessons are combined
+func (client *Client) AffectedLessons(absence Absence) ([]Lesson, error) {
+	teacherIDs, err := client.idsByName(teachersMethod)
+	if err != nil {
+		return nil, err
+	}
+	// the times of lessons are the wall clock times of the school stored as UTC
+	start, end := wallClock(absence.Start), wallClock(absence.End)
+	lessons := make([]Lesson, 0)
+	for _, teacher := range absence.Teachers {
+		id, ok := teacherIDs[teacher]
+		if !ok {
+			continue
+		}
+		timetable, err := client.getTimetable(id, TeacherType, absence.Start, absence.End)
+		if err != nil {
+			return nil, err
+		}
+		for _, lesson := range timetable {
+			if lesson.Cancelled() || !lesson.End.After(start) || !lesson.Start.Before(end) ||
+				!absence.AbsentDuring(teacher, lesson) || absence.OnlyAbsentClasses(lesson) {
+				continue
+			}
+			lessons = append(lessons, lesson)
+		}
+	}
+	return GroupLessons(MergeParallelLessons(lessons)), nil
+}
+
+// SuggestSubstitutions suggests teachers for each of the given lessons of the absence
+// candidates are teachers of the classes of the lessons (according to the timetables of the week of the absence),
+// who aren't absent themselves during the lesson and are either free or whose own lesson is only attended by absent classes
+// they are ranked by whether they are freed up, teach the class, teach the subject, and by their load on that day
+func (client *Client) SuggestSubstitutions(absence Absence, lessons []Lesson) ([]Suggestion, error) {
+	suggestions := make([]Suggestion, 0, len(lessons))
+	if len(lessons) == 0 {
+		return suggestions, nil
+	}
+	classIDs, err := client.idsByName(classesMethod)
+	if err != nil {
+		return nil, err
+	}
+	teacherIDs, err := client.idsByName(teachersMethod)
+	if err != nil {
+		return nil, err
+	}
+	weekStart, weekEnd := week(absence.Start, absence.End)
+	classTeachers := make(map[string]map[string]bool)
+	subjectTeachers := make(map[string]map[string]bool)
+	classes := make([]string, 0)
+	for _, lesson := range lessons {
+		classes = append(classes, lesson.Classes...)
+	}
+	for _, class := range distinctStrings(classes) {
+		id, ok := classIDs[class]
+		if !ok {
+			continue
+		}
+		timetable, err := client.getTimetable(id, ClassType, weekStart, weekEnd)
+		if err != nil {
+			return nil, err
+		}
+		for _, lesson := range timetable {
+			for _, teacher := range lesson.Teachers {
+				addTo(classTeachers, class, teacher)
+				for _, subject := range lesson.Subjects {
+					addTo(subjectTeachers, subject, teacher)
+				}
+			}
+		}
+	}
+	timetables := make(map[string][]Lesson)
+	for _, teachers := range classTeachers {
+		for teacher := range teachers {
+			id, ok := teacherIDs[teacher]
+			if !ok || timetables[teacher] != nil {
+				continue
+			}
+			timetable, err := client.getTimetable(id, TeacherType, absence.Start, absence.End)
+			if err != nil {
+				return nil, err
+			}
+			timetables[teacher] = WithoutCancelled(timetable)
+		}
+	}
+	for _, lesson := range lessons {
+		candidates := make([]Candidate, 0)
+		for teacher, timetable := range timetables {
+			if absence.AbsentDuring(teacher, lesson) {
+				continue
+			}
+			candidate, free := absence.candidate(teacher, lesson, timetable)
+			if !free {
+				continue
+			}
+			for _, class := range lesson.Classes {
+				candidate.TeachesClass = candidate.TeachesClass || classTeachers[class][teacher]
+			}
+			for _, subject := range lesson.Subjects {
+				candidate.TeachesSubject = candidate.TeachesSubject || subjectTeachers[subject][teacher]
+			}
+			candidates = append(candidates, candidate)
+		}
+		rankCandidates(candidates)
+		if len(candidates) > MaxCandidates {
+			candidates = candidates[:MaxCandidates]
+		}
+		suggestions = append(suggestions, Suggestion{lesson, candidates})
+	}
+	return suggestions, nil
+}
+
+// candidate checks whether the teacher with the given timetable is free during the lesson
+// a teacher is free if none of their lessons overlapping the lesson is attended by a class which isn't absent
+func (absence Absence) candidate(teacher string, lesson Lesson, timetable []Lesson) (Candidate, bool) {
+	candidate := Candidate{Teacher: teacher}
+	for _, own := range timetable {
+		overlaps := own.Start.Before(lesson.End) && lesson.Start.Before(own.End)
+		freed := absence.OnlyAbsentClasses(own)
+		if overlaps && !freed {
+			return candidate, false
+		}
+		if overlaps {
+			candidate.FreedUp = true
+		}
+		if sameDay(own.Start, lesson.Start) && !freed {
+			candidate.Load++
+		}
+	}
+	return candidate, true
+}
+
+// AbsentDuring checks whether the teacher is absent during (a part of) the lesson
+func (absence Absence) AbsentDuring(teacher string, lesson Lesson) bool {
+	listed := false
+	for _, t := range absence.Teachers {
+		listed = listed || t == teacher
+	}
+	if !listed {
+		return false
+	}
+	intervals, ok := absence.Intervals[teacher]
+	if !ok {
+		return true
+	}
+	return len(During([]Lesson{lesson}, intervals)) > 0
+}
+
+// OnlyAbsentClasses checks whether the lesson is attended by classes and all of them are absent
+func (absence Absence) OnlyAbsentClasses(lesson Lesson) bool {
+	if len(lesson.Classes) == 0 {
+		return false
+	}
+	for _, class := range lesson.Classes {
+		absent := false
+		for _, a := range absence.Classes {
+			if class == a {
+				absent = true
+				break
+			}
+		}
+		if !absent {
+			return false
+		}
+	}
+	return true
+}
+
+// rankCandidates orders the candidates by their suitability
+func rankCandidates(candidates []Candidate) {
+	sort.Slice(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if a.FreedUp != b.FreedUp {
+			return a.FreedUp
+		}
+		if a.TeachesClass != b.TeachesClass {
+			return a.TeachesClass
+		}
+		if a.TeachesSubject != b.TeachesSubject {
+			return a.TeachesSubject
+		}
+		if a.Load != b.Load {
+			return a.Load < b.Load
+		}
+		return a.Teacher < b.Teacher
+	})
+}
+
+// idsByName returns the ids of the elements returned by the given method mapped by their names
+func (client *Client) idsByName(method string) (map[string]int, error) {
+	elements, err := client.Elements(method)
+	if err != nil {
+		return nil, err
+	}
+	ids := make(map[string]int, len(elements))
+	for _, element := range elements {
+		ids[element.Name] = element.ID
+	}
+	return ids, nil
+}
+
+// wallClock returns the wall clock time of t as UTC, like the times of lessons are stored
+func wallClock(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), 0, time.UTC)
+}
+
+// week returns the monday of the week of start and the sunday of the week of end
+func week(start, end time.Time) (time.Time, time.Time) {
+	monday := start.AddDate(0, 0, -((int(start.Weekday()) + 6) % 7))
+	sunday := end.AddDate(0, 0, (7-int(end.Weekday()))%7)
+	return monday, sunday
+}
+
+// addTo adds the teacher to the set of the key
+func addTo(sets map[string]map[string]bool, key, teacher string) {
+	if sets[key] == nil {
+		sets[key] = make(map[string]bool)
+	}
+	sets[key][teacher] = true
+}
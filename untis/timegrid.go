@@ -0,0 +1,131 @@
+package untis
+
+import (
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// TimegridTTL is the time a loaded timegrid is used before it is loaded again from the untis service
+const TimegridTTL = 24 * time.Hour
+
+// TimeUnit is one lesson of the timegrid of the school
+type TimeUnit struct {
+	// Number is the lesson number of the unit on its day
+	Number int
+	// Name is the name of the unit in untis
+	Name string
+	// Start is the start time of the unit in the format hhmm
+	Start int
+	// End is the end time of the unit in the format hhmm
+	End int
+}
+
+// timegrid caches the time units of each weekday loaded from the untis service
+var timegrid = struct {
+	sync.RWMutex
+	days   map[time.Weekday][]TimeUnit
+	loaded time.Time
+}{}
+
+// LoadTimegrid loads the timegrid of the school from the untis service and caches it for TimegridTTL
+func (client *Client) LoadTimegrid() error {
+	result := make([]struct {
+		Day       int `json:"day"`
+		TimeUnits []struct {
+			Name      string `json:"name"`
+			StartTime int    `json:"startTime"`
+			EndTime   int    `json:"endTime"`
+		} `json:"timeUnits"`
+	}, 0)
+	if err := client.call("getTimegridUnits", map[string]interface{}{}, &result); err != nil {
+		return err
+	}
+	days := make(map[time.Weekday][]TimeUnit)
+	for _, day := range result {
+		units := make([]TimeUnit, 0)
+		for _, unit := range day.TimeUnits {
+			units = append(units, TimeUnit{Name: unit.Name, Start: unit.StartTime, End: unit.EndTime})
+		}
+		sort.Slice(units, func(i, j int) bool {
+			return units[i].Start < units[j].Start
+		})
+		for i := range units {
+			units[i].Number = i + 1
+			if number, err := strconv.Atoi(units[i].Name); err == nil {
+				units[i].Number = number
+			}
+		}
+		// untis counts the days of the week starting with 1 on sunday
+		days[time.Weekday(day.Day-1)] = units
+	}
+	timegrid.Lock()
+	defer timegrid.Unlock()
+	timegrid.days = days
+	timegrid.loaded = time.Now()
+	return nil
+}
+
+// ensureTimegrid loads the timegrid if it wasn't loaded yet or is older than TimegridTTL
+func (client *Client) ensureTimegrid() error {
+	timegrid.RLock()
+	valid := timegrid.days != nil && time.Since(timegrid.loaded) < TimegridTTL
+	timegrid.RUnlock()
+	if valid {
+		return nil
+	}
+	return client.LoadTimegrid()
+}
+
+// InvalidateTimegrid drops the cached timegrid, so it is loaded again on the next timetable request
+func InvalidateTimegrid() {
+	timegrid.Lock()
+	defer timegrid.Unlock()
+	timegrid.days = nil
+}
+
+// GetLessonNrByStart computes the lesson number by its start time using the cached timegrid
+// returns -1 if the timegrid isn't loaded or no lesson starts at this time
+func GetLessonNrByStart(start time.Time) int {
+	clock := start.Hour()*100 + start.Minute()
+	return findTimeUnit(start.Weekday(), func(unit TimeUnit) bool {
+		return unit.Start == clock
+	}, func(unit TimeUnit) bool {
+		return unit.Start <= clock && clock < unit.End
+	})
+}
+
+// GetLessonNrByEnd computes the lesson number by its end time using the cached timegrid
+// returns -1 if the timegrid isn't loaded or no lesson ends at this time
+func GetLessonNrByEnd(end time.Time) int {
+	clock := end.Hour()*100 + end.Minute()
+	return findTimeUnit(end.Weekday(), func(unit TimeUnit) bool {
+		return unit.End == clock
+	}, func(unit TimeUnit) bool {
+		return unit.Start < clock && clock <= unit.End
+	})
+}
+
+// findTimeUnit returns the number of the first time unit of the weekday matching exactly
+// or, if none matches exactly, of the first time unit containing the time
+// the units of all other days are searched if the weekday isn't part of the timegrid
+func findTimeUnit(weekday time.Weekday, exact, contains func(TimeUnit) bool) int {
+	timegrid.RLock()
+	defer timegrid.RUnlock()
+	units, ok := timegrid.days[weekday]
+	if !ok {
+		units = make([]TimeUnit, 0)
+		for day := time.Sunday; day <= time.Saturday; day++ {
+			units = append(units, timegrid.days[day]...)
+		}
+	}
+	for _, match := range []func(TimeUnit) bool{exact, contains} {
+		for _, unit := range units {
+			if match(unit) {
+				return unit.Number
+			}
+		}
+	}
+	return -1
+}
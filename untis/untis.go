@@ -3,30 +3,47 @@ package untis
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"math"
 	"math/rand"
 	"net/http"
+	"net/url"
+	"os"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
 // ClientName is the name of this client communicating with the api
 const ClientName = "Refundable"
 
-// URL is the path this api is available at
-const URL = "https://neilo.webuntis.com/WebUntis/jsonrpc.do?school=tgm"
+// DefaultURL is the path of the json rpc api used if UNTIS_URL isn't set
+const DefaultURL = "https://neilo.webuntis.com/WebUntis/jsonrpc.do"
 
-// activeClients is a map that maps a user (the username) to the active client during an active session
-var activeClients map[string]Client
+// DefaultSchool is the school used if UNTIS_SCHOOL isn't set
+const DefaultSchool = "tgm"
+
+// URLEnv is the environment variable containing the path of the json rpc api
+const URLEnv = "UNTIS_URL"
+
+// SchoolEnv is the environment variable containing the name of the school at the untis service
+const SchoolEnv = "UNTIS_SCHOOL"
+
+// RequestTimeout is the maximum time a request to the untis api may take
+const RequestTimeout = 30 * time.Second
+
+// HTTPClient is the http client used to send requests to the untis api by clients which don't have their own
+var HTTPClient = &http.Client{Timeout: RequestTimeout}
 
 // Client is the struct representing the client
 type Client struct {
 	// Username of the account the client uses
 	Username string
-	// Password of the account the client uses
+	// Password of the account the client uses, it is only kept for service accounts
 	Password string
 	// SessionID of the session the client is currently in
 	SessionID string
@@ -38,10 +55,35 @@ type Client struct {
 	Closed bool
 	// Authenticated whether the current session is active authenticated
 	Authenticated bool
+	// Service whether the client uses the service account instead of the account of a user
+	Service bool
+	// URL of the json rpc api including the school, Endpoint() is used if it is empty
+	URL string
+	// HTTPClient sends the requests of the client, the package wide HTTPClient is used if it is nil
+	HTTPClient *http.Client
+	// mu serializes the requests of the client, so the session isn't changed during a request
+	mu sync.Mutex
+	// lastUsed is the time the client sent its last request at in unix nanoseconds, it is accessed atomically
+	lastUsed int64
+}
+
+// RPCError is an error returned by the json rpc api of untis
+type RPCError struct {
+	// Code of the error
+	Code int `json:"code"`
+	// Message describing the error
+	Message string `json:"message"`
+}
+
+// Error returns the code and the message of the error
+func (e *RPCError) Error() string {
+	return fmt.Sprintf("untis error %d: %s", e.Code, e.Message)
 }
 
 // Lesson represents a lesson out of a timetable
 type Lesson struct {
+	// ID is the id untis gives the period, a period listed in the timetables of several elements has the same id in all of them
+	ID int
 	// Start is the start time of the lesson
 	Start time.Time
 	// End is the end time of the lesson
@@ -58,678 +100,309 @@ type Lesson struct {
 	RoomIDs []int
 	// Rooms are the room names this lesson takes place in
 	Rooms []string
+	// SubjectIDs are the ids of the subjects taught
+	SubjectIDs []int
+	// Subjects are the names of the subjects taught
+	Subjects []string
+	// Type is the type of the lesson (LessonTypeLesson, LessonTypeOfficeHour, LessonTypeStandby, LessonTypeBreakSupervision or LessonTypeExamination)
+	Type string
+	// Code marks lessons deviating from the timetable (CodeCancelled or CodeIrregular), it is empty for regular lessons
+	Code string
+	// SubstText is the text untis shows for a substitution
+	SubstText string
 }
 
-// CreateClient creates a new client to communicate with the API
-// the username and password are used to authenticate the client at the service
-func CreateClient(username, password string) *Client {
-	client := Client{
-		Username:      username,
-		Password:      password,
-		SessionID:     "",
-		PersonType:    -1,
-		PersonID:      -1,
-		Closed:        false,
-		Authenticated: false,
-	}
-	if activeClients == nil {
-		activeClients = make(map[string]Client)
-	}
-	activeClients[username] = client
-	return &client
+// Types of lessons
+const (
+	LessonTypeLesson           = "ls"
+	LessonTypeOfficeHour       = "oh"
+	LessonTypeStandby          = "sb"
+	LessonTypeBreakSupervision = "bs"
+	LessonTypeExamination      = "ex"
+)
+
+// Codes of lessons deviating from the timetable
+const (
+	CodeCancelled = "cancelled"
+	CodeIrregular = "irregular"
+)
+
+// Cancelled checks whether the lesson was cancelled
+func (lesson Lesson) Cancelled() bool {
+	return lesson.Code == CodeCancelled
 }
 
-// GetClient returns an active client using the corresponding username
-func GetClient(username string) *Client {
-	client := activeClients[username]
-	return &client
+// Endpoint returns the url of the json rpc api of the configured school
+// it is read from UNTIS_URL and UNTIS_SCHOOL, DefaultURL and DefaultSchool are used if they aren't set
+func Endpoint() string {
+	base := os.Getenv(URLEnv)
+	if base == "" {
+		base = DefaultURL
+	}
+	school := os.Getenv(SchoolEnv)
+	if school == "" {
+		school = DefaultSchool
+	}
+	endpoint, err := url.Parse(base)
+	if err != nil {
+		return base + "?school=" + url.QueryEscape(school)
+	}
+	query := endpoint.Query()
+	query.Set("school", school)
+	endpoint.RawQuery = query.Encode()
+	return endpoint.String()
 }
 
 // Authenticate authenticates the client at the untis service
+// an already authenticated client keeps its session
 func (client *Client) Authenticate() error {
+	client.mu.Lock()
+	defer client.mu.Unlock()
+	client.touch()
 	if client.Authenticated {
-		return fmt.Errorf("already authenticated")
-	}
-	id := rand.Intn(math.MaxInt64)
-	body, _ := json.Marshal(map[string]interface{}{
-		"id":     id,
-		"method": "authenticate",
-		"params": map[string]string{
-			"user":     client.Username,
-			"password": client.Password,
-			"client":   ClientName,
-		},
-		"jsonrpc": "2.0",
-	})
-	resp, err := http.Post(URL, "application/json", bytes.NewBuffer(body))
-	if err != nil {
-		return err
+		return nil
 	}
-	defer resp.Body.Close()
-	respBody, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return err
+	return client.authenticate()
+}
+
+// touch marks the client as used now
+func (client *Client) touch() {
+	atomic.StoreInt64(&client.lastUsed, time.Now().UnixNano())
+}
+
+// authenticate opens a new session at the untis service, the caller has to hold the lock of the client
+// clients of users don't keep their password, so their session can't be renewed
+func (client *Client) authenticate() error {
+	if client.Password == "" {
+		return ErrNoSession
 	}
 	r := struct {
-		JSONRPC string                 `json:"jsonrpc"`
-		ID      string                 `json:"id"`
-		Result  map[string]interface{} `json:"result"`
+		SessionID  string `json:"sessionId"`
+		PersonType int    `json:"personType"`
+		PersonID   int    `json:"personId"`
 	}{}
-	err = json.Unmarshal(respBody, &r)
+	err := client.send("authenticate", map[string]interface{}{
+		"user":     client.Username,
+		"password": client.Password,
+		"client":   ClientName,
+	}, &r)
 	if err != nil {
 		return err
 	}
-	personType, _ := r.Result["personType"].(float64)
-	personID, _ := r.Result["personId"].(float64)
-	if r.ID == strconv.Itoa(id) {
-		client.SessionID = r.Result["sessionId"].(string)
-		client.PersonType = int(personType)
-		client.PersonID = int(personID)
-		client.Authenticated = true
-		client.Closed = false
-		return nil
-	}
-	return fmt.Errorf("IDs not matching")
+	client.SessionID = r.SessionID
+	client.PersonType = r.PersonType
+	client.PersonID = r.PersonID
+	client.Authenticated = true
+	client.Closed = false
+	return nil
 }
 
+// NotAuthenticatedCode is the error code untis responds with if the session of a request isn't valid (anymore)
+const NotAuthenticatedCode = -8520
+
+// Element types of the untis api used to request timetables
+const (
+	ClassType   = 1
+	TeacherType = 2
+	SubjectType = 3
+	RoomType    = 4
+	StudentType = 5
+)
+
 // GetTimetableOfTeacher returns a list of lessons the teacher logged in with the client has in between start and end
-func (client Client) GetTimetableOfTeacher(start, end time.Time) ([]Lesson, error) {
-	if !client.Authenticated {
-		return nil, fmt.Errorf("not authenticated")
-	}
-	smonth := strconv.Itoa(int(start.Month()))
-	if len(smonth) == 1 {
-		smonth = "0" + smonth
-	}
-	sday := strconv.Itoa(start.Day())
-	if len(sday) == 1 {
-		sday = "0" + sday
-	}
-	emonth := strconv.Itoa(int(end.Month()))
-	if len(emonth) == 1 {
-		emonth = "0" + emonth
-	}
-	eday := strconv.Itoa(end.Day())
-	if len(eday) == 1 {
-		eday = "0" + eday
-	}
-	params := map[string]interface{}{
-		"id":        client.PersonID,
-		"type":      client.PersonType,
-		"startDate": strconv.Itoa(start.Year()) + smonth + sday,
-		"endDate":   strconv.Itoa(end.Year()) + emonth + eday,
-	}
-	resp, id, err := client.sendRequest("getTimetable", params)
-	if err != nil {
+func (client *Client) GetTimetableOfTeacher(start, end time.Time) ([]Lesson, error) {
+	if err := client.Authenticate(); err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
-	respBody, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return nil, err
-	}
-	r := struct {
-		JSONRPC string `json:"jsonrpc"`
-		ID      string `json:"id"`
-		Result  []struct {
-			ID        int `json:"id"`
-			Date      int `json:"date"`
-			StartTime int `json:"startTime"`
-			EndTime   int `json:"endTime"`
-			Kl        []struct {
-				ID int `json:"id"`
-			} `json:"kl"`
-			Te []struct {
-				ID int `json:"id"`
-			} `json:"te"`
-			Su []struct {
-				ID int `json:"id"`
-			} `json:"su"`
-			Ro []struct {
-				ID int `json:"id"`
-			} `json:"ro"`
-		} `json:"result"`
-	}{}
-	err = json.Unmarshal(respBody, &r)
-	if err != nil {
-		return nil, err
-	}
-	rid, _ := strconv.Atoi(r.ID)
-	if rid == id {
-		lessons := make([]Lesson, 0)
-		for _, l := range r.Result {
-			date := strconv.Itoa(l.Date)
-			year, _ := strconv.Atoi(date[0:4])
-			month, _ := strconv.Atoi(date[4:6])
-			day, _ := strconv.Atoi(date[6:8])
-			startTime := strconv.Itoa(l.StartTime)
-			startHour, _ := strconv.Atoi(startTime[0 : len(startTime)-2])
-			startMinute, _ := strconv.Atoi(startTime[len(startTime)-2:])
-			endTime := strconv.Itoa(l.EndTime)
-			endHour, _ := strconv.Atoi(endTime[0 : len(endTime)-2])
-			endMinute, _ := strconv.Atoi(endTime[len(endTime)-2:])
-			classIDArr := make([]int, 0)
-			for _, kls := range l.Kl {
-				classIDArr = append(classIDArr, kls.ID)
-			}
-			classArr, err := client.ResolveClasses(classIDArr)
-			if err != nil {
-				return nil, err
-			}
-			teachIDArr := make([]int, 0)
-			for _, tes := range l.Te {
-				teachIDArr = append(teachIDArr, tes.ID)
-			}
-			teachArr, err := client.ResolveTeachers(teachIDArr)
-			if err != nil {
-				return nil, err
-			}
-			roomIDArr := make([]int, 0)
-			for _, ros := range l.Ro {
-				roomIDArr = append(roomIDArr, ros.ID)
-			}
-			roomArr, err := client.ResolveRooms(roomIDArr)
-			if err != nil {
-				return nil, err
-			}
-			lessons = append(lessons, Lesson{
-				Start:      time.Date(year, time.Month(month), day, startHour, startMinute, 0, 0, time.UTC),
-				End:        time.Date(year, time.Month(month), day, endHour, endMinute, 0, 0, time.UTC),
-				ClassIDs:   classIDArr,
-				Classes:    classArr,
-				TeacherIDs: teachIDArr,
-				Teachers:   teachArr,
-				RoomIDs:    roomIDArr,
-				Rooms:      roomArr,
-			})
-		}
-		return lessons, nil
-	}
-	return nil, fmt.Errorf("ids not matching")
+	return client.getTimetable(client.PersonID, client.PersonType, start, end)
 }
 
 // GetTimetableOfClass returns a list of lessons a specified class has in between start and end
-func (client Client) GetTimetableOfClass(start, end time.Time, class string) ([]Lesson, error) {
-	if !client.Authenticated {
-		return nil, fmt.Errorf("not authenticated")
-	}
-	smonth := strconv.Itoa(int(start.Month()))
-	if len(smonth) == 1 {
-		smonth = "0" + smonth
-	}
-	sday := strconv.Itoa(start.Day())
-	if len(sday) == 1 {
-		sday = "0" + sday
-	}
-	emonth := strconv.Itoa(int(end.Month()))
-	if len(emonth) == 1 {
-		emonth = "0" + emonth
-	}
-	eday := strconv.Itoa(end.Day())
-	if len(eday) == 1 {
-		eday = "0" + eday
-	}
-	classID, _ := client.ResolveClassID(class)
-	params := map[string]interface{}{
-		"id":        classID,
-		"type":      1,
-		"startDate": strconv.Itoa(start.Year()) + smonth + sday,
-		"endDate":   strconv.Itoa(end.Year()) + emonth + eday,
-	}
-	resp, id, err := client.sendRequest("getTimetable", params)
+func (client *Client) GetTimetableOfClass(start, end time.Time, class string) ([]Lesson, error) {
+	classID, err := client.ResolveClassID(class)
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
-	respBody, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return nil, err
-	}
-	r := struct {
-		JSONRPC string `json:"jsonrpc"`
-		ID      string `json:"id"`
-		Result  []struct {
-			ID        int `json:"id"`
-			Date      int `json:"date"`
-			StartTime int `json:"startTime"`
-			EndTime   int `json:"endTime"`
-			Kl        []struct {
-				ID int `json:"id"`
-			} `json:"kl"`
-			Te []struct {
-				ID int `json:"id"`
-			} `json:"te"`
-			Su []struct {
-				ID int `json:"id"`
-			} `json:"su"`
-			Ro []struct {
-				ID int `json:"id"`
-			} `json:"ro"`
-		} `json:"result"`
-	}{}
-	err = json.Unmarshal(respBody, &r)
+	return client.getTimetable(classID, ClassType, start, end)
+}
+
+// GetTimetableOfSpecificTeacher returns a list of lessons a specified teacher has in between start and end
+func (client *Client) GetTimetableOfSpecificTeacher(start, end time.Time, teacher string) ([]Lesson, error) {
+	teacherID, err := client.ResolveTeacherID(teacher)
 	if err != nil {
 		return nil, err
 	}
-	rid, _ := strconv.Atoi(r.ID)
-	if rid == id {
-		lessons := make([]Lesson, 0)
-		for _, l := range r.Result {
-			date := strconv.Itoa(l.Date)
-			year, _ := strconv.Atoi(date[0:4])
-			month, _ := strconv.Atoi(date[4:6])
-			day, _ := strconv.Atoi(date[6:8])
-			startTime := strconv.Itoa(l.StartTime)
-			startHour, _ := strconv.Atoi(startTime[0 : len(startTime)-2])
-			startMinute, _ := strconv.Atoi(startTime[len(startTime)-2:])
-			endTime := strconv.Itoa(l.EndTime)
-			endHour, _ := strconv.Atoi(endTime[0 : len(endTime)-2])
-			endMinute, _ := strconv.Atoi(endTime[len(endTime)-2:])
-			classIDArr := make([]int, 0)
-			for _, kls := range l.Kl {
-				classIDArr = append(classIDArr, kls.ID)
-			}
-			classArr, err := client.ResolveClasses(classIDArr)
-			if err != nil {
-				return nil, err
-			}
-			teachIDArr := make([]int, 0)
-			for _, tes := range l.Te {
-				teachIDArr = append(teachIDArr, tes.ID)
-			}
-			teachArr, err := client.ResolveTeachers(teachIDArr)
-			if err != nil {
-				return nil, err
-			}
-			roomIDArr := make([]int, 0)
-			for _, ros := range l.Ro {
-				roomIDArr = append(roomIDArr, ros.ID)
-			}
-			roomArr, err := client.ResolveRooms(roomIDArr)
-			if err != nil {
-				return nil, err
-			}
-			lessons = append(lessons, Lesson{
-				Start:      time.Date(year, time.Month(month), day, startHour, startMinute, 0, 0, time.UTC),
-				End:        time.Date(year, time.Month(month), day, endHour, endMinute, 0, 0, time.UTC),
-				ClassIDs:   classIDArr,
-				Classes:    classArr,
-				TeacherIDs: teachIDArr,
-				Teachers:   teachArr,
-				RoomIDs:    roomIDArr,
-				Rooms:      roomArr,
-			})
-		}
-		return lessons, nil
-	}
-	return nil, fmt.Errorf("ids not matching")
+	return client.getTimetable(teacherID, TeacherType, start, end)
 }
 
-// GetTimetableOfSpecificTeacher returns a list of lessons a specified teacher has in between start and end
-func (client Client) GetTimetableOfSpecificTeacher(start, end time.Time, teacher string) ([]Lesson, error) {
-	if !client.Authenticated {
-		return nil, fmt.Errorf("not authenticated")
-	}
-	smonth := strconv.Itoa(int(start.Month()))
-	if len(smonth) == 1 {
-		smonth = "0" + smonth
-	}
-	sday := strconv.Itoa(start.Day())
-	if len(sday) == 1 {
-		sday = "0" + sday
-	}
-	emonth := strconv.Itoa(int(end.Month()))
-	if len(emonth) == 1 {
-		emonth = "0" + emonth
-	}
-	eday := strconv.Itoa(end.Day())
-	if len(eday) == 1 {
-		eday = "0" + eday
-	}
-	id, err := client.ResolveTeacherID(teacher)
-	if err != nil {
+// getTimetable returns the lessons of the element with the given id and type in between start and end
+// the ids of the classes, teachers, rooms and subjects of all lessons are resolved at once using the metadata cache
+func (client *Client) getTimetable(elementID, elementType int, start, end time.Time) ([]Lesson, error) {
+	if err := client.ensureTimegrid(); err != nil {
 		return nil, err
 	}
 	params := map[string]interface{}{
-		"id":        id,
-		"type":      2,
-		"startDate": strconv.Itoa(start.Year()) + smonth + sday,
-		"endDate":   strconv.Itoa(end.Year()) + emonth + eday,
-	}
-	resp, id, err := client.sendRequest("getTimetable", params)
-	if err != nil {
+		"id":        elementID,
+		"type":      elementType,
+		"startDate": start.Format("20060102"),
+		"endDate":   end.Format("20060102"),
+	}
+	result := make([]struct {
+		ID        int          `json:"id"`
+		Date      int          `json:"date"`
+		StartTime int          `json:"startTime"`
+		EndTime   int          `json:"endTime"`
+		Kl        []elementRef `json:"kl"`
+		Te        []elementRef `json:"te"`
+		Su        []elementRef `json:"su"`
+		Ro        []elementRef `json:"ro"`
+		LsType    string       `json:"lstype"`
+		Code      string       `json:"code"`
+		SubstText string       `json:"substText"`
+	}, 0)
+	if err := client.call("getTimetable", params, &result); err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
-	respBody, err := ioutil.ReadAll(resp.Body)
+	classes, err := client.names(classesMethod)
 	if err != nil {
 		return nil, err
 	}
-	r := struct {
-		JSONRPC string `json:"jsonrpc"`
-		ID      string `json:"id"`
-		Result  []struct {
-			ID        int `json:"id"`
-			Date      int `json:"date"`
-			StartTime int `json:"startTime"`
-			EndTime   int `json:"endTime"`
-			Kl        []struct {
-				ID int `json:"id"`
-			} `json:"kl"`
-			Te []struct {
-				ID int `json:"id"`
-			} `json:"te"`
-			Su []struct {
-				ID int `json:"id"`
-			} `json:"su"`
-			Ro []struct {
-				ID int `json:"id"`
-			} `json:"ro"`
-		} `json:"result"`
-	}{}
-	err = json.Unmarshal(respBody, &r)
+	teachers, err := client.names(teachersMethod)
 	if err != nil {
 		return nil, err
 	}
-	rid, _ := strconv.Atoi(r.ID)
-	if rid == id {
-		lessons := make([]Lesson, 0)
-		for _, l := range r.Result {
-			date := strconv.Itoa(l.Date)
-			year, _ := strconv.Atoi(date[0:4])
-			month, _ := strconv.Atoi(date[4:6])
-			day, _ := strconv.Atoi(date[6:8])
-			startTime := strconv.Itoa(l.StartTime)
-			startHour, _ := strconv.Atoi(startTime[0 : len(startTime)-2])
-			startMinute, _ := strconv.Atoi(startTime[len(startTime)-2:])
-			endTime := strconv.Itoa(l.EndTime)
-			endHour, _ := strconv.Atoi(endTime[0 : len(endTime)-2])
-			endMinute, _ := strconv.Atoi(endTime[len(endTime)-2:])
-			classIDArr := make([]int, 0)
-			for _, kls := range l.Kl {
-				classIDArr = append(classIDArr, kls.ID)
-			}
-			classArr, err := client.ResolveClasses(classIDArr)
-			if err != nil {
-				return nil, err
-			}
-			teachIDArr := make([]int, 0)
-			for _, tes := range l.Te {
-				teachIDArr = append(teachIDArr, tes.ID)
-			}
-			teachArr, err := client.ResolveTeachers(teachIDArr)
-			if err != nil {
-				return nil, err
-			}
-			roomIDArr := make([]int, 0)
-			for _, ros := range l.Ro {
-				roomIDArr = append(roomIDArr, ros.ID)
-			}
-			roomArr, err := client.ResolveRooms(roomIDArr)
-			if err != nil {
-				return nil, err
-			}
-			lessons = append(lessons, Lesson{
-				Start:      time.Date(year, time.Month(month), day, startHour, startMinute, 0, 0, time.UTC),
-				End:        time.Date(year, time.Month(month), day, endHour, endMinute, 0, 0, time.UTC),
-				ClassIDs:   classIDArr,
-				Classes:    classArr,
-				TeacherIDs: teachIDArr,
-				Teachers:   teachArr,
-				RoomIDs:    roomIDArr,
-				Rooms:      roomArr,
-			})
-		}
-		return lessons, nil
-	}
-	return nil, fmt.Errorf("ids not matching")
-}
-
-// ResolveTeachers converts an array of teacher ids into an array of teacher names
-func (client Client) ResolveTeachers(ids []int) ([]string, error) {
-	if !client.Authenticated {
-		return nil, fmt.Errorf("not authenticated")
-	}
-	resp, id, err := client.sendRequest("getTeachers", map[string]interface{}{})
+	rooms, err := client.names(roomsMethod)
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
-	respBody, err := ioutil.ReadAll(resp.Body)
+	subjects, err := client.names(subjectsMethod)
 	if err != nil {
 		return nil, err
 	}
-	r := struct {
-		JSONRPC string `json:"jsonrpc"`
-		ID      string `json:"id"`
-		Result  []struct {
-			ID        int    `json:"id"`
-			Name      string `json:"name"`
-			Forename  string `json:"foreName"`
-			Longname  string `json:"longName"`
-			ForeColor string `json:"foreColor"`
-			BackColor string `json:"backColor"`
-		} `json:"result"`
-	}{}
-	err = json.Unmarshal(respBody, &r)
-	if err != nil {
-		return nil, err
-	}
-	rid, _ := strconv.Atoi(r.ID)
-	if id == rid {
-		teacher := make([]string, 0)
-		for _, id := range ids {
-			for _, res := range r.Result {
-				if id == res.ID {
-					teacher = append(teacher, res.Name)
-				}
-			}
+	lessons := make([]Lesson, 0)
+	for _, l := range result {
+		year, month, day := l.Date/10000, l.Date/100%100, l.Date%100
+		classIDs := ids(l.Kl)
+		teacherIDs := ids(l.Te)
+		roomIDs := ids(l.Ro)
+		subjectIDs := ids(l.Su)
+		lessonType := l.LsType
+		if lessonType == "" {
+			lessonType = LessonTypeLesson
 		}
-		return teacher, nil
-	}
-	return nil, fmt.Errorf("ids not matching")
+		lessons = append(lessons, Lesson{
+			ID:         l.ID,
+			Start:      time.Date(year, time.Month(month), day, l.StartTime/100, l.StartTime%100, 0, 0, time.UTC),
+			End:        time.Date(year, time.Month(month), day, l.EndTime/100, l.EndTime%100, 0, 0, time.UTC),
+			ClassIDs:   classIDs,
+			Classes:    lookup(classes, classIDs),
+			TeacherIDs: teacherIDs,
+			Teachers:   lookup(teachers, teacherIDs),
+			RoomIDs:    roomIDs,
+			Rooms:      lookup(rooms, roomIDs),
+			SubjectIDs: subjectIDs,
+			Subjects:   lookup(subjects, subjectIDs),
+			Type:       lessonType,
+			Code:       l.Code,
+			SubstText:  l.SubstText,
+		})
+	}
+	return lessons, nil
+}
+
+// ResolveTeachers converts an array of teacher ids into an array of teacher names
+func (client *Client) ResolveTeachers(ids []int) ([]string, error) {
+	return client.resolve(teachersMethod, ids)
 }
 
 // ResolveTeacherID converts a teacher name to the corersponding teacher id
-func (client Client) ResolveTeacherID(teacher string) (int, error) {
-	if !client.Authenticated {
-		return -1, fmt.Errorf("not authenticated")
-	}
-	resp, id, err := client.sendRequest("getTeachers", map[string]interface{}{})
+func (client *Client) ResolveTeacherID(teacher string) (int, error) {
+	teachers, err := client.Elements(teachersMethod)
 	if err != nil {
 		return -1, err
 	}
-	defer resp.Body.Close()
-	respBody, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return -1, err
-	}
-	r := struct {
-		JSONRPC string `json:"jsonrpc"`
-		ID      string `json:"id"`
-		Result  []struct {
-			ID        int    `json:"id"`
-			Name      string `json:"name"`
-			ForeName  string `json:"foreName"`
-			LongName  string `json:"longName"`
-			ForeColor string `json:"foreColor"`
-			BackColor string `json:"backColor"`
-		} `json:"result"`
-	}{}
-	err = json.Unmarshal(respBody, &r)
-	if err != nil {
-		return -1, err
+	split := strings.Split(teacher, " ")
+	if len(split) < 2 {
+		return -1, fmt.Errorf("teacher not found")
 	}
-	rid, _ := strconv.Atoi(r.ID)
-	if rid == id {
-		split := strings.Split(teacher, " ")
-		forename := split[0]
-		longname := strings.ToUpper(split[1])
-		for _, res := range r.Result {
-			surname := strings.Split(res.LongName, " ")[0]
-			if forename == res.ForeName && longname == surname {
-				return res.ID, nil
-			}
+	forename := split[0]
+	longname := strings.ToUpper(split[1])
+	for _, res := range teachers {
+		surname := strings.Split(res.LongName, " ")[0]
+		if forename == res.ForeName && longname == surname {
+			return res.ID, nil
 		}
-		return -1, fmt.Errorf("teacher not found")
 	}
-	return -1, fmt.Errorf("ids not matching")
+	return -1, fmt.Errorf("teacher not found")
 }
 
 // ResolveRooms converts an array of room ids into an array of room names
-func (client Client) ResolveRooms(ids []int) ([]string, error) {
-	if !client.Authenticated {
-		return nil, fmt.Errorf("not authenticated")
-	}
-	resp, id, err := client.sendRequest("getRooms", map[string]interface{}{})
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-	respBody, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return nil, err
-	}
-	r := struct {
-		JSONRPC string `json:"jsonrpc"`
-		ID      string `json:"id"`
-		Result  []struct {
-			ID        int    `json:"id"`
-			Name      string `json:"name"`
-			Longname  string `json:"longName"`
-			ForeColor string `json:"foreColor"`
-			BackColor string `json:"backColor"`
-		} `json:"result"`
-	}{}
-	err = json.Unmarshal(respBody, &r)
-	if err != nil {
-		return nil, err
-	}
-	rid, _ := strconv.Atoi(r.ID)
-	if id == rid {
-		rooms := make([]string, 0)
-		for _, id := range ids {
-			for _, res := range r.Result {
-				if id == res.ID {
-					rooms = append(rooms, res.Name)
-				}
-			}
-		}
-		return rooms, nil
-	}
-	return nil, fmt.Errorf("ids not matching")
+func (client *Client) ResolveRooms(ids []int) ([]string, error) {
+	return client.resolve(roomsMethod, ids)
 }
 
 // ResolveClasses converts an array of class ids into an array of class names
-func (client Client) ResolveClasses(ids []int) ([]string, error) {
-	if !client.Authenticated {
-		return nil, fmt.Errorf("not authenticated")
-	}
-	resp, id, err := client.sendRequest("getKlassen", map[string]interface{}{})
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-	respBody, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return nil, err
-	}
-	r := struct {
-		JSONRPC string `json:"jsonrpc"`
-		ID      string `json:"id"`
-		Result  []struct {
-			ID        int    `json:"id"`
-			Name      string `json:"name"`
-			Longname  string `json:"longName"`
-			ForeColor string `json:"foreColor"`
-			BackColor string `json:"backColor"`
-			Teacher1  int    `json:"teacher1"`
-			Teacher2  int    `json:"teacher2"`
-		} `json:"result"`
-	}{}
-	err = json.Unmarshal(respBody, &r)
-	if err != nil {
-		return nil, err
-	}
-	rid, _ := strconv.Atoi(r.ID)
-	if rid == id {
-		classes := make([]string, 0)
-		for _, id := range ids {
-			for _, res := range r.Result {
-				if id == res.ID {
-					classes = append(classes, res.Name)
-				}
-			}
-		}
-		return classes, nil
-	}
-	return nil, fmt.Errorf("ids not matching")
+func (client *Client) ResolveClasses(ids []int) ([]string, error) {
+	return client.resolve(classesMethod, ids)
+}
+
+// ResolveSubjects converts an array of subject ids into an array of subject names
+func (client *Client) ResolveSubjects(ids []int) ([]string, error) {
+	return client.resolve(subjectsMethod, ids)
 }
 
 // ResolveClassID converts a class name to the corresponding class id
-func (client Client) ResolveClassID(class string) (int, error) {
-	if !client.Authenticated {
-		return -1, fmt.Errorf("not authenticated")
-	}
-	resp, id, err := client.sendRequest("getKlassen", map[string]interface{}{})
-	if err != nil {
-		return -1, err
-	}
-	defer resp.Body.Close()
-	respBody, err := ioutil.ReadAll(resp.Body)
+func (client *Client) ResolveClassID(class string) (int, error) {
+	classes, err := client.Elements(classesMethod)
 	if err != nil {
 		return -1, err
 	}
-	r := struct {
-		JSONRPC string `json:"jsonrpc"`
-		ID      string `json:"id"`
-		Result  []struct {
-			ID        int    `json:"id"`
-			Name      string `json:"name"`
-			Longname  string `json:"longName"`
-			ForeColor string `json:"foreColor"`
-			BackColor string `json:"backColor"`
-			Teacher1  int    `json:"teacher1"`
-			Teacher2  int    `json:"teacher2"`
-		} `json:"result"`
-	}{}
-	err = json.Unmarshal(respBody, &r)
-	if err != nil {
-		return -1, err
-	}
-	rid, _ := strconv.Atoi(r.ID)
-	if rid == id {
-		for _, res := range r.Result {
-			if class == res.Name {
-				return res.ID, nil
-			}
+	for _, res := range classes {
+		if class == res.Name {
+			return res.ID, nil
 		}
-		return -1, fmt.Errorf("class not found")
 	}
-	return -1, fmt.Errorf("ids not matching")
+	return -1, fmt.Errorf("class not found")
 }
 
 // Close closes an authenticated connection to the untis api
 func (client *Client) Close() error {
+	client.mu.Lock()
+	defer client.mu.Unlock()
 	if !client.Authenticated {
 		return fmt.Errorf("not authenticated")
 	}
 	client.Closed = true
 	client.Authenticated = false
-	_, _, err := client.sendRequest("logout", map[string]interface{}{})
-	if err != nil {
-		return err
-	}
-	return nil
+	return client.send("logout", map[string]interface{}{}, nil)
 }
 
-// DeleteClient deletes the current client out of the map of active clients
-func (client Client) DeleteClient() {
-	delete(activeClients, client.Username)
+// call sends a request to the untis api using the session of the client and decodes its result into result
+// the client is authenticated first if it isn't yet, and again if untis reports the session as expired
+// (which is only possible for the service client, as clients of users don't keep their password)
+func (client *Client) call(method string, params map[string]interface{}, result interface{}) error {
+	client.mu.Lock()
+	defer client.mu.Unlock()
+	client.touch()
+	if !client.Authenticated {
+		if err := client.authenticate(); err != nil {
+			return err
+		}
+	}
+	err := client.send(method, params, result)
+	var rpcErr *RPCError
+	if errors.As(err, &rpcErr) && rpcErr.Code == NotAuthenticatedCode {
+		client.Authenticated = false
+		if err := client.authenticate(); err != nil {
+			return err
+		}
+		err = client.send(method, params, result)
+	}
+	return err
 }
 
-// sendRequest helps this api to send requests to the untis api
-func (client Client) sendRequest(method string, params map[string]interface{}) (*http.Response, int, error) {
+// send sends a single request to the untis api and decodes its result into result, the caller has to hold the lock of the client
+func (client *Client) send(method string, params map[string]interface{}, result interface{}) error {
 	id := rand.Intn(math.MaxInt64)
 	body, _ := json.Marshal(map[string]interface{}{
 		"id":      id,
@@ -737,92 +410,49 @@ func (client Client) sendRequest(method string, params map[string]interface{}) (
 		"params":  params,
 		"jsonrpc": "2.0",
 	})
-	req, err := http.NewRequest("POST", URL, bytes.NewBuffer(body))
+	endpoint := client.URL
+	if endpoint == "" {
+		endpoint = Endpoint()
+	}
+	req, err := http.NewRequest("POST", endpoint, bytes.NewBuffer(body))
 	if err != nil {
-		return nil, -1, err
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	reqClient := client.HTTPClient
+	if reqClient == nil {
+		reqClient = HTTPClient
+	}
+	if client.SessionID != "" {
+		req.AddCookie(&http.Cookie{Name: "JSESSIONID", Value: client.SessionID})
 	}
-	reqClient := http.Client{}
-	req.AddCookie(&http.Cookie{Name: "JSESSIONID", Value: client.SessionID})
 	resp, err := reqClient.Do(req)
-	return resp, id, err
-}
-
-// GetLessonNrByStart computes the lesson number by its start time
-func GetLessonNrByStart(start time.Time) int {
-	switch start.Hour() {
-	case 8:
-		if start.Minute() == 0 {
-			return 1
-		} else if start.Minute() == 50 {
-			return 2
-		}
-	case 9:
-		return 3
-	case 10:
-		return 4
-	case 11:
-		return 5
-	case 12:
-		return 6
-	case 13:
-		return 7
-	case 14:
-		return 8
-	case 15:
-		return 9
-	case 16:
-		return 10
-	case 17:
-		if start.Minute() == 0 {
-			return 11
-		} else if start.Minute() == 45 {
-			return 12
-		}
-	case 18:
-		return 13
-	case 19:
-		return 14
-	case 20:
-		return 15
-	}
-	return -1
-}
-
-// GetLessonNrByEnd computes the lesson number by its end time
-func GetLessonNrByEnd(end time.Time) int {
-	switch end.Hour() {
-	case 8:
-		return 1
-	case 9:
-		return 2
-	case 10:
-		return 3
-	case 11:
-		return 4
-	case 12:
-		return 5
-	case 13:
-		return 6
-	case 14:
-		return 7
-	case 15:
-		return 8
-	case 16:
-		if end.Minute() == 0 {
-			return 9
-		} else if end.Minute() == 50 {
-			return 10
-		}
-	case 17:
-		return 11
-	case 18:
-		return 12
-	case 19:
-		return 13
-	case 20:
-		return 14
-	case 21:
-		return 15
-	}
-	return -1
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	r := struct {
+		JSONRPC string          `json:"jsonrpc"`
+		ID      json.Number     `json:"id"`
+		Result  json.RawMessage `json:"result"`
+		Error   *RPCError       `json:"error"`
+	}{}
+	err = json.Unmarshal(respBody, &r)
+	if err != nil {
+		return err
+	}
+	if r.Error != nil {
+		return r.Error
+	}
+	if r.ID.String() != strconv.Itoa(id) {
+		return fmt.Errorf("ids not matching")
+	}
+	if result == nil || len(r.Result) == 0 {
+		return nil
+	}
+	return json.Unmarshal(r.Result, result)
 }
@@ -0,0 +1,327 @@
+package untis_test
+
+import (
+	"errors"
+	"github.com/refundable-tgm/huginn/untis"
+	"github.com/refundable-tgm/huginn/untis/untistest"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// startFake starts the fake json rpc api with the fixtures of untistest and points UNTIS_URL at it
+// the caches of the untis package are dropped before and after the test
+func startFake(t *testing.T) (*untistest.Server, string) {
+	fixtures, err := untistest.LoadFixtures("untistest/testdata/fixtures.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fake := untistest.NewHandler(fixtures)
+	server := httptest.NewServer(fake)
+	previous, ok := os.LookupEnv(untis.URLEnv)
+	if err := os.Setenv(untis.URLEnv, server.URL); err != nil {
+		t.Fatal(err)
+	}
+	untis.InvalidateMetadata()
+	untis.InvalidateTimegrid()
+	t.Cleanup(func() {
+		server.Close()
+		untis.InvalidateMetadata()
+		untis.InvalidateTimegrid()
+		if ok {
+			_ = os.Setenv(untis.URLEnv, previous)
+		} else {
+			_ = os.Unsetenv(untis.URLEnv)
+		}
+	})
+	return fake, server.URL
+}
+
+// day returns midnight of the given day of march 2021, the 1st is a monday
+func day(d int) time.Time {
+	return time.Date(2021, time.March, d, 0, 0, 0, 0, time.UTC)
+}
+
+func TestAuthenticate(t *testing.T) {
+	_, url := startFake(t)
+	tests := []struct {
+		name       string
+		username   string
+		password   string
+		personType int
+		personID   int
+		code       int
+		err        error
+	}{
+		{name: "teacher", username: "szakall", password: "password", personType: untis.TeacherType, personID: 1},
+		{name: "service account", username: "huginn", password: "huginn", personType: -1, personID: -1},
+		{name: "wrong password", username: "szakall", password: "wrong", code: untistest.BadCredentialsCode},
+		{name: "unknown user", username: "nobody", password: "password", code: untistest.BadCredentialsCode},
+		{name: "no password", username: "szakall", err: untis.ErrNoSession},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client := &untis.Client{Username: test.username, Password: test.password, URL: url, PersonType: -1, PersonID: -1}
+			err := client.Authenticate()
+			if test.code != 0 || test.err != nil {
+				var rpcErr *untis.RPCError
+				if test.code != 0 && !(errors.As(err, &rpcErr) && rpcErr.Code == test.code) {
+					t.Fatalf("Authenticate() returned %v, want the untis error %v", err, test.code)
+				}
+				if test.err != nil && !errors.Is(err, test.err) {
+					t.Fatalf("Authenticate() returned %v, want %v", err, test.err)
+				}
+				if client.Authenticated {
+					t.Error("the client is authenticated after a failed authentication")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !client.Authenticated || client.SessionID == "" {
+				t.Errorf("the client didn't open a session: %+v", client)
+			}
+			if client.PersonType != test.personType || client.PersonID != test.personID {
+				t.Errorf("person = %v/%v, want %v/%v", client.PersonType, client.PersonID, test.personType, test.personID)
+			}
+		})
+	}
+}
+
+func TestTimetable(t *testing.T) {
+	_, url := startFake(t)
+	client := &untis.Client{Username: "szakall", Password: "password", URL: url, PersonType: -1, PersonID: -1}
+	tests := []struct {
+		name    string
+		fetch   func() ([]untis.Lesson, error)
+		count   int
+		first   untis.Lesson
+		checked func(t *testing.T, lessons []untis.Lesson)
+	}{
+		{
+			name:  "own week",
+			fetch: func() ([]untis.Lesson, error) { return client.GetTimetableOfTeacher(day(1), day(5)) },
+			count: 15,
+			first: untis.Lesson{
+				ID:         1,
+				Start:      day(1).Add(8 * time.Hour),
+				End:        day(1).Add(8*time.Hour + 50*time.Minute),
+				ClassIDs:   []int{1},
+				Classes:    []string{"5AHIT"},
+				TeacherIDs: []int{1},
+				Teachers:   []string{"ZAKS"},
+				RoomIDs:    []int{1},
+				Rooms:      []string{"H1101"},
+				SubjectIDs: []int{1},
+				Subjects:   []string{"SEW"},
+				Type:       untis.LessonTypeLesson,
+			},
+		},
+		{
+			name: "other teacher",
+			fetch: func() ([]untis.Lesson, error) {
+				return client.GetTimetableOfSpecificTeacher(day(1), day(1), "Eva Huber")
+			},
+			count: 2,
+			checked: func(t *testing.T, lessons []untis.Lesson) {
+				if !reflect.DeepEqual(lessons[0].Teachers, []string{"HUBE"}) {
+					t.Errorf("teachers = %v, want HUBE", lessons[0].Teachers)
+				}
+			},
+		},
+		{
+			name:  "class with an exam",
+			fetch: func() ([]untis.Lesson, error) { return client.GetTimetableOfClass(day(2), day(2), "5AHIT") },
+			count: 5,
+			checked: func(t *testing.T, lessons []untis.Lesson) {
+				for _, lesson := range lessons {
+					if lesson.Type == untis.LessonTypeExamination {
+						if lesson.SubstText != "Schularbeit" || !lesson.Start.Equal(day(2).Add(11*time.Hour+45*time.Minute)) {
+							t.Errorf("unexpected exam %+v", lesson)
+						}
+						return
+					}
+				}
+				t.Error("the exam is missing")
+			},
+		},
+		{
+			name:  "class with a cancelled lesson",
+			fetch: func() ([]untis.Lesson, error) { return client.GetTimetableOfClass(day(3), day(3), "5BHIT") },
+			count: 6,
+			checked: func(t *testing.T, lessons []untis.Lesson) {
+				if remaining := untis.WithoutCancelled(lessons); len(remaining) != 5 {
+					t.Errorf("%v lessons remain without the cancelled ones, want 5", len(remaining))
+				}
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			lessons, err := test.fetch()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(lessons) != test.count {
+				t.Fatalf("%v lessons returned, want %v", len(lessons), test.count)
+			}
+			if test.first.Type != "" && !reflect.DeepEqual(lessons[0], test.first) {
+				t.Errorf("first lesson = %+v, want %+v", lessons[0], test.first)
+			}
+			if test.checked != nil {
+				test.checked(t, lessons)
+			}
+		})
+	}
+	// the timegrid was loaded along with the timetable
+	if nr := untis.GetLessonNrByStart(day(1).Add(9*time.Hour + 55*time.Minute)); nr != 3 {
+		t.Errorf("lesson starting at 09:55 is number %v, want 3", nr)
+	}
+	if _, err := client.GetTimetableOfClass(day(1), day(1), "9ZZZ"); err == nil {
+		t.Error("the timetable of an unknown class was returned")
+	}
+}
+
+func TestReauthentication(t *testing.T) {
+	fake, url := startFake(t)
+	tests := []struct {
+		name     string
+		password string
+		err      error
+	}{
+		{name: "client keeping its password", password: "password"},
+		{name: "client of a user", password: "", err: untis.ErrNoSession},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client := &untis.Client{Username: "szakall", Password: "password", URL: url, PersonType: -1, PersonID: -1}
+			if err := client.Authenticate(); err != nil {
+				t.Fatal(err)
+			}
+			// clients of users forget their password once they are logged in
+			client.Password = test.password
+			expired := client.SessionID
+			fake.ExpireSessions()
+			_, err := client.GetTimetableOfTeacher(day(1), day(1))
+			if test.err != nil {
+				if !errors.Is(err, test.err) {
+					t.Fatalf("GetTimetableOfTeacher() returned %v, want %v", err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !client.Authenticated || client.SessionID == expired {
+				t.Errorf("the client didn't open a new session: %+v", client)
+			}
+		})
+	}
+}
+
+func TestLogout(t *testing.T) {
+	_, url := startFake(t)
+	client := &untis.Client{Username: "mborko", Password: "password", URL: url, PersonType: -1, PersonID: -1}
+	if err := client.Close(); err == nil {
+		t.Error("an unauthenticated client could be closed")
+	}
+	if err := client.Authenticate(); err != nil {
+		t.Fatal(err)
+	}
+	// a second client sharing the session can't renew it, so it notices the session was closed
+	shared := &untis.Client{Username: "mborko", URL: url, SessionID: client.SessionID, Authenticated: true}
+	if _, err := shared.Elements("getTeachers"); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if client.Authenticated || !client.Closed {
+		t.Errorf("the client wasn't closed: %+v", client)
+	}
+	untis.InvalidateMetadata()
+	if _, err := shared.Elements("getTeachers"); !errors.Is(err, untis.ErrNoSession) {
+		t.Errorf("request with the closed session returned %v, want ErrNoSession", err)
+	}
+}
+
+func TestSessions(t *testing.T) {
+	startFake(t)
+	client, err := untis.Login("szakall", "password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if client.Password != "" {
+		t.Error("the password of the user was kept")
+	}
+	if untis.GetClient("szakall") != client {
+		t.Error("the session of the user wasn't stored")
+	}
+	untis.RemoveClient("szakall")
+	if !client.Closed {
+		t.Error("the removed session wasn't logged out")
+	}
+	if untis.GetClient("szakall") == client {
+		t.Error("the removed session is still stored")
+	}
+	if _, err := untis.Login("szakall", "wrong"); err == nil {
+		t.Error("a login with a wrong password succeeded")
+	}
+}
+
+func TestExams(t *testing.T) {
+	_, url := startFake(t)
+	client := &untis.Client{Username: "huginn", Password: "huginn", URL: url, PersonType: -1, PersonID: -1}
+	tests := []struct {
+		name    string
+		absence untis.Absence
+		want    [][]string
+	}{
+		{
+			name:    "exam of a class",
+			absence: untis.Absence{Classes: []string{"5AHIT"}},
+			want:    [][]string{{"5AHIT"}},
+		},
+		{
+			name:    "exam listed for the class and the teacher",
+			absence: untis.Absence{Classes: []string{"5AHIT"}, Teachers: []string{"MAYR"}},
+			want:    [][]string{{"5AHIT"}},
+		},
+		{
+			name:    "exams of other classes at the same time",
+			absence: untis.Absence{Classes: []string{"5AHIT", "5BHIT"}},
+			want:    [][]string{{"5AHIT"}, {"5BHIT"}},
+		},
+		{
+			name: "teacher absent before the exam",
+			absence: untis.Absence{Teachers: []string{"MAYR"}, Intervals: map[string][]untis.Interval{
+				"MAYR": {{Start: day(2).Add(8 * time.Hour), End: day(2).Add(11 * time.Hour)}},
+			}},
+			want: [][]string{},
+		},
+		{
+			name:    "class without exams",
+			absence: untis.Absence{Classes: []string{"4AHIT"}},
+			want:    [][]string{},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.absence.Start, test.absence.End = day(2), day(3)
+			exams, err := client.Exams(test.absence)
+			if err != nil {
+				t.Fatal(err)
+			}
+			classes := make([][]string, 0)
+			for _, exam := range exams {
+				classes = append(classes, exam.Classes)
+			}
+			if !reflect.DeepEqual(classes, test.want) {
+				t.Errorf("exams of the classes %v returned, want %v", classes, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,362 @@
+// Package untistest provides a fake of the json rpc api of WebUntis serving fixture data,
+// so everything using the untis package can be run without access to the real WebUntis of the school.
+package untistest
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/refundable-tgm/huginn/untis"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Error codes of the json rpc api returned by the fake
+const (
+	// BadCredentialsCode is returned if authenticate is called with an unknown user or a wrong password
+	BadCredentialsCode = -8504
+	// MethodNotFoundCode is returned for methods the fake doesn't implement
+	MethodNotFoundCode = -32601
+	// InvalidParamsCode is returned if the parameters of a request can't be read
+	InvalidParamsCode = -32602
+)
+
+// Fixtures is the data served by the fake
+type Fixtures struct {
+	// Users which can authenticate
+	Users []User `json:"users"`
+	// Teachers returned by getTeachers
+	Teachers []untis.Element `json:"teachers"`
+	// Classes returned by getKlassen
+	Classes []untis.Element `json:"classes"`
+	// Rooms returned by getRooms
+	Rooms []untis.Element `json:"rooms"`
+	// Subjects returned by getSubjects
+	Subjects []untis.Element `json:"subjects"`
+	// Timegrid returned by getTimegridUnits
+	Timegrid []Day `json:"timegrid"`
+	// Periods the timetables returned by getTimetable consist of
+	Periods []Period `json:"periods"`
+}
+
+// User is an account of the fake
+type User struct {
+	// Username of the account
+	Username string `json:"username"`
+	// Password of the account
+	Password string `json:"password"`
+	// PersonType of the account (untis.TeacherType for teachers, -1 for accounts without a timetable)
+	PersonType int `json:"personType"`
+	// PersonID is the id of the teacher belonging to the account
+	PersonID int `json:"personId"`
+}
+
+// Day are the time units of one day of the timegrid
+type Day struct {
+	// Day of the week, counted like untis starting with 1 on sunday
+	Day int `json:"day"`
+	// TimeUnits of the day
+	TimeUnits []TimeUnit `json:"timeUnits"`
+}
+
+// TimeUnit is one lesson of the timegrid
+type TimeUnit struct {
+	// Name of the unit, usually its number
+	Name string `json:"name"`
+	// StartTime of the unit in the format hhmm
+	StartTime int `json:"startTime"`
+	// EndTime of the unit in the format hhmm
+	EndTime int `json:"endTime"`
+}
+
+// Period is a lesson of the timetable
+// it takes place either once on Date, or every week on Day if Date is 0
+type Period struct {
+	// ID of the period
+	ID int `json:"id"`
+	// Date of the period in the format yyyymmdd
+	Date int `json:"date"`
+	// Day of the week a weekly period takes place on, counted like untis starting with 1 on sunday
+	Day int `json:"day"`
+	// StartTime of the period in the format hhmm
+	StartTime int `json:"startTime"`
+	// EndTime of the period in the format hhmm
+	EndTime int `json:"endTime"`
+	// Classes are the ids of the classes attending the period
+	Classes []int `json:"classes"`
+	// Teachers are the ids of the teachers teaching the period
+	Teachers []int `json:"teachers"`
+	// Subjects are the ids of the subjects of the period
+	Subjects []int `json:"subjects"`
+	// Rooms are the ids of the rooms the period takes place in
+	Rooms []int `json:"rooms"`
+	// LsType is the type of the period (untis.LessonTypeLesson if it is empty)
+	LsType string `json:"lstype"`
+	// Code is the substitution code of the period, e.g. untis.CodeCancelled
+	Code string `json:"code"`
+	// SubstText is the substitution text of the period
+	SubstText string `json:"substText"`
+}
+
+// LoadFixtures reads fixtures from the json file at path
+func LoadFixtures(path string) (Fixtures, error) {
+	fixtures := Fixtures{}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return fixtures, err
+	}
+	if err := json.Unmarshal(data, &fixtures); err != nil {
+		return fixtures, fmt.Errorf("invalid fixtures %v: %v", path, err)
+	}
+	return fixtures, nil
+}
+
+// Server is a http handler answering requests to the json rpc api of untis with the fixtures
+// it implements authenticate, logout, getTeachers, getKlassen, getRooms, getSubjects, getTimegridUnits and getTimetable
+type Server struct {
+	// fixtures served by the fake
+	fixtures Fixtures
+	// sessions maps the ids of the open sessions to their users
+	sessions map[string]User
+	// mu guards sessions
+	mu sync.Mutex
+}
+
+// NewHandler returns a fake serving the given fixtures
+func NewHandler(fixtures Fixtures) *Server {
+	return &Server{fixtures: fixtures, sessions: make(map[string]User)}
+}
+
+// NewServer starts a local http server with a fake serving the given fixtures, it has to be closed by the caller
+// clients can be pointed at it by setting the URL of an untis.Client or UNTIS_URL to the URL of the server
+func NewServer(fixtures Fixtures) *httptest.Server {
+	return httptest.NewServer(NewHandler(fixtures))
+}
+
+// request is a json rpc request
+type request struct {
+	ID     json.RawMessage `json:"id"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+}
+
+// ServeHTTP answers a json rpc request
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "only POST is supported", http.StatusMethodNotAllowed)
+		return
+	}
+	req := request{}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid json rpc request", http.StatusBadRequest)
+		return
+	}
+	var result interface{}
+	var rpcErr *untis.RPCError
+	if req.Method == "authenticate" {
+		result, rpcErr = s.authenticate(w, req.Params)
+	} else if _, ok := s.session(r); !ok {
+		rpcErr = &untis.RPCError{Code: untis.NotAuthenticatedCode, Message: "not authenticated"}
+	} else {
+		result, rpcErr = s.dispatch(r, req)
+	}
+	response := map[string]interface{}{"jsonrpc": "2.0", "id": req.ID}
+	if rpcErr != nil {
+		response["error"] = rpcErr
+	} else {
+		response["result"] = result
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(response)
+}
+
+// dispatch answers a request of an authenticated user
+func (s *Server) dispatch(r *http.Request, req request) (interface{}, *untis.RPCError) {
+	switch req.Method {
+	case "logout":
+		s.logout(r)
+		return nil, nil
+	case "getTeachers":
+		return nonNil(s.fixtures.Teachers), nil
+	case "getKlassen":
+		return nonNil(s.fixtures.Classes), nil
+	case "getRooms":
+		return nonNil(s.fixtures.Rooms), nil
+	case "getSubjects":
+		return nonNil(s.fixtures.Subjects), nil
+	case "getTimegridUnits":
+		if s.fixtures.Timegrid == nil {
+			return make([]Day, 0), nil
+		}
+		return s.fixtures.Timegrid, nil
+	case "getTimetable":
+		return s.timetable(req.Params)
+	}
+	return nil, &untis.RPCError{Code: MethodNotFoundCode, Message: "method not found: " + req.Method}
+}
+
+// authenticate opens a session for the user in the parameters and sets it as JSESSIONID cookie
+func (s *Server) authenticate(w http.ResponseWriter, raw json.RawMessage) (interface{}, *untis.RPCError) {
+	params := struct {
+		User     string `json:"user"`
+		Password string `json:"password"`
+	}{}
+	if err := json.Unmarshal(raw, &params); err != nil {
+		return nil, &untis.RPCError{Code: InvalidParamsCode, Message: "invalid parameters"}
+	}
+	for _, user := range s.fixtures.Users {
+		if user.Username != params.User || user.Password != params.Password {
+			continue
+		}
+		id := make([]byte, 16)
+		_, _ = rand.Read(id)
+		sessionID := hex.EncodeToString(id)
+		s.mu.Lock()
+		s.sessions[sessionID] = user
+		s.mu.Unlock()
+		http.SetCookie(w, &http.Cookie{Name: "JSESSIONID", Value: sessionID, Path: "/"})
+		return map[string]interface{}{
+			"sessionId":  sessionID,
+			"personType": user.PersonType,
+			"personId":   user.PersonID,
+		}, nil
+	}
+	return nil, &untis.RPCError{Code: BadCredentialsCode, Message: "bad credentials"}
+}
+
+// session returns the user of the session the request belongs to
+func (s *Server) session(r *http.Request) (User, bool) {
+	cookie, err := r.Cookie("JSESSIONID")
+	if err != nil {
+		return User{}, false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	user, ok := s.sessions[cookie.Value]
+	return user, ok
+}
+
+// logout closes the session the request belongs to
+func (s *Server) logout(r *http.Request) {
+	if cookie, err := r.Cookie("JSESSIONID"); err == nil {
+		s.mu.Lock()
+		delete(s.sessions, cookie.Value)
+		s.mu.Unlock()
+	}
+}
+
+// ExpireSessions closes all open sessions, like untis does once they timed out
+// the next request of every client is answered with untis.NotAuthenticatedCode
+func (s *Server) ExpireSessions() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sessions = make(map[string]User)
+}
+
+// timetable returns the periods of the element in the parameters in between their start and end date
+func (s *Server) timetable(raw json.RawMessage) (interface{}, *untis.RPCError) {
+	params := struct {
+		ID        int         `json:"id"`
+		Type      int         `json:"type"`
+		StartDate interface{} `json:"startDate"`
+		EndDate   interface{} `json:"endDate"`
+	}{}
+	if err := json.Unmarshal(raw, &params); err != nil {
+		return nil, &untis.RPCError{Code: InvalidParamsCode, Message: "invalid parameters"}
+	}
+	start, err := parseDate(params.StartDate)
+	if err != nil {
+		return nil, &untis.RPCError{Code: InvalidParamsCode, Message: "invalid startDate"}
+	}
+	end, err := parseDate(params.EndDate)
+	if err != nil {
+		return nil, &untis.RPCError{Code: InvalidParamsCode, Message: "invalid endDate"}
+	}
+	periods := make([]map[string]interface{}, 0)
+	for day := start; !day.After(end); day = day.AddDate(0, 0, 1) {
+		date := day.Year()*10000 + int(day.Month())*100 + day.Day()
+		for _, period := range s.fixtures.Periods {
+			if period.Date != date && (period.Date != 0 || period.Day != int(day.Weekday())+1) {
+				continue
+			}
+			if !contains(elementsOf(period, params.Type), params.ID) {
+				continue
+			}
+			periods = append(periods, map[string]interface{}{
+				"id":        period.ID,
+				"date":      date,
+				"startTime": period.StartTime,
+				"endTime":   period.EndTime,
+				"kl":        refs(period.Classes),
+				"te":        refs(period.Teachers),
+				"su":        refs(period.Subjects),
+				"ro":        refs(period.Rooms),
+				"lstype":    period.LsType,
+				"code":      period.Code,
+				"substText": period.SubstText,
+			})
+		}
+	}
+	return periods, nil
+}
+
+// elementsOf returns the ids of the elements of the given type taking part in the period
+func elementsOf(period Period, elementType int) []int {
+	switch elementType {
+	case untis.ClassType:
+		return period.Classes
+	case untis.TeacherType:
+		return period.Teachers
+	case untis.SubjectType:
+		return period.Subjects
+	case untis.RoomType:
+		return period.Rooms
+	}
+	return nil
+}
+
+// parseDate reads a date given as number or string in the format yyyymmdd
+func parseDate(value interface{}) (time.Time, error) {
+	var text string
+	switch v := value.(type) {
+	case string:
+		text = v
+	case float64:
+		text = strconv.Itoa(int(v))
+	default:
+		return time.Time{}, fmt.Errorf("invalid date: %v", value)
+	}
+	return time.Parse("20060102", text)
+}
+
+// refs converts ids into the references used in timetables
+func refs(ids []int) []map[string]int {
+	converted := make([]map[string]int, 0, len(ids))
+	for _, id := range ids {
+		converted = append(converted, map[string]int{"id": id})
+	}
+	return converted
+}
+
+// contains checks whether the id is one of the ids
+func contains(ids []int, id int) bool {
+	for _, i := range ids {
+		if i == id {
+			return true
+		}
+	}
+	return false
+}
+
+// nonNil returns an empty list instead of nil, so it is encoded as empty json array
+func nonNil(elements []untis.Element) []untis.Element {
+	if elements == nil {
+		return make([]untis.Element, 0)
+	}
+	return elements
+}